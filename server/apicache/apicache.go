@@ -0,0 +1,194 @@
+// Package apicache caches encoded API responses until the data they were
+// built from changes.
+//
+// Every entry is tagged with the data domains it reads. Each domain has a
+// generation counter that its producer bumps when it is done writing: the
+// GitHub syncer after a pass, the on-chain syncer after each of its passes,
+// the teams config loader on reload, report generation when it stores a
+// report. Bumping a domain makes every entry tagged with it unreachable, so
+// nothing is recomputed while the data stays the same and nothing stale is
+// served after it changes. Responses that also depend on the current time
+// (a period ending now, an age) are tagged with Clock, which moves on by
+// itself every ClockStep.
+//
+// Responses also carry an ETag derived from their body; a request whose
+// If-None-Match matches gets a 304 instead of the body.
+package apicache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/dgraph-io/ristretto"
+
+	"github.com/samouraiworld/topofgnomes/server/handler/apierror"
+)
+
+// Domain names a family of data with a single producer.
+type Domain string
+
+const (
+	GitHub       Domain = "github"
+	Onchain      Domain = "onchain"
+	TeamsConfig  Domain = "teams-config"
+	TopicsConfig Domain = "topics-config"
+	Reports      Domain = "reports"
+	// Clock has no producer: its generation is the current ClockStep.
+	Clock Domain = "clock"
+)
+
+// ClockStep is how long a response tagged with Clock is served.
+const ClockStep = 5 * time.Minute
+
+// now is replaced in tests.
+var now = time.Now
+
+var domains = []Domain{GitHub, Onchain, TeamsConfig, TopicsConfig, Reports}
+
+// maxAge bounds how long an entry may live. Entries of bumped generations
+// are never read again; this is what eventually frees them when the cache
+// isn't under enough pressure to evict them.
+const maxAge = 24 * time.Hour
+
+// Cache is safe for concurrent use. A nil *Cache caches nothing but still
+// serves ETags.
+type Cache struct {
+	store       *ristretto.Cache
+	generations map[Domain]*atomic.Uint64
+}
+
+type entry struct {
+	body []byte
+	etag string
+}
+
+// New wraps store. Keys it writes are prefixed with the domain
+// generations, so store may be shared with other users.
+func New(store *ristretto.Cache) *Cache {
+	c := &Cache{store: store, generations: make(map[Domain]*atomic.Uint64, len(domains))}
+	for _, d := range domains {
+		c.generations[d] = new(atomic.Uint64)
+	}
+	return c
+}
+
+// Bump invalidates every entry tagged with one of ds.
+func (c *Cache) Bump(ds ...Domain) {
+	if c == nil {
+		return
+	}
+	for _, d := range ds {
+		if g := c.generations[d]; g != nil {
+			g.Add(1)
+		}
+	}
+}
+
+// Generation returns the current generation of d.
+func (c *Cache) Generation(d Domain) uint64 {
+	if c == nil {
+		return 0
+	}
+	if d == Clock {
+		return uint64(now().UnixNano() / int64(ClockStep))
+	}
+	return c.generations[d].Load()
+}
+
+// Wait blocks until every pending write is visible to Serve.
+func (c *Cache) Wait() {
+	if c != nil {
+		c.store.Wait()
+	}
+}
+
+func (c *Cache) key(key string, ds []Domain) string {
+	var b strings.Builder
+	for _, d := range ds {
+		b.WriteString(string(d))
+		b.WriteByte('@')
+		b.WriteString(strconv.FormatUint(c.Generation(d), 10))
+		b.WriteByte(':')
+	}
+	b.WriteString(key)
+	return b.String()
+}
+
+// Serve writes the JSON response cached under key, computing and caching
+// it on a miss. ds lists the domains compute reads from. An error from
+// compute is written with apierror and not cached.
+//
+// The caller sets Content-Type and any other header before calling Serve.
+func (c *Cache) Serve(w http.ResponseWriter, r *http.Request, key string, ds []Domain, compute func() (any, error)) {
+	var e entry
+	var hit bool
+	// The generations are read once, before compute: a Bump landing while
+	// it runs must leave its result under the old, now unreachable, key.
+	var k string
+	if c != nil {
+		k = c.key(key, ds)
+		var v any
+		v, hit = c.store.Get(k)
+		if hit {
+			e = v.(entry)
+		}
+	}
+	if !hit {
+		v, err := compute()
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).Encode(v); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+		e = entry{body: buf.Bytes(), etag: etagOf(buf.Bytes())}
+		if c != nil {
+			ttl := maxAge
+			if slices.Contains(ds, Clock) {
+				ttl = ClockStep
+			}
+			c.store.SetWithTTL(k, e, int64(len(e.body)), ttl)
+		}
+	}
+
+	w.Header().Set("ETag", e.etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if matches(r.Header.Get("If-None-Match"), e.etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	_, _ = w.Write(e.body)
+}
+
+// etagOf is weak: the Compress middleware may re-encode the body.
+func etagOf(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// matches implements the weak comparison of If-None-Match (RFC 9110
+// §13.1.2).
+func matches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	opaque := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == opaque {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+package apicache
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/ristretto"
+)
+
+func newCache(t *testing.T) *Cache {
+	t.Helper()
+	store, err := ristretto.NewCache(&ristretto.Config{NumCounters: 1000, MaxCost: 1 << 20, BufferItems: 64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(store)
+}
+
+// counter is a compute func returning how many times it ran.
+func counter() func() (any, error) {
+	n := 0
+	return func() (any, error) {
+		n++
+		return map[string]int{"n": n}, nil
+	}
+}
+
+func serve(c *Cache, key string, ds []Domain, compute func() (any, error), ifNoneMatch string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if ifNoneMatch != "" {
+		r.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	rec := httptest.NewRecorder()
+	c.Serve(rec, r, key, ds, compute)
+	return rec
+}
+
+func TestServeCachesUntilBump(t *testing.T) {
+	c := newCache(t)
+	compute := counter()
+	tags := []Domain{GitHub, TeamsConfig}
+
+	first := serve(c, "k", tags, compute, "")
+	c.Wait()
+	if got := serve(c, "k", tags, compute, "").Body.String(); got != first.Body.String() {
+		t.Fatalf("second call recomputed: %s then %s", first.Body, got)
+	}
+
+	// Bumping an unrelated domain keeps the entry.
+	c.Bump(Onchain)
+	if got := serve(c, "k", tags, compute, "").Body.String(); got != first.Body.String() {
+		t.Errorf("onchain bump invalidated a github entry: %s", got)
+	}
+
+	c.Bump(TeamsConfig)
+	if got := serve(c, "k", tags, compute, "").Body.String(); got != "{\"n\":2}\n" {
+		t.Errorf("after bump got %s, want a recomputed body", got)
+	}
+}
+
+func TestServeBumpDuringCompute(t *testing.T) {
+	c := newCache(t)
+	n := 0
+	compute := func() (any, error) {
+		n++
+		if n == 1 {
+			// A sync finishes while the first response is being computed.
+			c.Bump(GitHub)
+		}
+		return map[string]int{"n": n}, nil
+	}
+
+	serve(c, "k", []Domain{GitHub}, compute, "")
+	c.Wait()
+	if got := serve(c, "k", []Domain{GitHub}, compute, "").Body.String(); got != "{\"n\":2}\n" {
+		t.Errorf("after a bump during compute got %s, want a recomputed body", got)
+	}
+}
+
+func TestServeClockMovesOn(t *testing.T) {
+	defer func(orig func() time.Time) { now = orig }(now)
+	at := time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return at }
+	c := newCache(t)
+	compute := counter()
+	ds := []Domain{GitHub, Clock}
+
+	serve(c, "k", ds, compute, "")
+	c.Wait()
+	if rec := serve(c, "k", ds, compute, ""); rec.Body.String() != "{\"n\":1}\n" {
+		t.Errorf("within the step: %s", rec.Body)
+	}
+	at = at.Add(ClockStep)
+	if rec := serve(c, "k", ds, compute, ""); rec.Body.String() != "{\"n\":2}\n" {
+		t.Errorf("a step later: %s", rec.Body)
+	}
+	c.Bump(Clock) // no producer, nothing to do
+}
+
+func TestServeETag(t *testing.T) {
+	c := newCache(t)
+	compute := counter()
+	tags := []Domain{GitHub}
+
+	first := serve(c, "k", tags, compute, "")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" {
+		t.Fatalf("first call: status %d, etag %q", first.Code, etag)
+	}
+	c.Wait()
+
+	for _, inm := range []string{etag, `"other", ` + etag, etag[2:], "*"} {
+		rec := serve(c, "k", tags, compute, inm)
+		if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+			t.Errorf("If-None-Match %s: status %d, body %q", inm, rec.Code, rec.Body)
+		}
+	}
+	if rec := serve(c, "k", tags, compute, `W/"stale"`); rec.Code != http.StatusOK {
+		t.Errorf("stale ETag: status %d", rec.Code)
+	}
+
+	c.Bump(GitHub)
+	rec := serve(c, "k", tags, compute, etag)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Errorf("after bump: status %d, etag %q (was %q)", rec.Code, rec.Header().Get("ETag"), etag)
+	}
+}
+
+func TestServeDoesNotCacheErrors(t *testing.T) {
+	c := newCache(t)
+	calls := 0
+	failing := func() (any, error) {
+		calls++
+		return nil, errors.New("boom")
+	}
+	for i := 0; i < 2; i++ {
+		if rec := serve(c, "k", []Domain{GitHub}, failing, ""); rec.Code != http.StatusInternalServerError {
+			t.Errorf("status %d, want 500", rec.Code)
+		}
+		c.Wait()
+	}
+	if calls != 2 {
+		t.Errorf("compute ran %d times, want 2", calls)
+	}
+}
+
+func TestNilCache(t *testing.T) {
+	var c *Cache
+	compute := counter()
+	first := serve(c, "k", []Domain{GitHub}, compute, "")
+	if first.Code != http.StatusOK || first.Header().Get("ETag") == "" {
+		t.Fatalf("status %d, etag %q", first.Code, first.Header().Get("ETag"))
+	}
+	if got := serve(c, "k", []Domain{GitHub}, compute, "").Body.String(); got != "{\"n\":2}\n" {
+		t.Errorf("nil cache served %s, want a fresh computation", got)
+	}
+	c.Bump(GitHub)
+}
@@ -0,0 +1,163 @@
+// Package configwatch keeps a config file loaded while the server runs. It
+// polls the file's modification time and size, reloads it when either
+// changes, and swaps the new value in only if it loads and validates: a
+// broken edit leaves the previous config serving and is reported through
+// Status and the config_reloads_total metric.
+package configwatch
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/samouraiworld/topofgnomes/server/metrics"
+)
+
+// Status describes the last reload attempts of one watched file.
+type Status struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	// LoadedAt is when the config being served was loaded.
+	LoadedAt time.Time `json:"loadedAt"`
+	// LastError is why the newest version of the file was rejected; empty
+	// once a later version loads.
+	LastError   string     `json:"lastError,omitempty"`
+	LastErrorAt *time.Time `json:"lastErrorAt,omitempty"`
+}
+
+// Reporter is the part of a Watcher the status endpoint needs.
+type Reporter interface {
+	Status() Status
+}
+
+// Watcher holds the current value of one config file. Current is safe to
+// call from any goroutine.
+type Watcher[T any] struct {
+	name string
+	path string
+	load func(path string) (*T, error)
+
+	current atomic.Pointer[T]
+
+	mu       sync.Mutex
+	modTime  time.Time
+	size     int64
+	status   Status
+	onReload []func(*T)
+}
+
+// New loads path with load and fails if that fails: a server must not
+// start without a valid config. name labels logs and metrics.
+func New[T any](name, path string, load func(path string) (*T, error)) (*Watcher[T], error) {
+	w := &Watcher[T]{name: name, path: path, load: load, status: Status{Name: name, Path: path}}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := load(path)
+	if err != nil {
+		return nil, err
+	}
+	w.modTime, w.size = info.ModTime(), info.Size()
+	w.current.Store(cfg)
+	w.status.LoadedAt = time.Now().UTC()
+	return w, nil
+}
+
+// Current returns the config being served.
+func (w *Watcher[T]) Current() *T {
+	return w.current.Load()
+}
+
+// OnReload registers fn to run after each successful reload, with the new
+// config.
+func (w *Watcher[T]) OnReload(fn func(*T)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onReload = append(w.onReload, fn)
+}
+
+// Status reports the last reload attempts.
+func (w *Watcher[T]) Status() Status {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status
+}
+
+// Poll reloads the file if it changed since the last attempt. It reports
+// whether it tried, and the error if that attempt failed. A version that
+// failed to load is not retried until the file changes again.
+func (w *Watcher[T]) Poll() (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	info, err := os.Stat(w.path)
+	if err != nil {
+		// Editors and ConfigMap updates replace the file; it may be briefly
+		// missing. Keep serving and check again next time.
+		return false, err
+	}
+	if info.ModTime().Equal(w.modTime) && info.Size() == w.size {
+		return false, nil
+	}
+	w.modTime, w.size = info.ModTime(), info.Size()
+	return true, w.reloadLocked()
+}
+
+func (w *Watcher[T]) reloadLocked() error {
+	cfg, err := w.load(w.path)
+	metrics.ObserveConfigReload(w.name, err)
+	now := time.Now().UTC()
+	if err != nil {
+		w.status.LastError = err.Error()
+		w.status.LastErrorAt = &now
+		return err
+	}
+	w.current.Store(cfg)
+	w.status.LoadedAt = now
+	w.status.LastError = ""
+	w.status.LastErrorAt = nil
+	for _, fn := range w.onReload {
+		fn(cfg)
+	}
+	return nil
+}
+
+// Run polls every interval until ctx is done, logging each reload.
+func (w *Watcher[T]) Run(ctx context.Context, interval time.Duration, logger *zap.SugaredLogger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		tried, err := w.Poll()
+		switch {
+		case !tried && err != nil:
+			logger.Debugf("%s config %s: %v", w.name, w.path, err)
+		case err != nil:
+			logger.Errorf("%s config %s rejected, keeping the previous one: %v", w.name, w.path, err)
+		case tried:
+			logger.Infof("reloaded %s config from %s", w.name, w.path)
+		}
+	}
+}
+
+// Handler serves the Status of each reporter, in order.
+func Handler(reporters ...Reporter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		out := make([]Status, 0, len(reporters))
+		for _, rep := range reporters {
+			out = append(out, rep.Status())
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(out)
+	}
+}
@@ -0,0 +1,135 @@
+package configwatch
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type config struct{ Value string }
+
+// load accepts any file that doesn't start with "bad".
+func load(path string) (*config, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(string(raw), "bad") {
+		return nil, errors.New("invalid config")
+	}
+	return &config{Value: string(raw)}, nil
+}
+
+// write replaces the file's content and moves its mtime forward, so
+// changes are seen even on filesystems with a coarse mtime.
+func write(t *testing.T, path, content string, step int) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Now().Add(time.Duration(step) * time.Minute)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newWatcher(t *testing.T) (*Watcher[config], string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	write(t, path, "v1", 0)
+	w, err := New("test", path, load)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w, path
+}
+
+func TestNew(t *testing.T) {
+	w, _ := newWatcher(t)
+	if got := w.Current().Value; got != "v1" {
+		t.Errorf("Current = %q, want v1", got)
+	}
+	if st := w.Status(); st.Name != "test" || st.LoadedAt.IsZero() || st.LastError != "" {
+		t.Errorf("Status = %+v", st)
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.yaml")
+	write(t, bad, "bad", 0)
+	if _, err := New("test", bad, load); err == nil {
+		t.Error("New accepted an invalid file")
+	}
+	if _, err := New("test", filepath.Join(t.TempDir(), "missing.yaml"), load); err == nil {
+		t.Error("New accepted a missing file")
+	}
+}
+
+func TestPoll(t *testing.T) {
+	w, path := newWatcher(t)
+	var reloaded []string
+	w.OnReload(func(c *config) { reloaded = append(reloaded, c.Value) })
+
+	if tried, err := w.Poll(); tried || err != nil {
+		t.Fatalf("unchanged file: tried=%v err=%v", tried, err)
+	}
+
+	// A broken edit keeps the previous config.
+	write(t, path, "bad edit", 1)
+	if tried, err := w.Poll(); !tried || err == nil {
+		t.Fatalf("broken edit: tried=%v err=%v", tried, err)
+	}
+	if got := w.Current().Value; got != "v1" {
+		t.Errorf("after a broken edit Current = %q, want v1", got)
+	}
+	if st := w.Status(); st.LastError == "" || st.LastErrorAt == nil {
+		t.Errorf("after a broken edit Status = %+v", st)
+	}
+	// ...and is not retried until the file changes again.
+	if tried, _ := w.Poll(); tried {
+		t.Error("retried an unchanged broken file")
+	}
+
+	write(t, path, "v2", 2)
+	if tried, err := w.Poll(); !tried || err != nil {
+		t.Fatalf("fix: tried=%v err=%v", tried, err)
+	}
+	if got := w.Current().Value; got != "v2" {
+		t.Errorf("after the fix Current = %q, want v2", got)
+	}
+	if st := w.Status(); st.LastError != "" || st.LastErrorAt != nil {
+		t.Errorf("after the fix Status = %+v", st)
+	}
+	if len(reloaded) != 1 || reloaded[0] != "v2" {
+		t.Errorf("OnReload saw %v, want [v2]", reloaded)
+	}
+
+	// A briefly missing file keeps the config too.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if tried, err := w.Poll(); tried || err == nil {
+		t.Errorf("missing file: tried=%v err=%v", tried, err)
+	}
+	if got := w.Current().Value; got != "v2" {
+		t.Errorf("with the file missing Current = %q, want v2", got)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	w, _ := newWatcher(t)
+	rec := httptest.NewRecorder()
+	Handler(w).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config/status", nil))
+
+	var got []Status
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Name != "test" {
+		t.Errorf("got %+v", got)
+	}
+}
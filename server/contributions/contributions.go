@@ -0,0 +1,227 @@
+// Package contributions maintains the daily_contributions rollup
+// (models.DailyContribution) and answers the per-user totals that /stats
+// and the leaderboard webhooks are built on.
+//
+// The rollup counts, per user, repository and UTC day:
+//   - commits, on their commit date;
+//   - merged PRs, on their merge date (team stats);
+//   - opened PRs, and those of them merged since, on their creation date
+//     (/stats, the leaderboards and contributor pages);
+//   - issues, on their creation date;
+//   - reviews of merged PRs by someone other than their author, on the
+//     review date.
+//
+// The syncer calls MarkDirty with every contribution it saves, in the same
+// transaction, RefreshDirty for a repository after each of its passes, and
+// Backfill once at start. Totals is exact to
+// the instant: whole days come from the rollup, the rest of the first day
+// from the source tables.
+package contributions
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	dbpkg "github.com/samouraiworld/topofgnomes/server/db"
+	"github.com/samouraiworld/topofgnomes/server/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// refreshChunk bounds the days rewritten per statement.
+const refreshChunk = 100
+
+// Day formats t as a daily_contributions.day key.
+func Day(t time.Time) string {
+	return t.UTC().Format(time.DateOnly)
+}
+
+// Refresh rewrites the rollup rows of one repository on the given days
+// (Day keys) from its commits, pull requests, issues and reviews, so a
+// sync pass costs what it brought in rather than the repository's history.
+func Refresh(db *gorm.DB, repoID string, days []string) error {
+	days = slices.Compact(slices.Sorted(slices.Values(days)))
+	return db.Transaction(func(tx *gorm.DB) error {
+		for chunk := range slices.Chunk(days, refreshChunk) {
+			ranges := make([]string, len(chunk))
+			args := []any{repoID}
+			for i, day := range chunk {
+				start, err := time.Parse(time.DateOnly, day)
+				if err != nil {
+					return fmt.Errorf("invalid day %q: %w", day, err)
+				}
+				ranges[i] = "(event_at >= ? AND event_at < ?)"
+				args = append(args, start, start.AddDate(0, 0, 1))
+			}
+			if err := tx.Where("repo_id = ? AND day IN ?", repoID, chunk).Delete(&models.DailyContribution{}).Error; err != nil {
+				return fmt.Errorf("clear daily contributions of %s: %w", repoID, err)
+			}
+			query := insertQuery(tx, "repo_id = ? AND ("+strings.Join(ranges, " OR ")+")")
+			if err := tx.Exec(query, args...).Error; err != nil {
+				return fmt.Errorf("refresh daily contributions of %s: %w", repoID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// MarkDirty records that repoID's contributions at the given instants
+// changed. Call it in the transaction saving them, so the days are refreshed
+// by RefreshDirty even if the pass stops before its rollup step.
+func MarkDirty(tx *gorm.DB, repoID string, at ...time.Time) error {
+	var rows []models.RollupDirtyDay
+	seen := map[string]bool{}
+	for _, t := range at {
+		if t.IsZero() || seen[Day(t)] {
+			continue
+		}
+		seen[Day(t)] = true
+		rows = append(rows, models.RollupDirtyDay{RepoID: repoID, Day: Day(t)})
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error; err != nil {
+		return fmt.Errorf("mark daily contributions of %s dirty: %w", repoID, err)
+	}
+	return nil
+}
+
+// RefreshDirty refreshes the days MarkDirty recorded for repoID and clears
+// them, in one transaction.
+func RefreshDirty(db *gorm.DB, repoID string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var days []string
+		if err := tx.Model(&models.RollupDirtyDay{}).Where("repo_id = ?", repoID).Pluck("day", &days).Error; err != nil {
+			return fmt.Errorf("list dirty days of %s: %w", repoID, err)
+		}
+		if err := Refresh(tx, repoID, days); err != nil {
+			return err
+		}
+		for chunk := range slices.Chunk(days, refreshChunk) {
+			if err := tx.Where("repo_id = ? AND day IN ?", repoID, chunk).Delete(&models.RollupDirtyDay{}).Error; err != nil {
+				return fmt.Errorf("clear dirty days of %s: %w", repoID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// Backfill builds the whole rollup when it is empty, which is the case
+// right after a migration that creates or reshapes it.
+func Backfill(db *gorm.DB) error {
+	err := db.Take(&models.DailyContribution{}).Error
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if err := db.Exec(insertQuery(db, "")).Error; err != nil {
+		return fmt.Errorf("backfill daily contributions: %w", err)
+	}
+	return nil
+}
+
+// eventsQuery lists every counted contribution, one row each, with the
+// instant it counts at.
+const eventsQuery = `SELECT author_id AS user_id, repository_id AS repo_id, created_at AS event_at,
+		1 AS commits, 0 AS prs_merged, 0 AS prs_opened, 0 AS prs_opened_merged, 0 AS issues, 0 AS reviews
+	FROM commits WHERE author_id <> ''
+	UNION ALL
+	SELECT author_id, repository_id, merged_at, 0, 1, 0, 0, 0, 0
+	FROM pull_requests WHERE author_id <> '' AND state = 'MERGED' AND merged_at IS NOT NULL
+	UNION ALL
+	SELECT author_id, repository_id, created_at, 0, 0, 1, CASE WHEN state = 'MERGED' THEN 1 ELSE 0 END, 0, 0
+	FROM pull_requests WHERE author_id <> ''
+	UNION ALL
+	SELECT author_id, repository_id, created_at, 0, 0, 0, 0, 1, 0
+	FROM issues WHERE author_id <> ''
+	UNION ALL
+	SELECT r.author_id, r.repository_id, r.created_at, 0, 0, 0, 0, 0, 1
+	FROM reviews r JOIN pull_requests p ON p.id = r.pull_request_id
+	WHERE r.author_id <> '' AND p.state = 'MERGED' AND p.author_id <> r.author_id`
+
+// countColumns are the rollup's counters, in eventsQuery's order.
+const countColumns = "commits, prs_merged, prs_opened, prs_opened_merged, issues, reviews"
+
+// insertQuery aggregates the events matching where (all of them when
+// empty) into daily_contributions rows.
+func insertQuery(db *gorm.DB, where string) string {
+	if where != "" {
+		where = " WHERE " + where
+	}
+	day := dbpkg.DayExpr(db, "event_at")
+	return `INSERT INTO daily_contributions (user_id, repo_id, day, ` + countColumns + `)
+		SELECT user_id, repo_id, ` + day + `, SUM(commits), SUM(prs_merged), SUM(prs_opened),
+			SUM(prs_opened_merged), SUM(issues), SUM(reviews)
+		FROM (` + eventsQuery + `) AS events` + where + `
+		GROUP BY user_id, repo_id, ` + day
+}
+
+// Filter narrows Totals. Zero values don't filter.
+type Filter struct {
+	Since               time.Time
+	Repositories        []string
+	ExcludeRepositories []string
+	ExcludeLogins       []string // case-insensitive
+}
+
+// Total is one user's summed contributions.
+type Total struct {
+	UserID    string
+	Login     string
+	AvatarUrl string
+	URL       string
+	Name      string
+	Commits   int
+	PRsMerged int `gorm:"column:prs_merged"`
+	// PRsOpened counts the PRs opened in the period, PRsOpenedMerged those
+	// of them merged since.
+	PRsOpened       int `gorm:"column:prs_opened"`
+	PRsOpenedMerged int `gorm:"column:prs_opened_merged"`
+	Issues          int
+	Reviews         int
+}
+
+// Totals sums the rollup per user. Users without a users row (not synced
+// yet) are left out.
+func Totals(db *gorm.DB, f Filter) ([]Total, error) {
+	q := db.Table("daily_contributions dc")
+	if !f.Since.IsZero() {
+		// The rollup from the day after Since, the source tables before.
+		since := f.Since.UTC()
+		next := since.Truncate(24*time.Hour).AddDate(0, 0, 1)
+		q = db.Table(`(SELECT user_id, repo_id, `+countColumns+` FROM daily_contributions WHERE day >= ?
+			UNION ALL
+			SELECT user_id, repo_id, `+countColumns+` FROM (`+eventsQuery+`) AS events
+			WHERE event_at >= ? AND event_at < ?) dc`, Day(next), since, next)
+	}
+	q = q.Select(`u.id AS user_id, u.login, u.avatar_url, u.url, u.name,
+			SUM(dc.commits) AS commits, SUM(dc.prs_merged) AS prs_merged,
+			SUM(dc.prs_opened) AS prs_opened, SUM(dc.prs_opened_merged) AS prs_opened_merged,
+			SUM(dc.issues) AS issues, SUM(dc.reviews) AS reviews`).
+		Joins("JOIN users u ON u.id = dc.user_id").
+		Group("u.id, u.login, u.avatar_url, u.url, u.name")
+	if len(f.Repositories) > 0 {
+		q = q.Where("dc.repo_id IN ?", f.Repositories)
+	}
+	if len(f.ExcludeRepositories) > 0 {
+		q = q.Where("dc.repo_id NOT IN ?", f.ExcludeRepositories)
+	}
+	if len(f.ExcludeLogins) > 0 {
+		lowered := make([]string, len(f.ExcludeLogins))
+		for i, l := range f.ExcludeLogins {
+			lowered[i] = strings.ToLower(l)
+		}
+		q = q.Where("LOWER(u.login) NOT IN ?", lowered)
+	}
+	var out []Total
+	if err := q.Scan(&out).Error; err != nil {
+		return nil, fmt.Errorf("contribution totals: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,254 @@
+package contributions_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/samouraiworld/topofgnomes/server/contributions"
+	"github.com/samouraiworld/topofgnomes/server/db/dbtest"
+	"github.com/samouraiworld/topofgnomes/server/models"
+	"gorm.io/gorm"
+)
+
+var (
+	day1 = time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	day2 = time.Date(2026, 3, 3, 23, 59, 59, 999999999, time.UTC)
+)
+
+func newDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db := dbtest.Open(t, &models.User{}, &models.Commit{}, &models.PullRequest{},
+		&models.Issue{}, &models.Review{}, &models.DailyContribution{}, &models.RollupDirtyDay{})
+	for _, u := range []models.User{{ID: "u-alice", Login: "Alice"}, {ID: "u-bob", Login: "bob"}} {
+		if err := db.Create(&u).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func create(t *testing.T, db *gorm.DB, rows ...any) {
+	t.Helper()
+	for _, r := range rows {
+		if err := db.Create(r).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func rollup(t *testing.T, db *gorm.DB) map[string]models.DailyContribution {
+	t.Helper()
+	var rows []models.DailyContribution
+	if err := db.Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	out := map[string]models.DailyContribution{}
+	for _, r := range rows {
+		out[r.UserID+"|"+r.RepoID+"|"+r.Day] = r
+	}
+	return out
+}
+
+func seed(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	merged := day2
+	create(t, db,
+		&models.Commit{ID: "c1", AuthorID: "u-alice", RepositoryID: "gnolang/gno", CreatedAt: day1},
+		&models.Commit{ID: "c2", AuthorID: "u-alice", RepositoryID: "gnolang/gno", CreatedAt: day1.Add(time.Hour)},
+		&models.Issue{ID: "i1", AuthorID: "u-bob", RepositoryID: "gnolang/gno", CreatedAt: day1},
+		// Counted on its merge day, not its creation day.
+		&models.PullRequest{ID: "p1", AuthorID: "u-alice", RepositoryID: "gnolang/gno", State: "MERGED", CreatedAt: day1, MergedAt: &merged},
+		&models.PullRequest{ID: "p2", AuthorID: "u-alice", RepositoryID: "gnolang/gno", State: "OPEN", CreatedAt: day1},
+		// Only bob's review of alice's merged PR counts.
+		&models.Review{ID: "r1", AuthorID: "u-bob", PullRequestID: "p1", RepositoryID: "gnolang/gno", CreatedAt: day1},
+		&models.Review{ID: "r2", AuthorID: "u-alice", PullRequestID: "p1", RepositoryID: "gnolang/gno", CreatedAt: day1},
+		&models.Review{ID: "r3", AuthorID: "u-bob", PullRequestID: "p2", RepositoryID: "gnolang/gno", CreatedAt: day1},
+		&models.Commit{ID: "c3", AuthorID: "u-bob", RepositoryID: "gnolang/hackerspace", CreatedAt: day2},
+	)
+}
+
+func TestRefresh(t *testing.T) {
+	db := newDB(t)
+	seed(t, db)
+	days := []string{contributions.Day(day1), contributions.Day(day2)}
+	if err := contributions.Refresh(db, "gnolang/gno", days); err != nil {
+		t.Fatal(err)
+	}
+
+	got := rollup(t, db)
+	want := map[string]models.DailyContribution{
+		"u-alice|gnolang/gno|2026-03-02": {Commits: 2, PRsOpened: 2, PRsOpenedMerged: 1},
+		"u-alice|gnolang/gno|2026-03-03": {PRsMerged: 1},
+		"u-bob|gnolang/gno|2026-03-02":   {Issues: 1, Reviews: 1},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("rollup has %d rows, want %d: %+v", len(got), len(want), got)
+	}
+	for k, w := range want {
+		g, ok := got[k]
+		if !ok {
+			t.Errorf("missing row %s", k)
+			continue
+		}
+		g.UserID, g.RepoID, g.Day = "", "", ""
+		if g != w {
+			t.Errorf("%s = %+v, want %+v", k, g, w)
+		}
+	}
+
+	// Refreshing again rewrites rather than adds.
+	if err := contributions.Refresh(db, "gnolang/gno", days); err != nil {
+		t.Fatal(err)
+	}
+	if again := rollup(t, db); again["u-alice|gnolang/gno|2026-03-02"].Commits != 2 || len(again) != len(want) {
+		t.Errorf("second refresh changed the rollup: %+v", again)
+	}
+
+	// Only the given days are rewritten.
+	create(t, db,
+		&models.Commit{ID: "c4", AuthorID: "u-alice", RepositoryID: "gnolang/gno", CreatedAt: day1},
+		&models.Commit{ID: "c5", AuthorID: "u-alice", RepositoryID: "gnolang/gno", CreatedAt: day2},
+	)
+	if err := contributions.Refresh(db, "gnolang/gno", []string{contributions.Day(day2), contributions.Day(day2)}); err != nil {
+		t.Fatal(err)
+	}
+	if got := rollup(t, db); got["u-alice|gnolang/gno|2026-03-02"].Commits != 2 || got["u-alice|gnolang/gno|2026-03-03"].Commits != 1 {
+		t.Errorf("after refreshing day 2: %+v", got)
+	}
+
+	// Other repositories are left alone until refreshed themselves.
+	if err := contributions.Refresh(db, "gnolang/hackerspace", days); err != nil {
+		t.Fatal(err)
+	}
+	if got := rollup(t, db); len(got) != len(want)+1 || got["u-bob|gnolang/hackerspace|2026-03-03"].Commits != 1 {
+		t.Errorf("after refreshing hackerspace: %+v", got)
+	}
+	if err := contributions.Refresh(db, "gnolang/gno", []string{"March"}); err == nil {
+		t.Error("refresh with an invalid day: expected an error")
+	}
+}
+
+func TestRefreshDirty(t *testing.T) {
+	db := newDB(t)
+	seed(t, db)
+	// A pass saved day 1's and day 2's contributions, then stopped before
+	// refreshing the rollup.
+	if err := contributions.MarkDirty(db, "gnolang/gno", day1, day1.Add(time.Hour), day2, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := contributions.MarkDirty(db, "gnolang/gno", day2); err != nil {
+		t.Fatal(err)
+	}
+	var dirty []models.RollupDirtyDay
+	if err := db.Order("day").Find(&dirty).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(dirty) != 2 || dirty[0].Day != "2026-03-02" || dirty[1].Day != "2026-03-03" {
+		t.Fatalf("dirty days = %+v, want 2026-03-02 and 2026-03-03", dirty)
+	}
+
+	// The next pass picks them up.
+	if err := contributions.RefreshDirty(db, "gnolang/gno"); err != nil {
+		t.Fatal(err)
+	}
+	if got := rollup(t, db); got["u-alice|gnolang/gno|2026-03-02"].Commits != 2 || got["u-alice|gnolang/gno|2026-03-03"].PRsMerged != 1 {
+		t.Errorf("rollup after RefreshDirty: %+v", got)
+	}
+	var left int64
+	if err := db.Model(&models.RollupDirtyDay{}).Count(&left).Error; err != nil {
+		t.Fatal(err)
+	}
+	if left != 0 {
+		t.Errorf("%d dirty days left after RefreshDirty, want 0", left)
+	}
+
+	// Marks roll back with the rows they came with.
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := contributions.MarkDirty(tx, "gnolang/gno", day1); err != nil {
+			return err
+		}
+		return errors.New("save failed")
+	})
+	if err == nil {
+		t.Fatal("transaction: expected an error")
+	}
+	if err := db.Model(&models.RollupDirtyDay{}).Count(&left).Error; err != nil {
+		t.Fatal(err)
+	}
+	if left != 0 {
+		t.Errorf("%d dirty days after a rolled back save, want 0", left)
+	}
+}
+
+func TestBackfill(t *testing.T) {
+	db := newDB(t)
+	seed(t, db)
+	if err := contributions.Backfill(db); err != nil {
+		t.Fatal(err)
+	}
+	if got := rollup(t, db); len(got) != 4 {
+		t.Fatalf("backfill built %d rows, want 4", len(got))
+	}
+
+	// A non-empty rollup is left as is.
+	create(t, db, &models.Commit{ID: "c4", AuthorID: "u-bob", RepositoryID: "gnolang/gno", CreatedAt: day1})
+	if err := contributions.Backfill(db); err != nil {
+		t.Fatal(err)
+	}
+	if got := rollup(t, db); got["u-bob|gnolang/gno|2026-03-02"].Commits != 0 {
+		t.Errorf("backfill ran over a non-empty rollup: %+v", got)
+	}
+}
+
+func TestTotals(t *testing.T) {
+	db := newDB(t)
+	seed(t, db)
+	if err := contributions.Backfill(db); err != nil {
+		t.Fatal(err)
+	}
+
+	totals := func(f contributions.Filter) map[string]contributions.Total {
+		t.Helper()
+		rows, err := contributions.Totals(db, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := map[string]contributions.Total{}
+		for _, r := range rows {
+			out[r.Login] = r
+		}
+		return out
+	}
+
+	all := totals(contributions.Filter{})
+	if a := all["Alice"]; a.Commits != 2 || a.PRsMerged != 1 || a.UserID != "u-alice" {
+		t.Errorf("Alice = %+v", a)
+	}
+	if b := all["bob"]; b.Commits != 1 || b.Issues != 1 || b.Reviews != 1 {
+		t.Errorf("bob = %+v", b)
+	}
+
+	if a := all["Alice"]; a.PRsOpened != 2 || a.PRsOpenedMerged != 1 {
+		t.Errorf("Alice opened PRs = %+v", a)
+	}
+
+	// Since is exact: day 1's rows after it come from the source tables.
+	since := totals(contributions.Filter{Since: day1.Add(30 * time.Minute)})
+	if a := since["Alice"]; a.Commits != 1 || a.PRsMerged != 1 || a.PRsOpened != 0 {
+		t.Errorf("Alice since day 1 10:30 = %+v", a)
+	}
+	if b := since["bob"]; b.Commits != 1 || b.Issues != 0 || b.Reviews != 0 {
+		t.Errorf("bob since day 1 10:30 = %+v", b)
+	}
+
+	if got := totals(contributions.Filter{Repositories: []string{"gnolang/hackerspace"}}); len(got) != 1 || got["bob"].Commits != 1 {
+		t.Errorf("hackerspace only = %+v", got)
+	}
+	if got := totals(contributions.Filter{ExcludeRepositories: []string{"gnolang/hackerspace"}}); got["bob"].Commits != 0 {
+		t.Errorf("without hackerspace = %+v", got)
+	}
+	if got := totals(contributions.Filter{ExcludeLogins: []string{"ALICE"}}); len(got) != 1 {
+		t.Errorf("excluding ALICE = %+v", got)
+	}
+}
@@ -4,54 +4,90 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/samouraiworld/topofgnomes/server/models"
+	"github.com/samouraiworld/topofgnomes/server/db/migrations"
+	"github.com/samouraiworld/topofgnomes/server/tracing"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
-func InitDB() (*gorm.DB, error) {
-	var err error
+// Dialector picks the storage backend: PostgreSQL when DATABASE_URL is set
+// (a postgres:// URL or a libpq key=value DSN), otherwise the SQLite file at
+// DATABASE_PATH.
+func Dialector() gorm.Dialector {
+	if url := os.Getenv("DATABASE_URL"); url != "" {
+		return postgres.Open(url)
+	}
 	dbPath := os.Getenv("DATABASE_PATH")
 	if dbPath == "" {
-		fmt.Println("DATABASE_PATH environment variable is not set, using default path")
+		zap.S().Info("DATABASE_PATH environment variable is not set, using default path")
 		dbPath = "db/database.db"
 	}
-	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{
+	return sqlite.Open(dbPath)
+}
+
+// Open connects to the configured backend with the settings every caller
+// shares (silent GORM logger, statement tracing) without touching the schema.
+func Open() (*gorm.DB, error) {
+	db, err := gorm.Open(Dialector(), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Silent),
 	})
 	if err != nil {
 		return nil, err
 	}
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
 
-	err = db.AutoMigrate(
-		&models.User{},
-		&models.Commit{},
-		&models.Review{},
-		&models.PullRequest{},
-		&models.Issue{},
-		&models.Milestone{},
-		&models.Repository{},
-		&models.GnoNamespace{},
-		&models.GnoPackage{},
-		&models.GnoProposal{},
-		&models.GnoVote{},
-		&models.File{},
-		&models.Report{},
-		&models.GovDaoMember{},
-		&models.LeaderboardWebhook{},
-		&models.SyncStatus{},
-	)
+// migrate brings the schema up to date, or with MIGRATE_ON_START=false
+// refuses to start on an outdated one so that deployments running several
+// replicas can apply migrations once, beforehand, with `server migrate up`.
+func migrate(db *gorm.DB) error {
+	if os.Getenv("MIGRATE_ON_START") == "false" {
+		pending, err := migrations.Pending(db)
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d schema migrations pending (next: %d %s), run `migrate up`", len(pending), pending[0].Version, pending[0].Name)
+		}
+		return nil
+	}
+	ran, err := migrations.Up(db)
+	for _, m := range ran {
+		zap.S().Infow("applied schema migration", "version", m.Version, "name", m.Name)
+	}
+	return err
+}
+
+func InitDB() (*gorm.DB, error) {
+	db, err := Open()
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+
+	if err := migrate(db); err != nil {
+		return nil, err
 	}
 
-	// Backfill legacy reports written before the prompt_version column existed.
-	// AutoMigrate creates the column with default=1 for new rows, but rows
-	// written under the legacy schema land at 0 — flip those to 1 so the
-	// frontend can rely on the version being present and meaningful.
-	if err := db.Exec("UPDATE reports SET prompt_version = 1 WHERE prompt_version = 0").Error; err != nil {
-		return nil, fmt.Errorf("backfill reports.prompt_version: %w", err)
+	if IsPostgres(db) {
+		zap.S().Warn("PostgreSQL has no full-text index, issue and /search queries fall back to unranked LIKE matching")
+		return db, nil
+	}
+	ftsEnabled, err := EnsureIssuesFTS(db)
+	if err != nil {
+		return nil, err
+	}
+	if ftsEnabled {
+		if _, err := EnsureSearchFTS(db); err != nil {
+			return nil, err
+		}
+	} else {
+		zap.S().Warn("SQLite was built without FTS5 (build tag sqlite_fts5), issue and /search queries fall back to LIKE")
 	}
 
 	return db, nil
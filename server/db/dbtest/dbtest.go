@@ -0,0 +1,98 @@
+// Package dbtest opens throwaway databases for tests.
+//
+// By default every call returns a private in-memory SQLite database. When
+// TEST_DATABASE_URL points at a PostgreSQL server, every call instead gets a
+// fresh schema on that server, dropped when the test ends, so the same
+// suites exercise both backends:
+//
+//	TEST_DATABASE_URL=postgres://postgres@localhost:5432/gnolove_test?sslmode=disable go test ./...
+package dbtest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Open returns an empty database migrated for models.
+func Open(t testing.TB, models ...any) *gorm.DB {
+	t.Helper()
+	var db *gorm.DB
+	if dsn := os.Getenv("TEST_DATABASE_URL"); dsn != "" {
+		db = openPostgres(t, dsn)
+	} else {
+		db = openSQLite(t)
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+func config() *gorm.Config {
+	return &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)}
+}
+
+func openSQLite(t testing.TB) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), config())
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	// Every connection to :memory: gets its own empty database, so keep a
+	// single one for handlers that query concurrently.
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	return db
+}
+
+func openPostgres(t testing.TB, dsn string) *gorm.DB {
+	admin, err := gorm.Open(postgres.Open(dsn), config())
+	if err != nil {
+		t.Fatalf("open postgres: %v", err)
+	}
+	adminDB, _ := admin.DB()
+
+	buf := make([]byte, 6)
+	_, _ = rand.Read(buf)
+	schema := "test_" + hex.EncodeToString(buf)
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+
+	db, err := gorm.Open(postgres.Open(withSearchPath(dsn, schema)), config())
+	if err != nil {
+		t.Fatalf("open postgres schema %s: %v", schema, err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() {
+		_ = sqlDB.Close()
+		if err := admin.Exec("DROP SCHEMA " + schema + " CASCADE").Error; err != nil {
+			t.Logf("drop schema %s: %v", schema, err)
+		}
+		_ = adminDB.Close()
+	})
+	return db
+}
+
+// withSearchPath points every connection of dsn, a URL or a key=value DSN,
+// at schema.
+func withSearchPath(dsn, schema string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		if u, err := url.Parse(dsn); err == nil {
+			q := u.Query()
+			q.Set("search_path", schema)
+			u.RawQuery = q.Encode()
+			return u.String()
+		}
+	}
+	return dsn + " search_path=" + schema
+}
@@ -0,0 +1,18 @@
+package db
+
+import "gorm.io/gorm"
+
+// IsPostgres reports whether db talks to PostgreSQL rather than SQLite.
+func IsPostgres(db *gorm.DB) bool {
+	return db.Dialector.Name() == "postgres"
+}
+
+// DayExpr returns a SQL expression formatting the timestamp column col as
+// its UTC date, "YYYY-MM-DD". col must be a trusted identifier: it is
+// inlined into the query.
+func DayExpr(db *gorm.DB, col string) string {
+	if IsPostgres(db) {
+		return "to_char(" + col + " AT TIME ZONE 'UTC', 'YYYY-MM-DD')"
+	}
+	return "strftime('%Y-%m-%d', " + col + ")"
+}
@@ -0,0 +1,27 @@
+package db_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/samouraiworld/topofgnomes/server/db"
+	"github.com/samouraiworld/topofgnomes/server/db/dbtest"
+	"github.com/samouraiworld/topofgnomes/server/models"
+)
+
+func TestDayExpr(t *testing.T) {
+	conn := dbtest.Open(t, &models.Commit{})
+	// 23:30 in UTC-5 is already the next day in UTC.
+	at := time.Date(2026, 3, 7, 23, 30, 5, 123456789, time.FixedZone("EST", -5*3600))
+	if err := conn.Create(&models.Commit{ID: "c1", CreatedAt: at}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var day string
+	if err := conn.Raw("SELECT " + db.DayExpr(conn, "created_at") + " FROM commits").Scan(&day).Error; err != nil {
+		t.Fatal(err)
+	}
+	if day != "2026-03-08" {
+		t.Errorf("day = %q, want the UTC date 2026-03-08", day)
+	}
+}
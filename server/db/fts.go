@@ -0,0 +1,122 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// IssuesFTSTable is the FTS5 index over issues.title. It's an external
+// content table (no copy of the title is stored) kept in sync by triggers,
+// so the syncer's plain `Save(issue)` calls need no extra bookkeeping.
+const IssuesFTSTable = "issues_fts"
+
+// EnsureIssuesFTS creates the issues FTS5 index and its sync triggers, and
+// rebuilds it from the issues table the first time it's created.
+//
+// FTS5 is a compile-time option of mattn/go-sqlite3 (build tag
+// `sqlite_fts5`). Without it this returns (false, nil) and callers fall back
+// to LIKE matching, which keeps `go test` usable without the tag. The same
+// holds on PostgreSQL: there is no tsvector index yet, so it always gets the
+// LIKE fallback (InitDB warns about it).
+func EnsureIssuesFTS(db *gorm.DB) (bool, error) {
+	if IsPostgres(db) {
+		return false, nil
+	}
+	existed := db.Migrator().HasTable(IssuesFTSTable)
+	err := db.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS ` + IssuesFTSTable + ` USING fts5(title, content='issues', content_rowid='rowid')`).Error
+	if err != nil {
+		if strings.Contains(err.Error(), "no such module") {
+			return false, nil
+		}
+		return false, fmt.Errorf("create %s: %w", IssuesFTSTable, err)
+	}
+
+	triggers := []string{
+		`CREATE TRIGGER IF NOT EXISTS issues_fts_ai AFTER INSERT ON issues BEGIN
+			INSERT INTO issues_fts(rowid, title) VALUES (new.rowid, new.title);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS issues_fts_ad AFTER DELETE ON issues BEGIN
+			INSERT INTO issues_fts(issues_fts, rowid, title) VALUES ('delete', old.rowid, old.title);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS issues_fts_au AFTER UPDATE OF title ON issues BEGIN
+			INSERT INTO issues_fts(issues_fts, rowid, title) VALUES ('delete', old.rowid, old.title);
+			INSERT INTO issues_fts(rowid, title) VALUES (new.rowid, new.title);
+		END`,
+	}
+	for _, t := range triggers {
+		if err := db.Exec(t).Error; err != nil {
+			return false, fmt.Errorf("create %s trigger: %w", IssuesFTSTable, err)
+		}
+	}
+
+	if !existed {
+		if err := db.Exec(`INSERT INTO ` + IssuesFTSTable + `(` + IssuesFTSTable + `) VALUES ('rebuild')`).Error; err != nil {
+			return false, fmt.Errorf("rebuild %s: %w", IssuesFTSTable, err)
+		}
+	}
+	return true, nil
+}
+
+// FTSQuery turns free text into a safe FTS5 MATCH expression: every token
+// is quoted (so `-`, `:` or `"` in titles can't be parsed as operators) and
+// the last one is a prefix match, which makes search-as-you-type work.
+func FTSQuery(text string) string {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return ""
+	}
+	parts := make([]string, len(fields))
+	for i, f := range fields {
+		parts[i] = `"` + strings.ReplaceAll(f, `"`, `""`) + `"`
+	}
+	parts[len(parts)-1] += "*"
+	return strings.Join(parts, " ")
+}
+
+// SearchFTSTable is the FTS5 index behind /search. Its content table is
+// search_documents (models.SearchDocument); triggers mirror every upsert
+// the syncers make there, exactly like issues_fts does for issues.
+const SearchFTSTable = "search_index"
+
+// EnsureSearchFTS creates the unified search index and its triggers. Same
+// contract as EnsureIssuesFTS: (false, nil) when FTS5 isn't available.
+func EnsureSearchFTS(db *gorm.DB) (bool, error) {
+	if IsPostgres(db) {
+		return false, nil
+	}
+	existed := db.Migrator().HasTable(SearchFTSTable)
+	err := db.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS ` + SearchFTSTable + ` USING fts5(title, body, content='search_documents', content_rowid='id', tokenize='porter unicode61')`).Error
+	if err != nil {
+		if strings.Contains(err.Error(), "no such module") {
+			return false, nil
+		}
+		return false, fmt.Errorf("create %s: %w", SearchFTSTable, err)
+	}
+
+	triggers := []string{
+		`CREATE TRIGGER IF NOT EXISTS search_index_ai AFTER INSERT ON search_documents BEGIN
+			INSERT INTO search_index(rowid, title, body) VALUES (new.id, new.title, new.body);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS search_index_ad AFTER DELETE ON search_documents BEGIN
+			INSERT INTO search_index(search_index, rowid, title, body) VALUES ('delete', old.id, old.title, old.body);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS search_index_au AFTER UPDATE ON search_documents BEGIN
+			INSERT INTO search_index(search_index, rowid, title, body) VALUES ('delete', old.id, old.title, old.body);
+			INSERT INTO search_index(rowid, title, body) VALUES (new.id, new.title, new.body);
+		END`,
+	}
+	for _, t := range triggers {
+		if err := db.Exec(t).Error; err != nil {
+			return false, fmt.Errorf("create %s trigger: %w", SearchFTSTable, err)
+		}
+	}
+
+	if !existed {
+		if err := db.Exec(`INSERT INTO ` + SearchFTSTable + `(` + SearchFTSTable + `) VALUES ('rebuild')`).Error; err != nil {
+			return false, fmt.Errorf("rebuild %s: %w", SearchFTSTable, err)
+		}
+	}
+	return true, nil
+}
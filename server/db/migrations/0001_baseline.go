@@ -0,0 +1,292 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// baseline is the schema AutoMigrate produced before migrations existed.
+// On databases created back then it finds every table in place and only
+// adds what is missing; on empty ones it creates them.
+//
+// The structs below are frozen copies of the models at that point, minus
+// the relation fields: GORM would turn those into foreign keys, which
+// SQLite never enforced and which PostgreSQL would, rejecting the PRs and
+// issues the syncer stores before their authors.
+var baseline = Migration{
+	Version: 1,
+	Name:    "baseline",
+	Up: func(tx *gorm.DB) error {
+		return tx.Migrator().AutoMigrate(baselineTables...)
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(baselineTables...)
+	},
+}
+
+var baselineTables = []any{
+	&user0001{},
+	&commit0001{},
+	&review0001{},
+	&pullRequest0001{},
+	&issue0001{},
+	&label0001{},
+	&issueLabel0001{},
+	&assignee0001{},
+	&issueAssignee0001{},
+	&milestone0001{},
+	&repository0001{},
+	&gnoNamespace0001{},
+	&gnoPackage0001{},
+	&gnoProposal0001{},
+	&gnoVote0001{},
+	&file0001{},
+	&report0001{},
+	&govDaoMember0001{},
+	&leaderboardWebhook0001{},
+	&syncStatus0001{},
+	&searchDocument0001{},
+}
+
+type user0001 struct {
+	TopRepositories string `gorm:"column:top_repositories;type:text"`
+	Login           string
+	ID              string `gorm:"primarykey"`
+	AvatarUrl       string
+	URL             string
+	Name            string
+	Wallet          string
+	Bio             string
+	Location        string
+	JoinDate        time.Time
+	WebsiteUrl      string
+	TwitterUsername string
+	TotalStars      int
+	TotalRepos      int
+	Followers       int
+	Following       int
+	DetailsSyncedAt time.Time `gorm:"index"`
+}
+
+func (user0001) TableName() string { return "users" }
+
+type commit0001 struct {
+	ID           string    `gorm:"primaryKey"`
+	CreatedAt    time.Time `gorm:"index:idx_commits_author_created,priority:2"`
+	UpdatedAt    time.Time
+	AuthorID     string `gorm:"index;index:idx_commits_author_created,priority:1"`
+	Title        string
+	URL          string
+	RepositoryID string `gorm:"index"`
+}
+
+func (commit0001) TableName() string { return "commits" }
+
+type review0001 struct {
+	ID            string `gorm:"primaryKey"`
+	RepositoryID  string `gorm:"index"`
+	AuthorID      string `gorm:"index"`
+	PullRequestID string
+	CreatedAt     time.Time
+}
+
+func (review0001) TableName() string { return "reviews" }
+
+type pullRequest0001 struct {
+	CreatedAt        time.Time `gorm:"index:idx_pull_requests_author_created,priority:2"`
+	UpdatedAt        time.Time
+	ID               string
+	RepositoryID     string `gorm:"index"`
+	Number           int
+	State            string
+	Title            string
+	AuthorID         string `gorm:"index;index:idx_pull_requests_author_created,priority:1"`
+	MilestoneID      string `gorm:"index"`
+	URL              string
+	ReviewDecision   string
+	Mergeable        string
+	MergeStateStatus string
+	MergedAt         *time.Time
+	IsDraft          bool
+}
+
+func (pullRequest0001) TableName() string { return "pull_requests" }
+
+type issue0001 struct {
+	CreatedAt    time.Time `gorm:"index:idx_issues_author_created,priority:2"`
+	UpdatedAt    time.Time
+	ID           string
+	RepositoryID string `gorm:"index"`
+	Number       int
+	State        string
+	Title        string
+	AuthorID     string `gorm:"index;index:idx_issues_author_created,priority:1"`
+	MilestoneID  string `gorm:"index"`
+	URL          string
+	ClosedAt     *time.Time
+}
+
+func (issue0001) TableName() string { return "issues" }
+
+type label0001 struct {
+	ID    uint `gorm:"primaryKey"`
+	Name  string
+	Color string
+}
+
+func (label0001) TableName() string { return "labels" }
+
+type issueLabel0001 struct {
+	IssueID string `gorm:"primaryKey"`
+	LabelID uint   `gorm:"primaryKey"`
+}
+
+func (issueLabel0001) TableName() string { return "issue_labels" }
+
+type assignee0001 struct {
+	ID      uint `gorm:"primaryKey"`
+	UserID  string
+	IssueID string
+}
+
+func (assignee0001) TableName() string { return "assignees" }
+
+type issueAssignee0001 struct {
+	IssueID    string `gorm:"primaryKey"`
+	AssigneeID uint   `gorm:"primaryKey"`
+}
+
+func (issueAssignee0001) TableName() string { return "issue_assignees" }
+
+type milestone0001 struct {
+	ID           string `gorm:"primaryKey"`
+	RepositoryID string `gorm:"index"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Number       int
+	Title        string
+	State        string
+	AuthorID     string
+	Description  string
+	Url          string
+	DueOn        *time.Time
+	ClosedAt     *time.Time
+}
+
+func (milestone0001) TableName() string { return "milestones" }
+
+type repository0001 struct {
+	ID         string
+	Name       string
+	Owner      string
+	BaseBranch string
+}
+
+func (repository0001) TableName() string { return "repositories" }
+
+type gnoNamespace0001 struct {
+	Hash        string `gorm:"primaryKey"`
+	Namespace   string `gorm:"primaryKey,index"`
+	Address     string
+	BlockHeight int64
+}
+
+func (gnoNamespace0001) TableName() string { return "gno_namespaces" }
+
+type gnoPackage0001 struct {
+	Publisher   string `gorm:"primaryKey"`
+	Path        string `gorm:"primaryKey"`
+	Namespace   string `gorm:"index"`
+	BlockHeight int64
+}
+
+func (gnoPackage0001) TableName() string { return "gno_packages" }
+
+type gnoProposal0001 struct {
+	ID              string `gorm:"primaryKey"`
+	Title           string
+	Description     string
+	Address         string `gorm:"index"`
+	Path            string `gorm:"index"`
+	BlockHeight     int64  `gorm:"index"`
+	ExecutionHeight int64
+	Status          string
+}
+
+func (gnoProposal0001) TableName() string { return "gno_proposals" }
+
+type gnoVote0001 struct {
+	ProposalID  string `gorm:"primaryKey,index"`
+	Address     string `gorm:"primaryKey,index"`
+	BlockHeight int64  `gorm:"index"`
+	Vote        string
+	Hash        string `gorm:"primaryKey"`
+}
+
+func (gnoVote0001) TableName() string { return "gno_votes" }
+
+type file0001 struct {
+	ID            string `gorm:"primaryKey"`
+	Name          string
+	Body          string
+	GnoProposalID string
+}
+
+func (file0001) TableName() string { return "files" }
+
+type report0001 struct {
+	ID            string `gorm:"primaryKey"`
+	CreatedAt     time.Time
+	Data          string `gorm:"type:json"`
+	UserPrompt    string `gorm:"type:text"`
+	PromptVersion int    `gorm:"default:1;not null"`
+}
+
+func (report0001) TableName() string { return "reports" }
+
+type govDaoMember0001 struct {
+	Address string `gorm:"primaryKey"`
+	Tier    string
+}
+
+func (govDaoMember0001) TableName() string { return "gov_dao_members" }
+
+type leaderboardWebhook0001 struct {
+	ID           uint `gorm:"primarykey;autoIncrement"`
+	Url          string
+	UserID       string
+	Type         string    `gorm:"column:type;not null;check:type IN ('discord','slack');default:'discord'"`
+	Frequency    string    `gorm:"column:frequency;not null;check:frequency IN ('daily','weekly');default:'weekly'"`
+	Day          int       `gorm:"column:day;not null;check:day >= 0 AND day <= 6;default:4"`
+	Hour         int       `gorm:"column:hour;not null;default:15"`
+	Minute       int       `gorm:"column:minute;not null;default:0"`
+	Timezone     string    `gorm:"column:timezone;not null;default:'Europe/Paris'"`
+	Repositories string    `gorm:"column:repositories;type:text"`
+	Active       bool      `gorm:"column:active;not null;default:true"`
+	NextRunAt    time.Time `gorm:"column:next_run_at;"`
+	CreatedAt    time.Time `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt    time.Time `gorm:"column:updated_at;autoUpdateTime"`
+}
+
+func (leaderboardWebhook0001) TableName() string { return "leaderboard_webhooks" }
+
+type syncStatus0001 struct {
+	ID           uint `gorm:"primaryKey"`
+	LastSyncedAt time.Time
+}
+
+func (syncStatus0001) TableName() string { return "sync_statuses" }
+
+type searchDocument0001 struct {
+	ID           uint   `gorm:"primaryKey;autoIncrement"`
+	Kind         string `gorm:"uniqueIndex:idx_search_documents_kind_ref;not null"`
+	RefID        string `gorm:"uniqueIndex:idx_search_documents_kind_ref;not null"`
+	RepositoryID string `gorm:"index"`
+	Title        string
+	Body         string
+	URL          string
+	CreatedAt    *time.Time
+}
+
+func (searchDocument0001) TableName() string { return "search_documents" }
@@ -0,0 +1,17 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// backfillReportPromptVersion flips reports written before the
+// prompt_version column existed to version 1. The column defaults to 1
+// for new rows, but rows from the legacy schema landed at 0 and the
+// frontend relies on the version being meaningful.
+var backfillReportPromptVersion = Migration{
+	Version: 2,
+	Name:    "backfill_report_prompt_version",
+	Up: func(tx *gorm.DB) error {
+		return tx.Exec("UPDATE reports SET prompt_version = 1 WHERE prompt_version = 0").Error
+	},
+	// Version 1 is right for those rows either way.
+	Down: func(tx *gorm.DB) error { return nil },
+}
@@ -0,0 +1,28 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// dailyContributions creates the rollup behind /stats, the leaderboards
+// and team stats. It starts empty; the syncer fills it on its next start.
+var dailyContributions = Migration{
+	Version: 3,
+	Name:    "daily_contributions",
+	Up: func(tx *gorm.DB) error {
+		return tx.Migrator().CreateTable(&dailyContribution0003{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&dailyContribution0003{})
+	},
+}
+
+type dailyContribution0003 struct {
+	UserID    string `gorm:"primaryKey"`
+	RepoID    string `gorm:"primaryKey;index:idx_daily_contributions_repo_day,priority:1"`
+	Day       string `gorm:"primaryKey;size:10;index:idx_daily_contributions_repo_day,priority:2;index"`
+	Commits   int    `gorm:"not null;default:0"`
+	PRsMerged int    `gorm:"column:prs_merged;not null;default:0"`
+	Issues    int    `gorm:"not null;default:0"`
+	Reviews   int    `gorm:"not null;default:0"`
+}
+
+func (dailyContribution0003) TableName() string { return "daily_contributions" }
@@ -0,0 +1,53 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// teams creates the database-backed roster and its audit log. They stay
+// empty unless TEAMS_SOURCE=db, which seeds them from teams.yaml.
+var teams = Migration{
+	Version: 4,
+	Name:    "teams",
+	Up: func(tx *gorm.DB) error {
+		return tx.Migrator().CreateTable(&team0004{}, &teamMembership0004{}, &teamAuditEvent0004{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&teamAuditEvent0004{}, &teamMembership0004{}, &team0004{})
+	},
+}
+
+type team0004 struct {
+	ID          uint   `gorm:"primarykey;autoIncrement"`
+	Slug        string `gorm:"uniqueIndex;not null"`
+	Name        string `gorm:"not null"`
+	Color       string `gorm:"not null"`
+	Description string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (team0004) TableName() string { return "teams" }
+
+type teamMembership0004 struct {
+	ID       uint   `gorm:"primarykey;autoIncrement"`
+	TeamID   uint   `gorm:"index;not null"`
+	Login    string `gorm:"index;not null"`
+	JoinedAt *time.Time
+	LeftAt   *time.Time
+}
+
+func (teamMembership0004) TableName() string { return "team_memberships" }
+
+type teamAuditEvent0004 struct {
+	ID     uint      `gorm:"primarykey;autoIncrement"`
+	At     time.Time `gorm:"index;not null"`
+	Actor  string    `gorm:"not null"`
+	Action string    `gorm:"not null"`
+	Team   string    `gorm:"index"`
+	Detail string    `gorm:"type:text"`
+}
+
+func (teamAuditEvent0004) TableName() string { return "team_audit_events" }
@@ -0,0 +1,39 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// pullRequestTopic adds the classified topic to pull requests. It starts
+// empty; the syncer classifies every PR on its next start.
+var pullRequestTopic = Migration{
+	Version: 5,
+	Name:    "pull_request_topic",
+	Up: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		if !m.HasColumn(&pullRequest0005{}, "Topic") {
+			if err := m.AddColumn(&pullRequest0005{}, "Topic"); err != nil {
+				return err
+			}
+		}
+		if !m.HasIndex(&pullRequest0005{}, "Topic") {
+			return m.CreateIndex(&pullRequest0005{}, "Topic")
+		}
+		return nil
+	},
+	Down: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		// SQLite loses the index when a later Down rebuilds the table.
+		if m.HasIndex(&pullRequest0005{}, "Topic") {
+			if err := m.DropIndex(&pullRequest0005{}, "Topic"); err != nil {
+				return err
+			}
+		}
+		return m.DropColumn(&pullRequest0005{}, "Topic")
+	},
+}
+
+// pullRequest0005 is the part of pull_requests this migration touches.
+type pullRequest0005 struct {
+	Topic string `gorm:"index"`
+}
+
+func (pullRequest0005) TableName() string { return "pull_requests" }
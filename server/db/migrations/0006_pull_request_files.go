@@ -0,0 +1,77 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// pullRequestFiles stores the files and labels of pull requests, which the
+// topic rules of schemaVersion 2 match on, and every topic a PR matches
+// rather than only the best one. PRs already synced get their files on
+// the syncer's next passes.
+var pullRequestFiles = Migration{
+	Version: 6,
+	Name:    "pull_request_files",
+	Up: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		for _, col := range []string{"Labels", "FilesSynced"} {
+			if !m.HasColumn(&pullRequest0006{}, col) {
+				if err := m.AddColumn(&pullRequest0006{}, col); err != nil {
+					return err
+				}
+			}
+		}
+		if !m.HasIndex(&pullRequest0006{}, "FilesSynced") {
+			if err := m.CreateIndex(&pullRequest0006{}, "FilesSynced"); err != nil {
+				return err
+			}
+		}
+		for _, table := range []any{&pullRequestFile0006{}, &pullRequestTopic0006{}} {
+			if !m.HasTable(table) {
+				if err := m.CreateTable(table); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	},
+	Down: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		if err := m.DropTable(&pullRequestTopic0006{}, &pullRequestFile0006{}); err != nil {
+			return err
+		}
+		if m.HasIndex(&pullRequest0006{}, "FilesSynced") {
+			if err := m.DropIndex(&pullRequest0006{}, "FilesSynced"); err != nil {
+				return err
+			}
+		}
+		if err := m.DropColumn(&pullRequest0006{}, "FilesSynced"); err != nil {
+			return err
+		}
+		return m.DropColumn(&pullRequest0006{}, "Labels")
+	},
+}
+
+// pullRequest0006 is the part of pull_requests this migration touches.
+type pullRequest0006 struct {
+	Labels      string `gorm:"type:text"`
+	FilesSynced bool   `gorm:"index"`
+}
+
+func (pullRequest0006) TableName() string { return "pull_requests" }
+
+type pullRequestFile0006 struct {
+	PullRequestID string `gorm:"primaryKey"`
+	Path          string `gorm:"primaryKey"`
+	Additions     int
+	Deletions     int
+}
+
+func (pullRequestFile0006) TableName() string { return "pull_request_files" }
+
+type pullRequestTopic0006 struct {
+	PullRequestID string `gorm:"primaryKey"`
+	Topic         string `gorm:"primaryKey;index"`
+	Rank          int
+	Score         float64
+	Confidence    float64
+}
+
+func (pullRequestTopic0006) TableName() string { return "pull_request_topics" }
@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// repositoryRelease adds the latest release to repositories. It stays empty
+// until the syncer's next pass.
+var repositoryRelease = Migration{
+	Version: 7,
+	Name:    "repository_release",
+	Up: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		for _, col := range []string{"LatestReleaseTag", "LatestReleaseAt"} {
+			if !m.HasColumn(&repository0007{}, col) {
+				if err := m.AddColumn(&repository0007{}, col); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	},
+	Down: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		if err := m.DropColumn(&repository0007{}, "LatestReleaseAt"); err != nil {
+			return err
+		}
+		return m.DropColumn(&repository0007{}, "LatestReleaseTag")
+	},
+}
+
+// repository0007 is the part of repositories this migration touches.
+type repository0007 struct {
+	LatestReleaseTag string
+	LatestReleaseAt  *time.Time
+}
+
+func (repository0007) TableName() string { return "repositories" }
@@ -0,0 +1,37 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// dailyContributionsOpenedPRs adds the PRs opened per day to the rollup and
+// empties it, so the syncer rebuilds it on its next start.
+var dailyContributionsOpenedPRs = Migration{
+	Version: 8,
+	Name:    "daily_contributions_opened_prs",
+	Up: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		for _, col := range []string{"PRsOpened", "PRsOpenedMerged"} {
+			if !m.HasColumn(&dailyContribution0008{}, col) {
+				if err := m.AddColumn(&dailyContribution0008{}, col); err != nil {
+					return err
+				}
+			}
+		}
+		return tx.Where("1 = 1").Delete(&dailyContribution0008{}).Error
+	},
+	Down: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		if err := m.DropColumn(&dailyContribution0008{}, "PRsOpenedMerged"); err != nil {
+			return err
+		}
+		return m.DropColumn(&dailyContribution0008{}, "PRsOpened")
+	},
+}
+
+// dailyContribution0008 is the part of daily_contributions this migration
+// touches.
+type dailyContribution0008 struct {
+	PRsOpened       int `gorm:"column:prs_opened;not null;default:0"`
+	PRsOpenedMerged int `gorm:"column:prs_opened_merged;not null;default:0"`
+}
+
+func (dailyContribution0008) TableName() string { return "daily_contributions" }
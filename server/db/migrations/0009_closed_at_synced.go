@@ -0,0 +1,50 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// closedAtSynced records, per repository, whether the full issue and
+// milestone passes that fill closed_at have run. Repositories with no
+// closed row missing it are marked already; the others get one full pass
+// instead of one every sync for as long as a deleted or transferred issue
+// keeps a NULL closed_at.
+var closedAtSynced = Migration{
+	Version: 9,
+	Name:    "closed_at_synced",
+	Up: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		for _, col := range []string{"IssuesClosedAtSynced", "MilestonesClosedAtSynced"} {
+			if !m.HasColumn(&repository0009{}, col) {
+				if err := m.AddColumn(&repository0009{}, col); err != nil {
+					return err
+				}
+			}
+		}
+		for column, table := range map[string]string{
+			"issues_closed_at_synced":     "issues",
+			"milestones_closed_at_synced": "milestones",
+		} {
+			err := tx.Exec(`UPDATE repositories SET ` + column + ` = NOT EXISTS (
+				SELECT 1 FROM ` + table + ` t
+				WHERE t.repository_id = repositories.id AND t.state = 'CLOSED' AND t.closed_at IS NULL)`).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Down: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		if err := m.DropColumn(&repository0009{}, "MilestonesClosedAtSynced"); err != nil {
+			return err
+		}
+		return m.DropColumn(&repository0009{}, "IssuesClosedAtSynced")
+	},
+}
+
+// repository0009 is the part of repositories this migration touches.
+type repository0009 struct {
+	IssuesClosedAtSynced     bool
+	MilestonesClosedAtSynced bool
+}
+
+func (repository0009) TableName() string { return "repositories" }
@@ -0,0 +1,24 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// rollupDirtyDays persists the days the syncer still has to refresh in the
+// rollup, which used to live in memory only. It starts empty: the rollup is
+// current as of the last completed pass.
+var rollupDirtyDays = Migration{
+	Version: 10,
+	Name:    "rollup_dirty_days",
+	Up: func(tx *gorm.DB) error {
+		return tx.Migrator().CreateTable(&rollupDirtyDay0010{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&rollupDirtyDay0010{})
+	},
+}
+
+type rollupDirtyDay0010 struct {
+	RepoID string `gorm:"primaryKey"`
+	Day    string `gorm:"primaryKey;size:10"`
+}
+
+func (rollupDirtyDay0010) TableName() string { return "rollup_dirty_days" }
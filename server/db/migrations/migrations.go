@@ -0,0 +1,166 @@
+// Package migrations versions the database schema.
+//
+// Every schema change is a numbered Migration appended to All. Applied
+// versions are recorded in the schema_migrations table, so each migration
+// runs once per database, inside a transaction with its bookkeeping row.
+//
+// Migrations describe the schema with their own frozen struct copies (see
+// 0001_baseline.go) or explicit Migrator calls, never by AutoMigrate-ing
+// the live models: a model change must come with the migration that
+// produces it, and TestMigrationsMatchModels fails until it does.
+package migrations
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration is one schema step. Down may be nil for steps that can't be
+// reverted (data backfills); Rollback refuses to go past them.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// All lists the migrations in the order they apply. Versions are never
+// reused or renumbered once released.
+var All = []Migration{
+	baseline,
+	backfillReportPromptVersion,
+	dailyContributions,
+	teams,
+	pullRequestTopic,
+	pullRequestFiles,
+	repositoryRelease,
+	dailyContributionsOpenedPRs,
+	closedAtSynced,
+	rollupDirtyDays,
+}
+
+// record is a row of schema_migrations.
+type record struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (record) TableName() string { return "schema_migrations" }
+
+// Status is a migration and, when it has been applied, when.
+type Status struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+func applied(db *gorm.DB) (map[int]record, error) {
+	if err := db.AutoMigrate(&record{}); err != nil {
+		return nil, fmt.Errorf("create schema_migrations: %w", err)
+	}
+	var rows []record
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("read schema_migrations: %w", err)
+	}
+	done := make(map[int]record, len(rows))
+	for _, r := range rows {
+		done[r.Version] = r
+	}
+	latest := All[len(All)-1].Version
+	for v := range done {
+		if v > latest {
+			return nil, fmt.Errorf("database is at schema version %d, newer than this build (%d)", v, latest)
+		}
+	}
+	return done, nil
+}
+
+// Pending returns the migrations Up would apply.
+func Pending(db *gorm.DB) ([]Migration, error) {
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, m := range All {
+		if _, ok := done[m.Version]; !ok {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies every pending migration in order and returns them. It stops
+// at the first failure; the failed migration's changes are rolled back
+// and the ones before it stay applied.
+func Up(db *gorm.DB) ([]Migration, error) {
+	pending, err := Pending(db)
+	if err != nil {
+		return nil, err
+	}
+	var ran []Migration
+	for _, m := range pending {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&record{Version: m.Version, Name: m.Name, AppliedAt: time.Now().UTC()}).Error
+		})
+		if err != nil {
+			return ran, fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+		ran = append(ran, m)
+	}
+	return ran, nil
+}
+
+// Rollback reverts the last steps applied migrations, newest first, and
+// returns them.
+func Rollback(db *gorm.DB, steps int) ([]Migration, error) {
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+	var ran []Migration
+	for i := len(All) - 1; i >= 0 && len(ran) < steps; i-- {
+		m := All[i]
+		if _, ok := done[m.Version]; !ok {
+			continue
+		}
+		if m.Down == nil {
+			return ran, fmt.Errorf("migration %d (%s) can't be reverted", m.Version, m.Name)
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&record{Version: m.Version}).Error
+		})
+		if err != nil {
+			return ran, fmt.Errorf("revert migration %d (%s): %w", m.Version, m.Name, err)
+		}
+		ran = append(ran, m)
+	}
+	return ran, nil
+}
+
+// Statuses lists every known migration, in All order, with its applied
+// time.
+func Statuses(db *gorm.DB) ([]Status, error) {
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Status, len(All))
+	for i, m := range All {
+		out[i] = Status{Version: m.Version, Name: m.Name}
+		if r, ok := done[m.Version]; ok {
+			at := r.AppliedAt
+			out[i].AppliedAt = &at
+		}
+	}
+	return out, nil
+}
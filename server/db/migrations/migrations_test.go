@@ -0,0 +1,205 @@
+package migrations_test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/samouraiworld/topofgnomes/server/db/dbtest"
+	"github.com/samouraiworld/topofgnomes/server/db/migrations"
+	"github.com/samouraiworld/topofgnomes/server/models"
+	"gorm.io/gorm"
+)
+
+// legacyModels are the models InitDB AutoMigrated before migrations
+// existed.
+var legacyModels = []any{
+	&models.User{},
+	&models.Commit{},
+	&models.Review{},
+	&models.PullRequest{},
+	&models.Issue{},
+	&models.Label{},
+	&models.Assignee{},
+	&models.Milestone{},
+	&models.Repository{},
+	&models.GnoNamespace{},
+	&models.GnoPackage{},
+	&models.GnoProposal{},
+	&models.GnoVote{},
+	&models.File{},
+	&models.Report{},
+	&models.GovDaoMember{},
+	&models.LeaderboardWebhook{},
+	&models.SyncStatus{},
+	&models.SearchDocument{},
+}
+
+// liveModels are the models the application reads and writes. Add new
+// ones here together with the migration that creates their table.
+var liveModels = append(legacyModels[:len(legacyModels):len(legacyModels)],
+	&models.DailyContribution{},
+	&models.Team{},
+	&models.TeamMembership{},
+	&models.TeamAuditEvent{},
+	&models.PullRequestFile{},
+	&models.PullRequestTopic{},
+	&models.RollupDirtyDay{},
+)
+
+func up(t *testing.T, db *gorm.DB) []migrations.Migration {
+	t.Helper()
+	ran, err := migrations.Up(db)
+	if err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	return ran
+}
+
+func columns(t *testing.T, db *gorm.DB, table string) []string {
+	t.Helper()
+	types, err := db.Migrator().ColumnTypes(table)
+	if err != nil {
+		t.Fatalf("columns of %s: %v", table, err)
+	}
+	names := make([]string, len(types))
+	for i, c := range types {
+		names[i] = c.Name()
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sorted(s []string) []string {
+	out := append([]string(nil), s...)
+	sort.Strings(out)
+	return out
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// TestMigrationsMatchModels applies every migration to an empty database
+// and checks that the result has exactly the tables, columns and indexes
+// the models expect.
+func TestMigrationsMatchModels(t *testing.T) {
+	db := dbtest.Open(t)
+	if ran := up(t, db); len(ran) != len(migrations.All) {
+		t.Fatalf("applied %d of %d migrations", len(ran), len(migrations.All))
+	}
+
+	for _, m := range liveModels {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(m); err != nil {
+			t.Fatal(err)
+		}
+		s := stmt.Schema
+		if !db.Migrator().HasTable(s.Table) {
+			t.Errorf("%s: no table %s", s.Name, s.Table)
+			continue
+		}
+		if got, want := columns(t, db, s.Table), sorted(s.DBNames); !equal(got, want) {
+			t.Errorf("%s: columns %v, model has %v", s.Table, got, want)
+		}
+		for name := range s.ParseIndexes() {
+			if !db.Migrator().HasIndex(m, name) {
+				t.Errorf("%s: missing index %s", s.Table, name)
+			}
+		}
+		for _, rel := range s.Relationships.Relations {
+			if rel.JoinTable == nil {
+				continue
+			}
+			jt := rel.JoinTable
+			if got, want := columns(t, db, jt.Table), sorted(jt.DBNames); !equal(got, want) {
+				t.Errorf("join table %s: columns %v, model has %v", jt.Table, got, want)
+			}
+		}
+	}
+}
+
+func TestRollbackThenUp(t *testing.T) {
+	db := dbtest.Open(t)
+	up(t, db)
+
+	ran, err := migrations.Rollback(db, len(migrations.All))
+	if err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+	if len(ran) != len(migrations.All) || ran[0].Version != migrations.All[len(migrations.All)-1].Version {
+		t.Fatalf("rollback reverted %+v", ran)
+	}
+	if db.Migrator().HasTable("users") {
+		t.Error("users survived reverting the baseline")
+	}
+	statuses, err := migrations.Statuses(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range statuses {
+		if s.AppliedAt != nil {
+			t.Errorf("migration %d still marked applied", s.Version)
+		}
+	}
+
+	if ran := up(t, db); len(ran) != len(migrations.All) {
+		t.Fatalf("re-applied %d of %d migrations", len(ran), len(migrations.All))
+	}
+	if ran := up(t, db); len(ran) != 0 {
+		t.Errorf("second up applied %d migrations", len(ran))
+	}
+}
+
+// TestUpgradesAutoMigratedDatabase covers databases created by AutoMigrate
+// before migrations existed: the baseline adopts their tables and the
+// prompt_version backfill runs.
+func TestUpgradesAutoMigratedDatabase(t *testing.T) {
+	db := dbtest.Open(t, legacyModels...)
+	if err := db.Create(&models.Report{ID: "legacy", Data: "{}"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("UPDATE reports SET prompt_version = 0").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	up(t, db)
+
+	var r models.Report
+	if err := db.First(&r, "id = ?", "legacy").Error; err != nil {
+		t.Fatal(err)
+	}
+	if r.PromptVersion != 1 {
+		t.Errorf("prompt_version = %d, want the backfilled 1", r.PromptVersion)
+	}
+}
+
+func TestRefusesNewerSchema(t *testing.T) {
+	db := dbtest.Open(t)
+	up(t, db)
+	future := migrations.All[len(migrations.All)-1].Version + 1
+	if err := db.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)", future, "from_the_future", "2030-01-01 00:00:00").Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrations.Up(db); err == nil {
+		t.Error("up ran against a schema newer than the build")
+	}
+}
+
+func TestVersionsIncrease(t *testing.T) {
+	for i, m := range migrations.All {
+		if m.Version != i+1 {
+			t.Errorf("All[%d] has version %d, want %d", i, m.Version, i+1)
+		}
+		if m.Name == "" || m.Up == nil {
+			t.Errorf("migration %d is missing a name or Up", m.Version)
+		}
+	}
+}
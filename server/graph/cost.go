@@ -0,0 +1,117 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// DefaultMaxCost bounds a single query. The stats page (leaderboard with
+// per-user PRs) is around 2,500; anything far above that is a crawl.
+const DefaultMaxCost = 5000
+
+// unboundedListSize is the assumed length of lists without a `first`
+// argument (teams, labels, a user's votes...).
+const unboundedListSize = 20
+
+// fieldWeights overrides the per-node weight of fields that run an
+// aggregate rather than a lookup. Keys are Type.field.
+var fieldWeights = map[string]int{
+	"Query.stats": 10,
+	"User.stats":  5,
+}
+
+// costModel prices a query before it runs: each object or list field costs
+// its weight (1 by default) plus its children, times the number of nodes it
+// can return (`first`, or unboundedListSize). Scalars and introspection are
+// free, so the price tracks the rows a query can make us load.
+type costModel struct {
+	schema *ast.Schema
+}
+
+func newCostModel(sdl string) (*costModel, error) {
+	s, err := gqlparser.LoadSchema(&ast.Source{Name: "schema.graphql", Input: sdl})
+	if err != nil {
+		return nil, fmt.Errorf("load schema: %w", err)
+	}
+	return &costModel{schema: s}, nil
+}
+
+// cost returns the price of operationName in query. A query that doesn't
+// validate returns an error; the caller lets graphql-go report it properly.
+func (m *costModel) cost(query, operationName string, variables map[string]any) (int, error) {
+	doc, errs := gqlparser.LoadQuery(m.schema, query)
+	if len(errs) > 0 {
+		return 0, errs
+	}
+	var op *ast.OperationDefinition
+	if operationName == "" && len(doc.Operations) == 1 {
+		op = doc.Operations[0]
+	} else {
+		op = doc.Operations.ForName(operationName)
+	}
+	if op == nil {
+		return 0, fmt.Errorf("unknown operation %q", operationName)
+	}
+	return m.selectionCost(op.SelectionSet, variables), nil
+}
+
+func (m *costModel) selectionCost(set ast.SelectionSet, vars map[string]any) int {
+	total := 0
+	for _, sel := range set {
+		switch s := sel.(type) {
+		case *ast.Field:
+			total += m.fieldCost(s, vars)
+		case *ast.InlineFragment:
+			total += m.selectionCost(s.SelectionSet, vars)
+		case *ast.FragmentSpread:
+			if s.Definition != nil {
+				total += m.selectionCost(s.Definition.SelectionSet, vars)
+			}
+		}
+	}
+	return total
+}
+
+func (m *costModel) fieldCost(f *ast.Field, vars map[string]any) int {
+	if f.Definition == nil || len(f.SelectionSet) == 0 || strings.HasPrefix(f.Name, "__") {
+		return 0
+	}
+	weight := 1
+	if f.ObjectDefinition != nil {
+		if w, ok := fieldWeights[f.ObjectDefinition.Name+"."+f.Name]; ok {
+			weight = w
+		}
+	}
+	nodes := 1
+	if f.Definition.Type.Elem != nil {
+		nodes = unboundedListSize
+		if n, ok := intArg(f, "first", vars); ok {
+			nodes = clampFirst(n)
+		}
+	}
+	return nodes * (weight + m.selectionCost(f.SelectionSet, vars))
+}
+
+// intArg resolves an Int argument from the query, its variables or the
+// schema default.
+func intArg(f *ast.Field, name string, vars map[string]any) (int, bool) {
+	var v any
+	if arg := f.Arguments.ForName(name); arg != nil {
+		v, _ = arg.Value.Value(vars)
+	}
+	if def := f.Definition.Arguments.ForName(name); v == nil && def != nil && def.DefaultValue != nil {
+		v, _ = def.DefaultValue.Value(nil)
+	}
+	switch n := v.(type) {
+	case int64:
+		return int(n), true
+	case int:
+		return n, true
+	case float64:
+		return int(n), true
+	}
+	return 0, false
+}
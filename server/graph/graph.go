@@ -0,0 +1,101 @@
+// Package graph serves the gnolove dataset over GraphQL at /graphql, so a
+// page can fetch users, their PRs, teams and on-chain activity in one round
+// trip instead of composing a dozen REST calls.
+//
+// The schema (schema.graphql) is resolved with graph-gophers/graphql-go.
+// Every request gets its own batch loaders (loaders.go) so nested fields
+// cost one query per level rather than one per row, and queries are priced
+// up front (cost.go) and rejected above the configured limit.
+package graph
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/samouraiworld/topofgnomes/server/teams"
+	"github.com/samouraiworld/topofgnomes/server/topics"
+	"gorm.io/gorm"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+const maxDepth = 10
+
+type request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// Handle serves GET and POST /graphql. maxCost <= 0 uses DefaultMaxCost.
+func Handle(db *gorm.DB, roster teams.Provider, taxonomy topics.Provider, maxCost int) http.HandlerFunc {
+	if maxCost <= 0 {
+		maxCost = DefaultMaxCost
+	}
+	root := &resolver{db: db, teams: roster, topics: taxonomy, now: time.Now}
+	schema := graphql.MustParseSchema(schemaSDL, root, graphql.MaxDepth(maxDepth))
+	costs, err := newCostModel(schemaSDL)
+	if err != nil {
+		panic(err)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		req, err := parseRequest(r)
+		if err != nil {
+			writeErrors(w, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+
+		// Queries that don't validate fall through: graphql-go reports
+		// them with locations, which is more useful than our message.
+		cost, err := costs.cost(req.Query, req.OperationName, req.Variables)
+		if err == nil && cost > maxCost {
+			writeErrors(w, http.StatusBadRequest,
+				fmt.Sprintf("query cost %d exceeds the limit of %d; lower `first` or select fewer nested lists", cost, maxCost),
+				map[string]any{"code": "QUERY_TOO_COSTLY", "cost": cost, "limit": maxCost})
+			return
+		}
+
+		ctx := withLoaders(r.Context(), db)
+		resp := schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+		if err == nil {
+			resp.Extensions = map[string]any{"cost": map[string]int{"requested": cost, "limit": maxCost}}
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
+
+func parseRequest(r *http.Request) (request, error) {
+	var req request
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		req.Query, req.OperationName = q.Get("query"), q.Get("operationName")
+		if v := q.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				return req, fmt.Errorf("invalid variables: %w", err)
+			}
+		}
+	default:
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return req, fmt.Errorf("invalid request body: %w", err)
+		}
+	}
+	if req.Query == "" {
+		return req, fmt.Errorf("missing query")
+	}
+	return req, nil
+}
+
+func writeErrors(w http.ResponseWriter, status int, message string, extensions map[string]any) {
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"errors": []map[string]any{{"message": message, "extensions": extensions}},
+	})
+}
@@ -0,0 +1,245 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/samouraiworld/topofgnomes/server/db/dbtest"
+	"github.com/samouraiworld/topofgnomes/server/models"
+	"github.com/samouraiworld/topofgnomes/server/teams"
+	"github.com/samouraiworld/topofgnomes/server/topics"
+	"gorm.io/gorm"
+)
+
+// newTestDB opens an in-memory database and counts the SQL statements run
+// against it, which is how the tests see N+1 patterns.
+func newTestDB(t *testing.T) (*gorm.DB, *atomic.Int64) {
+	t.Helper()
+	db := dbtest.Open(t, &models.User{}, &models.PullRequest{}, &models.Review{}, &models.Issue{}, &models.Label{},
+		&models.Commit{}, &models.GnoProposal{}, &models.GnoVote{}, &models.GnoPackage{}, &models.Report{})
+	var queries atomic.Int64
+	count := func(tx *gorm.DB) {
+		if !tx.DryRun { // subqueries are rendered with a dry run
+			queries.Add(1)
+		}
+	}
+	_ = db.Callback().Query().After("gorm:query").Register("test:count", count)
+	_ = db.Callback().Row().After("gorm:row").Register("test:count", count)
+	return db, &queries
+}
+
+// seed creates n users, each with two merged PRs in gnolang/gno reviewed by
+// the next user, one labelled issue and one vote on a shared proposal.
+func seed(t *testing.T, db *gorm.DB, n int) {
+	t.Helper()
+	now := time.Now()
+	label := models.Label{Name: "bug", Color: "red"}
+	must(t, db.Create(&label).Error)
+	must(t, db.Create(&models.GnoProposal{ID: "1", Title: "Upgrade", Address: "g1user0"}).Error)
+	for i := range n {
+		id := fmt.Sprintf("u%d", i)
+		must(t, db.Create(&models.User{ID: id, Login: fmt.Sprintf("user%d", i), Wallet: fmt.Sprintf("g1user%d", i)}).Error)
+		for j := range 2 {
+			pr := fmt.Sprintf("pr-%d-%d", i, j)
+			must(t, db.Create(&models.PullRequest{
+				ID: pr, AuthorID: id, RepositoryID: "gnolang/gno", State: "MERGED",
+				Title: fmt.Sprintf("PR %d/%d", i, j), CreatedAt: now.Add(-time.Duration(j) * time.Hour),
+			}).Error)
+			must(t, db.Create(&models.Review{
+				ID: "r-" + pr, PullRequestID: pr, RepositoryID: "gnolang/gno",
+				AuthorID: fmt.Sprintf("u%d", (i+1)%n), CreatedAt: now,
+			}).Error)
+		}
+		must(t, db.Create(&models.Issue{
+			ID: fmt.Sprintf("i%d", i), AuthorID: id, RepositoryID: "gnolang/gno", Title: "Bug", CreatedAt: now,
+			Labels: []models.Label{label},
+		}).Error)
+		must(t, db.Create(&models.GnoVote{ProposalID: "1", Address: fmt.Sprintf("g1user%d", i), Vote: "YES", Hash: id}).Error)
+	}
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+type gqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string         `json:"message"`
+		Extensions map[string]any `json:"extensions"`
+	} `json:"errors"`
+}
+
+func exec(t *testing.T, h http.HandlerFunc, query string, vars map[string]any) (int, gqlResponse) {
+	t.Helper()
+	body, _ := json.Marshal(map[string]any{"query": query, "variables": vars})
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body)))
+	var resp gqlResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal %s: %v", rec.Body.String(), err)
+	}
+	return rec.Code, resp
+}
+
+func newHandler(db *gorm.DB, maxCost int) http.HandlerFunc {
+	cfg := &teams.Config{Teams: []teams.Team{{Slug: "core", Name: "Core", Members: []string{"User0", "user1", "ghost"}}}}
+	return Handle(db, teams.Static(cfg), topics.Static(&topics.Config{}), maxCost)
+}
+
+const nestedQuery = `{
+	users(first: 10) {
+		login
+		pullRequests(first: 5) { title author { login } reviews { author { login } } }
+		issues(first: 5) { labels { name } }
+		votes { vote proposal { title author { login } } }
+		teams { slug members { login } }
+	}
+}`
+
+func TestNestedFieldsBatch(t *testing.T) {
+	counts := map[int]int64{}
+	for _, n := range []int{3, 8} {
+		db, queries := newTestDB(t)
+		seed(t, db, n)
+		h := newHandler(db, 20000)
+		queries.Store(0)
+		code, resp := exec(t, h, nestedQuery, nil)
+		if code != http.StatusOK || len(resp.Errors) > 0 {
+			t.Fatalf("n=%d: status %d, errors %+v", n, code, resp.Errors)
+		}
+		var data struct {
+			Users []struct {
+				Login        string
+				PullRequests []struct {
+					Title   string
+					Author  struct{ Login string }
+					Reviews []struct{ Author struct{ Login string } }
+				}
+				Issues []struct{ Labels []struct{ Name string } }
+				Votes  []struct{ Vote string }
+				Teams  []struct{ Members []struct{ Login string } }
+			}
+		}
+		must(t, json.Unmarshal(resp.Data, &data))
+		if len(data.Users) != n {
+			t.Fatalf("n=%d: got %d users", n, len(data.Users))
+		}
+		u0 := data.Users[0]
+		if len(u0.PullRequests) != 2 || u0.PullRequests[0].Author.Login != "user0" || u0.PullRequests[0].Reviews[0].Author.Login != "user1" {
+			t.Errorf("n=%d: user0 PRs = %+v", n, u0.PullRequests)
+		}
+		if len(u0.Issues) != 1 || len(u0.Issues[0].Labels) != 1 || len(u0.Votes) != 1 {
+			t.Errorf("n=%d: user0 issues/votes = %+v / %+v", n, u0.Issues, u0.Votes)
+		}
+		// Members resolve case-insensitively; "ghost" has no profile.
+		if len(u0.Teams) != 1 || len(u0.Teams[0].Members) != 2 {
+			t.Errorf("n=%d: user0 teams = %+v", n, u0.Teams)
+		}
+		counts[n] = queries.Load()
+	}
+	if counts[3] != counts[8] {
+		t.Errorf("query count grows with the result size: %d queries for 3 users, %d for 8", counts[3], counts[8])
+	}
+}
+
+func TestStats(t *testing.T) {
+	db, _ := newTestDB(t)
+	seed(t, db, 3)
+	must(t, db.Create(&models.PullRequest{ID: "extra", AuthorID: "u2", RepositoryID: "gnolang/gno", State: "MERGED", CreatedAt: time.Now()}).Error)
+	must(t, db.Create(&models.PullRequest{ID: "old", AuthorID: "u2", RepositoryID: "gnolang/gno", State: "MERGED", CreatedAt: time.Now().AddDate(0, -2, 0)}).Error)
+	must(t, db.Create(&models.PullRequest{ID: "elsewhere", AuthorID: "u1", RepositoryID: "other/repo", State: "MERGED", CreatedAt: time.Now()}).Error)
+	h := newHandler(db, 0)
+
+	code, resp := exec(t, h, `query($exclude: [String!]) {
+		stats(period: MONTHLY, exclude: $exclude) { user { login } pullRequests issues reviews score }
+	}`, map[string]any{"exclude": []string{"USER1"}})
+	if code != http.StatusOK || len(resp.Errors) > 0 {
+		t.Fatalf("status %d, errors %+v", code, resp.Errors)
+	}
+	var data struct {
+		Stats []struct {
+			User         struct{ Login string }
+			PullRequests int
+			Issues       int
+			Reviews      int
+			Score        float64
+		}
+	}
+	must(t, json.Unmarshal(resp.Data, &data))
+	if len(data.Stats) != 2 {
+		t.Fatalf("got %d rows, want 2 (user1 excluded): %+v", len(data.Stats), data.Stats)
+	}
+	top := data.Stats[0]
+	// user2: 2 seeded + "extra"; "old" is outside the month.
+	if top.User.Login != "user2" || top.PullRequests != 3 || top.Issues != 1 || top.Reviews != 2 {
+		t.Errorf("top row = %+v", top)
+	}
+	if want := 3*2.0 + 0.5 + 2*2.0; top.Score != want {
+		t.Errorf("score = %v, want %v", top.Score, want)
+	}
+
+	_, resp = exec(t, h, `{ user(login: "USER1") { stats(repositories: ["other/repo"]) { pullRequests } } }`, nil)
+	if !strings.Contains(string(resp.Data), `"pullRequests":1`) {
+		t.Errorf("user1 stats on other/repo = %s %+v", resp.Data, resp.Errors)
+	}
+}
+
+func TestCostLimit(t *testing.T) {
+	db, _ := newTestDB(t)
+	h := newHandler(db, 0)
+
+	code, resp := exec(t, h, `{ users(first: 200) { pullRequests(first: 200) { reviews { author { login } } } } }`, nil)
+	if code != http.StatusBadRequest || len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != "QUERY_TOO_COSTLY" {
+		t.Fatalf("status %d, errors %+v", code, resp.Errors)
+	}
+
+	code, resp = exec(t, h, `{ users(first: 5) { login } }`, nil)
+	if code != http.StatusOK || len(resp.Errors) > 0 {
+		t.Fatalf("cheap query rejected: %d %+v", code, resp.Errors)
+	}
+}
+
+func TestCostModel(t *testing.T) {
+	m, err := newCostModel(schemaSDL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		query string
+		vars  map[string]any
+		want  int
+	}{
+		{"scalars are free", `{ users(first: 10) { login } }`, nil, 10},
+		{"schema default", `{ users { login } }`, nil, 50},
+		{"nested lists multiply", `{ users(first: 10) { pullRequests(first: 5) { title } } }`, nil, 10 * (1 + 5)},
+		{"variables", `query($n: Int) { users(first: $n) { login } }`, map[string]any{"n": float64(7)}, 7},
+		{"first is capped", `{ users(first: 100000) { login } }`, nil, maxFirst},
+		{"weighted field", `{ stats(first: 2) { commits } }`, nil, 2 * 10},
+		{"unbounded list", `{ teams { slug } }`, nil, unboundedListSize},
+		{"fragments", `{ users(first: 2) { ...f } } fragment f on User { votes { vote } }`, nil, 2 * (1 + unboundedListSize)},
+		{"introspection", `{ __schema { types { name } } }`, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := m.cost(tt.query, "", tt.vars)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("cost = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
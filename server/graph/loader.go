@@ -0,0 +1,77 @@
+package graph
+
+import (
+	"context"
+	gosync "sync"
+)
+
+// loader batches lookups by key. List resolvers announce the keys their
+// items will ask for with want; the first load then fetches every wanted
+// key in one query and later loads are served from memory. graphql-go
+// resolves list items concurrently, so load holds fetchMu across the fetch:
+// siblings queue behind the first one and find their key already loaded.
+// want only takes mu, so a fetch can prime other loaders, or this one,
+// without waiting on their fetches.
+//
+// A loader lives for one request (see loaders); nothing is shared between
+// requests, so there is no invalidation.
+type loader[K comparable, V any] struct {
+	fetch func(ctx context.Context, keys []K) (map[K]V, error)
+
+	fetchMu gosync.Mutex
+	mu      gosync.Mutex
+	done    map[K]V
+	wanted  map[K]struct{}
+}
+
+func newLoader[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{fetch: fetch, done: map[K]V{}, wanted: map[K]struct{}{}}
+}
+
+// want queues keys for the next batch.
+func (l *loader[K, V]) want(keys ...K) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, k := range keys {
+		if _, ok := l.done[k]; !ok {
+			l.wanted[k] = struct{}{}
+		}
+	}
+}
+
+// load returns the value for key, fetching it together with every queued
+// key on a miss. Keys the fetch doesn't return load as the zero V.
+func (l *loader[K, V]) load(ctx context.Context, key K) (V, error) {
+	l.fetchMu.Lock()
+	defer l.fetchMu.Unlock()
+
+	l.mu.Lock()
+	if v, ok := l.done[key]; ok {
+		l.mu.Unlock()
+		return v, nil
+	}
+	l.wanted[key] = struct{}{}
+	keys := make([]K, 0, len(l.wanted))
+	for k := range l.wanted {
+		keys = append(keys, k)
+	}
+	clear(l.wanted)
+	l.mu.Unlock()
+
+	got, err := l.fetch(ctx, keys)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err != nil {
+		// Requeue so the next load retries the batch.
+		for _, k := range keys {
+			l.wanted[k] = struct{}{}
+		}
+		var zero V
+		return zero, err
+	}
+	for _, k := range keys {
+		l.done[k] = got[k]
+		delete(l.wanted, k)
+	}
+	return l.done[key], nil
+}
@@ -0,0 +1,276 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	gosync "sync"
+	"time"
+
+	"github.com/samouraiworld/topofgnomes/server/models"
+	"gorm.io/gorm"
+)
+
+// defaultRepository mirrors handler.getRepositoriesWithRequest.
+const defaultRepository = "gnolang/gno"
+
+// filter is the parsed `period` and `repositories` arguments.
+type filter struct {
+	since        time.Time
+	repositories []string
+}
+
+func newFilter(period string, repositories *[]string, now time.Time) filter {
+	f := filter{repositories: []string{defaultRepository}}
+	if repositories != nil && len(*repositories) > 0 {
+		f.repositories = *repositories
+	}
+	switch period {
+	case "DAILY":
+		f.since = now.AddDate(0, 0, -1)
+	case "WEEKLY":
+		f.since = now.AddDate(0, 0, -7)
+	case "MONTHLY":
+		f.since = now.AddDate(0, -1, 0)
+	case "YEARLY":
+		f.since = now.AddDate(-1, 0, 0)
+	}
+	return f
+}
+
+func (f filter) key() string {
+	return fmt.Sprintf("%d:%s", f.since.Unix(), strings.Join(f.repositories, ","))
+}
+
+// apply narrows a query on table to the filter.
+func (f filter) apply(q *gorm.DB, table string) *gorm.DB {
+	q = q.Where(table+".repository_id IN ?", f.repositories)
+	if !f.since.IsZero() {
+		q = q.Where(table+".created_at > ?", f.since)
+	}
+	return q
+}
+
+// loaders holds the request's batch loaders. The per-user connections
+// (User.pullRequests, User.stats...) depend on their arguments, so they are
+// created on first use per argument set and primed with every user the
+// request has surfaced so far: a 100-user leaderboard asking for each
+// user's PRs costs one query, not a hundred.
+type loaders struct {
+	usersByID       *loader[string, *models.User]
+	usersByLogin    *loader[string, *models.User]
+	usersByWallet   *loader[string, *models.User]
+	pullRequests    *loader[string, *models.PullRequest]
+	proposals       *loader[string, *models.GnoProposal]
+	reviewsByPR     *loader[string, []models.Review]
+	labelsByIssue   *loader[string, []models.Label]
+	votesByProposal *loader[string, []models.GnoVote]
+	votesByVoter    *loader[string, []models.GnoVote]
+	packagesByOwner *loader[string, []models.GnoPackage]
+
+	mu        gosync.Mutex
+	seenUsers []string
+	seen      map[string]struct{}
+	scoped    map[string]interface{ want(keys ...string) }
+}
+
+type loadersKey struct{}
+
+func withLoaders(ctx context.Context, db *gorm.DB) context.Context {
+	return context.WithValue(ctx, loadersKey{}, newLoaders(db))
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+func newLoaders(db *gorm.DB) *loaders {
+	l := &loaders{seen: map[string]struct{}{}, scoped: map[string]interface{ want(keys ...string) }{}}
+	l.usersByID = newLoader(byKey(db, "id", func(u *models.User) string { return u.ID }))
+	l.usersByLogin = newLoader(byKey(db, "LOWER(login)", func(u *models.User) string { return strings.ToLower(u.Login) }))
+	l.usersByWallet = newLoader(byKey(db, "wallet", func(u *models.User) string { return u.Wallet }))
+	l.pullRequests = newLoader(byKey(db, "id", func(pr *models.PullRequest) string { return pr.ID }))
+	l.proposals = newLoader(byKey(db, "id", func(p *models.GnoProposal) string { return p.ID }))
+	l.reviewsByPR = newLoader(primed(l.primeReviews, groupBy(db, "pull_request_id", "created_at ASC", func(r models.Review) string { return r.PullRequestID })))
+	l.labelsByIssue = newLoader(labelsByIssue(db))
+	l.votesByProposal = newLoader(primed(l.primeVotes, groupBy(db, "proposal_id", "block_height ASC", func(v models.GnoVote) string { return v.ProposalID })))
+	l.votesByVoter = newLoader(primed(l.primeVotes, groupBy(db, "address", "block_height DESC", func(v models.GnoVote) string { return v.Address })))
+	l.packagesByOwner = newLoader(primed(l.primePackages, groupBy(db, "publisher", "block_height DESC", func(p models.GnoPackage) string { return p.Publisher })))
+	return l
+}
+
+// seeUsers records users the response contains so per-user loaders
+// created later, or already created, batch them.
+func (l *loaders) seeUsers(ids ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fresh := ids[:0:0]
+	for _, id := range ids {
+		if _, ok := l.seen[id]; !ok {
+			l.seen[id] = struct{}{}
+			fresh = append(fresh, id)
+		}
+	}
+	l.seenUsers = append(l.seenUsers, fresh...)
+	for _, s := range l.scoped {
+		s.want(fresh...)
+	}
+}
+
+// The prime* methods queue every key the rows' object fields may ask for.
+// They run when rows are fetched, for the whole batch: when the first of a
+// hundred users resolves its PRs' reviews, the reviews of all hundred users'
+// PRs are already wanted.
+
+func (l *loaders) primeUsers(users []models.User) {
+	ids := make([]string, len(users))
+	for i, u := range users {
+		ids[i] = u.ID
+		if u.Wallet != "" {
+			l.packagesByOwner.want(u.Wallet)
+			l.votesByVoter.want(u.Wallet)
+		}
+	}
+	l.seeUsers(ids...)
+}
+
+func (l *loaders) primePullRequests(prs []models.PullRequest) {
+	for _, pr := range prs {
+		l.usersByID.want(pr.AuthorID)
+		l.reviewsByPR.want(pr.ID)
+	}
+}
+
+func (l *loaders) primeIssues(issues []models.Issue) {
+	for _, i := range issues {
+		l.usersByID.want(i.AuthorID)
+		l.labelsByIssue.want(i.ID)
+	}
+}
+
+func (l *loaders) primeReviews(reviews []models.Review) {
+	for _, r := range reviews {
+		l.usersByID.want(r.AuthorID)
+		l.pullRequests.want(r.PullRequestID)
+	}
+}
+
+func (l *loaders) primeProposals(proposals []models.GnoProposal) {
+	for _, p := range proposals {
+		if p.Address != "" {
+			l.usersByWallet.want(p.Address)
+		}
+		l.votesByProposal.want(p.ID)
+	}
+}
+
+func (l *loaders) primeVotes(votes []models.GnoVote) {
+	for _, v := range votes {
+		l.usersByWallet.want(v.Address)
+		l.proposals.want(v.ProposalID)
+	}
+}
+
+func (l *loaders) primePackages(pkgs []models.GnoPackage) {
+	for _, p := range pkgs {
+		l.usersByWallet.want(p.Publisher)
+	}
+}
+
+// primed wraps a grouped fetch so it primes every row it returns.
+func primed[T any](prime func([]T), fetch func(context.Context, []string) (map[string][]T, error)) func(context.Context, []string) (map[string][]T, error) {
+	return func(ctx context.Context, keys []string) (map[string][]T, error) {
+		out, err := fetch(ctx, keys)
+		for _, rows := range out {
+			prime(rows)
+		}
+		return out, err
+	}
+}
+
+// perUser returns the loader named key, creating it with fetch.
+func perUser[V any](l *loaders, key string, fetch func(ctx context.Context, userIDs []string) (map[string]V, error)) *loader[string, V] {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if s, ok := l.scoped[key]; ok {
+		return s.(*loader[string, V])
+	}
+	ld := newLoader(fetch)
+	ld.want(l.seenUsers...)
+	l.scoped[key] = ld
+	return ld
+}
+
+// byKey fetches rows whose column is one of the keys.
+func byKey[T any](db *gorm.DB, column string, keyOf func(*T) string) func(context.Context, []string) (map[string]*T, error) {
+	return func(ctx context.Context, keys []string) (map[string]*T, error) {
+		var rows []T
+		if err := db.WithContext(ctx).Where(column+" IN ?", keys).Find(&rows).Error; err != nil {
+			return nil, err
+		}
+		out := make(map[string]*T, len(rows))
+		for i := range rows {
+			out[keyOf(&rows[i])] = &rows[i]
+		}
+		return out, nil
+	}
+}
+
+// groupBy fetches every row whose column is one of the keys, grouped.
+func groupBy[T any](db *gorm.DB, column, order string, keyOf func(T) string) func(context.Context, []string) (map[string][]T, error) {
+	return func(ctx context.Context, keys []string) (map[string][]T, error) {
+		var rows []T
+		if err := db.WithContext(ctx).Where(column+" IN ?", keys).Order(order).Find(&rows).Error; err != nil {
+			return nil, err
+		}
+		out := map[string][]T{}
+		for _, r := range rows {
+			out[keyOf(r)] = append(out[keyOf(r)], r)
+		}
+		return out, nil
+	}
+}
+
+// labelsByIssue goes through the issue_labels join table directly, which
+// gorm's many2many Preload can't do for issues loaded by topPerAuthor.
+func labelsByIssue(db *gorm.DB) func(context.Context, []string) (map[string][]models.Label, error) {
+	return func(ctx context.Context, issueIDs []string) (map[string][]models.Label, error) {
+		var rows []struct {
+			models.Label
+			IssueID string
+		}
+		err := db.WithContext(ctx).Table("labels").
+			Select("labels.*, issue_labels.issue_id AS issue_id").
+			Joins("JOIN issue_labels ON issue_labels.label_id = labels.id").
+			Where("issue_labels.issue_id IN ?", issueIDs).
+			Order("labels.name ASC").
+			Scan(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+		out := map[string][]models.Label{}
+		for _, r := range rows {
+			out[r.IssueID] = append(out[r.IssueID], r.Label)
+		}
+		return out, nil
+	}
+}
+
+// topPerAuthor loads the `first` newest rows of q per author in one query,
+// ranking with ROW_NUMBER() so a prolific author can't starve the others.
+func topPerAuthor[T any](ctx context.Context, q *gorm.DB, table string, authorIDs []string, first int, keyOf func(T) string) (map[string][]T, error) {
+	outer := q.Session(&gorm.Session{NewDB: true, Context: ctx})
+	ranked := q.Session(&gorm.Session{}).
+		Select(table+".*, ROW_NUMBER() OVER (PARTITION BY "+table+".author_id ORDER BY "+table+".created_at DESC) AS rn").
+		Where(table+".author_id IN ?", authorIDs)
+	var rows []T
+	err := outer.Table("(?) AS ranked", ranked).Where("rn <= ?", first).Order("created_at DESC").Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	out := map[string][]T{}
+	for _, r := range rows {
+		out[keyOf(r)] = append(out[keyOf(r)], r)
+	}
+	return out, nil
+}
@@ -0,0 +1,45 @@
+package graph
+
+import (
+	"net/http"
+
+	"github.com/samouraiworld/topofgnomes/server/openapi"
+)
+
+// response documents the GraphQL response envelope; the shape of data
+// depends on the query and is described by schema.graphql, not here.
+type response struct {
+	Data   map[string]any `json:"data,omitempty"`
+	Errors []struct {
+		Message    string         `json:"message"`
+		Path       []any          `json:"path,omitempty"`
+		Extensions map[string]any `json:"extensions,omitempty"`
+	} `json:"errors,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+// OpenAPIRoutes describes the routes served by this package.
+func OpenAPIRoutes() []openapi.Route {
+	const description = "Queries are priced before they run (see extensions.cost in the response); " +
+		"queries above the limit are rejected with 400 and errors[0].extensions.code QUERY_TOO_COSTLY."
+	return []openapi.Route{
+		{
+			Method: http.MethodGet, Path: "/graphql", Tag: "graphql",
+			Summary:     "Run a GraphQL query passed in the query string",
+			Description: description,
+			Params: []openapi.Param{
+				{Name: "query", In: "query", Type: "string", Description: "GraphQL document", Required: true},
+				openapi.QueryParam("operationName", "string", "Operation to run when the document has several"),
+				openapi.QueryParam("variables", "string", "JSON-encoded variables"),
+			},
+			Response: response{},
+		},
+		{
+			Method: http.MethodPost, Path: "/graphql", Tag: "graphql",
+			Summary:     "Run a GraphQL query",
+			Description: description,
+			Body:        request{},
+			Response:    response{},
+		},
+	}
+}
@@ -0,0 +1,230 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/samouraiworld/topofgnomes/server/models"
+	"github.com/samouraiworld/topofgnomes/server/teams"
+	"github.com/samouraiworld/topofgnomes/server/topics"
+	"gorm.io/gorm"
+)
+
+// maxFirst caps every `first` argument, like listquery.MaxLimit caps REST
+// pages.
+const maxFirst = 200
+
+func clampFirst(n int) int {
+	return min(max(n, 0), maxFirst)
+}
+
+// resolver is the Query root.
+type resolver struct {
+	db     *gorm.DB
+	teams  teams.Provider
+	topics topics.Provider
+	now    func() time.Time
+}
+
+// Arguments with a schema default are plain values: graphql-go always
+// fills them in.
+type pageArgs struct {
+	First  int32
+	Offset int32
+}
+
+func (a pageArgs) apply(q *gorm.DB) *gorm.DB {
+	return q.Limit(clampFirst(int(a.First))).Offset(max(int(a.Offset), 0))
+}
+
+type filterArgs struct {
+	Period       string
+	Repositories *[]string
+}
+
+func (r *resolver) filter(a filterArgs) filter {
+	return newFilter(a.Period, a.Repositories, r.now())
+}
+
+func (r *resolver) User(ctx context.Context, args struct {
+	Login   *string
+	Address *string
+}) (*userResolver, error) {
+	l := loadersFrom(ctx)
+	switch {
+	case args.Login != nil:
+		return r.loadUser(ctx, l.usersByLogin, strings.ToLower(*args.Login))
+	case args.Address != nil:
+		return r.loadUser(ctx, l.usersByWallet, *args.Address)
+	}
+	return nil, errors.New("login or address is required")
+}
+
+func (r *resolver) Users(ctx context.Context, args pageArgs) ([]*userResolver, error) {
+	var users []models.User
+	if err := args.apply(r.db.WithContext(ctx).Order("login ASC")).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return r.newUsers(ctx, users), nil
+}
+
+func (r *resolver) Stats(ctx context.Context, args struct {
+	filterArgs
+	Exclude *[]string
+	First   int32
+}) ([]*userStatsResolver, error) {
+	stats, err := queryStats(ctx, r.db, r.filter(args.filterArgs), nil)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]*userStats, 0, len(stats))
+	for _, s := range stats {
+		rows = append(rows, s)
+	}
+	slices.SortFunc(rows, func(a, b *userStats) int {
+		if a.total() != b.total() {
+			return b.total() - a.total()
+		}
+		return strings.Compare(a.userID, b.userID)
+	})
+
+	var exclude []string
+	if args.Exclude != nil {
+		for _, login := range *args.Exclude {
+			exclude = append(exclude, strings.ToLower(login))
+		}
+	}
+	first := clampFirst(int(args.First))
+	l := loadersFrom(ctx)
+	for _, s := range rows[:min(len(rows), first+len(exclude))] {
+		l.usersByID.want(s.userID)
+	}
+	out := make([]*userStatsResolver, 0, min(first, len(rows)))
+	for _, s := range rows {
+		if len(out) == first {
+			break
+		}
+		u, err := r.loadUser(ctx, l.usersByID, s.userID)
+		if err != nil {
+			return nil, err
+		}
+		if u == nil || slices.Contains(exclude, strings.ToLower(u.u.Login)) {
+			continue
+		}
+		out = append(out, &userStatsResolver{s: s, user: u})
+	}
+	return out, nil
+}
+
+func (r *resolver) PullRequests(ctx context.Context, args struct {
+	filterArgs
+	pageArgs
+	State *string
+}) ([]*pullRequestResolver, error) {
+	q := r.filter(args.filterArgs).apply(r.db.WithContext(ctx).Model(&models.PullRequest{}), "pull_requests")
+	if args.State != nil {
+		q = q.Where("state = ?", *args.State)
+	}
+	var prs []models.PullRequest
+	if err := args.pageArgs.apply(q.Order("created_at DESC")).Find(&prs).Error; err != nil {
+		return nil, err
+	}
+	return r.newPullRequests(ctx, prs), nil
+}
+
+func (r *resolver) Issues(ctx context.Context, args struct {
+	filterArgs
+	pageArgs
+	State *string
+}) ([]*issueResolver, error) {
+	q := r.filter(args.filterArgs).apply(r.db.WithContext(ctx).Model(&models.Issue{}), "issues")
+	if args.State != nil {
+		q = q.Where("state = ?", *args.State)
+	}
+	var issues []models.Issue
+	if err := args.pageArgs.apply(q.Order("created_at DESC")).Find(&issues).Error; err != nil {
+		return nil, err
+	}
+	return r.newIssues(ctx, issues), nil
+}
+
+func (r *resolver) Repositories(ctx context.Context) ([]*repositoryResolver, error) {
+	var repos []models.Repository
+	if err := r.db.WithContext(ctx).Order("id ASC").Find(&repos).Error; err != nil {
+		return nil, err
+	}
+	out := make([]*repositoryResolver, len(repos))
+	for i := range repos {
+		out[i] = &repositoryResolver{repo: &repos[i]}
+	}
+	return out, nil
+}
+
+func (r *resolver) Teams(ctx context.Context) []*teamResolver {
+	return r.newTeams(ctx, r.teams().Teams)
+}
+
+func (r *resolver) Team(ctx context.Context, args struct{ Slug string }) *teamResolver {
+	t, ok := r.teams().FindBySlug(args.Slug)
+	if !ok {
+		return nil
+	}
+	return r.newTeams(ctx, []teams.Team{t})[0]
+}
+
+func (r *resolver) Topics() []*topicResolver {
+	cfg := r.topics()
+	out := make([]*topicResolver, len(cfg.Topics))
+	for i := range cfg.Topics {
+		out[i] = &topicResolver{t: &cfg.Topics[i]}
+	}
+	return out
+}
+
+func (r *resolver) Proposals(ctx context.Context, args pageArgs) ([]*proposalResolver, error) {
+	var proposals []models.GnoProposal
+	if err := args.apply(r.db.WithContext(ctx).Order("block_height DESC")).Find(&proposals).Error; err != nil {
+		return nil, err
+	}
+	return r.newProposals(ctx, proposals), nil
+}
+
+func (r *resolver) Proposal(ctx context.Context, args struct{ ID graphql.ID }) (*proposalResolver, error) {
+	p, err := loadersFrom(ctx).proposals.load(ctx, string(args.ID))
+	if err != nil || p == nil {
+		return nil, err
+	}
+	return r.newProposals(ctx, []models.GnoProposal{*p})[0], nil
+}
+
+func (r *resolver) Packages(ctx context.Context, args struct {
+	pageArgs
+	Publisher *string
+}) ([]*packageResolver, error) {
+	q := r.db.WithContext(ctx).Order("block_height DESC")
+	if args.Publisher != nil {
+		q = q.Where("publisher = ?", *args.Publisher)
+	}
+	var pkgs []models.GnoPackage
+	if err := args.pageArgs.apply(q).Find(&pkgs).Error; err != nil {
+		return nil, err
+	}
+	return r.newPackages(ctx, pkgs), nil
+}
+
+func (r *resolver) Reports(ctx context.Context, args struct{ First int32 }) ([]*reportResolver, error) {
+	var reports []models.Report
+	q := r.db.WithContext(ctx).Order("created_at DESC").Limit(clampFirst(int(args.First)))
+	if err := q.Find(&reports).Error; err != nil {
+		return nil, err
+	}
+	out := make([]*reportResolver, len(reports))
+	for i := range reports {
+		out[i] = &reportResolver{rep: &reports[i]}
+	}
+	return out, nil
+}
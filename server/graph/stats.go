@@ -0,0 +1,75 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/samouraiworld/topofgnomes/server/handler"
+	"gorm.io/gorm"
+)
+
+// userStats are one user's contribution counts under a filter.
+type userStats struct {
+	userID       string
+	commits      int
+	pullRequests int
+	issues       int
+	reviews      int
+}
+
+func (s userStats) total() int {
+	return s.commits + s.pullRequests + s.issues + s.reviews
+}
+
+func (s userStats) score() float64 {
+	return handler.CalculateScore(int64(s.commits), int64(s.issues), int64(s.pullRequests), int64(s.reviews))
+}
+
+// queryStats counts contributions per author with the GET /stats rules:
+// merged PRs only, reviews only on merged PRs by someone else. userIDs nil
+// means every author.
+func queryStats(ctx context.Context, db *gorm.DB, f filter, userIDs []string) (map[string]*userStats, error) {
+	out := map[string]*userStats{}
+	get := func(id string) *userStats {
+		if out[id] == nil {
+			out[id] = &userStats{userID: id}
+		}
+		return out[id]
+	}
+	type row struct {
+		AuthorID string
+		N        int
+	}
+	count := func(q *gorm.DB, table string, set func(*userStats, int)) error {
+		q = f.apply(q, table)
+		if userIDs != nil {
+			q = q.Where(table+".author_id IN ?", userIDs)
+		}
+		var rows []row
+		if err := q.Select(table + ".author_id AS author_id, COUNT(*) AS n").Group(table + ".author_id").Scan(&rows).Error; err != nil {
+			return err
+		}
+		for _, r := range rows {
+			set(get(r.AuthorID), r.N)
+		}
+		return nil
+	}
+
+	db = db.WithContext(ctx)
+	if err := count(db.Table("commits"), "commits", func(s *userStats, n int) { s.commits = n }); err != nil {
+		return nil, err
+	}
+	if err := count(db.Table("pull_requests").Where("pull_requests.state = ?", "MERGED"), "pull_requests",
+		func(s *userStats, n int) { s.pullRequests = n }); err != nil {
+		return nil, err
+	}
+	if err := count(db.Table("issues"), "issues", func(s *userStats, n int) { s.issues = n }); err != nil {
+		return nil, err
+	}
+	reviews := db.Table("reviews").
+		Joins("JOIN pull_requests ON pull_requests.id = reviews.pull_request_id").
+		Where("pull_requests.state = ? AND pull_requests.author_id <> reviews.author_id", "MERGED")
+	if err := count(reviews, "reviews", func(s *userStats, n int) { s.reviews = n }); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
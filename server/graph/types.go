@@ -0,0 +1,447 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/samouraiworld/topofgnomes/server/models"
+	"github.com/samouraiworld/topofgnomes/server/teams"
+	"github.com/samouraiworld/topofgnomes/server/topics"
+)
+
+// The new* constructors wrap rows in resolvers. The rows were primed (see
+// loaders.prime*) when they were fetched, so their object fields batch.
+
+func (r *resolver) loadUser(ctx context.Context, ld *loader[string, *models.User], key string) (*userResolver, error) {
+	if key == "" {
+		return nil, nil
+	}
+	u, err := ld.load(ctx, key)
+	if err != nil || u == nil {
+		return nil, err
+	}
+	return r.newUsers(ctx, []models.User{*u})[0], nil
+}
+
+func (r *resolver) newUsers(ctx context.Context, users []models.User) []*userResolver {
+	loadersFrom(ctx).primeUsers(users)
+	out := make([]*userResolver, len(users))
+	for i := range users {
+		out[i] = &userResolver{r: r, u: &users[i]}
+	}
+	return out
+}
+
+func (r *resolver) newPullRequests(ctx context.Context, prs []models.PullRequest) []*pullRequestResolver {
+	loadersFrom(ctx).primePullRequests(prs)
+	out := make([]*pullRequestResolver, len(prs))
+	for i := range prs {
+		out[i] = &pullRequestResolver{r: r, pr: &prs[i]}
+	}
+	return out
+}
+
+func (r *resolver) newIssues(ctx context.Context, issues []models.Issue) []*issueResolver {
+	loadersFrom(ctx).primeIssues(issues)
+	out := make([]*issueResolver, len(issues))
+	for i := range issues {
+		out[i] = &issueResolver{r: r, issue: &issues[i]}
+	}
+	return out
+}
+
+func (r *resolver) newReviews(ctx context.Context, reviews []models.Review) []*reviewResolver {
+	loadersFrom(ctx).primeReviews(reviews)
+	out := make([]*reviewResolver, len(reviews))
+	for i := range reviews {
+		out[i] = &reviewResolver{r: r, review: &reviews[i]}
+	}
+	return out
+}
+
+func (r *resolver) newTeams(ctx context.Context, ts []teams.Team) []*teamResolver {
+	l := loadersFrom(ctx)
+	out := make([]*teamResolver, len(ts))
+	for i, t := range ts {
+		out[i] = &teamResolver{r: r, t: t}
+		for _, m := range t.Members {
+			l.usersByLogin.want(strings.ToLower(m))
+		}
+	}
+	return out
+}
+
+func (r *resolver) newProposals(ctx context.Context, proposals []models.GnoProposal) []*proposalResolver {
+	loadersFrom(ctx).primeProposals(proposals)
+	out := make([]*proposalResolver, len(proposals))
+	for i := range proposals {
+		out[i] = &proposalResolver{r: r, p: &proposals[i]}
+	}
+	return out
+}
+
+func (r *resolver) newVotes(ctx context.Context, votes []models.GnoVote) []*voteResolver {
+	loadersFrom(ctx).primeVotes(votes)
+	out := make([]*voteResolver, len(votes))
+	for i := range votes {
+		out[i] = &voteResolver{r: r, v: &votes[i]}
+	}
+	return out
+}
+
+func (r *resolver) newPackages(ctx context.Context, pkgs []models.GnoPackage) []*packageResolver {
+	loadersFrom(ctx).primePackages(pkgs)
+	out := make([]*packageResolver, len(pkgs))
+	for i := range pkgs {
+		out[i] = &packageResolver{r: r, p: &pkgs[i]}
+	}
+	return out
+}
+
+type userResolver struct {
+	r *resolver
+	u *models.User
+}
+
+func (u *userResolver) ID() graphql.ID    { return graphql.ID(u.u.ID) }
+func (u *userResolver) Login() string     { return u.u.Login }
+func (u *userResolver) Name() string      { return u.u.Name }
+func (u *userResolver) AvatarURL() string { return u.u.AvatarUrl }
+func (u *userResolver) URL() string       { return u.u.URL }
+func (u *userResolver) Wallet() string    { return u.u.Wallet }
+func (u *userResolver) Bio() string       { return u.u.Bio }
+func (u *userResolver) Location() string  { return u.u.Location }
+func (u *userResolver) Followers() int32  { return int32(u.u.Followers) }
+func (u *userResolver) Following() int32  { return int32(u.u.Following) }
+
+func (u *userResolver) Stats(ctx context.Context, args filterArgs) (*userStatsResolver, error) {
+	f := u.r.filter(args)
+	ld := perUser(loadersFrom(ctx), "stats:"+f.key(), func(ctx context.Context, ids []string) (map[string]*userStats, error) {
+		return queryStats(ctx, u.r.db, f, ids)
+	})
+	s, err := ld.load(ctx, u.u.ID)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		s = &userStats{userID: u.u.ID}
+	}
+	return &userStatsResolver{s: s, user: u}, nil
+}
+
+func (u *userResolver) PullRequests(ctx context.Context, args struct {
+	filterArgs
+	State *string
+	First int32
+}) ([]*pullRequestResolver, error) {
+	f, first, state := u.r.filter(args.filterArgs), clampFirst(int(args.First)), ""
+	if args.State != nil {
+		state = *args.State
+	}
+	key := fmt.Sprintf("prs:%s:%s:%d", f.key(), state, first)
+	l := loadersFrom(ctx)
+	ld := perUser(l, key, primed(l.primePullRequests, func(ctx context.Context, ids []string) (map[string][]models.PullRequest, error) {
+		q := f.apply(u.r.db.Model(&models.PullRequest{}), "pull_requests")
+		if state != "" {
+			q = q.Where("pull_requests.state = ?", state)
+		}
+		return topPerAuthor(ctx, q, "pull_requests", ids, first, func(pr models.PullRequest) string { return pr.AuthorID })
+	}))
+	prs, err := ld.load(ctx, u.u.ID)
+	if err != nil {
+		return nil, err
+	}
+	return u.r.newPullRequests(ctx, prs), nil
+}
+
+func (u *userResolver) Issues(ctx context.Context, args struct {
+	filterArgs
+	First int32
+}) ([]*issueResolver, error) {
+	f, first := u.r.filter(args.filterArgs), clampFirst(int(args.First))
+	l := loadersFrom(ctx)
+	ld := perUser(l, fmt.Sprintf("issues:%s:%d", f.key(), first), primed(l.primeIssues, func(ctx context.Context, ids []string) (map[string][]models.Issue, error) {
+		q := f.apply(u.r.db.Model(&models.Issue{}), "issues")
+		return topPerAuthor(ctx, q, "issues", ids, first, func(i models.Issue) string { return i.AuthorID })
+	}))
+	issues, err := ld.load(ctx, u.u.ID)
+	if err != nil {
+		return nil, err
+	}
+	return u.r.newIssues(ctx, issues), nil
+}
+
+func (u *userResolver) Reviews(ctx context.Context, args struct {
+	filterArgs
+	First int32
+}) ([]*reviewResolver, error) {
+	f, first := u.r.filter(args.filterArgs), clampFirst(int(args.First))
+	l := loadersFrom(ctx)
+	ld := perUser(l, fmt.Sprintf("reviews:%s:%d", f.key(), first), primed(l.primeReviews, func(ctx context.Context, ids []string) (map[string][]models.Review, error) {
+		q := f.apply(u.r.db.Model(&models.Review{}), "reviews")
+		return topPerAuthor(ctx, q, "reviews", ids, first, func(r models.Review) string { return r.AuthorID })
+	}))
+	reviews, err := ld.load(ctx, u.u.ID)
+	if err != nil {
+		return nil, err
+	}
+	return u.r.newReviews(ctx, reviews), nil
+}
+
+func (u *userResolver) Teams(ctx context.Context) []*teamResolver {
+	var ts []teams.Team
+	for _, t := range u.r.teams().Teams {
+		for _, m := range t.Members {
+			if strings.EqualFold(m, u.u.Login) {
+				ts = append(ts, t)
+				break
+			}
+		}
+	}
+	return u.r.newTeams(ctx, ts)
+}
+
+func (u *userResolver) Packages(ctx context.Context) ([]*packageResolver, error) {
+	if u.u.Wallet == "" {
+		return nil, nil
+	}
+	pkgs, err := loadersFrom(ctx).packagesByOwner.load(ctx, u.u.Wallet)
+	if err != nil {
+		return nil, err
+	}
+	return u.r.newPackages(ctx, pkgs), nil
+}
+
+func (u *userResolver) Votes(ctx context.Context) ([]*voteResolver, error) {
+	if u.u.Wallet == "" {
+		return nil, nil
+	}
+	votes, err := loadersFrom(ctx).votesByVoter.load(ctx, u.u.Wallet)
+	if err != nil {
+		return nil, err
+	}
+	return u.r.newVotes(ctx, votes), nil
+}
+
+type userStatsResolver struct {
+	s    *userStats
+	user *userResolver
+}
+
+func (s *userStatsResolver) User() *userResolver { return s.user }
+func (s *userStatsResolver) Commits() int32      { return int32(s.s.commits) }
+func (s *userStatsResolver) PullRequests() int32 { return int32(s.s.pullRequests) }
+func (s *userStatsResolver) Issues() int32       { return int32(s.s.issues) }
+func (s *userStatsResolver) Reviews() int32      { return int32(s.s.reviews) }
+func (s *userStatsResolver) Score() float64      { return s.s.score() }
+
+type pullRequestResolver struct {
+	r  *resolver
+	pr *models.PullRequest
+}
+
+func (p *pullRequestResolver) ID() graphql.ID          { return graphql.ID(p.pr.ID) }
+func (p *pullRequestResolver) Number() int32           { return int32(p.pr.Number) }
+func (p *pullRequestResolver) Title() string           { return p.pr.Title }
+func (p *pullRequestResolver) State() string           { return p.pr.State }
+func (p *pullRequestResolver) URL() string             { return p.pr.URL }
+func (p *pullRequestResolver) RepositoryID() string    { return p.pr.RepositoryID }
+func (p *pullRequestResolver) IsDraft() bool           { return p.pr.IsDraft }
+func (p *pullRequestResolver) CreatedAt() graphql.Time { return graphql.Time{Time: p.pr.CreatedAt} }
+
+func (p *pullRequestResolver) MergedAt() *graphql.Time {
+	if p.pr.MergedAt == nil {
+		return nil
+	}
+	return &graphql.Time{Time: *p.pr.MergedAt}
+}
+
+func (p *pullRequestResolver) Author(ctx context.Context) (*userResolver, error) {
+	return p.r.loadUser(ctx, loadersFrom(ctx).usersByID, p.pr.AuthorID)
+}
+
+func (p *pullRequestResolver) Reviews(ctx context.Context) ([]*reviewResolver, error) {
+	reviews, err := loadersFrom(ctx).reviewsByPR.load(ctx, p.pr.ID)
+	if err != nil {
+		return nil, err
+	}
+	return p.r.newReviews(ctx, reviews), nil
+}
+
+type issueResolver struct {
+	r     *resolver
+	issue *models.Issue
+}
+
+func (i *issueResolver) ID() graphql.ID          { return graphql.ID(i.issue.ID) }
+func (i *issueResolver) Number() int32           { return int32(i.issue.Number) }
+func (i *issueResolver) Title() string           { return i.issue.Title }
+func (i *issueResolver) State() string           { return i.issue.State }
+func (i *issueResolver) URL() string             { return i.issue.URL }
+func (i *issueResolver) RepositoryID() string    { return i.issue.RepositoryID }
+func (i *issueResolver) CreatedAt() graphql.Time { return graphql.Time{Time: i.issue.CreatedAt} }
+
+func (i *issueResolver) ClosedAt() *graphql.Time {
+	if i.issue.ClosedAt == nil {
+		return nil
+	}
+	return &graphql.Time{Time: *i.issue.ClosedAt}
+}
+
+func (i *issueResolver) Author(ctx context.Context) (*userResolver, error) {
+	return i.r.loadUser(ctx, loadersFrom(ctx).usersByID, i.issue.AuthorID)
+}
+
+func (i *issueResolver) Labels(ctx context.Context) ([]*labelResolver, error) {
+	labels, err := loadersFrom(ctx).labelsByIssue.load(ctx, i.issue.ID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*labelResolver, len(labels))
+	for j := range labels {
+		out[j] = &labelResolver{label: &labels[j]}
+	}
+	return out, nil
+}
+
+type labelResolver struct{ label *models.Label }
+
+func (l *labelResolver) Name() string  { return l.label.Name }
+func (l *labelResolver) Color() string { return l.label.Color }
+
+type reviewResolver struct {
+	r      *resolver
+	review *models.Review
+}
+
+func (v *reviewResolver) ID() graphql.ID          { return graphql.ID(v.review.ID) }
+func (v *reviewResolver) RepositoryID() string    { return v.review.RepositoryID }
+func (v *reviewResolver) CreatedAt() graphql.Time { return graphql.Time{Time: v.review.CreatedAt} }
+
+func (v *reviewResolver) Author(ctx context.Context) (*userResolver, error) {
+	return v.r.loadUser(ctx, loadersFrom(ctx).usersByID, v.review.AuthorID)
+}
+
+func (v *reviewResolver) PullRequest(ctx context.Context) (*pullRequestResolver, error) {
+	pr, err := loadersFrom(ctx).pullRequests.load(ctx, v.review.PullRequestID)
+	if err != nil || pr == nil {
+		return nil, err
+	}
+	return v.r.newPullRequests(ctx, []models.PullRequest{*pr})[0], nil
+}
+
+type repositoryResolver struct{ repo *models.Repository }
+
+func (r *repositoryResolver) ID() graphql.ID     { return graphql.ID(r.repo.ID) }
+func (r *repositoryResolver) Owner() string      { return r.repo.Owner }
+func (r *repositoryResolver) Name() string       { return r.repo.Name }
+func (r *repositoryResolver) BaseBranch() string { return r.repo.BaseBranch }
+
+type teamResolver struct {
+	r *resolver
+	t teams.Team
+}
+
+func (t *teamResolver) Slug() string        { return t.t.Slug }
+func (t *teamResolver) Name() string        { return t.t.Name }
+func (t *teamResolver) Color() string       { return t.t.Color }
+func (t *teamResolver) Description() string { return t.t.Description }
+
+func (t *teamResolver) Members(ctx context.Context) ([]*userResolver, error) {
+	l := loadersFrom(ctx)
+	out := make([]*userResolver, 0, len(t.t.Members))
+	for _, m := range t.t.Members {
+		u, err := t.r.loadUser(ctx, l.usersByLogin, strings.ToLower(m))
+		if err != nil {
+			return nil, err
+		}
+		if u != nil {
+			out = append(out, u)
+		}
+	}
+	return out, nil
+}
+
+type topicResolver struct{ t *topics.Topic }
+
+func (t *topicResolver) Slug() string  { return t.t.Slug }
+func (t *topicResolver) Label() string { return t.t.Label }
+
+func (t *topicResolver) Patterns() []string {
+	out := make([]string, len(t.t.Patterns))
+	for i, p := range t.t.Patterns {
+		out[i] = p.Match
+	}
+	return out
+}
+
+type proposalResolver struct {
+	r *resolver
+	p *models.GnoProposal
+}
+
+func (p *proposalResolver) ID() graphql.ID         { return graphql.ID(p.p.ID) }
+func (p *proposalResolver) Title() string          { return p.p.Title }
+func (p *proposalResolver) Description() string    { return p.p.Description }
+func (p *proposalResolver) Address() string        { return p.p.Address }
+func (p *proposalResolver) Path() string           { return p.p.Path }
+func (p *proposalResolver) Status() string         { return p.p.Status }
+func (p *proposalResolver) BlockHeight() int32     { return int32(p.p.BlockHeight) }
+func (p *proposalResolver) ExecutionHeight() int32 { return int32(p.p.ExecutionHeight) }
+
+func (p *proposalResolver) Author(ctx context.Context) (*userResolver, error) {
+	return p.r.loadUser(ctx, loadersFrom(ctx).usersByWallet, p.p.Address)
+}
+
+func (p *proposalResolver) Votes(ctx context.Context) ([]*voteResolver, error) {
+	votes, err := loadersFrom(ctx).votesByProposal.load(ctx, p.p.ID)
+	if err != nil {
+		return nil, err
+	}
+	return p.r.newVotes(ctx, votes), nil
+}
+
+type voteResolver struct {
+	r *resolver
+	v *models.GnoVote
+}
+
+func (v *voteResolver) ProposalID() graphql.ID { return graphql.ID(v.v.ProposalID) }
+func (v *voteResolver) Address() string        { return v.v.Address }
+func (v *voteResolver) Vote() string           { return v.v.Vote }
+func (v *voteResolver) BlockHeight() int32     { return int32(v.v.BlockHeight) }
+
+func (v *voteResolver) Voter(ctx context.Context) (*userResolver, error) {
+	return v.r.loadUser(ctx, loadersFrom(ctx).usersByWallet, v.v.Address)
+}
+
+func (v *voteResolver) Proposal(ctx context.Context) (*proposalResolver, error) {
+	p, err := loadersFrom(ctx).proposals.load(ctx, v.v.ProposalID)
+	if err != nil || p == nil {
+		return nil, err
+	}
+	return v.r.newProposals(ctx, []models.GnoProposal{*p})[0], nil
+}
+
+type packageResolver struct {
+	r *resolver
+	p *models.GnoPackage
+}
+
+func (p *packageResolver) Address() string    { return p.p.Publisher }
+func (p *packageResolver) Path() string       { return p.p.Path }
+func (p *packageResolver) Namespace() string  { return p.p.Namespace }
+func (p *packageResolver) BlockHeight() int32 { return int32(p.p.BlockHeight) }
+
+func (p *packageResolver) Publisher(ctx context.Context) (*userResolver, error) {
+	return p.r.loadUser(ctx, loadersFrom(ctx).usersByWallet, p.p.Publisher)
+}
+
+type reportResolver struct{ rep *models.Report }
+
+func (r *reportResolver) ID() graphql.ID          { return graphql.ID(r.rep.ID) }
+func (r *reportResolver) CreatedAt() graphql.Time { return graphql.Time{Time: r.rep.CreatedAt} }
+func (r *reportResolver) PromptVersion() int32    { return int32(r.rep.PromptVersion) }
+func (r *reportResolver) Data() string            { return r.rep.Data }
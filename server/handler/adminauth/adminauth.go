@@ -0,0 +1,57 @@
+// Package adminauth restricts routes to gnolove administrators: the Clerk
+// users listed in ADMIN_USER_IDS. It runs after Clerk's header
+// authorization middleware, which puts the session claims in the context.
+package adminauth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/clerk/clerk-sdk-go/v2"
+
+	"github.com/samouraiworld/topofgnomes/server/handler/apierror"
+)
+
+// ParseIDs splits the comma-separated ADMIN_USER_IDS value.
+func ParseIDs(raw string) []string {
+	var ids []string
+	for _, id := range strings.Split(raw, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Require answers 401 to requests without a session or whose user isn't
+// one of ids. With no ids, every request is refused.
+func Require(ids []string) func(http.Handler) http.Handler {
+	allowed := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		allowed[id] = true
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			actor := Actor(r.Context())
+			if actor == "" {
+				apierror.Write(w, r, apierror.Unauthorized("missing session"))
+				return
+			}
+			if !allowed[actor] {
+				apierror.Write(w, r, apierror.Unauthorized("administrators only"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Actor returns the Clerk user id of the session in ctx, or "".
+func Actor(ctx context.Context) string {
+	claims, ok := clerk.SessionClaimsFromContext(ctx)
+	if !ok {
+		return ""
+	}
+	return claims.Subject
+}
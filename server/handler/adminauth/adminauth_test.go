@@ -0,0 +1,53 @@
+package adminauth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clerk/clerk-sdk-go/v2"
+)
+
+func TestParseIDs(t *testing.T) {
+	got := ParseIDs(" user_a, ,user_b,")
+	if len(got) != 2 || got[0] != "user_a" || got[1] != "user_b" {
+		t.Errorf("ParseIDs = %q", got)
+	}
+	if got := ParseIDs(""); len(got) != 0 {
+		t.Errorf("ParseIDs(\"\") = %q", got)
+	}
+}
+
+func TestRequire(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(Actor(r.Context())))
+	})
+	h := Require([]string{"user_admin"})(ok)
+
+	tests := []struct {
+		name    string
+		subject string
+		status  int
+	}{
+		{"no session", "", http.StatusUnauthorized},
+		{"not an admin", "user_other", http.StatusUnauthorized},
+		{"admin", "user_admin", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			if tt.subject != "" {
+				claims := &clerk.SessionClaims{RegisteredClaims: clerk.RegisteredClaims{Subject: tt.subject}}
+				r = r.WithContext(clerk.ContextWithSessionClaims(r.Context(), claims))
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, r)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if tt.status == http.StatusOK && rec.Body.String() != tt.subject {
+				t.Errorf("Actor = %q", rec.Body.String())
+			}
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/samouraiworld/topofgnomes/server/handler/apierror"
 	"github.com/samouraiworld/topofgnomes/server/models"
 	"github.com/samouraiworld/topofgnomes/server/providers"
 	"gorm.io/gorm"
@@ -31,7 +32,7 @@ func GetLastReport(db *gorm.DB) (*models.Report, error) {
 	var lastReport models.Report
 	if err := db.Order("created_at desc").First(&lastReport).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("no reports found")
+			return nil, apierror.NotFound("no reports found")
 		}
 		return nil, err
 	}
@@ -42,22 +43,13 @@ func GetReportByWeek(db *gorm.DB, weekStart, weekEnd time.Time) (*models.Report,
 	var report models.Report
 	if err := db.Where("created_at BETWEEN ? AND ?", weekStart, weekEnd).First(&report).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("no report found for the specified week")
+			return nil, apierror.NotFound("no report found for the specified week")
 		}
 		return nil, err
 	}
 	return &report, nil
 }
 
-// GetAllReports fetches all reports from the database, ordered by creation date descending.
-func GetAllReports(db *gorm.DB) ([]models.Report, error) {
-	var reports []models.Report
-	if err := db.Order("created_at desc").Find(&reports).Error; err != nil {
-		return nil, err
-	}
-	return reports, nil
-}
-
 // GenerateReport creates a new report for the current week.
 // Idempotent: if a report already exists for the current week, returns it.
 // Honours the daily cooldown to avoid hammering LLM APIs on retries.
@@ -71,9 +63,10 @@ func GenerateReport(db *gorm.DB) (models.Report, error) {
 	lastAttemptMu.Lock()
 	if time.Since(lastAttemptTime) < reportCooldown {
 		lastAttemptMu.Unlock()
-		return models.Report{}, fmt.Errorf("report generation on cooldown (last attempt: %s, retry after %s)",
+		retryAt := lastAttemptTime.Add(reportCooldown)
+		return models.Report{}, apierror.RateLimited(time.Until(retryAt), "report generation on cooldown (last attempt: %s, retry after %s)",
 			lastAttemptTime.Format(time.RFC3339),
-			lastAttemptTime.Add(reportCooldown).Format(time.RFC3339))
+			retryAt.Format(time.RFC3339))
 	}
 	lastAttemptTime = time.Now().UTC()
 	lastAttemptMu.Unlock()
@@ -117,11 +110,11 @@ func generateOnce(db *gorm.DB, llm LLMFunc, startTime, endTime time.Time, prompt
 		}
 		raw, err := llm(systemPrompt, string(userPrompt), schema)
 		if err != nil {
-			return models.Report{}, err
+			return models.Report{}, apierror.Upstream("LLM", err)
 		}
 		var parsed map[string]interface{}
 		if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
-			return models.Report{}, fmt.Errorf("invalid JSON in LLM response: %w", err)
+			return models.Report{}, apierror.Upstream("LLM", fmt.Errorf("invalid JSON in LLM response: %w", err))
 		}
 		if cycleLabel == "" {
 			if v, ok := parsed["cycle"].(string); ok {
@@ -8,21 +8,14 @@ import (
 	"testing"
 	"time"
 
+	"github.com/samouraiworld/topofgnomes/server/db/dbtest"
 	"github.com/samouraiworld/topofgnomes/server/models"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
 func newTestDB(t *testing.T) *gorm.DB {
 	t.Helper()
-	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
-	if err != nil {
-		t.Fatalf("open sqlite: %v", err)
-	}
-	if err := db.AutoMigrate(&models.User{}, &models.PullRequest{}, &models.Issue{}, &models.Report{}); err != nil {
-		t.Fatalf("migrate: %v", err)
-	}
+	db := dbtest.Open(t, &models.User{}, &models.PullRequest{}, &models.Issue{}, &models.Report{})
 	return db
 }
 
@@ -0,0 +1,50 @@
+package ai
+
+import (
+	"net/http"
+
+	"github.com/samouraiworld/topofgnomes/server/handler/listquery"
+	"github.com/samouraiworld/topofgnomes/server/openapi"
+)
+
+// OpenAPIRoutes describes the routes served by this package.
+func OpenAPIRoutes() []openapi.Route {
+	return []openapi.Route{
+		{
+			Method: http.MethodGet, Path: "/ai/report", Tag: "ai",
+			Summary:  "Latest weekly AI report",
+			Response: reportResponse{},
+			Cached:   true,
+		},
+		{
+			Method: http.MethodGet, Path: "/ai/report/weekly", Tag: "ai",
+			Summary: "AI report created within a date range",
+			Params: []openapi.Param{
+				openapi.QueryParam("start", "string", "RFC3339; required"),
+				openapi.QueryParam("end", "string", "RFC3339; required"),
+			},
+			Response: reportResponse{},
+			Cached:   true,
+		},
+		{
+			Method: http.MethodGet, Path: "/ai/reports", Tag: "ai",
+			Summary:  "List AI reports",
+			Params:   listquery.OpenAPIParams(reportsList),
+			Response: []reportResponse{},
+		},
+		{
+			Method: http.MethodPost, Path: "/ai/report/generate", Tag: "ai",
+			Summary:  "Generate this week's report unless it already exists",
+			Response: reportResponse{},
+		},
+		{
+			Method: http.MethodPost, Path: "/ai/report/regenerate", Tag: "ai",
+			Summary: "Overwrite the report of a cycle",
+			Params: []openapi.Param{
+				openapi.QueryParam("cycleStart", "string", "RFC3339 instant inside the cycle, default now"),
+				openapi.QueryParam("promptVersion", "integer", "1 or 2, default 2"),
+			},
+			Response: reportResponse{},
+		},
+	}
+}
@@ -2,160 +2,167 @@ package ai
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/samouraiworld/topofgnomes/server/apicache"
+	"github.com/samouraiworld/topofgnomes/server/handler/apierror"
+	"github.com/samouraiworld/topofgnomes/server/handler/listquery"
 	"github.com/samouraiworld/topofgnomes/server/models"
 	"gorm.io/gorm"
 )
 
-func HandleGetLastReport(db *gorm.DB) http.HandlerFunc {
+// reportResponse is the public shape of a report: the stored JSON data is
+// decoded so clients don't have to double-parse it. PromptVersion is only
+// set by the generate/regenerate endpoints.
+type reportResponse struct {
+	ID            string                 `json:"id"`
+	CreatedAt     time.Time              `json:"createdAt"`
+	PromptVersion int                    `json:"promptVersion,omitempty"`
+	Data          map[string]interface{} `json:"data"`
+}
+
+func HandleGetLastReport(db *gorm.DB, cache *apicache.Cache) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
-		lastReport, err := GetLastReport(db)
-		if err != nil {
-			if err.Error() == "no reports found" {
-				http.Error(w, err.Error(), http.StatusNotFound)
-				return
+		cache.Serve(w, r, "ai:report:last", []apicache.Domain{apicache.Reports}, func() (any, error) {
+			lastReport, err := GetLastReport(db)
+			if err != nil {
+				return nil, err
 			}
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		dataObj, err := unmarshalReportData(*lastReport)
-		if err != nil {
-			http.Error(w, "Failed to decode report data or user prompt", http.StatusInternalServerError)
-			return
-		}
 
-		response := map[string]interface{}{
-			"id":        lastReport.ID,
-			"createdAt": lastReport.CreatedAt,
-			"data":      dataObj,
-		}
+			dataObj, err := unmarshalReportData(*lastReport)
+			if err != nil {
+				return nil, fmt.Errorf("decode report %s: %w", lastReport.ID, err)
+			}
 
-		if err := json.NewEncoder(w).Encode(response); err != nil {
-			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
-			return
-		}
+			return reportResponse{
+				ID:        lastReport.ID,
+				CreatedAt: lastReport.CreatedAt,
+				Data:      dataObj,
+			}, nil
+		})
 	}
 }
 
-func HandleGetReportByWeek(db *gorm.DB) http.HandlerFunc {
+func HandleGetReportByWeek(db *gorm.DB, cache *apicache.Cache) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
 		startStr := r.URL.Query().Get("start")
 		endStr := r.URL.Query().Get("end")
 		if startStr == "" || endStr == "" {
-			http.Error(w, "start and end parameters are required", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.InvalidInput("start and end parameters are required"))
 			return
 		}
 
 		startDate, err := time.Parse(time.RFC3339, startStr)
 		if err != nil {
-			http.Error(w, "invalid start date format (expected RFC3339)", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.InvalidInput("invalid start date format (expected RFC3339)"))
 			return
 		}
 		endDate, err := time.Parse(time.RFC3339, endStr)
 		if err != nil {
-			http.Error(w, "invalid end date format (expected RFC3339)", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.InvalidInput("invalid end date format (expected RFC3339)"))
 			return
 		}
 
-		report, err := GetReportByWeek(db, startDate, endDate)
-		if err != nil {
-			if err.Error() == "no report found for the specified week" {
-				http.Error(w, err.Error(), http.StatusNotFound)
-				return
+		key := "ai:report:week:" + startDate.UTC().Format(time.RFC3339) + ":" + endDate.UTC().Format(time.RFC3339)
+		cache.Serve(w, r, key, []apicache.Domain{apicache.Reports}, func() (any, error) {
+			report, err := GetReportByWeek(db, startDate, endDate)
+			if err != nil {
+				return nil, err
 			}
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
 
-		dataObj, err := unmarshalReportData(*report)
-		if err != nil {
-			http.Error(w, "Failed to decode report data or user prompt", http.StatusInternalServerError)
-			return
-		}
-
-		response := map[string]interface{}{
-			"id":        report.ID,
-			"createdAt": report.CreatedAt,
-			"data":      dataObj,
-		}
+			dataObj, err := unmarshalReportData(*report)
+			if err != nil {
+				return nil, fmt.Errorf("decode report %s: %w", report.ID, err)
+			}
 
-		if err := json.NewEncoder(w).Encode(response); err != nil {
-			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
-			return
-		}
+			return reportResponse{
+				ID:        report.ID,
+				CreatedAt: report.CreatedAt,
+				Data:      dataObj,
+			}, nil
+		})
 	}
 }
 
+var reportsList = listquery.Spec{
+	Model:       &models.Report{},
+	Sorts:       map[string]string{"createdAt": "created_at", "id": "id"},
+	DefaultSort: "createdAt",
+	DefaultDesc: true,
+	Keys:        []string{"id"},
+	// Rows are reshaped below, so only these keys exist in the response.
+	Fields: []string{"id", "createdAt", "data"},
+}
+
+// HandleGetAllReports handles GET /ai/reports, newest first by default and
+// paginated through listquery.
 func HandleGetAllReports(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
-		reports, err := GetAllReports(db)
+		params, err := listquery.Parse(r.URL.Query(), reportsList)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.InvalidInput("%v", err))
 			return
 		}
 
-		var formattedReports []map[string]interface{}
+		var reports []models.Report
+		page, err := listquery.Find(db.Model(&models.Report{}), params, reportsList, &reports)
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		formattedReports := make([]reportResponse, 0, len(reports))
 		for _, report := range reports {
 			dataObj, err := unmarshalReportData(report)
 			if err != nil {
-				http.Error(w, "Failed to decode report data or user prompt", http.StatusInternalServerError)
+				apierror.Write(w, r, fmt.Errorf("decode report %s: %w", report.ID, err))
 				return
 			}
 
-			formattedReports = append(formattedReports, map[string]interface{}{
-				"id":        report.ID,
-				"createdAt": report.CreatedAt,
-				"data":      dataObj,
+			formattedReports = append(formattedReports, reportResponse{
+				ID:        report.ID,
+				CreatedAt: report.CreatedAt,
+				Data:      dataObj,
 			})
 		}
 
-		if formattedReports == nil {
-			formattedReports = []map[string]interface{}{}
-		}
-		if err := json.NewEncoder(w).Encode(formattedReports); err != nil {
-			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
-			return
-		}
+		_ = listquery.Write(w, r, params, page, formattedReports)
 	}
 }
 
 // HandleGenerateReport handles POST /ai/report/generate
 // Triggers manual report generation. Idempotent — returns existing report if one exists for the current week.
-func HandleGenerateReport(db *gorm.DB) http.HandlerFunc {
+func HandleGenerateReport(db *gorm.DB, cache *apicache.Cache) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPost {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			return
-		}
 		w.Header().Set("Content-Type", "application/json")
 
 		report, err := GenerateReport(db)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			apierror.Write(w, r, err)
 			return
 		}
+		cache.Bump(apicache.Reports)
 
 		dataObj, err := unmarshalReportData(report)
 		if err != nil {
-			http.Error(w, "Report generated but failed to parse data", http.StatusInternalServerError)
+			apierror.Write(w, r, fmt.Errorf("decode generated report %s: %w", report.ID, err))
 			return
 		}
 
-		response := map[string]interface{}{
-			"id":            report.ID,
-			"createdAt":     report.CreatedAt,
-			"promptVersion": report.PromptVersion,
-			"data":          dataObj,
+		response := reportResponse{
+			ID:            report.ID,
+			CreatedAt:     report.CreatedAt,
+			PromptVersion: report.PromptVersion,
+			Data:          dataObj,
 		}
 
 		json.NewEncoder(w).Encode(response)
@@ -167,19 +174,15 @@ func HandleGenerateReport(db *gorm.DB) http.HandlerFunc {
 // (defaults to this week's Monday) using ?promptVersion=N (defaults to 2).
 // Bypasses the daily cooldown — this is the operator-triggered fallback for
 // when the Sunday cron misses a cycle.
-func HandleRegenerateReport(db *gorm.DB) http.HandlerFunc {
+func HandleRegenerateReport(db *gorm.DB, cache *apicache.Cache) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPost {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			return
-		}
 		w.Header().Set("Content-Type", "application/json")
 
 		cycleStart := time.Now().UTC()
 		if cs := r.URL.Query().Get("cycleStart"); cs != "" {
 			t, err := time.Parse(time.RFC3339, cs)
 			if err != nil {
-				http.Error(w, "invalid cycleStart (expected RFC3339)", http.StatusBadRequest)
+				apierror.Write(w, r, apierror.InvalidInput("invalid cycleStart (expected RFC3339)"))
 				return
 			}
 			cycleStart = t
@@ -188,7 +191,7 @@ func HandleRegenerateReport(db *gorm.DB) http.HandlerFunc {
 		if v := r.URL.Query().Get("promptVersion"); v != "" {
 			parsed, err := strconv.Atoi(v)
 			if err != nil || (parsed != 1 && parsed != PromptVersion2) {
-				http.Error(w, "promptVersion must be 1 or 2", http.StatusBadRequest)
+				apierror.Write(w, r, apierror.InvalidInput("promptVersion must be 1 or 2"))
 				return
 			}
 			promptVersion = parsed
@@ -196,19 +199,20 @@ func HandleRegenerateReport(db *gorm.DB) http.HandlerFunc {
 
 		report, err := RegenerateReport(db, nil, cycleStart, promptVersion)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			apierror.Write(w, r, err)
 			return
 		}
+		cache.Bump(apicache.Reports)
 		dataObj, err := unmarshalReportData(report)
 		if err != nil {
-			http.Error(w, "regenerated but failed to parse data", http.StatusInternalServerError)
+			apierror.Write(w, r, fmt.Errorf("decode regenerated report %s: %w", report.ID, err))
 			return
 		}
-		_ = json.NewEncoder(w).Encode(map[string]interface{}{
-			"id":            report.ID,
-			"createdAt":     report.CreatedAt,
-			"promptVersion": report.PromptVersion,
-			"data":          dataObj,
+		_ = json.NewEncoder(w).Encode(reportResponse{
+			ID:            report.ID,
+			CreatedAt:     report.CreatedAt,
+			PromptVersion: report.PromptVersion,
+			Data:          dataObj,
 		})
 	}
 }
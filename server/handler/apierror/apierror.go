@@ -0,0 +1,159 @@
+// Package apierror is the error vocabulary of the HTTP API. Handlers, and
+// the functions they call, return typed errors (NotFound, InvalidInput,
+// Upstream, RateLimited, Unauthorized, Conflict) and hand every error to
+// Write, which answers with an RFC 9457 application/problem+json body:
+//
+//	{"type":"about:blank","title":"Not Found","status":404,
+//	 "detail":"no user with wallet \"g1...\"","code":"not_found"}
+//
+// Clients branch on `code`, which is stable; `detail` is for humans. Errors
+// that are not typed are answered with a generic 500 and logged, so raw
+// database or provider messages never reach the client.
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/samouraiworld/topofgnomes/server/logging"
+	"gorm.io/gorm"
+)
+
+// Code is the machine-readable error kind sent as `code`.
+type Code string
+
+const (
+	CodeNotFound     Code = "not_found"
+	CodeInvalidInput Code = "invalid_input"
+	CodeUnauthorized Code = "unauthorized"
+	CodeConflict     Code = "conflict"
+	CodeUpstream     Code = "upstream_error"
+	CodeRateLimited  Code = "rate_limited"
+	CodeInternal     Code = "internal_error"
+)
+
+var statuses = map[Code]int{
+	CodeNotFound:     http.StatusNotFound,
+	CodeInvalidInput: http.StatusBadRequest,
+	CodeUnauthorized: http.StatusUnauthorized,
+	CodeConflict:     http.StatusConflict,
+	CodeUpstream:     http.StatusBadGateway,
+	CodeRateLimited:  http.StatusTooManyRequests,
+	CodeInternal:     http.StatusInternalServerError,
+}
+
+// Error is a typed API error. Message is sent to the client; Err, the
+// underlying cause, is only logged.
+type Error struct {
+	Code    Code
+	Message string
+	Err     error
+	// RetryAfter is sent as the Retry-After header of rate-limited errors.
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Status is the HTTP status the error is answered with.
+func (e *Error) Status() int {
+	if s, ok := statuses[e.Code]; ok {
+		return s
+	}
+	return http.StatusInternalServerError
+}
+
+// NotFound reports a missing resource.
+func NotFound(format string, args ...any) *Error {
+	return &Error{Code: CodeNotFound, Message: fmt.Sprintf(format, args...)}
+}
+
+// InvalidInput reports a request the client has to fix: a bad parameter,
+// an undecodable body.
+func InvalidInput(format string, args ...any) *Error {
+	return &Error{Code: CodeInvalidInput, Message: fmt.Sprintf(format, args...)}
+}
+
+// Unauthorized reports a missing or insufficient session.
+func Unauthorized(format string, args ...any) *Error {
+	return &Error{Code: CodeUnauthorized, Message: fmt.Sprintf(format, args...)}
+}
+
+// Conflict reports a request the current state of the server refuses,
+// whatever its content.
+func Conflict(format string, args ...any) *Error {
+	return &Error{Code: CodeConflict, Message: fmt.Sprintf(format, args...)}
+}
+
+// Upstream reports a failure of a service we depend on (GitHub, the gno
+// chain, the LLM provider). service is named in the response; err is not.
+func Upstream(service string, err error) *Error {
+	return &Error{Code: CodeUpstream, Message: service + " request failed", Err: err}
+}
+
+// RateLimited reports a request refused until retryAfter has passed.
+func RateLimited(retryAfter time.Duration, format string, args ...any) *Error {
+	return &Error{Code: CodeRateLimited, Message: fmt.Sprintf(format, args...), RetryAfter: retryAfter}
+}
+
+// CodeOf returns the code Write would answer err with.
+func CodeOf(err error) Code {
+	return classify(err).Code
+}
+
+// Problem is the response body.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     Code   `json:"code"`
+}
+
+// Write answers the request with err. Untyped errors, and the causes of
+// typed ones, are logged with the request line.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	e := classify(err)
+	if e.Code == CodeInternal || e.Err != nil {
+		logging.FromContext(r.Context()).Errorw("request failed", "method", r.Method, "path", r.URL.Path, "error", err)
+	}
+	if e.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(e.RetryAfter.Round(time.Second)/time.Second)))
+	}
+	status := e.Status()
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   e.Message,
+		Instance: r.URL.Path,
+		Code:     e.Code,
+	})
+}
+
+// classify maps any error to a typed one. A gorm.ErrRecordNotFound that
+// reached a handler unmapped still answers 404 rather than 500.
+func classify(err error) *Error {
+	var e *Error
+	switch {
+	case errors.As(err, &e):
+		return e
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return NotFound("not found")
+	default:
+		return &Error{Code: CodeInternal, Message: "internal server error", Err: err}
+	}
+}
@@ -0,0 +1,64 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		status     int
+		code       Code
+		detail     string
+		retryAfter string
+	}{
+		{"not found", NotFound("team %q not found", "core"), http.StatusNotFound, CodeNotFound, `team "core" not found`, ""},
+		{"invalid input", InvalidInput("limit must be positive"), http.StatusBadRequest, CodeInvalidInput, "limit must be positive", ""},
+		{"unauthorized", Unauthorized("missing session"), http.StatusUnauthorized, CodeUnauthorized, "missing session", ""},
+		{"upstream hides the cause", Upstream("GitHub", errors.New("token ghp_secret rejected")), http.StatusBadGateway, CodeUpstream, "GitHub request failed", ""},
+		{"rate limited", RateLimited(90*time.Second, "on cooldown"), http.StatusTooManyRequests, CodeRateLimited, "on cooldown", "90"},
+		{"wrapped", fmt.Errorf("load: %w", NotFound("gone")), http.StatusNotFound, CodeNotFound, "gone", ""},
+		{"record not found", fmt.Errorf("first: %w", gorm.ErrRecordNotFound), http.StatusNotFound, CodeNotFound, "not found", ""},
+		{"untyped hides the message", errors.New("no such table: users"), http.StatusInternalServerError, CodeInternal, "internal server error", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			Write(rec, httptest.NewRequest(http.MethodGet, "/teams/core", nil), tt.err)
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+				t.Errorf("Content-Type = %q", ct)
+			}
+			if got := rec.Header().Get("Retry-After"); got != tt.retryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, tt.retryAfter)
+			}
+			var p Problem
+			if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+				t.Fatal(err)
+			}
+			want := Problem{Type: "about:blank", Title: http.StatusText(tt.status), Status: tt.status, Detail: tt.detail, Instance: "/teams/core", Code: tt.code}
+			if p != want {
+				t.Errorf("problem = %+v, want %+v", p, want)
+			}
+			if strings.Contains(rec.Body.String(), "secret") || strings.Contains(rec.Body.String(), "no such table") {
+				t.Errorf("cause leaked: %s", rec.Body.String())
+			}
+			if got := CodeOf(tt.err); got != tt.code {
+				t.Errorf("CodeOf = %q, want %q", got, tt.code)
+			}
+		})
+	}
+}
@@ -1,20 +1,18 @@
 package contributor
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"sort"
 	"time"
 
-	"github.com/dgraph-io/ristretto"
+	"github.com/samouraiworld/topofgnomes/server/apicache"
 	"github.com/samouraiworld/topofgnomes/server/models"
 	"gorm.io/gorm"
 )
 
 const (
 	cohortsCacheKey  = "contributors:cohorts:v1"
-	cohortsCacheTTL  = 5 * time.Minute
 	cohortsSchemaVer = 1
 	// Cap the cohort window so the response stays bounded and the chart
 	// stays legible. Cohorts older than this still count as "cohort 0"
@@ -48,31 +46,23 @@ type cohortsResponse struct {
 // at least one PR in the Nth month after cohort.
 //
 // Plan §2 "contributor-cohort retention curve" lives in /gnolove/analytics.
-// Cached 5 min via ristretto; no path params.
-func HandleGetCohorts(db *gorm.DB, cache *ristretto.Cache) http.HandlerFunc {
+// Cached until the next sync, five minutes at most; no path params.
+func HandleGetCohorts(db *gorm.DB, cache *apicache.Cache) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
 		w.Header().Set("Content-Type", "application/json")
-		if cache != nil {
-			if cached, ok := cache.Get(cohortsCacheKey); ok {
-				_ = json.NewEncoder(w).Encode(cached.(cohortsResponse))
-				return
+		cache.Serve(w, r, cohortsCacheKey, []apicache.Domain{apicache.GitHub, apicache.Clock}, func() (any, error) {
+			rows, lastSyncedAt, err := computeCohorts(db, time.Now().UTC(), cohortsLookbackMonths)
+			if err != nil {
+				return nil, err
 			}
-		}
-		rows, lastSyncedAt, err := computeCohorts(db, time.Now().UTC(), cohortsLookbackMonths)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		resp := cohortsResponse{
-			SchemaVersion: cohortsSchemaVer,
-			LastSyncedAt:  lastSyncedAt,
-			GeneratedAt:   time.Now().UTC(),
-			Cohorts:       rows,
-		}
-		if cache != nil {
-			cache.SetWithTTL(cohortsCacheKey, resp, 0, cohortsCacheTTL)
-		}
-		_ = json.NewEncoder(w).Encode(resp)
+			return cohortsResponse{
+				SchemaVersion: cohortsSchemaVer,
+				LastSyncedAt:  lastSyncedAt,
+				GeneratedAt:   time.Now().UTC(),
+				Cohorts:       rows,
+			}, nil
+		})
 	}
 }
 
@@ -9,21 +9,15 @@ import (
 	"time"
 
 	"github.com/dgraph-io/ristretto"
+	"github.com/samouraiworld/topofgnomes/server/apicache"
+	"github.com/samouraiworld/topofgnomes/server/db/dbtest"
 	"github.com/samouraiworld/topofgnomes/server/models"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
 func newTestDB(t *testing.T) *gorm.DB {
 	t.Helper()
-	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
-	if err != nil {
-		t.Fatalf("open sqlite: %v", err)
-	}
-	if err := db.AutoMigrate(&models.User{}, &models.PullRequest{}, &models.SyncStatus{}); err != nil {
-		t.Fatalf("migrate: %v", err)
-	}
+	db := dbtest.Open(t, &models.User{}, &models.PullRequest{}, &models.SyncStatus{})
 	return db
 }
 
@@ -148,7 +142,8 @@ func TestComputeCohorts_LookbackTrimsOldCohorts(t *testing.T) {
 
 func TestHandleGetCohorts_RespectsCache(t *testing.T) {
 	db := newTestDB(t)
-	cache, _ := ristretto.NewCache(&ristretto.Config{NumCounters: 1000, MaxCost: 1 << 20, BufferItems: 64})
+	store, _ := ristretto.NewCache(&ristretto.Config{NumCounters: 1000, MaxCost: 1 << 20, BufferItems: 64})
+	cache := apicache.New(store)
 
 	seedPR(t, db, "p-0", "alice", time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC))
 
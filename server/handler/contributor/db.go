@@ -5,6 +5,10 @@ import (
 	"errors"
 	"time"
 
+	"github.com/samouraiworld/topofgnomes/server/contributions"
+	dbpkg "github.com/samouraiworld/topofgnomes/server/db"
+	"github.com/samouraiworld/topofgnomes/server/handler/apierror"
+	"github.com/samouraiworld/topofgnomes/server/models"
 	"gorm.io/gorm"
 )
 
@@ -46,7 +50,7 @@ func GetContributorDataFromDatabase(db *gorm.DB, login string) (contributorDBRes
 	}
 
 	if dbUser.Login == "" {
-		return contributorDBResponse{}, dbUser, errors.New("user not found")
+		return contributorDBResponse{}, dbUser, apierror.NotFound("user %q not found", login)
 	}
 
 	userID := dbUser.ID
@@ -83,49 +87,46 @@ func getUser(db *gorm.DB, login string) (contributorDBUser, error) {
 	return dbUser, err
 }
 
+// getMonthlyCounts reads the last 12 months of commits, merged PRs and
+// issues from the daily_contributions rollup, one query for all three.
 func getMonthlyCounts(db *gorm.DB, userID string) ([]TimeCount, []TimeCount, []TimeCount) {
-	now := time.Now()
+	now := time.Now().UTC()
 	months := make([]string, 12)
 	for i := 0; i < 12; i++ {
 		m := now.AddDate(0, -i, 0)
 		months[11-i] = m.Format("2006-01")
 	}
 
-	commitsPerMonth := getEntityMonthlyCounts(db, "commits", userID, months, now)
-	prsPerMonth := getEntityMonthlyCounts(db, "pull_requests", userID, months, now)
-	issuesPerMonth := getEntityMonthlyCounts(db, "issues", userID, months, now)
-
-	return commitsPerMonth, prsPerMonth, issuesPerMonth
-}
-
-// getEntityMonthlyCounts returns the monthly counts for a given entity table (commits, pull_requests, issues)
-func getEntityMonthlyCounts(db *gorm.DB, tableName string, userID string, months []string, now time.Time) []TimeCount {
-	// Whitelist of allowed table names
-	allowedTables := map[string]bool{
-		"commits":        true,
-		"pull_requests":  true,
-		"issues":         true,
+	var rows []struct {
+		Period    string
+		Commits   int
+		PRsOpened int `gorm:"column:prs_opened"`
+		Issues    int
 	}
-	if !allowedTables[tableName] {
-		// Optionally log or handle the error here
-		return make([]TimeCount, 12) // Return empty result for invalid table
+	db.Model(&models.DailyContribution{}).
+		Select("substr(day, 1, 7) AS period, SUM(commits) AS commits, SUM(prs_opened) AS prs_opened, SUM(issues) AS issues").
+		Where("user_id = ? AND day >= ?", userID, contributions.Day(now.AddDate(0, -12, 0))).
+		Group("substr(day, 1, 7)").
+		Scan(&rows)
+	byMonth := make(map[string]int, len(rows))
+	for i, r := range rows {
+		byMonth[r.Period] = i
 	}
 
-	var counts []struct {
-		Period string
-		Count  int
-	}
-	query := "SELECT strftime('%Y-%m', created_at) as period, COUNT(*) as count FROM " + tableName + " WHERE author_id = ? AND created_at >= ? GROUP BY period"
-	db.Raw(query, userID, now.AddDate(0, -12, 0)).Scan(&counts)
-	countMap := make(map[string]int)
-	for _, c := range counts {
-		countMap[c.Period] = c.Count
-	}
-	result := make([]TimeCount, 12)
+	commitsPerMonth := make([]TimeCount, 12)
+	prsPerMonth := make([]TimeCount, 12)
+	issuesPerMonth := make([]TimeCount, 12)
 	for i, m := range months {
-		result[i] = TimeCount{Period: m, Count: countMap[m]}
+		commitsPerMonth[i] = TimeCount{Period: m}
+		prsPerMonth[i] = TimeCount{Period: m}
+		issuesPerMonth[i] = TimeCount{Period: m}
+		if j, ok := byMonth[m]; ok {
+			commitsPerMonth[i].Count = rows[j].Commits
+			prsPerMonth[i].Count = rows[j].PRsOpened
+			issuesPerMonth[i].Count = rows[j].Issues
+		}
 	}
-	return result
+	return commitsPerMonth, prsPerMonth, issuesPerMonth
 }
 
 func getDailyContributions(db *gorm.DB, userID string) []TimeCount {
@@ -142,13 +143,13 @@ func getDailyContributions(db *gorm.DB, userID string) []TimeCount {
 		Count  int
 	}
 	db.Raw(`
-		SELECT strftime('%Y-%m-%d', created_at) as period, COUNT(*) as count FROM (
+		SELECT `+dbpkg.DayExpr(db, "created_at")+` as period, COUNT(*) as count FROM (
 			SELECT created_at FROM commits WHERE author_id = ? AND created_at >= ?
 			UNION ALL
 			SELECT created_at FROM pull_requests WHERE author_id = ? AND created_at >= ?
 			UNION ALL
 			SELECT created_at FROM issues WHERE author_id = ? AND created_at >= ?
-		) GROUP BY period
+		) as contributions GROUP BY period
 	`, userID, now.AddDate(-1, 0, 0), userID, now.AddDate(-1, 0, 0), userID, now.AddDate(-1, 0, 0)).Scan(&dailyCounts)
 	dailyMap := map[string]int{}
 	for _, c := range dailyCounts {
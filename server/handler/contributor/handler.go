@@ -2,38 +2,32 @@ package contributor
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/samouraiworld/topofgnomes/server/handler/apierror"
+	"github.com/samouraiworld/topofgnomes/server/logging"
 	"gorm.io/gorm"
 )
 
 func HandleGetContributor(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		log.Printf("[Contributor Handler] Request for: %s", r.URL.Path)
 		login := strings.TrimPrefix(r.URL.Path, "/contributors/")
-		log.Printf("[Contributor Handler] Extracted login from URL.Path: '%s'", login)
 		if login == "" {
-			http.Error(w, "Missing user login", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.InvalidInput("missing user login"))
 			return
 		}
 
-		dbData, dbUser, err := GetContributorDataFromDatabase(db, login)
+		dbData, dbUser, err := GetContributorDataFromDatabase(db.WithContext(r.Context()), login)
 		if err != nil {
-			if err.Error() == "user not found" {
-				http.Error(w, "User not found", http.StatusNotFound)
-				return
-			}
-			log.Printf("[Contributor Handler] DB error for login %s: %v", login, err)
-			http.Error(w, "Database error", http.StatusInternalServerError)
+			apierror.Write(w, r, err)
 			return
 		}
 
 		gnoBalance := "0"
 		if dbUser.Wallet != "" {
-			balanceStruct, _ := GetContributorOnChainData(dbUser.Wallet)
+			balanceStruct, _ := GetContributorOnChainData(r.Context(), dbUser.Wallet)
 			gnoBalance = balanceStruct.GnoBalance
 		}
 
@@ -75,9 +69,7 @@ func HandleGetContributor(db *gorm.DB) http.HandlerFunc {
 
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(resp); err != nil {
-			log.Printf("[Contributor Handler] Error encoding response for user %s: %v", login, err)
-		} else {
-			log.Printf("[Contributor Handler] Response sent for user: %s", login)
+			logging.FromContext(r.Context()).Warnw("encoding contributor response failed", "login", login, "error", err)
 		}
 	}
 }
@@ -5,10 +5,10 @@ import (
 	"github.com/samouraiworld/topofgnomes/server/onchain"
 )
 
-func GetContributorOnChainData(wallet string) (struct {
+func GetContributorOnChainData(ctx context.Context, wallet string) (struct {
 	GnoBalance string
 }, error) {
-	balance, err := onchain.GetGnoBalance(context.Background(), wallet)
+	balance, err := onchain.GetGnoBalance(ctx, wallet)
 	if err != nil {
 		return struct{ GnoBalance string }{GnoBalance: "0"}, err
 	}
@@ -0,0 +1,25 @@
+package contributor
+
+import (
+	"net/http"
+
+	"github.com/samouraiworld/topofgnomes/server/openapi"
+)
+
+// OpenAPIRoutes describes the routes served by this package.
+func OpenAPIRoutes() []openapi.Route {
+	return []openapi.Route{
+		{
+			Method: http.MethodGet, Path: "/contributors/cohorts", Tag: "contributors",
+			Summary:  "Monthly contributor cohorts and their retention",
+			Response: cohortsResponse{},
+			Cached:   true,
+		},
+		{
+			Method: http.MethodGet, Path: "/contributors/{login}", Tag: "contributors",
+			Summary:  "Contributor profile with GitHub and on-chain activity",
+			Params:   []openapi.Param{openapi.PathParam("login", "GitHub login")},
+			Response: githubUserResponse{},
+		},
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/google/go-github/v64/github"
+	"github.com/samouraiworld/topofgnomes/server/handler/apierror"
 	"github.com/samouraiworld/topofgnomes/server/models"
 	"github.com/samouraiworld/topofgnomes/server/signer"
 	"gorm.io/gorm"
@@ -45,7 +46,6 @@ func exchangeCodeForToken(code string) (*GitHubTokenResponse, error) {
 
 type resCallback struct {
 	Success string `json:"success"`
-	Error   string `json:"error"`
 }
 
 type GithubInfo struct {
@@ -57,31 +57,27 @@ func HandleVerifyGithubAccount(signer *signer.Signer, database *gorm.DB) func(w
 	return func(w http.ResponseWriter, r *http.Request) {
 		err := verifyGithubLoginBelongsToUser(r)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(resCallback{Error: err.Error()})
+			apierror.Write(w, r, err)
 			return
 		}
 		address := r.URL.Query().Get("address")
 		login := r.URL.Query().Get("login")
 
-		err = signer.CallVerify(address, login)
+		err = signer.CallVerify(r.Context(), address, login)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(resCallback{Error: err.Error()})
+			apierror.Write(w, r, apierror.Upstream("gno chain", err))
 			return
 		}
 
 		err = linkAddressToUser(database, address, login)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(resCallback{Error: err.Error()})
+			apierror.Write(w, r, err)
 			return
 		}
 
-		err = signer.ClaimTier(r.URL.Query().Get("login"))
+		err = signer.ClaimTier(r.Context(), r.URL.Query().Get("login"))
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(resCallback{Error: err.Error()})
+			apierror.Write(w, r, apierror.Upstream("gno chain", err))
 			return
 		}
 
@@ -99,8 +95,7 @@ func HandleGetGithubUserAndTokenByCode(signer *signer.Signer, database *gorm.DB)
 		ghUser, token, err := getGithubUserByCode(code)
 
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(resCallback{Error: err.Error()})
+			apierror.Write(w, r, err)
 			return
 		}
 
@@ -111,25 +106,25 @@ func HandleGetGithubUserAndTokenByCode(signer *signer.Signer, database *gorm.DB)
 func verifyGithubLoginBelongsToUser(r *http.Request) error {
 	token := r.URL.Query().Get("token")
 	if token == "" {
-		return fmt.Errorf("token not found")
+		return apierror.InvalidInput("token not found")
 	}
 	login := r.URL.Query().Get("login")
 	address := r.URL.Query().Get("address")
 	if login == "" {
-		return fmt.Errorf("login not found")
+		return apierror.InvalidInput("login not found")
 	}
 	if address == "" {
-		return fmt.Errorf("address not found")
+		return apierror.InvalidInput("address not found")
 	}
 
 	client := github.NewClient(nil).WithAuthToken(token)
 	user, _, err := client.Users.Get(context.Background(), "")
 	if err != nil {
-		return fmt.Errorf("failed to get user: %w", err)
+		return apierror.Upstream("GitHub", fmt.Errorf("failed to get user: %w", err))
 	}
 
 	if *user.Login != login {
-		return fmt.Errorf("github login does not belong to user")
+		return apierror.Unauthorized("github login does not belong to user")
 	}
 
 	return nil
@@ -137,18 +132,18 @@ func verifyGithubLoginBelongsToUser(r *http.Request) error {
 
 func getGithubUserByCode(code string) (*github.User, string, error) {
 	if code == "" {
-		return nil, "", fmt.Errorf("code not found")
+		return nil, "", apierror.InvalidInput("code not found")
 	}
 
 	token, err := exchangeCodeForToken(code)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to exchange code for token: %w", err)
+		return nil, "", apierror.Upstream("GitHub", fmt.Errorf("failed to exchange code for token: %w", err))
 	}
 
 	client := github.NewClient(nil).WithAuthToken(token.AccessToken)
 	user, _, err := client.Users.Get(context.Background(), "")
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to get user: %w", err)
+		return nil, "", apierror.Upstream("GitHub", fmt.Errorf("failed to get user: %w", err))
 	}
 
 	return user, token.AccessToken, nil
@@ -165,21 +160,18 @@ func HandleLink(db *gorm.DB) func(w http.ResponseWriter, r *http.Request) {
 
 		var body linkBody
 		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(resCallback{Error: err.Error()})
+			apierror.Write(w, r, apierror.InvalidInput("%v", err))
 			return
 		}
 
 		if body.Address == "" || body.Login == "" {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(resCallback{Error: "address or login not found"})
+			apierror.Write(w, r, apierror.InvalidInput("address or login not found"))
 			return
 		}
 
 		err := linkAddressToUser(db, body.Address, body.Login)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(resCallback{Error: err.Error()})
+			apierror.Write(w, r, err)
 			return
 		}
 
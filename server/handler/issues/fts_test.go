@@ -0,0 +1,56 @@
+//go:build sqlite_fts5
+
+package issues
+
+import (
+	"testing"
+	"time"
+
+	dbpkg "github.com/samouraiworld/topofgnomes/server/db"
+)
+
+func TestHandleGetIssues_TextSearchFTS5(t *testing.T) {
+	db := newTestDB(t)
+	if !db.Migrator().HasTable(dbpkg.IssuesFTSTable) {
+		t.Fatalf("%s missing: built with sqlite_fts5 but the index wasn't created", dbpkg.IssuesFTSTable)
+	}
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	deposit := seedIssue(t, db, "realm storage deposit is too high", base)
+	seedIssue(t, db, "gnovm: panic in parser", base.Add(time.Hour))
+
+	for _, tc := range []struct {
+		q    string
+		want []string
+	}{
+		// Tokens match in any order, the last one as a prefix.
+		{"deposit%20stor", []string{"realm storage deposit is too high"}},
+		// Punctuation is quoted rather than parsed as FTS5 syntax.
+		{"gnovm%3A", []string{"gnovm: panic in parser"}},
+		{"%22parser%22%20OR", nil},
+		{"panic%20-parser", []string{"gnovm: panic in parser"}},
+	} {
+		got, _ := get(t, db, "/issues?q="+tc.q)
+		if len(got) != len(tc.want) {
+			t.Errorf("q=%s: got %v, want %v", tc.q, ids(got), tc.want)
+			continue
+		}
+		for i := range got {
+			if got[i].Title != tc.want[i] {
+				t.Errorf("q=%s: got %v, want %v", tc.q, ids(got), tc.want)
+				break
+			}
+		}
+	}
+
+	// The update trigger keeps the index in step with the syncer's saves.
+	deposit.Title = "realm gas refund is too low"
+	if err := db.Save(&deposit).Error; err != nil {
+		t.Fatalf("update issue: %v", err)
+	}
+	if got, _ := get(t, db, "/issues?q=deposit"); len(got) != 0 {
+		t.Errorf("q=deposit after rename: got %v, want none", ids(got))
+	}
+	if got, _ := get(t, db, "/issues?q=refund"); len(got) != 1 || got[0].ID != deposit.ID {
+		t.Errorf("q=refund after rename: got %v, want the renamed issue", ids(got))
+	}
+}
@@ -0,0 +1,195 @@
+// Package issues wires GET /issues: server-side filtering over the synced
+// GitHub issues, sorted and paginated by listquery. Everything is pushed to
+// SQL so the page size, not the repo size, bounds the work per request.
+package issues
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	dbpkg "github.com/samouraiworld/topofgnomes/server/db"
+	"github.com/samouraiworld/topofgnomes/server/handler/apierror"
+	"github.com/samouraiworld/topofgnomes/server/handler/listquery"
+	"github.com/samouraiworld/topofgnomes/server/models"
+	"gorm.io/gorm"
+)
+
+// defaultRepository mirrors handler.getRepositoriesWithRequest so existing
+// callers that omit ?repositories= keep their results.
+const defaultRepository = "gnolang/gno"
+
+// issuesList is the listquery spec of GET /issues. Its sort names predate
+// listquery and are kept for existing callers.
+var issuesList = listquery.Spec{
+	Model:       &models.Issue{},
+	Sorts:       map[string]string{"created": "created_at", "updated": "updated_at", "number": "number"},
+	DefaultSort: "created",
+	DefaultDesc: true,
+	Keys:        []string{"id"},
+}
+
+// issueQuery is the issue-specific filters of a request; listquery.Params
+// carries the sort, page and fields.
+type issueQuery struct {
+	Repositories []string
+	State        string
+	Labels       []string
+	LabelsMatch  string // "any" | "all"
+	Assignee     string
+	Author       string
+	Milestone    string
+	CreatedAfter time.Time
+	Text         string
+}
+
+// HandleGetIssues serves GET /issues. The body stays a plain JSON array for
+// backward compatibility; pagination travels in the listquery headers, and
+// without `limit` or `cursor` every matching issue comes back.
+func HandleGetIssues(db *gorm.DB) http.HandlerFunc {
+	useFTS := db.Migrator().HasTable(dbpkg.IssuesFTSTable)
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		w.Header().Set("Content-Type", "application/json")
+		params, err := listquery.Parse(r.URL.Query(), issuesList)
+		if err != nil {
+			apierror.Write(w, r, apierror.InvalidInput("%v", err))
+			return
+		}
+		q, err := parseIssueQuery(r.URL.Query())
+		if err != nil {
+			apierror.Write(w, r, apierror.InvalidInput("%v", err))
+			return
+		}
+
+		var issues []models.Issue
+		page, err := listquery.Find(filterIssues(db, q, useFTS), params, issuesList, &issues,
+			"Author", "Assignees", "Assignees.User", "Labels")
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+		listquery.Write(w, r, params, page, issues)
+	}
+}
+
+func parseIssueQuery(v url.Values) (issueQuery, error) {
+	q := issueQuery{
+		Repositories: splitList(v.Get("repositories")),
+		State:        strings.ToUpper(strings.TrimSpace(v.Get("state"))),
+		Labels:       splitList(v.Get("labels")),
+		LabelsMatch:  strings.ToLower(v.Get("labelsMatch")),
+		Assignee:     strings.TrimSpace(v.Get("assignee")),
+		Author:       strings.TrimSpace(v.Get("author")),
+		Milestone:    strings.TrimSpace(v.Get("milestone")),
+		Text:         strings.TrimSpace(v.Get("q")),
+	}
+	if len(q.Repositories) == 0 {
+		q.Repositories = []string{defaultRepository}
+	}
+	switch q.State {
+	case "", "OPEN", "CLOSED":
+	default:
+		return q, fmt.Errorf("invalid state %q (want OPEN or CLOSED)", q.State)
+	}
+	switch q.LabelsMatch {
+	case "":
+		q.LabelsMatch = "any"
+	case "any", "all":
+	default:
+		return q, fmt.Errorf("invalid labelsMatch %q (want any or all)", q.LabelsMatch)
+	}
+	if s := v.Get("createdAfter"); s != "" {
+		t, err := parseDate(s)
+		if err != nil {
+			return q, fmt.Errorf("invalid createdAfter, use RFC3339 or YYYY-MM-DD")
+		}
+		q.CreatedAfter = t
+	}
+	return q, nil
+}
+
+// filterIssues returns the issues matching q, for listquery.Find to sort
+// and page.
+func filterIssues(db *gorm.DB, q issueQuery, useFTS bool) *gorm.DB {
+	tx := db.Model(&models.Issue{}).Where("issues.repository_id IN ?", q.Repositories)
+
+	if q.State != "" {
+		tx = tx.Where("issues.state = ?", q.State)
+	}
+	if len(q.Labels) > 0 {
+		sub := db.Table("issue_labels").
+			Select("issue_labels.issue_id").
+			Joins("JOIN labels ON labels.id = issue_labels.label_id").
+			Where("labels.name IN ?", q.Labels)
+		if q.LabelsMatch == "all" {
+			sub = sub.Group("issue_labels.issue_id").
+				Having("COUNT(DISTINCT labels.name) = ?", len(q.Labels))
+		}
+		tx = tx.Where("issues.id IN (?)", sub)
+	}
+	if q.Assignee != "" {
+		sub := db.Table("issue_assignees").
+			Select("issue_assignees.issue_id").
+			Joins("JOIN assignees ON assignees.id = issue_assignees.assignee_id").
+			Joins("JOIN users ON users.id = assignees.user_id").
+			Where("LOWER(users.login) = ?", strings.ToLower(q.Assignee))
+		tx = tx.Where("issues.id IN (?)", sub)
+	}
+	if q.Author != "" {
+		sub := db.Table("users").Select("id").Where("LOWER(login) = ?", strings.ToLower(q.Author))
+		tx = tx.Where("issues.author_id IN (?)", sub)
+	}
+	if q.Milestone != "" {
+		if number, err := strconv.Atoi(q.Milestone); err == nil {
+			sub := db.Table("milestones").Select("id").
+				Where("number = ? AND repository_id IN ?", number, q.Repositories)
+			tx = tx.Where("issues.milestone_id IN (?)", sub)
+		} else {
+			tx = tx.Where("issues.milestone_id = ?", q.Milestone)
+		}
+	}
+	if !q.CreatedAfter.IsZero() {
+		tx = tx.Where("issues.created_at >= ?", q.CreatedAfter)
+	}
+	if q.Text != "" {
+		if useFTS {
+			tx = tx.Where("issues.rowid IN (SELECT rowid FROM "+dbpkg.IssuesFTSTable+" WHERE "+dbpkg.IssuesFTSTable+" MATCH ?)", dbpkg.FTSQuery(q.Text))
+		} else {
+			tx = tx.Where(`LOWER(issues.title) LIKE ? ESCAPE '\'`, "%"+escapeLike(strings.ToLower(q.Text))+"%")
+		}
+	}
+	return tx
+}
+
+// escapeLike makes s match literally in a LIKE pattern escaped by `\`, so
+// `%` and `_` in a search don't act as wildcards.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// splitList splits a comma-separated parameter, dropping empty and repeated
+// entries: labelsMatch=all counts the distinct labels an issue has, so a
+// repeated label would otherwise never match.
+func splitList(s string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" && !seen[p] {
+			seen[p] = true
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// parseDate accepts RFC3339 or YYYY-MM-DD, like /pull-requests/report.
+func parseDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", s)
+}
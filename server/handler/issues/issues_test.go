@@ -0,0 +1,227 @@
+package issues
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	dbpkg "github.com/samouraiworld/topofgnomes/server/db"
+	"github.com/samouraiworld/topofgnomes/server/db/dbtest"
+	"github.com/samouraiworld/topofgnomes/server/models"
+	"gorm.io/gorm"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db := dbtest.Open(t, &models.User{}, &models.Issue{}, &models.Milestone{})
+	if _, err := dbpkg.EnsureIssuesFTS(db); err != nil {
+		t.Fatalf("fts: %v", err)
+	}
+	return db
+}
+
+var issueSeq int
+
+type issueOpt func(*models.Issue)
+
+func seedIssue(t *testing.T, db *gorm.DB, title string, createdAt time.Time, opts ...issueOpt) models.Issue {
+	t.Helper()
+	issueSeq++
+	is := models.Issue{
+		ID:           fmt.Sprintf("i-%03d", issueSeq),
+		Number:       issueSeq,
+		RepositoryID: "gnolang/gno",
+		State:        "OPEN",
+		Title:        title,
+		CreatedAt:    createdAt,
+		UpdatedAt:    createdAt,
+	}
+	for _, o := range opts {
+		o(&is)
+	}
+	if err := db.Create(&is).Error; err != nil {
+		t.Fatalf("seed issue: %v", err)
+	}
+	return is
+}
+
+func withLabels(names ...string) issueOpt {
+	return func(is *models.Issue) {
+		for _, n := range names {
+			is.Labels = append(is.Labels, models.Label{Name: n})
+		}
+	}
+}
+
+func get(t *testing.T, db *gorm.DB, path string) ([]models.Issue, *httptest.ResponseRecorder) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	HandleGetIssues(db).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("%s: status = %d body=%s", path, rec.Code, rec.Body.String())
+	}
+	var got []models.Issue
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	return got, rec
+}
+
+func ids(issues []models.Issue) []string {
+	out := make([]string, len(issues))
+	for i, is := range issues {
+		out[i] = is.Title
+	}
+	return out
+}
+
+func TestHandleGetIssues_LabelsAnyAndAll(t *testing.T) {
+	db := newTestDB(t)
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	seedIssue(t, db, "both", base, withLabels("bounty", "help wanted"))
+	seedIssue(t, db, "bounty-only", base.Add(time.Hour), withLabels("bounty"))
+	seedIssue(t, db, "none", base.Add(2*time.Hour))
+
+	got, _ := get(t, db, "/issues?labels=bounty,help%20wanted")
+	if len(got) != 2 {
+		t.Errorf("any: got %v, want both + bounty-only", ids(got))
+	}
+	got, _ = get(t, db, "/issues?labels=bounty,help%20wanted&labelsMatch=all")
+	if len(got) != 1 || got[0].Title != "both" {
+		t.Errorf("all: got %v, want [both]", ids(got))
+	}
+	got, _ = get(t, db, "/issues?labels=bounty,help%20wanted,bounty&labelsMatch=all")
+	if len(got) != 1 || got[0].Title != "both" {
+		t.Errorf("all with a repeated label: got %v, want [both]", ids(got))
+	}
+}
+
+func TestHandleGetIssues_StateAuthorAndCreatedAfter(t *testing.T) {
+	db := newTestDB(t)
+	if err := db.Create(&models.User{ID: "u-alice", Login: "Alice"}).Error; err != nil {
+		t.Fatalf("seed user: %v", err)
+	}
+	old := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	recent := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	seedIssue(t, db, "old-open", old, func(is *models.Issue) { is.AuthorID = "u-alice" })
+	seedIssue(t, db, "recent-closed", recent, func(is *models.Issue) { is.AuthorID = "u-alice"; is.State = "CLOSED" })
+	seedIssue(t, db, "recent-open", recent, func(is *models.Issue) { is.AuthorID = "u-alice" })
+	seedIssue(t, db, "someone-else", recent)
+
+	got, _ := get(t, db, "/issues?author=alice&state=open&createdAfter=2026-01-01")
+	if len(got) != 1 || got[0].Title != "recent-open" {
+		t.Errorf("got %v, want [recent-open]", ids(got))
+	}
+}
+
+func TestHandleGetIssues_TextSearch(t *testing.T) {
+	db := newTestDB(t)
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	seedIssue(t, db, "realm storage deposit is too high", base)
+	seedIssue(t, db, "gnovm: panic in parser", base)
+
+	got, _ := get(t, db, "/issues?q=storage%20depo")
+	if len(got) != 1 || got[0].Title != "realm storage deposit is too high" {
+		t.Errorf("got %v, want the storage deposit issue", ids(got))
+	}
+}
+
+func TestHandleGetIssues_TextSearchIsLiteral(t *testing.T) {
+	db := newTestDB(t)
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	seedIssue(t, db, "gas used at 100% of the limit", base)
+	seedIssue(t, db, "gas used at 1000 of the limit", base)
+	seedIssue(t, db, "rename max_gas", base)
+	seedIssue(t, db, "rename maxigas", base)
+
+	// The LIKE fallback, whether or not this build has FTS5.
+	for q, want := range map[string]string{
+		"100%":    "gas used at 100% of the limit",
+		"max_gas": "rename max_gas",
+	} {
+		var got []models.Issue
+		if err := filterIssues(db, issueQuery{Repositories: []string{defaultRepository}, Text: q}, false).Find(&got).Error; err != nil {
+			t.Fatalf("q=%s: %v", q, err)
+		}
+		if len(got) != 1 || got[0].Title != want {
+			t.Errorf("q=%s: got %v, want [%s]", q, ids(got), want)
+		}
+	}
+}
+
+func TestHandleGetIssues_NoLimitReturnsEverything(t *testing.T) {
+	db := newTestDB(t)
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 120 {
+		seedIssue(t, db, fmt.Sprintf("issue-%d", i), base.Add(time.Duration(i)*time.Minute), withLabels("bounty"))
+	}
+	got, rec := get(t, db, "/issues?labels=help%20wanted,bounty")
+	if len(got) != 120 {
+		t.Errorf("got %d issues, want all 120", len(got))
+	}
+	if next := rec.Header().Get("X-Next-Cursor"); next != "" {
+		t.Errorf("X-Next-Cursor = %q on an unpaginated request", next)
+	}
+}
+
+func TestHandleGetIssues_CursorPagination(t *testing.T) {
+	db := newTestDB(t)
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 5 {
+		seedIssue(t, db, fmt.Sprintf("issue-%d", i), base.Add(time.Duration(i)*time.Hour))
+	}
+	// Two issues sharing a timestamp exercise the id tie-breaker.
+	seedIssue(t, db, "issue-tie", base.Add(2*time.Hour))
+
+	var seen []string
+	path := "/issues?limit=2"
+	for page := 0; page < 10; page++ {
+		got, rec := get(t, db, path)
+		seen = append(seen, ids(got)...)
+		next := rec.Header().Get("X-Next-Cursor")
+		if next == "" {
+			break
+		}
+		if rec.Header().Get("Link") == "" {
+			t.Fatal("Link header missing while a next page exists")
+		}
+		path = "/issues?limit=2&cursor=" + next
+	}
+	if len(seen) != 6 {
+		t.Fatalf("seen %d issues across pages (%v), want 6", len(seen), seen)
+	}
+	if _, rec := get(t, db, "/issues?limit=2"); rec.Header().Get("X-Total-Count") != "6" {
+		t.Errorf("X-Total-Count = %q, want 6", rec.Header().Get("X-Total-Count"))
+	}
+	if seen[0] != "issue-4" || seen[5] != "issue-0" {
+		t.Errorf("order = %v, want newest first", seen)
+	}
+	dup := map[string]bool{}
+	for _, s := range seen {
+		if dup[s] {
+			t.Errorf("duplicate %q across pages", s)
+		}
+		dup[s] = true
+	}
+}
+
+func TestHandleGetIssues_RejectsBadParams(t *testing.T) {
+	db := newTestDB(t)
+	for _, path := range []string{
+		"/issues?state=merged",
+		"/issues?sort=title",
+		"/issues?labelsMatch=some",
+		"/issues?limit=-1",
+		"/issues?cursor=not-a-cursor",
+		"/issues?createdAfter=yesterday",
+	} {
+		rec := httptest.NewRecorder()
+		HandleGetIssues(db).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", path, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package issues
+
+import (
+	"net/http"
+
+	"github.com/samouraiworld/topofgnomes/server/handler/listquery"
+	"github.com/samouraiworld/topofgnomes/server/models"
+	"github.com/samouraiworld/topofgnomes/server/openapi"
+)
+
+// OpenAPIRoutes describes the routes served by this package.
+func OpenAPIRoutes() []openapi.Route {
+	return []openapi.Route{
+		{
+			Method: http.MethodGet, Path: "/issues", Tag: "issues",
+			Summary:     "List issues",
+			Description: "Pagination travels in the X-Total-Count, X-Next-Cursor and Link headers.",
+			Params: append([]openapi.Param{
+				openapi.QueryParam("repositories", "string", "Comma-separated owner/name list, default "+defaultRepository),
+				openapi.QueryParam("state", "string", "OPEN or CLOSED"),
+				openapi.QueryParam("labels", "string", "Comma-separated label names"),
+				openapi.QueryParam("labelsMatch", "string", "any (default) or all"),
+				openapi.QueryParam("assignee", "string", "Assignee login"),
+				openapi.QueryParam("author", "string", "Author login"),
+				openapi.QueryParam("milestone", "string", "Milestone node ID, or number within repositories"),
+				openapi.QueryParam("createdAfter", "string", "RFC3339 or YYYY-MM-DD"),
+				openapi.QueryParam("q", "string", "Title search, prefix match on the last word"),
+			}, listquery.OpenAPIParams(issuesList)...),
+			Response: []models.Issue{},
+		},
+	}
+}
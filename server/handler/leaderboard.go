@@ -11,6 +11,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/samouraiworld/topofgnomes/server/contributions"
 	"github.com/samouraiworld/topofgnomes/server/models"
 	"gorm.io/gorm"
 )
@@ -29,8 +30,6 @@ type ContributorStats struct {
 
 // Get contributors with stats and scores for the given period and repositories
 func GetContributorsWithScores(db *gorm.DB, since time.Time, repositories []string) ([]ContributorStats, error) {
-	var users []models.User
-
 	// Build excluded repositories slice from env var (comma separated)
 	excludedEnv := os.Getenv("LEADERBOARD_EXCLUDED_REPOS")
 	var excludedRepos []string
@@ -46,51 +45,28 @@ func GetContributorsWithScores(db *gorm.DB, since time.Time, repositories []stri
 		}
 	}
 
-	// Preload associations with conditions (since + repositories filters)
-	cond := func(table string) func(tx *gorm.DB) *gorm.DB {
-		return func(tx *gorm.DB) *gorm.DB {
-			tx2 := tx.Where(table+".created_at >= ?", since)
-			if len(excludedRepos) > 0 {
-				tx2 = tx2.Where(table+".repository_id NOT IN ?", excludedRepos)
-			}
-			if len(repositories) > 0 {
-				tx2 = tx2.Where(table+".repository_id IN ?", repositories)
-			}
-			return tx2
-		}
-	}
-
-	// Retrieve users with data based on conditions
-	err := db.Model(&models.User{}).
-		Preload("Issues", cond("issues")).
-		Preload("Commits", cond("commits")).
-		Preload("PullRequests", cond("pull_requests")).
-		Preload("Reviews", func(tx *gorm.DB) *gorm.DB {
-			scoped := cond("reviews")(tx)
-			return scoped.
-				Joins("JOIN pull_requests ON pull_requests.id = reviews.pull_request_id").
-				Where("pull_requests.state = ?", "MERGED").
-				Where("pull_requests.author_id <> reviews.author_id")
-		}).
-		Find(&users).Error
-
+	totals, err := contributions.Totals(db, contributions.Filter{
+		Since:               since,
+		Repositories:        repositories,
+		ExcludeRepositories: excludedRepos,
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	// Now build the stats slice
-	stats := make([]ContributorStats, 0, len(users))
-	for _, user := range users {
-		commits := int64(len(user.Commits))
-		issues := int64(len(user.Issues))
-		prs := int64(len(user.PullRequests))
-		reviewed := int64(len(user.Reviews))
+	stats := make([]ContributorStats, 0, len(totals))
+	for _, t := range totals {
+		commits := int64(t.Commits)
+		issues := int64(t.Issues)
+		prs := int64(t.PRsOpened)
+		reviewed := int64(t.Reviews)
 		score := CalculateScore(commits, issues, prs, reviewed)
 		if score > 0 {
 			stats = append(stats, ContributorStats{
-				UserID:        user.ID,
-				Login:         user.Login,
-				Name:          user.Name,
+				UserID:        t.UserID,
+				Login:         t.Login,
+				Name:          t.Name,
 				TotalCommits:  commits,
 				TotalIssues:   issues,
 				TotalPRs:      prs,
@@ -3,6 +3,7 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -11,6 +12,8 @@ import (
 	"time"
 
 	"github.com/clerk/clerk-sdk-go/v2"
+	"github.com/samouraiworld/topofgnomes/server/handler/apierror"
+	"github.com/samouraiworld/topofgnomes/server/metrics"
 	"github.com/samouraiworld/topofgnomes/server/models"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
@@ -116,16 +119,14 @@ func HandleGetLeaderboardWebhooks(db *gorm.DB) http.HandlerFunc {
 		w.Header().Set("Content-Type", "application/json")
 		claims, ok := clerk.SessionClaimsFromContext(r.Context())
 		if !ok {
-			w.WriteHeader(http.StatusUnauthorized)
-			w.Write([]byte("Unauthorized"))
+			apierror.Write(w, r, apierror.Unauthorized("missing session"))
 			return
 		}
 		userID := claims.Subject
 		var webhooks []models.LeaderboardWebhook
 		err := db.Where("user_id = ?", userID).Find(&webhooks).Error
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte(err.Error()))
+			apierror.Write(w, r, err)
 			return
 		}
 		json.NewEncoder(w).Encode(webhooks)
@@ -138,29 +139,25 @@ func HandleCreateLeaderboardWebhook(db *gorm.DB) http.HandlerFunc {
 		w.Header().Set("Content-Type", "application/json")
 		claims, ok := clerk.SessionClaimsFromContext(r.Context())
 		if !ok {
-			w.WriteHeader(http.StatusUnauthorized)
-			w.Write([]byte("Unauthorized"))
+			apierror.Write(w, r, apierror.Unauthorized("missing session"))
 			return
 		}
 		userID := claims.Subject
 		var webhook models.LeaderboardWebhook
 		err := json.NewDecoder(r.Body).Decode(&webhook)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte(err.Error()))
+			apierror.Write(w, r, apierror.InvalidInput("%v", err))
 			return
 		}
 		webhook.UserID = userID
 		err = checkWebhookInput(&webhook)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte(err.Error()))
+			apierror.Write(w, r, apierror.InvalidInput("%v", err))
 			return
 		}
 		err = db.Create(&webhook).Error
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte(err.Error()))
+			apierror.Write(w, r, err)
 			return
 		}
 
@@ -175,40 +172,38 @@ func HandleUpdateLeaderboardWebhook(db *gorm.DB) http.HandlerFunc {
 		w.Header().Set("Content-Type", "application/json")
 		claims, ok := clerk.SessionClaimsFromContext(r.Context())
 		if !ok {
-			w.WriteHeader(http.StatusUnauthorized)
-			w.Write([]byte("Unauthorized"))
+			apierror.Write(w, r, apierror.Unauthorized("missing session"))
 			return
 		}
 		userID := claims.Subject
 		id := chi.URLParam(r, "id")
 		var webhook models.LeaderboardWebhook
 		err := db.First(&webhook, id).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			apierror.Write(w, r, apierror.NotFound("webhook %s not found", id))
+			return
+		}
 		if err != nil {
-			w.WriteHeader(http.StatusNotFound)
-			w.Write([]byte("Webhook not found"))
+			apierror.Write(w, r, err)
 			return
 		}
 		if webhook.UserID != userID {
-			w.WriteHeader(http.StatusUnauthorized)
-			w.Write([]byte("Unauthorized"))
+			apierror.Write(w, r, apierror.Unauthorized("webhook %s belongs to another user", id))
 			return
 		}
 		err = json.NewDecoder(r.Body).Decode(&webhook)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte(err.Error()))
+			apierror.Write(w, r, apierror.InvalidInput("%v", err))
 			return
 		}
 		err = checkWebhookInput(&webhook)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte(err.Error()))
+			apierror.Write(w, r, apierror.InvalidInput("%v", err))
 			return
 		}
 		err = db.Save(&webhook).Error
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte(err.Error()))
+			apierror.Write(w, r, err)
 			return
 		}
 		json.NewEncoder(w).Encode(webhook)
@@ -221,28 +216,28 @@ func HandleDeleteLeaderboardWebhook(db *gorm.DB) http.HandlerFunc {
 		w.Header().Set("Content-Type", "application/json")
 		claims, ok := clerk.SessionClaimsFromContext(r.Context())
 		if !ok {
-			w.WriteHeader(http.StatusUnauthorized)
-			w.Write([]byte("Unauthorized"))
+			apierror.Write(w, r, apierror.Unauthorized("missing session"))
 			return
 		}
 		userID := claims.Subject
 		id := chi.URLParam(r, "id")
 		var webhook models.LeaderboardWebhook
 		err := db.First(&webhook, id).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			apierror.Write(w, r, apierror.NotFound("webhook %s not found", id))
+			return
+		}
 		if err != nil {
-			w.WriteHeader(http.StatusNotFound)
-			w.Write([]byte("Webhook not found"))
+			apierror.Write(w, r, err)
 			return
 		}
 		if webhook.UserID != userID {
-			w.WriteHeader(http.StatusUnauthorized)
-			w.Write([]byte("Unauthorized"))
+			apierror.Write(w, r, apierror.Unauthorized("webhook %s belongs to another user", id))
 			return
 		}
 		err = db.Delete(&webhook).Error
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte(err.Error()))
+			apierror.Write(w, r, err)
 			return
 		}
 		w.WriteHeader(http.StatusOK)
@@ -269,6 +264,7 @@ func LoopTriggerLeaderboardWebhooks(ctx context.Context, db *gorm.DB, logger *za
 		for i := range webhooks {
 			webhook := webhooks[i]
 			err := TriggerLeaderboardWebhook(db, webhook)
+			metrics.ObserveWebhookDelivery(webhook.Type, err)
 			if err != nil {
 				logger.Error("Failed to send leaderboard webhook", err)
 				continue
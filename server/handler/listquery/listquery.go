@@ -0,0 +1,357 @@
+// Package listquery is the shared query-parameter layer of the list
+// endpoints: `limit`/`cursor` keyset pagination, `sort`/`order` over a
+// per-endpoint whitelist and `fields=` sparse fieldsets. Bodies stay plain
+// JSON arrays; pagination travels in headers, like GET /issues:
+//
+//	X-Total-Count: rows matching the filters, across all pages
+//	X-Next-Cursor: opaque cursor of the next page (absent on the last one)
+//	Link:          <...>; rel="first", <...>; rel="next"
+//
+// Without `limit` or `cursor` every row comes back, as it did before the
+// endpoints were paginated.
+package listquery
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	gosync "sync"
+
+	"github.com/samouraiworld/topofgnomes/server/handler/apierror"
+	"github.com/samouraiworld/topofgnomes/server/openapi"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	// DefaultLimit is the page size when a cursor comes without a limit.
+	DefaultLimit = 100
+	MaxLimit     = 500
+)
+
+// Spec describes one list endpoint.
+type Spec struct {
+	// Model is a pointer to the listed model; its schema resolves sort
+	// columns and its json tags are the default `fields=` whitelist.
+	Model any
+	// Sorts maps public sort names (the json field names) to columns.
+	Sorts       map[string]string
+	DefaultSort string
+	DefaultDesc bool
+	// Keys are the columns that together identify a row (its primary key),
+	// used as keyset tie-breakers.
+	Keys []string
+	// Fields overrides the `fields=` whitelist when the handler reshapes
+	// rows before encoding them.
+	Fields []string
+	// OptIn are whitelisted fields left out unless `fields=` names them,
+	// for associations too heavy to send by default.
+	OptIn []string
+}
+
+// Params is a parsed list request.
+type Params struct {
+	Sort string
+	Desc bool
+	// Limit is 0 when the client asked for every row.
+	Limit  int
+	Cursor *Cursor
+	Fields []string
+}
+
+// Cursor is the keyset position of the last row of the previous page: the
+// JSON encoding of its sort column value, then of its key columns, so it
+// round-trips any type.
+type Cursor struct {
+	V []json.RawMessage `json:"v"`
+}
+
+// Page is what Find returns besides the rows.
+type Page struct {
+	Total int64
+	Next  string
+}
+
+// Parse validates the shared parameters against spec.
+func Parse(v url.Values, spec Spec) (Params, error) {
+	p := Params{Sort: v.Get("sort"), Desc: spec.DefaultDesc}
+	if p.Sort == "" {
+		p.Sort = spec.DefaultSort
+	}
+	if _, ok := spec.Sorts[p.Sort]; !ok {
+		return p, fmt.Errorf("invalid sort %q (want one of %s)", p.Sort, strings.Join(sortedKeys(spec.Sorts), ", "))
+	}
+	switch o := v.Get("order"); o {
+	case "":
+	case "asc":
+		p.Desc = false
+	case "desc":
+		p.Desc = true
+	default:
+		return p, fmt.Errorf("invalid order %q (want asc or desc)", o)
+	}
+	if s := v.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			return p, fmt.Errorf("invalid limit %q", s)
+		}
+		p.Limit = min(n, MaxLimit)
+	}
+	if s := v.Get("cursor"); s != "" {
+		c, err := decodeCursor(s)
+		if err != nil {
+			return p, fmt.Errorf("invalid cursor")
+		}
+		p.Cursor = &c
+		if p.Limit == 0 {
+			p.Limit = DefaultLimit
+		}
+	}
+	allowed := spec.Fields
+	if allowed == nil {
+		allowed = jsonFields(reflect.TypeOf(spec.Model))
+	}
+	if s := v.Get("fields"); s != "" {
+		for _, f := range strings.Split(s, ",") {
+			if f = strings.TrimSpace(f); f == "" {
+				continue
+			}
+			if !slices.Contains(allowed, f) {
+				return p, fmt.Errorf("invalid field %q", f)
+			}
+			p.Fields = append(p.Fields, f)
+		}
+	} else if len(spec.OptIn) > 0 {
+		for _, f := range allowed {
+			if !slices.Contains(spec.OptIn, f) {
+				p.Fields = append(p.Fields, f)
+			}
+		}
+	}
+	return p, nil
+}
+
+// OpenAPIParams documents the shared parameters for spec's endpoint.
+func OpenAPIParams(spec Spec) []openapi.Param {
+	return []openapi.Param{
+		openapi.QueryParam("limit", "integer", fmt.Sprintf("Page size, max %d; every row without limit or cursor, %d with only a cursor", MaxLimit, DefaultLimit)),
+		openapi.QueryParam("cursor", "string", "Opaque cursor from the X-Next-Cursor header"),
+		openapi.QueryParam("sort", "string", "One of "+strings.Join(sortedKeys(spec.Sorts), ", ")+"; default "+spec.DefaultSort),
+		openapi.QueryParam("order", "string", "asc or desc"),
+		openapi.QueryParam("fields", "string", "Comma-separated JSON field names to keep"),
+	}
+}
+
+// Wants reports whether field is part of the response, so handlers can skip
+// preloading associations the client didn't ask for.
+func (p Params) Wants(field string) bool {
+	return len(p.Fields) == 0 || slices.Contains(p.Fields, field)
+}
+
+var schemaCache gosync.Map
+
+// Find counts the rows matched by tx, then loads one page of them into dest
+// (a pointer to a slice of spec.Model's type). tx must carry only filters:
+// preloads go in preloads so they don't leak into the COUNT query.
+func Find(tx *gorm.DB, p Params, spec Spec, dest any, preloads ...string) (Page, error) {
+	var page Page
+	if err := tx.Session(&gorm.Session{}).Count(&page.Total).Error; err != nil {
+		return page, fmt.Errorf("count: %w", err)
+	}
+
+	sch, err := schema.Parse(spec.Model, &schemaCache, tx.NamingStrategy)
+	if err != nil {
+		return page, fmt.Errorf("parse schema: %w", err)
+	}
+	cols := []string{spec.Sorts[p.Sort]}
+	for _, k := range spec.Keys {
+		if k != cols[0] {
+			cols = append(cols, k)
+		}
+	}
+	fields := make([]*schema.Field, len(cols))
+	for i, col := range cols {
+		if fields[i] = sch.LookUpField(col); fields[i] == nil {
+			return page, fmt.Errorf("unknown column %q on %s", col, sch.Table)
+		}
+	}
+
+	dir, cmp := "ASC", ">"
+	if p.Desc {
+		dir, cmp = "DESC", "<"
+	}
+	q := tx.Session(&gorm.Session{})
+	if p.Cursor != nil {
+		if len(p.Cursor.V) != len(cols) {
+			return page, apierror.InvalidInput("invalid cursor")
+		}
+		values := make([]any, len(cols))
+		for i, f := range fields {
+			v, err := decodeValue(p.Cursor.V[i], f)
+			if err != nil {
+				return page, apierror.InvalidInput("%v", err)
+			}
+			values[i] = v
+		}
+		clause, args := after(cols, values, cmp)
+		q = q.Where(clause, args...)
+	}
+	for _, pre := range preloads {
+		q = q.Preload(pre)
+	}
+	order := make([]string, len(cols))
+	for i, col := range cols {
+		order[i] = col + " " + dir
+	}
+	q = q.Order(strings.Join(order, ", "))
+	if p.Limit > 0 {
+		q = q.Limit(p.Limit + 1)
+	}
+	if err := q.Find(dest).Error; err != nil {
+		return page, err
+	}
+
+	rows := reflect.ValueOf(dest).Elem()
+	if p.Limit > 0 && rows.Len() > p.Limit {
+		rows.SetLen(p.Limit)
+		last := rows.Index(p.Limit - 1)
+		ctx := tx.Statement.Context
+		values := make([]any, len(fields))
+		for i, f := range fields {
+			values[i], _ = f.ValueOf(ctx, last)
+		}
+		page.Next = encodeCursor(values)
+	}
+	return page, nil
+}
+
+// after builds the keyset condition for the rows that come after values in
+// the (cols...) order, comparing a column only where the previous ones tie.
+func after(cols []string, values []any, cmp string) (string, []any) {
+	n := len(cols) - 1
+	clause, args := fmt.Sprintf("%s %s ?", cols[n], cmp), []any{values[n]}
+	for i := n - 1; i >= 0; i-- {
+		clause = fmt.Sprintf("(%s %s ? OR (%s = ? AND %s))", cols[i], cmp, cols[i], clause)
+		args = append([]any{values[i], values[i]}, args...)
+	}
+	return clause, args
+}
+
+// Write sets the pagination headers and encodes rows, keeping only
+// p.Fields when `fields=` was given.
+func Write(w http.ResponseWriter, r *http.Request, p Params, page Page, rows any) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Total-Count", strconv.FormatInt(page.Total, 10))
+	first := *r.URL
+	params := first.Query()
+	params.Del("cursor")
+	first.RawQuery = params.Encode()
+	links := []string{fmt.Sprintf("<%s>; rel=\"first\"", first.RequestURI())}
+	if page.Next != "" {
+		w.Header().Set("X-Next-Cursor", page.Next)
+		params.Set("cursor", page.Next)
+		next := first
+		next.RawQuery = params.Encode()
+		links = append(links, fmt.Sprintf("<%s>; rel=\"next\"", next.RequestURI()))
+	}
+	w.Header().Set("Link", strings.Join(links, ", "))
+
+	if len(p.Fields) == 0 {
+		return json.NewEncoder(w).Encode(rows)
+	}
+	raw, err := json.Marshal(rows)
+	if err != nil {
+		return err
+	}
+	var items []map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return err
+	}
+	out := make([]map[string]json.RawMessage, len(items))
+	for i, item := range items {
+		out[i] = make(map[string]json.RawMessage, len(p.Fields))
+		for _, f := range p.Fields {
+			if v, ok := item[f]; ok {
+				out[i][f] = v
+			}
+		}
+	}
+	return json.NewEncoder(w).Encode(out)
+}
+
+func encodeCursor(values []any) string {
+	var c Cursor
+	for _, v := range values {
+		raw, _ := json.Marshal(v)
+		c.V = append(c.V, raw)
+	}
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(s string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, err
+	}
+	var c Cursor
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return Cursor{}, err
+	}
+	if len(c.V) == 0 {
+		return Cursor{}, fmt.Errorf("incomplete cursor")
+	}
+	return c, nil
+}
+
+// decodeValue turns a cursor component back into the column's Go type so
+// times compare as times rather than as their JSON text.
+func decodeValue(raw json.RawMessage, f *schema.Field) (any, error) {
+	ptr := reflect.New(f.FieldType)
+	if err := json.Unmarshal(raw, ptr.Interface()); err != nil {
+		return nil, fmt.Errorf("invalid cursor value: %w", err)
+	}
+	return ptr.Elem().Interface(), nil
+}
+
+// jsonFields lists the top-level json names of a struct (or pointer to one).
+func jsonFields(t reflect.Type) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var out []string
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			out = append(out, jsonFields(f.Type)...)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		out = append(out, name)
+	}
+	return out
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,209 @@
+package listquery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/samouraiworld/topofgnomes/server/db/dbtest"
+	"github.com/samouraiworld/topofgnomes/server/models"
+	"gorm.io/gorm"
+)
+
+var packagesSpec = Spec{
+	Model:       &models.GnoPackage{},
+	Sorts:       map[string]string{"blockHeight": "block_height", "path": "path"},
+	DefaultSort: "blockHeight",
+	DefaultDesc: true,
+	Keys:        []string{"publisher", "path"},
+}
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db := dbtest.Open(t, &models.GnoPackage{}, &models.Report{})
+	return db
+}
+
+// list runs one request through Parse/Find/Write like the handlers do.
+func list(t *testing.T, db *gorm.DB, spec Spec, dest any, query string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/things?"+query, nil)
+	rec := httptest.NewRecorder()
+	p, err := Parse(r.URL.Query(), spec)
+	if err != nil {
+		t.Fatalf("parse %q: %v", query, err)
+	}
+	page, err := Find(db.Model(spec.Model), p, spec, dest)
+	if err != nil {
+		t.Fatalf("find %q: %v", query, err)
+	}
+	if err := Write(rec, r, p, page, dest); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	return rec
+}
+
+func TestFind_PagesWithTiesAndTotal(t *testing.T) {
+	db := newTestDB(t)
+	// Heights repeat so the path tie-breaker decides the order inside a block.
+	for i := range 7 {
+		pkg := models.GnoPackage{Publisher: "g1", Path: fmt.Sprintf("gno.land/r/demo/p%d", i), BlockHeight: int64(10 + i/2)}
+		if err := db.Create(&pkg).Error; err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+
+	var seen []string
+	query := "limit=3"
+	for range 10 {
+		var pkgs []models.GnoPackage
+		rec := list(t, db, packagesSpec, &pkgs, query)
+		if got := rec.Header().Get("X-Total-Count"); got != "7" {
+			t.Fatalf("X-Total-Count = %q, want 7", got)
+		}
+		for _, p := range pkgs {
+			seen = append(seen, p.Path)
+		}
+		next := rec.Header().Get("X-Next-Cursor")
+		if next == "" {
+			break
+		}
+		query = "limit=3&cursor=" + url.QueryEscape(next)
+	}
+	want := []string{"p6", "p5", "p4", "p3", "p2", "p1", "p0"}
+	if len(seen) != len(want) {
+		t.Fatalf("seen %v, want %d rows", seen, len(want))
+	}
+	for i, w := range want {
+		if seen[i] != "gno.land/r/demo/"+w {
+			t.Errorf("row %d = %s, want %s", i, seen[i], w)
+		}
+	}
+}
+
+func TestFind_TimeSortCursor(t *testing.T) {
+	db := newTestDB(t)
+	base := time.Date(2026, 1, 5, 23, 59, 0, 0, time.UTC)
+	for i := range 3 {
+		r := models.Report{ID: fmt.Sprintf("r%d", i), CreatedAt: base.AddDate(0, 0, 7*i), Data: "{}"}
+		if err := db.Create(&r).Error; err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+	spec := Spec{Model: &models.Report{}, Sorts: map[string]string{"createdAt": "created_at"}, DefaultSort: "createdAt", DefaultDesc: true, Keys: []string{"id"}}
+
+	var first []models.Report
+	rec := list(t, db, spec, &first, "limit=2")
+	var second []models.Report
+	list(t, db, spec, &second, "limit=2&cursor="+url.QueryEscape(rec.Header().Get("X-Next-Cursor")))
+	if len(first) != 2 || first[0].ID != "r2" || len(second) != 1 || second[0].ID != "r0" {
+		t.Errorf("pages = %v / %v, want [r2 r1] / [r0]", first, second)
+	}
+}
+
+func TestFind_CompositeKey(t *testing.T) {
+	db := newTestDB(t)
+	// The same path under several publishers, all in one block.
+	for _, publisher := range []string{"g1", "g2", "g3"} {
+		for _, path := range []string{"a", "b"} {
+			if err := db.Create(&models.GnoPackage{Publisher: publisher, Path: path, BlockHeight: 1}).Error; err != nil {
+				t.Fatalf("seed: %v", err)
+			}
+		}
+	}
+	var seen []string
+	query := "sort=path&order=asc&limit=2"
+	for range 10 {
+		var pkgs []models.GnoPackage
+		rec := list(t, db, packagesSpec, &pkgs, query)
+		for _, p := range pkgs {
+			seen = append(seen, p.Path+"@"+p.Publisher)
+		}
+		next := rec.Header().Get("X-Next-Cursor")
+		if next == "" {
+			break
+		}
+		query = "sort=path&order=asc&limit=2&cursor=" + url.QueryEscape(next)
+	}
+	want := "a@g1 a@g2 a@g3 b@g1 b@g2 b@g3"
+	if got := strings.Join(seen, " "); got != want {
+		t.Errorf("pages = %s, want %s", got, want)
+	}
+}
+
+func TestFind_NoLimitReturnsEverything(t *testing.T) {
+	db := newTestDB(t)
+	for i := range DefaultLimit + 5 {
+		if err := db.Create(&models.GnoPackage{Publisher: "g1", Path: fmt.Sprintf("p%03d", i)}).Error; err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+	var pkgs []models.GnoPackage
+	rec := list(t, db, packagesSpec, &pkgs, "")
+	if len(pkgs) != DefaultLimit+5 || rec.Header().Get("X-Next-Cursor") != "" {
+		t.Errorf("got %d rows, next %q, want every row", len(pkgs), rec.Header().Get("X-Next-Cursor"))
+	}
+}
+
+func TestWrite_SparseFieldsAndLinks(t *testing.T) {
+	db := newTestDB(t)
+	for i := range 2 {
+		if err := db.Create(&models.GnoPackage{Publisher: "g1", Path: fmt.Sprintf("p%d", i), Namespace: "demo"}).Error; err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+	var pkgs []models.GnoPackage
+	rec := list(t, db, packagesSpec, &pkgs, "fields=path&sort=path&order=asc&limit=1")
+
+	var body []map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(body) != 1 || len(body[0]) != 1 || body[0]["path"] != "p0" {
+		t.Errorf("body = %v, want [{path: p0}]", body)
+	}
+	link := rec.Header().Get("Link")
+	if link == "" || !strings.Contains(link, `rel="first"`) || !strings.Contains(link, `rel="next"`) {
+		t.Errorf("Link = %q, want first and next", link)
+	}
+}
+
+func TestParse_OptInFields(t *testing.T) {
+	spec := Spec{Model: &models.GnoProposal{}, Sorts: map[string]string{"id": "id"}, DefaultSort: "id", OptIn: []string{"files"}}
+
+	p, err := Parse(url.Values{}, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Wants("files") || !p.Wants("votes") || !p.Wants("title") {
+		t.Errorf("default fields = %v, want everything but files", p.Fields)
+	}
+
+	p, err = Parse(url.Values{"fields": {"id,files"}}, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Wants("files") || p.Wants("votes") {
+		t.Errorf("fields=id,files gave %v", p.Fields)
+	}
+}
+
+func TestParse_RejectsBadParams(t *testing.T) {
+	for _, q := range []string{
+		"sort=title",
+		"order=up",
+		"limit=0",
+		"cursor=not-a-cursor",
+		"fields=path,secret",
+	} {
+		v, _ := url.ParseQuery(q)
+		if _, err := Parse(v, packagesSpec); err == nil {
+			t.Errorf("%s: expected an error", q)
+		}
+	}
+}
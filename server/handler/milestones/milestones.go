@@ -0,0 +1,302 @@
+// Package milestones wires the milestone progress endpoints: per-repository
+// listings with open/closed counts and a single-milestone view with a daily
+// burndown reconstructed from issue close timestamps.
+package milestones
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/samouraiworld/topofgnomes/server/apicache"
+	"github.com/samouraiworld/topofgnomes/server/handler/apierror"
+	"github.com/samouraiworld/topofgnomes/server/models"
+	"gorm.io/gorm"
+)
+
+const (
+	milestonesSchemaVer = 1
+	// burndownMaxDays caps the series so year-long milestones (gno's test
+	// milestones run for a long time) keep a bounded payload.
+	burndownMaxDays = 365
+	// legacyRepositoryID is the repo numeric ids resolve against when no
+	// `?repository=` is given — the frontend still calls /milestones/{number}.
+	legacyRepositoryID = "gnolang/gno"
+)
+
+// Progress is the open/closed breakdown of a milestone. Closed PRs include
+// merged ones; PercentComplete counts issues and PRs together, like GitHub.
+type Progress struct {
+	OpenIssues         int     `json:"openIssues"`
+	ClosedIssues       int     `json:"closedIssues"`
+	OpenPullRequests   int     `json:"openPullRequests"`
+	ClosedPullRequests int     `json:"closedPullRequests"`
+	PercentComplete    float64 `json:"percentComplete"`
+}
+
+// BurndownPoint is the state of a milestone's issues at the end of one UTC day.
+type BurndownPoint struct {
+	Date   string `json:"date"`
+	Open   int    `json:"open"`
+	Closed int    `json:"closed"`
+}
+
+// MilestoneSummary is one row of the per-repository listing.
+type MilestoneSummary struct {
+	ID           string     `json:"id"`
+	RepositoryID string     `json:"repositoryID"`
+	Number       int        `json:"number"`
+	Title        string     `json:"title"`
+	State        string     `json:"state"`
+	URL          string     `json:"URL"`
+	DueOn        *time.Time `json:"dueOn"`
+	ClosedAt     *time.Time `json:"closedAt"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
+	Progress     Progress   `json:"progress"`
+}
+
+type listResponse struct {
+	SchemaVersion int                `json:"schemaVersion"`
+	RepositoryID  string             `json:"repositoryID"`
+	Milestones    []MilestoneSummary `json:"milestones"`
+}
+
+// milestoneResponse embeds the model so the legacy /milestones/{number}
+// shape (issues, author, ...) stays intact for existing clients.
+type milestoneResponse struct {
+	models.Milestone
+	SchemaVersion int             `json:"schemaVersion"`
+	Progress      Progress        `json:"progress"`
+	Burndown      []BurndownPoint `json:"burndown"`
+}
+
+// HandleListByRepository serves GET /repositories/{owner}/{name}/milestones.
+// `?state=OPEN|CLOSED` narrows the listing. Cached per (repo, state) until
+// the next sync.
+func HandleListByRepository(db *gorm.DB, cache *apicache.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		w.Header().Set("Content-Type", "application/json")
+		repoID := chi.URLParam(r, "owner") + "/" + chi.URLParam(r, "name")
+		state := r.URL.Query().Get("state")
+		key := fmt.Sprintf("milestones:list:%s:%s", repoID, state)
+		cache.Serve(w, r, key, []apicache.Domain{apicache.GitHub}, func() (any, error) {
+			summaries, err := listMilestones(db, repoID, state)
+			if err != nil {
+				return nil, err
+			}
+			return listResponse{
+				SchemaVersion: milestonesSchemaVer,
+				RepositoryID:  repoID,
+				Milestones:    summaries,
+			}, nil
+		})
+	}
+}
+
+// HandleGetByID serves GET /milestones/{id}. The id is the GitHub node id;
+// a purely numeric id is treated as a milestone number inside
+// `?repository=owner/name` (default gnolang/gno) for backward compatibility.
+func HandleGetByID(db *gorm.DB, cache *apicache.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		w.Header().Set("Content-Type", "application/json")
+		id := chi.URLParam(r, "id")
+		repoID := r.URL.Query().Get("repository")
+		if repoID == "" {
+			repoID = legacyRepositoryID
+		}
+		key := fmt.Sprintf("milestones:one:%s:%s", id, repoID)
+		cache.Serve(w, r, key, []apicache.Domain{apicache.GitHub, apicache.Clock}, func() (any, error) {
+			resp, err := getMilestone(db, id, repoID, time.Now().UTC())
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, apierror.NotFound("milestone %q not found", id)
+			}
+			return resp, err
+		})
+	}
+}
+
+func listMilestones(db *gorm.DB, repoID, state string) ([]MilestoneSummary, error) {
+	q := db.Model(&models.Milestone{}).Where("repository_id = ?", repoID).Order("number DESC")
+	if state != "" {
+		q = q.Where("state = ?", state)
+	}
+	var ms []models.Milestone
+	if err := q.Find(&ms).Error; err != nil {
+		return nil, fmt.Errorf("milestones query: %w", err)
+	}
+	ids := make([]string, len(ms))
+	for i, m := range ms {
+		ids[i] = m.ID
+	}
+	progress, err := queryProgress(db, ids)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]MilestoneSummary, 0, len(ms))
+	for _, m := range ms {
+		out = append(out, MilestoneSummary{
+			ID:           m.ID,
+			RepositoryID: m.RepositoryID,
+			Number:       m.Number,
+			Title:        m.Title,
+			State:        m.State,
+			URL:          m.Url,
+			DueOn:        m.DueOn,
+			ClosedAt:     m.ClosedAt,
+			CreatedAt:    m.CreatedAt,
+			UpdatedAt:    m.UpdatedAt,
+			Progress:     progress[m.ID],
+		})
+	}
+	return out, nil
+}
+
+func getMilestone(db *gorm.DB, id, repoID string, now time.Time) (milestoneResponse, error) {
+	q := db.Model(&models.Milestone{}).
+		Preload("Author").
+		Preload("Issues").Preload("Issues.Author").Preload("Issues.Assignees.User").Preload("Issues.Labels").
+		Preload("PullRequests").Preload("PullRequests.Author")
+	if number, err := strconv.Atoi(id); err == nil {
+		q = q.Where("number = ? AND repository_id = ?", number, repoID)
+	} else {
+		q = q.Where("id = ?", id)
+	}
+	var m models.Milestone
+	if err := q.First(&m).Error; err != nil {
+		return milestoneResponse{}, err
+	}
+	progress, err := queryProgress(db, []string{m.ID})
+	if err != nil {
+		return milestoneResponse{}, err
+	}
+	return milestoneResponse{
+		Milestone:     m,
+		SchemaVersion: milestonesSchemaVer,
+		Progress:      progress[m.ID],
+		Burndown:      burndown(m, now),
+	}, nil
+}
+
+// queryProgress counts issues and PRs per (milestone, state) in two GROUP BY
+// queries and folds them into a Progress per milestone id.
+func queryProgress(db *gorm.DB, milestoneIDs []string) (map[string]Progress, error) {
+	out := make(map[string]Progress, len(milestoneIDs))
+	if len(milestoneIDs) == 0 {
+		return out, nil
+	}
+	type row struct {
+		MilestoneID string `gorm:"column:milestone_id"`
+		State       string `gorm:"column:state"`
+		Cnt         int    `gorm:"column:cnt"`
+	}
+
+	var issueRows []row
+	if err := db.Model(&models.Issue{}).
+		Select("milestone_id, state, COUNT(*) AS cnt").
+		Where("milestone_id IN ?", milestoneIDs).
+		Group("milestone_id, state").
+		Scan(&issueRows).Error; err != nil {
+		return nil, fmt.Errorf("milestone issues query: %w", err)
+	}
+	for _, r := range issueRows {
+		p := out[r.MilestoneID]
+		if r.State == "OPEN" {
+			p.OpenIssues += r.Cnt
+		} else {
+			p.ClosedIssues += r.Cnt
+		}
+		out[r.MilestoneID] = p
+	}
+
+	var prRows []row
+	if err := db.Model(&models.PullRequest{}).
+		Select("milestone_id, state, COUNT(*) AS cnt").
+		Where("milestone_id IN ?", milestoneIDs).
+		Group("milestone_id, state").
+		Scan(&prRows).Error; err != nil {
+		return nil, fmt.Errorf("milestone pull requests query: %w", err)
+	}
+	for _, r := range prRows {
+		p := out[r.MilestoneID]
+		if r.State == "OPEN" {
+			p.OpenPullRequests += r.Cnt
+		} else {
+			p.ClosedPullRequests += r.Cnt
+		}
+		out[r.MilestoneID] = p
+	}
+
+	for id, p := range out {
+		total := p.OpenIssues + p.ClosedIssues + p.OpenPullRequests + p.ClosedPullRequests
+		if total > 0 {
+			p.PercentComplete = float64(p.ClosedIssues+p.ClosedPullRequests) * 100 / float64(total)
+		}
+		out[id] = p
+	}
+	return out, nil
+}
+
+// burndown replays the milestone's issues day by day: an issue counts as
+// open from its creation day until the day it was closed. Closed issues
+// without a closed_at (synced before the column existed) fall back to
+// their last update, which is the best approximation we have.
+func burndown(m models.Milestone, now time.Time) []BurndownPoint {
+	if len(m.Issues) == 0 {
+		return []BurndownPoint{}
+	}
+	start := m.CreatedAt.UTC()
+	for _, is := range m.Issues {
+		if is.CreatedAt.Before(start) {
+			start = is.CreatedAt.UTC()
+		}
+	}
+	end := now.UTC()
+	if m.ClosedAt != nil && m.ClosedAt.Before(end) {
+		end = m.ClosedAt.UTC()
+	}
+	start = truncateDay(start)
+	end = truncateDay(end)
+	if days := int(end.Sub(start).Hours() / 24); days >= burndownMaxDays {
+		start = end.AddDate(0, 0, -(burndownMaxDays - 1))
+	}
+
+	points := make([]BurndownPoint, 0, int(end.Sub(start).Hours()/24)+1)
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		dayEnd := day.AddDate(0, 0, 1)
+		p := BurndownPoint{Date: day.Format("2006-01-02")}
+		for _, is := range m.Issues {
+			if !is.CreatedAt.Before(dayEnd) {
+				continue
+			}
+			closedAt := issueClosedAt(is)
+			if closedAt != nil && closedAt.Before(dayEnd) {
+				p.Closed++
+			} else {
+				p.Open++
+			}
+		}
+		points = append(points, p)
+	}
+	return points
+}
+
+func issueClosedAt(is models.Issue) *time.Time {
+	if is.ClosedAt != nil {
+		return is.ClosedAt
+	}
+	if is.State == "CLOSED" {
+		ts := is.UpdatedAt
+		return &ts
+	}
+	return nil
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,166 @@
+package milestones
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/samouraiworld/topofgnomes/server/db/dbtest"
+	"github.com/samouraiworld/topofgnomes/server/models"
+	"gorm.io/gorm"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db := dbtest.Open(t, &models.User{}, &models.Milestone{}, &models.Issue{}, &models.PullRequest{})
+	return db
+}
+
+func day(s string) time.Time {
+	ts, _ := time.Parse("2006-01-02", s)
+	return ts.UTC()
+}
+
+func seedMilestone(t *testing.T, db *gorm.DB, id, repoID string, number int, createdAt time.Time) {
+	t.Helper()
+	m := models.Milestone{ID: id, RepositoryID: repoID, Number: number, State: "OPEN", Title: id, CreatedAt: createdAt}
+	if err := db.Create(&m).Error; err != nil {
+		t.Fatalf("seed milestone: %v", err)
+	}
+}
+
+func seedIssue(t *testing.T, db *gorm.DB, id, milestoneID string, createdAt time.Time, closedAt *time.Time) {
+	t.Helper()
+	state := "OPEN"
+	if closedAt != nil {
+		state = "CLOSED"
+	}
+	is := models.Issue{ID: id, RepositoryID: "gnolang/gno", MilestoneID: milestoneID, State: state, CreatedAt: createdAt, ClosedAt: closedAt}
+	if err := db.Create(&is).Error; err != nil {
+		t.Fatalf("seed issue: %v", err)
+	}
+}
+
+func seedPR(t *testing.T, db *gorm.DB, id, milestoneID, state string) {
+	t.Helper()
+	pr := models.PullRequest{ID: id, RepositoryID: "gnolang/gno", MilestoneID: milestoneID, State: state}
+	if err := db.Create(&pr).Error; err != nil {
+		t.Fatalf("seed pr: %v", err)
+	}
+}
+
+func TestHandleListByRepository_ProgressCounts(t *testing.T) {
+	db := newTestDB(t)
+	seedMilestone(t, db, "MI_1", "gnolang/gno", 1, day("2026-01-01"))
+	seedMilestone(t, db, "MI_2", "onbloc/gnoscan", 1, day("2026-01-01"))
+
+	closed := day("2026-01-03")
+	seedIssue(t, db, "i-1", "MI_1", day("2026-01-01"), nil)
+	seedIssue(t, db, "i-2", "MI_1", day("2026-01-01"), &closed)
+	seedPR(t, db, "pr-1", "MI_1", "MERGED")
+	seedPR(t, db, "pr-2", "MI_1", "OPEN")
+	seedIssue(t, db, "i-3", "MI_2", day("2026-01-01"), nil) // other repo — must not leak
+
+	r := chi.NewRouter()
+	r.Get("/repositories/{owner}/{name}/milestones", HandleListByRepository(db, nil))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/repositories/gnolang/gno/milestones", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", rec.Code, rec.Body.String())
+	}
+	var got listResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(got.Milestones) != 1 || got.Milestones[0].ID != "MI_1" {
+		t.Fatalf("milestones = %+v, want only MI_1", got.Milestones)
+	}
+	p := got.Milestones[0].Progress
+	if p.OpenIssues != 1 || p.ClosedIssues != 1 || p.OpenPullRequests != 1 || p.ClosedPullRequests != 1 {
+		t.Errorf("progress = %+v, want 1/1/1/1", p)
+	}
+	if p.PercentComplete != 50 {
+		t.Errorf("percentComplete = %v, want 50", p.PercentComplete)
+	}
+}
+
+func TestHandleGetByID_NodeIDAndLegacyNumber(t *testing.T) {
+	db := newTestDB(t)
+	seedMilestone(t, db, "MI_gno", "gnolang/gno", 7, day("2026-01-01"))
+	seedMilestone(t, db, "MI_scan", "onbloc/gnoscan", 7, day("2026-01-01"))
+
+	r := chi.NewRouter()
+	r.Get("/milestones/{id}", HandleGetByID(db, nil))
+
+	cases := []struct {
+		path string
+		want string
+	}{
+		{"/milestones/MI_scan", "MI_scan"},
+		{"/milestones/7", "MI_gno"}, // legacy: number within gnolang/gno
+		{"/milestones/7?repository=onbloc/gnoscan", "MI_scan"},
+	}
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, c.path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d", c.path, rec.Code)
+		}
+		var got milestoneResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		if got.ID != c.want {
+			t.Errorf("%s: id = %q, want %q", c.path, got.ID, c.want)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/milestones/MI_missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("missing milestone status = %d, want 404", rec.Code)
+	}
+}
+
+func TestBurndown_ReplaysCloseTimestamps(t *testing.T) {
+	closedDay2 := day("2026-01-02").Add(10 * time.Hour)
+	m := models.Milestone{
+		CreatedAt: day("2026-01-01"),
+		Issues: []models.Issue{
+			{CreatedAt: day("2026-01-01"), ClosedAt: &closedDay2, State: "CLOSED"},
+			{CreatedAt: day("2026-01-01"), State: "OPEN"},
+			{CreatedAt: day("2026-01-03"), State: "OPEN"},
+		},
+	}
+	got := burndown(m, day("2026-01-03").Add(12*time.Hour))
+	want := []BurndownPoint{
+		{Date: "2026-01-01", Open: 2, Closed: 0},
+		{Date: "2026-01-02", Open: 1, Closed: 1},
+		{Date: "2026-01-03", Open: 2, Closed: 1},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("points = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("point %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestBurndown_CapsWindow(t *testing.T) {
+	m := models.Milestone{
+		CreatedAt: day("2023-01-01"),
+		Issues:    []models.Issue{{CreatedAt: day("2023-01-01"), State: "OPEN"}},
+	}
+	got := burndown(m, day("2026-01-01"))
+	if len(got) != burndownMaxDays {
+		t.Errorf("len = %d, want %d", len(got), burndownMaxDays)
+	}
+	if got[len(got)-1].Date != "2026-01-01" {
+		t.Errorf("last point = %s, want 2026-01-01", got[len(got)-1].Date)
+	}
+}
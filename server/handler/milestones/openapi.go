@@ -0,0 +1,34 @@
+package milestones
+
+import (
+	"net/http"
+
+	"github.com/samouraiworld/topofgnomes/server/openapi"
+)
+
+// OpenAPIRoutes describes the routes served by this package.
+func OpenAPIRoutes() []openapi.Route {
+	return []openapi.Route{
+		{
+			Method: http.MethodGet, Path: "/repositories/{owner}/{name}/milestones", Tag: "milestones",
+			Summary: "Milestones of a repository with progress",
+			Params: []openapi.Param{
+				openapi.PathParam("owner", "Repository owner"),
+				openapi.PathParam("name", "Repository name"),
+				openapi.QueryParam("state", "string", "OPEN or CLOSED"),
+			},
+			Response: listResponse{},
+			Cached:   true,
+		},
+		{
+			Method: http.MethodGet, Path: "/milestones/{id}", Tag: "milestones",
+			Summary: "One milestone with progress and burndown",
+			Params: []openapi.Param{
+				openapi.PathParam("id", "GitHub node id, or a milestone number within ?repository="),
+				openapi.QueryParam("repository", "string", "owner/name for numeric ids, default "+legacyRepositoryID),
+			},
+			Response: milestoneResponse{},
+			Cached:   true,
+		},
+	}
+}
@@ -1,209 +1,237 @@
 package handler
 
 import (
-	"encoding/json"
-	"log"
 	"net/http"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/samouraiworld/topofgnomes/server/apicache"
+	"github.com/samouraiworld/topofgnomes/server/handler/apierror"
+	"github.com/samouraiworld/topofgnomes/server/handler/listquery"
+	"github.com/samouraiworld/topofgnomes/server/logging"
 	"github.com/samouraiworld/topofgnomes/server/models"
 	"github.com/samouraiworld/topofgnomes/server/sync"
 	"gorm.io/gorm"
 )
 
+var packagesList = listquery.Spec{
+	Model:       &models.GnoPackage{},
+	Sorts:       map[string]string{"blockHeight": "block_height", "path": "path", "namespace": "namespace"},
+	DefaultSort: "blockHeight",
+	DefaultDesc: true,
+	Keys:        []string{"publisher", "path"},
+}
+
 // HandleGetAllPackages handles GET /api/onchain/packages
-// It returns all the packages registered on the Gno blockchain
+// It returns one page of the packages registered on the Gno blockchain
+// (see listquery for limit/cursor/sort/order/fields)
 func HandleGetAllPackages(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
 		w.Header().Set("Content-Type", "application/json")
+		params, err := listquery.Parse(r.URL.Query(), packagesList)
+		if err != nil {
+			apierror.Write(w, r, apierror.InvalidInput("%v", err))
+			return
+		}
 		var pkgs []models.GnoPackage
-		if err := db.Find(&pkgs).Error; err != nil {
-			log.Printf("[HandleGetAllPackages] DB error: %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		page, err := listquery.Find(db.Model(&models.GnoPackage{}), params, packagesList, &pkgs)
+		if err != nil {
+			apierror.Write(w, r, err)
 			return
 		}
-		log.Printf("[HandleGetAllPackages] Found %d packages", len(pkgs))
-		json.NewEncoder(w).Encode(pkgs)
+		logging.FromContext(r.Context()).Debugw("listed packages", "count", len(pkgs), "total", page.Total)
+		listquery.Write(w, r, params, page, pkgs)
 	}
 }
 
 // HandleGetPackagesByUser handles GET /api/onchain/packages/{address}
 // It returns all the packages registered on the Gno blockchain by a specific address
-func HandleGetPackagesByUser(db *gorm.DB) http.HandlerFunc {
+func HandleGetPackagesByUser(db *gorm.DB, cache *apicache.Cache) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
 		w.Header().Set("Content-Type", "application/json")
 		address := chi.URLParam(r, "address")
 		if address == "" {
-			log.Printf("[HandleGetPackagesByUser] Missing address parameter")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{"error": "address parameter is required"})
+			apierror.Write(w, r, apierror.InvalidInput("address parameter is required"))
 			return
 		}
-		var pkgs []models.GnoPackage
-		if err := db.Where("publisher = ?", address).Find(&pkgs).Error; err != nil {
-			log.Printf("[HandleGetPackagesByUser] DB error for address %s: %v", address, err)
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
-			return
-		}
-		log.Printf("[HandleGetPackagesByUser] Found %d packages for address %s", len(pkgs), address)
-		json.NewEncoder(w).Encode(pkgs)
+		cache.Serve(w, r, "onchain:packages:"+address, []apicache.Domain{apicache.Onchain}, func() (any, error) {
+			var pkgs []models.GnoPackage
+			if err := db.Where("publisher = ?", address).Find(&pkgs).Error; err != nil {
+				return nil, err
+			}
+			logging.FromContext(r.Context()).Debugw("listed packages", "count", len(pkgs), "address", address)
+			return pkgs, nil
+		})
 	}
 }
 
+var namespacesList = listquery.Spec{
+	Model:       &models.GnoNamespace{},
+	Sorts:       map[string]string{"blockHeight": "block_height", "namespace": "namespace"},
+	DefaultSort: "blockHeight",
+	DefaultDesc: true,
+	Keys:        []string{"hash"},
+}
+
 // HandleGetAllNamespaces handles GET /api/onchain/namespaces
-// It returns all the namespaces registered on the Gno blockchain
+// It returns one page of the namespaces registered on the Gno blockchain
 func HandleGetAllNamespaces(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
 		w.Header().Set("Content-Type", "application/json")
+		params, err := listquery.Parse(r.URL.Query(), namespacesList)
+		if err != nil {
+			apierror.Write(w, r, apierror.InvalidInput("%v", err))
+			return
+		}
 		var namespaces []models.GnoNamespace
-		if err := db.Find(&namespaces).Error; err != nil {
-			log.Printf("[HandleGetAllNamespaces] DB error: %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		page, err := listquery.Find(db.Model(&models.GnoNamespace{}), params, namespacesList, &namespaces)
+		if err != nil {
+			apierror.Write(w, r, err)
 			return
 		}
-		log.Printf("[HandleGetAllNamespaces] Found %d namespaces", len(namespaces))
-		json.NewEncoder(w).Encode(namespaces)
+		logging.FromContext(r.Context()).Debugw("listed namespaces", "count", len(namespaces), "total", page.Total)
+		listquery.Write(w, r, params, page, namespaces)
 	}
 }
 
 // HandleGetNamespacesByUser handles GET /api/onchain/namespaces/{address}
 // It returns all the namespaces registered on the Gno blockchain by a specific address
-func HandleGetNamespacesByUser(db *gorm.DB) http.HandlerFunc {
+func HandleGetNamespacesByUser(db *gorm.DB, cache *apicache.Cache) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
 		w.Header().Set("Content-Type", "application/json")
 		address := chi.URLParam(r, "address")
 		if address == "" {
-			log.Printf("[HandleGetNamespacesByUser] Missing address parameter")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{"error": "address parameter is required"})
+			apierror.Write(w, r, apierror.InvalidInput("address parameter is required"))
 			return
 		}
-		var namespaces []models.GnoNamespace
-		if err := db.Where("address = ?", address).Find(&namespaces).Error; err != nil {
-			log.Printf("[HandleGetNamespacesByUser] DB error for address %s: %v", address, err)
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
-			return
-		}
-		log.Printf("[HandleGetNamespacesByUser] Found %d namespaces for address %s", len(namespaces), address)
-		json.NewEncoder(w).Encode(namespaces)
+		cache.Serve(w, r, "onchain:namespaces:"+address, []apicache.Domain{apicache.Onchain}, func() (any, error) {
+			var namespaces []models.GnoNamespace
+			if err := db.Where("address = ?", address).Find(&namespaces).Error; err != nil {
+				return nil, err
+			}
+			logging.FromContext(r.Context()).Debugw("listed namespaces", "count", len(namespaces), "address", address)
+			return namespaces, nil
+		})
 	}
 }
 
+var proposalsList = listquery.Spec{
+	Model:       &models.GnoProposal{},
+	Sorts:       map[string]string{"blockHeight": "block_height", "id": "id", "executionHeight": "execution_height"},
+	DefaultSort: "blockHeight",
+	DefaultDesc: true,
+	Keys:        []string{"id"},
+	// File bodies make up most of a proposal: /onchain/proposals/{id} has
+	// them, the list only with `fields=...,files`.
+	OptIn: []string{"files"},
+}
+
 // HandleGetAllProposals handles GET /api/onchain/proposals
-// It returns all the proposals registered on the Gno blockchain
+// It returns one page of the proposals registered on the Gno blockchain,
+// with their votes unless `fields=` leaves them out, and their files only
+// when it names them.
 func HandleGetAllProposals(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
 		w.Header().Set("Content-Type", "application/json")
-		var pkgs []models.GnoProposal
-		address := r.URL.Query().Get("address")
-		query := db.Model(&models.GnoProposal{}).Preload("Files").Preload("Votes")
-
-		if address != "" {
+		params, err := listquery.Parse(r.URL.Query(), proposalsList)
+		if err != nil {
+			apierror.Write(w, r, apierror.InvalidInput("%v", err))
+			return
+		}
+		query := db.Model(&models.GnoProposal{})
+		if address := r.URL.Query().Get("address"); address != "" {
 			query = query.Where("address = ?", address)
 		}
+		var preloads []string
+		if params.Wants("files") {
+			preloads = append(preloads, "Files")
+		}
+		if params.Wants("votes") {
+			preloads = append(preloads, "Votes")
+		}
 
-		err := query.Find(&pkgs).Error
+		var proposals []models.GnoProposal
+		page, err := listquery.Find(query, params, proposalsList, &proposals, preloads...)
 		if err != nil {
-			log.Printf("[HandleGetAllProposals] DB error : %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			apierror.Write(w, r, err)
 			return
 		}
-		json.NewEncoder(w).Encode(pkgs)
+		listquery.Write(w, r, params, page, proposals)
 	}
 }
 
 // HandleGetProposal handles GET /api/onchain/proposals/{id}
 // It returns a specific proposal registered on the Gno blockchain
-func HandleGetProposal(db *gorm.DB) http.HandlerFunc {
+func HandleGetProposal(db *gorm.DB, cache *apicache.Cache) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
 		w.Header().Set("Content-Type", "application/json")
-		var proposal models.GnoProposal
 		id := chi.URLParam(r, "id")
 
 		if id == "" {
-			log.Printf("[HandleGetProposal] Missing id parameter")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{"error": "id parameter is required"})
+			apierror.Write(w, r, apierror.InvalidInput("id parameter is required"))
 			return
 		}
 
-		err := db.Model(&models.GnoProposal{}).Preload("Files").Preload("Votes").Where("id = ?", id).First(&proposal).Error
-
-		if err != nil {
+		cache.Serve(w, r, "onchain:proposal:"+id, []apicache.Domain{apicache.Onchain}, func() (any, error) {
+			var proposal models.GnoProposal
+			err := db.Model(&models.GnoProposal{}).Preload("Files").Preload("Votes").Where("id = ?", id).First(&proposal).Error
 			if err == gorm.ErrRecordNotFound {
-				log.Printf("[HandleGetProposal] Proposal not found: %s", id)
-				w.WriteHeader(http.StatusNotFound)
-				json.NewEncoder(w).Encode(map[string]string{"error": "proposal not found"})
-				return
+				return nil, apierror.NotFound("proposal %s not found", id)
 			}
-			log.Printf("[HandleGetProposal] DB error : %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
-			return
-		}
-		json.NewEncoder(w).Encode(proposal)
+			return proposal, err
+		})
 	}
 }
 
 // HandleGetGovdaoMembers handles GET /api/onchain/govdao-members
 // It returns all the current govdao members registered on the Gno blockchain
-func HandleGetGovdaoMembers(db *gorm.DB) http.HandlerFunc {
+func HandleGetGovdaoMembers(db *gorm.DB, cache *apicache.Cache) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
 		w.Header().Set("Content-Type", "application/json")
-		var members []models.GovDaoMember
-		query := db.Model(&models.GovDaoMember{})
-
-		err := query.Find(&members).Error
-		if err != nil {
-			log.Printf("[HandleGetAllProposals] DB error : %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
-			return
-		}
-		json.NewEncoder(w).Encode(members)
+		cache.Serve(w, r, "onchain:govdao-members", []apicache.Domain{apicache.Onchain}, func() (any, error) {
+			var members []models.GovDaoMember
+			err := db.Model(&models.GovDaoMember{}).Find(&members).Error
+			return members, err
+		})
 	}
 }
 
+// voteWithProposal is one row of GET /onchain/votes/{address}.
+type voteWithProposal struct {
+	ProposalID    string `json:"proposalId"`
+	ProposalTitle string `json:"proposalTitle"`
+	Vote          string `json:"vote"`
+}
+
 // HandleGetVotesByUser handles GET /onchain/votes/{address}
 // It returns the list of votes made by a specific address across all proposals.
-func HandleGetVotesByUser(db *gorm.DB) http.HandlerFunc {
-	type voteWithProposal struct {
-		ProposalID    string `json:"proposalId"`
-		ProposalTitle string `json:"proposalTitle"`
-		Vote          string `json:"vote"`
-	}
-
+func HandleGetVotesByUser(db *gorm.DB, cache *apicache.Cache) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
 		w.Header().Set("Content-Type", "application/json")
 		address := chi.URLParam(r, "address")
 		if address == "" {
-			log.Printf("[HandleGetVotesByUser] Missing address parameter")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{"error": "address parameter is required"})
-			return
-		}
-
-		var results []voteWithProposal
-		err := db.Table("gno_votes as v").
-			Select("p.id as proposal_id, p.title as proposal_title, v.vote").
-			Joins("JOIN gno_proposals p ON p.id = v.proposal_id").
-			Where("v.address = ?", address).
-			Order("p.block_height DESC, v.block_height DESC").
-			Scan(&results).Error
-		if err != nil {
-			log.Printf("[HandleGetVotesByUser] DB error for address %s: %v", address, err)
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			apierror.Write(w, r, apierror.InvalidInput("address parameter is required"))
 			return
 		}
 
-		json.NewEncoder(w).Encode(results)
+		cache.Serve(w, r, "onchain:votes:"+address, []apicache.Domain{apicache.Onchain}, func() (any, error) {
+			var results []voteWithProposal
+			err := db.Table("gno_votes as v").
+				Select("p.id as proposal_id, p.title as proposal_title, v.vote").
+				Joins("JOIN gno_proposals p ON p.id = v.proposal_id").
+				Where("v.address = ?", address).
+				Order("p.block_height DESC, v.block_height DESC").
+				Scan(&results).Error
+			return results, err
+		})
 	}
 }
 
@@ -215,9 +243,7 @@ func HandleSynchronizeVotes(syncer *sync.Syncer) http.HandlerFunc {
 			time.Sleep(time.Second)
 			newVotes, err := syncer.SyncVotesOnProposals(r.Context())
 			if err != nil {
-				log.Printf("[HandleSynchronizeVotes] DB error : %v", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+				apierror.Write(w, r, apierror.Upstream("gno indexer", err))
 				return
 			}
 			if newVotes{
@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/samouraiworld/topofgnomes/server/handler/listquery"
+	"github.com/samouraiworld/topofgnomes/server/handler/viewmodels"
+	"github.com/samouraiworld/topofgnomes/server/models"
+	"github.com/samouraiworld/topofgnomes/server/openapi"
+)
+
+var (
+	timeParam         = openapi.QueryParam("time", "string", "Period: daily, weekly, monthly or yearly; all time when omitted")
+	repositoriesParam = openapi.QueryParam("repositories", "string", "Comma-separated owner/name list, default gnolang/gno")
+)
+
+// OpenAPIRoutes describes the routes served by this package.
+func OpenAPIRoutes() []openapi.Route {
+	return []openapi.Route{
+		{
+			Method: http.MethodGet, Path: "/repositories", Tag: "repositories",
+			Summary:  "List tracked repositories",
+			Params:   listquery.OpenAPIParams(repositoriesList),
+			Response: []models.Repository{},
+		},
+		{
+			Method: http.MethodGet, Path: "/stats", Tag: "stats",
+			Summary: "Contributor leaderboard with per-user stats",
+			Params: []openapi.Param{
+				timeParam,
+				repositoriesParam,
+				openapi.QueryParam("exclude", "string", "Login to leave out; repeatable"),
+			},
+			Response: UserStatsResponse{},
+			Cached:   true,
+		},
+		{
+			Method: http.MethodGet, Path: "/last-prs", Tag: "stats",
+			Summary:  "Most recent pull requests",
+			Params:   []openapi.Param{timeParam, repositoriesParam},
+			Response: []*models.PullRequest{},
+			Cached:   true,
+		},
+		{
+			Method: http.MethodGet, Path: "/users", Tag: "users",
+			Summary: "List users",
+			Params: append([]openapi.Param{
+				openapi.QueryParam("addresses", "string", "Comma-separated wallet addresses to restrict to"),
+			}, listquery.OpenAPIParams(usersList)...),
+			Response: []models.User{},
+		},
+		{
+			Method: http.MethodGet, Path: "/users/{address}", Tag: "users",
+			Summary:  "Get the user linked to a wallet address",
+			Params:   []openapi.Param{openapi.PathParam("address", "Gno wallet address")},
+			Response: models.User{},
+		},
+		{
+			Method: http.MethodGet, Path: "/contributors/newest", Tag: "users",
+			Summary: "Newest contributors by first pull request",
+			Params: []openapi.Param{
+				openapi.QueryParam("number", "integer", "How many contributors to return"),
+				repositoriesParam,
+			},
+			Response: []models.User{},
+		},
+		{
+			Method: http.MethodGet, Path: "/pull-requests/report", Tag: "pull-requests",
+			Summary: "Pull requests grouped by status over a date range",
+			Params: []openapi.Param{
+				openapi.QueryParam("startdate", "string", "RFC3339 or YYYY-MM-DD; required"),
+				openapi.QueryParam("enddate", "string", "RFC3339 or YYYY-MM-DD; required"),
+			},
+			Response: viewmodels.PullRequestsReportResponse{},
+		},
+		{
+			Method: http.MethodGet, Path: "/score-factors", Tag: "stats",
+			Summary:  "Weights used to compute contributor scores",
+			Response: map[string]float64{},
+		},
+		{
+			Method: http.MethodGet, Path: "/github/verify", Tag: "github",
+			Summary: "Verify a GitHub account and register it on-chain",
+			Params: []openapi.Param{
+				openapi.QueryParam("token", "string", "GitHub access token"),
+				openapi.QueryParam("login", "string", "GitHub login"),
+				openapi.QueryParam("address", "string", "Gno wallet address"),
+			},
+			Response: resCallback{},
+		},
+		{
+			Method: http.MethodGet, Path: "/github/oauth/exchange", Tag: "github",
+			Summary:  "Exchange a GitHub OAuth code for the user and token",
+			Params:   []openapi.Param{openapi.QueryParam("code", "string", "OAuth authorization code")},
+			Response: GithubInfo{},
+		},
+		{
+			Method: http.MethodPost, Path: "/github/link", Tag: "github",
+			Summary:  "Link a wallet address to a GitHub login",
+			Body:     linkBody{},
+			Response: resCallback{},
+		},
+		{
+			Method: http.MethodGet, Path: "/leaderboard-webhooks", Tag: "webhooks", Auth: true,
+			Summary:  "List the caller's leaderboard webhooks",
+			Response: []models.LeaderboardWebhook{},
+		},
+		{
+			Method: http.MethodPost, Path: "/leaderboard-webhooks", Tag: "webhooks", Auth: true,
+			Summary:  "Create a leaderboard webhook",
+			Body:     models.LeaderboardWebhook{},
+			Response: models.LeaderboardWebhook{},
+			Status:   http.StatusCreated,
+		},
+		{
+			Method: http.MethodPut, Path: "/leaderboard-webhooks/{id}", Tag: "webhooks", Auth: true,
+			Summary:  "Update a leaderboard webhook",
+			Params:   []openapi.Param{openapi.PathParam("id", "Webhook id")},
+			Body:     models.LeaderboardWebhook{},
+			Response: models.LeaderboardWebhook{},
+		},
+		{
+			Method: http.MethodDelete, Path: "/leaderboard-webhooks/{id}", Tag: "webhooks", Auth: true,
+			Summary: "Delete a leaderboard webhook",
+			Params:  []openapi.Param{openapi.PathParam("id", "Webhook id")},
+		},
+		{
+			Method: http.MethodGet, Path: "/onchain/packages", Tag: "onchain",
+			Summary:  "List published gno packages",
+			Params:   listquery.OpenAPIParams(packagesList),
+			Response: []models.GnoPackage{},
+		},
+		{
+			Method: http.MethodGet, Path: "/onchain/packages/{address}", Tag: "onchain",
+			Summary:  "Packages published by an address",
+			Params:   []openapi.Param{openapi.PathParam("address", "Gno wallet address")},
+			Response: []models.GnoPackage{},
+			Cached:   true,
+		},
+		{
+			Method: http.MethodGet, Path: "/onchain/namespaces", Tag: "onchain",
+			Summary:  "List registered namespaces",
+			Params:   listquery.OpenAPIParams(namespacesList),
+			Response: []models.GnoNamespace{},
+		},
+		{
+			Method: http.MethodGet, Path: "/onchain/namespaces/{address}", Tag: "onchain",
+			Summary:  "Namespaces registered by an address",
+			Params:   []openapi.Param{openapi.PathParam("address", "Gno wallet address")},
+			Response: []models.GnoNamespace{},
+			Cached:   true,
+		},
+		{
+			Method: http.MethodGet, Path: "/onchain/proposals", Tag: "onchain",
+			Summary: "List GovDAO proposals",
+			Params: append([]openapi.Param{
+				openapi.QueryParam("address", "string", "Only proposals created by this address"),
+			}, listquery.OpenAPIParams(proposalsList)...),
+			Response: []models.GnoProposal{},
+		},
+		{
+			Method: http.MethodGet, Path: "/onchain/proposals/{id}", Tag: "onchain",
+			Summary:  "Get a GovDAO proposal with its files and votes",
+			Params:   []openapi.Param{openapi.PathParam("id", "Proposal id")},
+			Response: models.GnoProposal{},
+			Cached:   true,
+		},
+		{
+			Method: http.MethodGet, Path: "/onchain/govdao-members", Tag: "onchain",
+			Summary:  "Current GovDAO members",
+			Response: []models.GovDaoMember{},
+			Cached:   true,
+		},
+		{
+			Method: http.MethodGet, Path: "/onchain/votes/{address}", Tag: "onchain",
+			Summary:  "Votes cast by an address",
+			Params:   []openapi.Param{openapi.PathParam("address", "Gno wallet address")},
+			Response: []voteWithProposal{},
+			Cached:   true,
+		},
+		{
+			Method: http.MethodPut, Path: "/on-chain/votes", Tag: "onchain",
+			Summary: "Re-sync proposal votes after a new vote was cast",
+		},
+	}
+}
@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/samouraiworld/topofgnomes/server/models"
+	"gorm.io/gorm"
+)
+
+// PeriodStart returns the start of a ?period= / ?time= window (daily,
+// weekly, monthly or yearly) ending now, in UTC; the zero time for
+// anything else, meaning all-time.
+func PeriodStart(period string) time.Time {
+	now := time.Now().UTC()
+	switch period {
+	case "daily":
+		return now.AddDate(0, 0, -1)
+	case "weekly":
+		return now.AddDate(0, 0, -7)
+	case "monthly":
+		return now.AddDate(0, -1, 0)
+	case "yearly":
+		return now.AddDate(-1, 0, 0)
+	default:
+		return time.Time{} // all-time
+	}
+}
+
+// LastSyncedAt reads the global sync_status row, nil if unset.
+func LastSyncedAt(db *gorm.DB) *time.Time {
+	var syncStatus models.SyncStatus
+	if err := db.First(&syncStatus, 1).Error; err != nil || syncStatus.LastSyncedAt.IsZero() {
+		return nil
+	}
+	ts := syncStatus.LastSyncedAt.UTC()
+	return &ts
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/samouraiworld/topofgnomes/server/handler/apierror"
 	"github.com/samouraiworld/topofgnomes/server/handler/viewmodels"
 	"github.com/samouraiworld/topofgnomes/server/models"
 	"github.com/samouraiworld/topofgnomes/server/repository"
@@ -19,8 +20,7 @@ func GetPullrequestsReportByDate(repo repository.PullRequestRepository) func(w h
 		var mergedPRs, inProgressPRs, reviewedPRs, waitingForReviewPRs, blockedPRs []models.PullRequest
 
 		if startDate == "" || endDate == "" {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte("startdate and enddate are required"))
+			apierror.Write(w, r, apierror.InvalidInput("startdate and enddate are required"))
 			return
 		}
 
@@ -33,21 +33,18 @@ func GetPullrequestsReportByDate(repo repository.PullRequestRepository) func(w h
 		}
 		start, err := parseDate(startDate)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte("invalid startdate format, use RFC3339 or YYYY-MM-DD"))
+			apierror.Write(w, r, apierror.InvalidInput("invalid startdate format, use RFC3339 or YYYY-MM-DD"))
 			return
 		}
 		end, err := parseDate(endDate)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte("invalid enddate format, use RFC3339 or YYYY-MM-DD"))
+			apierror.Write(w, r, apierror.InvalidInput("invalid enddate format, use RFC3339 or YYYY-MM-DD"))
 			return
 		}
 
 		pullRequests, err := repo.FindForReport(start, end)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte(err.Error()))
+			apierror.Write(w, r, err)
 			return
 		}
 
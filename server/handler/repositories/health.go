@@ -0,0 +1,312 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/samouraiworld/topofgnomes/server/apicache"
+	"github.com/samouraiworld/topofgnomes/server/contributions"
+	"github.com/samouraiworld/topofgnomes/server/handler"
+	"github.com/samouraiworld/topofgnomes/server/handler/apierror"
+	"github.com/samouraiworld/topofgnomes/server/models"
+	"gorm.io/gorm"
+)
+
+const (
+	healthSchemaVer = 1
+	// PRs with no update for this many days are stale.
+	defaultStaleDays = 30
+	maxStaleDays     = 365
+	maxStalePRs      = 20
+)
+
+// openPRAgeBuckets are the upper bounds, in days, of the open PR age
+// distribution; the last bucket has none.
+var openPRAgeBuckets = []struct {
+	label string
+	days  int
+}{
+	{"<1d", 1},
+	{"1-7d", 7},
+	{"7-30d", 30},
+	{"30-90d", 90},
+	{">=90d", 0},
+}
+
+// AgeBucket is how many open PRs are of an age.
+type AgeBucket struct {
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+// IssueFlow is how many issues a repository opened and closed over the
+// window.
+type IssueFlow struct {
+	Open   int `json:"open"`
+	Opened int `json:"opened"`
+	Closed int `json:"closed"`
+	// ClosedPerOpened is Closed / Opened, nil when no issue was opened.
+	ClosedPerOpened *float64 `json:"closedPerOpened"`
+}
+
+// StalePR is an open PR nobody touched for a while.
+type StalePR struct {
+	Number    int       `json:"number"`
+	Title     string    `json:"title"`
+	URL       string    `json:"URL"`
+	Author    string    `json:"author"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	DaysStale int       `json:"daysStale"`
+}
+
+// ContributorCounts counts contributors over the last 30 and 90 days.
+type ContributorCounts struct {
+	Days30 int `json:"days30"`
+	Days90 int `json:"days90"`
+}
+
+// Release is a repository's latest GitHub release.
+type Release struct {
+	Tag         string     `json:"tag"`
+	PublishedAt *time.Time `json:"publishedAt"`
+}
+
+type healthResponse struct {
+	SchemaVersion int        `json:"schemaVersion"`
+	LastSyncedAt  *time.Time `json:"lastSyncedAt"`
+	RepositoryID  string     `json:"repositoryID"`
+	WindowDays    int        `json:"windowDays"`
+	StaleDays     int        `json:"staleDays"`
+	OpenPRs       int        `json:"openPRs"`
+	// OpenPRAges buckets the open PRs by how long ago they were opened.
+	OpenPRAges []AgeBucket `json:"openPRAges"`
+	// MedianTimeToMergeHours is over the PRs merged in the window, nil when
+	// none was.
+	MedianTimeToMergeHours *float64  `json:"medianTimeToMergeHours"`
+	MergedPRs              int       `json:"mergedPRs"`
+	Issues                 IssueFlow `json:"issues"`
+	StalePRCount           int       `json:"stalePRCount"`
+	// StalePRs are the least recently updated stale PRs, at most 20.
+	StalePRs []StalePR `json:"stalePRs"`
+	// ActiveContributors did anything to the repository in the last 30 and
+	// 90 days; FirstTimeContributors did so for the first time.
+	ActiveContributors    ContributorCounts `json:"activeContributors"`
+	FirstTimeContributors ContributorCounts `json:"firstTimeContributors"`
+	LastRelease           *Release          `json:"lastRelease"`
+}
+
+// HandleGetHealth serves GET /repositories/{owner}/{name}/health: open PRs
+// and how old they are, the median time to merge and the issue flow over
+// ?window= days (90 by default), PRs without update for ?staleDays= days (30
+// by default), active and first-time contributors, and the latest release.
+// Cached until the next sync, five minutes at most.
+func HandleGetHealth(db *gorm.DB, cache *apicache.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		w.Header().Set("Content-Type", "application/json")
+		repoID := chi.URLParam(r, "owner") + "/" + chi.URLParam(r, "name")
+		days, staleDays, err := healthParams(r)
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+		key := fmt.Sprintf("repositories:health:%s:%d:%d", repoID, days, staleDays)
+		cache.Serve(w, r, key, []apicache.Domain{apicache.GitHub, apicache.Clock}, func() (any, error) {
+			var repo models.Repository
+			if err := db.First(&repo, "id = ?", repoID).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, apierror.NotFound("repository %q not found", repoID)
+			} else if err != nil {
+				return nil, err
+			}
+			return computeHealth(db, repo, days, staleDays, time.Now().UTC())
+		})
+	}
+}
+
+func healthParams(r *http.Request) (days, staleDays int, err error) {
+	days, staleDays = defaultWindowDays, defaultStaleDays
+	if s := r.URL.Query().Get("window"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > maxWindowDays {
+			return 0, 0, apierror.InvalidInput("invalid window %q, use 1 to %d days", s, maxWindowDays)
+		}
+		days = n
+	}
+	if s := r.URL.Query().Get("staleDays"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > maxStaleDays {
+			return 0, 0, apierror.InvalidInput("invalid staleDays %q, use 1 to %d", s, maxStaleDays)
+		}
+		staleDays = n
+	}
+	return days, staleDays, nil
+}
+
+// openPRRow is an open pull request with its author's login.
+type openPRRow struct {
+	Number    int       `gorm:"column:number"`
+	Title     string    `gorm:"column:title"`
+	URL       string    `gorm:"column:url"`
+	Login     string    `gorm:"column:login"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at"`
+}
+
+func computeHealth(db *gorm.DB, repo models.Repository, days, staleDays int, now time.Time) (healthResponse, error) {
+	resp := healthResponse{
+		SchemaVersion: healthSchemaVer,
+		LastSyncedAt:  handler.LastSyncedAt(db),
+		RepositoryID:  repo.ID,
+		WindowDays:    days,
+		StaleDays:     staleDays,
+		StalePRs:      []StalePR{},
+	}
+	from := now.AddDate(0, 0, -days)
+
+	var open []openPRRow
+	err := db.Model(&models.PullRequest{}).
+		Select("pull_requests.number AS number, pull_requests.title AS title, pull_requests.url AS url, users.login AS login, pull_requests.created_at AS created_at, pull_requests.updated_at AS updated_at").
+		Joins("LEFT JOIN users ON users.id = pull_requests.author_id").
+		Where("pull_requests.repository_id = ? AND pull_requests.state = ?", repo.ID, "OPEN").
+		Order("pull_requests.updated_at, pull_requests.number").
+		Scan(&open).Error
+	if err != nil {
+		return healthResponse{}, fmt.Errorf("open pull requests query: %w", err)
+	}
+	resp.OpenPRs = len(open)
+	resp.OpenPRAges = make([]AgeBucket, len(openPRAgeBuckets))
+	for i, b := range openPRAgeBuckets {
+		resp.OpenPRAges[i].Label = b.label
+	}
+	staleBefore := now.AddDate(0, 0, -staleDays)
+	for _, pr := range open {
+		resp.OpenPRAges[ageBucket(now.Sub(pr.CreatedAt))].Count++
+		if !pr.UpdatedAt.Before(staleBefore) {
+			continue
+		}
+		resp.StalePRCount++
+		if len(resp.StalePRs) < maxStalePRs {
+			resp.StalePRs = append(resp.StalePRs, StalePR{
+				Number:    pr.Number,
+				Title:     pr.Title,
+				URL:       pr.URL,
+				Author:    pr.Login,
+				UpdatedAt: pr.UpdatedAt.UTC(),
+				DaysStale: int(now.Sub(pr.UpdatedAt).Hours() / 24),
+			})
+		}
+	}
+
+	var merged []models.PullRequest
+	err = db.Select("created_at", "merged_at").
+		Where("repository_id = ? AND state = ? AND merged_at >= ?", repo.ID, "MERGED", from).
+		Find(&merged).Error
+	if err != nil {
+		return healthResponse{}, fmt.Errorf("merged pull requests query: %w", err)
+	}
+	resp.MergedPRs = len(merged)
+	hours := make([]float64, 0, len(merged))
+	for _, pr := range merged {
+		hours = append(hours, pr.MergedAt.Sub(pr.CreatedAt).Hours())
+	}
+	resp.MedianTimeToMergeHours = median(hours)
+
+	if resp.Issues, err = issueFlow(db, repo.ID, from); err != nil {
+		return healthResponse{}, err
+	}
+	if resp.ActiveContributors, resp.FirstTimeContributors, err = contributorCounts(db, repo.ID, now); err != nil {
+		return healthResponse{}, err
+	}
+	if repo.LatestReleaseTag != "" {
+		resp.LastRelease = &Release{Tag: repo.LatestReleaseTag, PublishedAt: repo.LatestReleaseAt}
+	}
+	return resp, nil
+}
+
+// ageBucket returns the index in openPRAgeBuckets of a PR that age.
+func ageBucket(age time.Duration) int {
+	for i, b := range openPRAgeBuckets {
+		if b.days > 0 && age < time.Duration(b.days)*24*time.Hour {
+			return i
+		}
+	}
+	return len(openPRAgeBuckets) - 1
+}
+
+// median rounds to a tenth of an hour; nil for no value.
+func median(values []float64) *float64 {
+	if len(values) == 0 {
+		return nil
+	}
+	sort.Float64s(values)
+	m := values[len(values)/2]
+	if len(values)%2 == 0 {
+		m = (values[len(values)/2-1] + m) / 2
+	}
+	m = math.Round(m*10) / 10
+	return &m
+}
+
+func issueFlow(db *gorm.DB, repoID string, from time.Time) (IssueFlow, error) {
+	var flow struct {
+		Open   int
+		Opened int
+		Closed int
+	}
+	err := db.Model(&models.Issue{}).
+		Select("SUM(CASE WHEN state = ? THEN 1 ELSE 0 END) AS open, SUM(CASE WHEN created_at >= ? THEN 1 ELSE 0 END) AS opened, SUM(CASE WHEN closed_at >= ? THEN 1 ELSE 0 END) AS closed", "OPEN", from, from).
+		Where("repository_id = ?", repoID).
+		Scan(&flow).Error
+	if err != nil {
+		return IssueFlow{}, fmt.Errorf("issues query: %w", err)
+	}
+	out := IssueFlow{Open: flow.Open, Opened: flow.Opened, Closed: flow.Closed}
+	if out.Opened > 0 {
+		rate := math.Round(float64(out.Closed)/float64(out.Opened)*100) / 100
+		out.ClosedPerOpened = &rate
+	}
+	return out, nil
+}
+
+// contributorCounts reads when each contributor was last and first active
+// on the repository from daily_contributions.
+func contributorCounts(db *gorm.DB, repoID string, now time.Time) (active, firstTime ContributorCounts, err error) {
+	var rows []struct {
+		UserID string
+		First  string
+		Last   string
+	}
+	err = db.Model(&models.DailyContribution{}).
+		Select("user_id, MIN(day) AS first, MAX(day) AS last").
+		Where("repo_id = ?", repoID).
+		Where("(commits > 0 OR prs_opened > 0 OR prs_merged > 0 OR issues > 0 OR reviews > 0)").
+		Group("user_id").
+		Scan(&rows).Error
+	if err != nil {
+		return active, firstTime, fmt.Errorf("contributors query: %w", err)
+	}
+	today := now.Truncate(24 * time.Hour)
+	from30 := contributions.Day(today.AddDate(0, 0, -29))
+	from90 := contributions.Day(today.AddDate(0, 0, -89))
+	for _, r := range rows {
+		if r.Last >= from30 {
+			active.Days30++
+		}
+		if r.Last >= from90 {
+			active.Days90++
+		}
+		if r.First >= from30 {
+			firstTime.Days30++
+		}
+		if r.First >= from90 {
+			firstTime.Days90++
+		}
+	}
+	return active, firstTime, nil
+}
@@ -0,0 +1,152 @@
+package repositories
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/samouraiworld/topofgnomes/server/db/dbtest"
+	"github.com/samouraiworld/topofgnomes/server/models"
+	"gorm.io/gorm"
+)
+
+func seedHealth(t *testing.T, now time.Time) *gorm.DB {
+	t.Helper()
+	db := dbtest.Open(t, &models.Repository{}, &models.User{}, &models.PullRequest{}, &models.Issue{}, &models.DailyContribution{}, &models.SyncStatus{})
+	released := now.AddDate(0, 0, -10)
+	records := []any{
+		&models.Repository{ID: "o/r", Owner: "o", Name: "r", LatestReleaseTag: "v1.2.0", LatestReleaseAt: &released},
+		&models.Repository{ID: "o/quiet", Owner: "o", Name: "quiet"},
+		&models.User{ID: "u-alice", Login: "alice"},
+	}
+	ago := func(days float64) time.Time { return now.Add(-time.Duration(days * 24 * float64(time.Hour))) }
+	ptr := func(t time.Time) *time.Time { return &t }
+	for i, pr := range []models.PullRequest{
+		{State: "OPEN", CreatedAt: ago(0.5), UpdatedAt: ago(0.5)},
+		{State: "OPEN", CreatedAt: ago(3), UpdatedAt: ago(1)},
+		{State: "OPEN", CreatedAt: ago(40), UpdatedAt: ago(35), AuthorID: "u-alice"},
+		{State: "OPEN", CreatedAt: ago(200), UpdatedAt: ago(100)},
+		{State: "MERGED", CreatedAt: ago(12), MergedAt: ptr(ago(10))},
+		{State: "MERGED", CreatedAt: ago(5), MergedAt: ptr(ago(4))},
+		{State: "MERGED", CreatedAt: ago(3), MergedAt: ptr(ago(2.5))},
+		{State: "MERGED", CreatedAt: ago(300), MergedAt: ptr(ago(200))}, // before the window
+		{State: "CLOSED", CreatedAt: ago(2), UpdatedAt: ago(100)},
+	} {
+		pr.ID, pr.Number, pr.RepositoryID = "pr"+string(rune('a'+i)), i+1, "o/r"
+		records = append(records, &pr)
+	}
+	for i, issue := range []models.Issue{
+		{State: "OPEN", CreatedAt: ago(5)},
+		{State: "OPEN", CreatedAt: ago(200)},
+		{State: "CLOSED", CreatedAt: ago(20), ClosedAt: ptr(ago(2))},
+		{State: "CLOSED", CreatedAt: ago(300), ClosedAt: ptr(ago(50))},
+		{State: "CLOSED", CreatedAt: ago(300), ClosedAt: ptr(ago(250))},
+	} {
+		issue.ID, issue.Number, issue.RepositoryID = "issue"+string(rune('a'+i)), i+1, "o/r"
+		records = append(records, &issue)
+	}
+	for _, row := range []models.DailyContribution{
+		{UserID: "u-alice", Day: "2026-06-10", Commits: 1},
+		{UserID: "u-alice", Day: "2025-01-01", Commits: 1},
+		{UserID: "u-bob", Day: "2026-05-01", Reviews: 1},
+		{UserID: "u-carol", Day: "2026-06-14", Issues: 1},
+		{UserID: "u-dave", Day: "2026-06-14"}, // nothing counted
+	} {
+		row.RepoID = "o/r"
+		records = append(records, &row)
+	}
+	for _, rec := range records {
+		if err := db.Create(rec).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func TestComputeHealth(t *testing.T) {
+	now := time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC)
+	db := seedHealth(t, now)
+	var repo models.Repository
+	if err := db.First(&repo, "id = ?", "o/r").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := computeHealth(db, repo, 90, 30, now)
+	if err != nil {
+		t.Fatalf("computeHealth: %v", err)
+	}
+	if resp.OpenPRs != 4 {
+		t.Errorf("open PRs = %d, want 4", resp.OpenPRs)
+	}
+	wantAges := []AgeBucket{{"<1d", 1}, {"1-7d", 1}, {"7-30d", 0}, {"30-90d", 1}, {">=90d", 1}}
+	if !reflect.DeepEqual(resp.OpenPRAges, wantAges) {
+		t.Errorf("ages = %+v, want %+v", resp.OpenPRAges, wantAges)
+	}
+	if resp.MergedPRs != 3 || resp.MedianTimeToMergeHours == nil || *resp.MedianTimeToMergeHours != 24 {
+		t.Errorf("merged = %d, median = %v, want 3, 24", resp.MergedPRs, resp.MedianTimeToMergeHours)
+	}
+	if i := resp.Issues; i.Open != 2 || i.Opened != 2 || i.Closed != 2 || i.ClosedPerOpened == nil || *i.ClosedPerOpened != 1 {
+		t.Errorf("issues = %+v", i)
+	}
+	if resp.StalePRCount != 2 || len(resp.StalePRs) != 2 {
+		t.Fatalf("stale = %d %+v, want 2", resp.StalePRCount, resp.StalePRs)
+	}
+	if s := resp.StalePRs[0]; s.Number != 4 || s.DaysStale != 100 {
+		t.Errorf("stalest PR = %+v, want #4, 100 days", s)
+	}
+	if s := resp.StalePRs[1]; s.Number != 3 || s.Author != "alice" || s.DaysStale != 35 {
+		t.Errorf("second stale PR = %+v, want #3 by alice, 35 days", s)
+	}
+	if want := (ContributorCounts{Days30: 2, Days90: 3}); resp.ActiveContributors != want {
+		t.Errorf("active = %+v, want %+v", resp.ActiveContributors, want)
+	}
+	if want := (ContributorCounts{Days30: 1, Days90: 2}); resp.FirstTimeContributors != want {
+		t.Errorf("first-time = %+v, want %+v", resp.FirstTimeContributors, want)
+	}
+	if resp.LastRelease == nil || resp.LastRelease.Tag != "v1.2.0" || resp.LastRelease.PublishedAt == nil {
+		t.Errorf("last release = %+v", resp.LastRelease)
+	}
+
+	resp, err = computeHealth(db, repo, 30, 90, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StalePRCount != 1 || resp.Issues.Opened != 2 || resp.Issues.Closed != 1 {
+		t.Errorf("30-day window, 90 stale days = %+v", resp)
+	}
+}
+
+func TestHandleHealth(t *testing.T) {
+	db := seedHealth(t, time.Now().UTC())
+	r := chi.NewRouter()
+	r.Get("/repositories/{owner}/{name}/health", HandleGetHealth(db, nil))
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/repositories/o/quiet/health?window=30&staleDays=7")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", rec.Code, rec.Body)
+	}
+	var resp healthResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v body=%s", err, rec.Body)
+	}
+	if resp.OpenPRs != 0 || resp.MedianTimeToMergeHours != nil || resp.Issues.ClosedPerOpened != nil || resp.LastRelease != nil || len(resp.OpenPRAges) != 5 {
+		t.Errorf("quiet repository = %+v", resp)
+	}
+	if rec := get("/repositories/o/nope/health"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown repository: status = %d", rec.Code)
+	}
+	for _, q := range []string{"window=0", "window=x", "staleDays=366"} {
+		if rec := get("/repositories/o/r/health?" + q); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d", q, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package repositories
+
+import (
+	"net/http"
+
+	"github.com/samouraiworld/topofgnomes/server/openapi"
+)
+
+// OpenAPIRoutes describes the routes served by this package.
+func OpenAPIRoutes() []openapi.Route {
+	owner := openapi.PathParam("owner", "Repository owner")
+	name := openapi.PathParam("name", "Repository name")
+	window := openapi.QueryParam("window", "integer", "Rolling window of the bus factor, top owners and trend, in days; default 90, max 730")
+	months := openapi.QueryParam("months", "integer", "Monthly trend points, default 12, max 36")
+	staleDays := openapi.QueryParam("staleDays", "integer", "Days without update after which an open PR is stale; default 30, max 365")
+	return []openapi.Route{
+		{
+			Method: http.MethodGet, Path: "/repositories/ownership", Tag: "repositories",
+			Summary:     "Bus factor of every tracked repository",
+			Description: "The bus factor is the fewest contributors behind half of the merged PRs of the window; droppedToOne flags the repositories where it fell to 1.",
+			Params:      []openapi.Param{window, months},
+			Response:    []RepoOwnership{},
+			Cached:      true,
+		},
+		{
+			Method: http.MethodGet, Path: "/repositories/{owner}/{name}/ownership", Tag: "repositories",
+			Summary:     "Knowledge concentration of a repository",
+			Description: "The fewest contributors behind 50% and 80% of merged PRs and commits over 30, 90 and 365 days and all time, the top owners and a monthly trend over the window.",
+			Params:      []openapi.Param{owner, name, window, months},
+			Response:    ownershipResponse{},
+			Cached:      true,
+		},
+		{
+			Method: http.MethodGet, Path: "/repositories/{owner}/{name}/health", Tag: "repositories",
+			Summary:     "Health of a repository",
+			Description: "Open PRs by age, the median time to merge and issues opened and closed over the window, stale PRs, active and first-time contributors over 30 and 90 days and the latest release.",
+			Params:      []openapi.Param{owner, name, openapi.QueryParam("window", "integer", "Window of the merge time and issue flow, in days; default 90, max 730"), staleDays},
+			Response:    healthResponse{},
+			Cached:      true,
+		},
+	}
+}
@@ -0,0 +1,322 @@
+// Package repositories wires the per-repository analytics endpoints under
+// /repositories/{owner}/{name}, built on the data the syncer stores.
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/samouraiworld/topofgnomes/server/apicache"
+	"github.com/samouraiworld/topofgnomes/server/contributions"
+	"github.com/samouraiworld/topofgnomes/server/handler"
+	"github.com/samouraiworld/topofgnomes/server/handler/apierror"
+	"github.com/samouraiworld/topofgnomes/server/models"
+	"gorm.io/gorm"
+)
+
+const (
+	ownershipSchemaVer = 1
+	// The rolling window the bus factor, top owners and trend use, in days.
+	defaultWindowDays = 90
+	maxWindowDays     = 730
+	// How many monthly points the trend has.
+	defaultTrendMonths = 12
+	maxTrendMonths     = 36
+	maxTopOwners       = 10
+)
+
+// ownershipWindows are the windows every response reports, in days; 0 is
+// all time.
+var ownershipWindows = []int{30, 90, 365, 0}
+
+// Concentration is how a kind of contribution spreads over contributors.
+type Concentration struct {
+	Total        int `json:"total"`
+	Contributors int `json:"contributors"`
+	// BusFactor50 and BusFactor80 are the fewest contributors behind 50%
+	// and 80% of Total; 0 when there is none.
+	BusFactor50 int `json:"busFactor50"`
+	BusFactor80 int `json:"busFactor80"`
+}
+
+// OwnershipWindow is the concentration of merged PRs and commits over the
+// days up to today.
+type OwnershipWindow struct {
+	// Days is the window's length, 0 for all time.
+	Days      int           `json:"days"`
+	MergedPRs Concentration `json:"mergedPRs"`
+	Commits   Concentration `json:"commits"`
+}
+
+// Owner is one of the contributors most behind a repository.
+type Owner struct {
+	Login     string `json:"login"`
+	AvatarURL string `json:"avatarURL"`
+	MergedPRs int    `json:"mergedPRs"`
+	Commits   int    `json:"commits"`
+	// PRShare and CommitShare are the owner's part of the window's merged
+	// PRs and commits.
+	PRShare     float64 `json:"prShare"`
+	CommitShare float64 `json:"commitShare"`
+}
+
+// OwnershipPoint is the concentration over the window ending on Date.
+type OwnershipPoint struct {
+	Date      string        `json:"date"`
+	MergedPRs Concentration `json:"mergedPRs"`
+	Commits   Concentration `json:"commits"`
+}
+
+type ownershipResponse struct {
+	SchemaVersion int        `json:"schemaVersion"`
+	LastSyncedAt  *time.Time `json:"lastSyncedAt"`
+	RepositoryID  string     `json:"repositoryID"`
+	WindowDays    int        `json:"windowDays"`
+	// BusFactor is the fewest contributors behind half of the merged PRs of
+	// the window.
+	BusFactor int `json:"busFactor"`
+	// DroppedToOne is set when BusFactor is 1 but was higher at some point
+	// of the trend.
+	DroppedToOne bool              `json:"droppedToOne"`
+	Windows      []OwnershipWindow `json:"windows"`
+	TopOwners    []Owner           `json:"topOwners"`
+	// Trend has a point on the first day of each month, oldest first.
+	Trend []OwnershipPoint `json:"trend"`
+}
+
+// RepoOwnership is a tracked repository's bus factor.
+type RepoOwnership struct {
+	RepositoryID string `json:"repositoryID"`
+	BusFactor    int    `json:"busFactor"`
+	DroppedToOne bool   `json:"droppedToOne"`
+}
+
+// HandleGetOwnership serves GET /repositories/{owner}/{name}/ownership: how
+// concentrated the merged PRs and commits of a repository are, over a few
+// fixed windows and, for ?window= days (90 by default), today and on the
+// first of each of the last ?months= months. Cached until the next sync,
+// five minutes at most.
+func HandleGetOwnership(db *gorm.DB, cache *apicache.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		w.Header().Set("Content-Type", "application/json")
+		repoID := chi.URLParam(r, "owner") + "/" + chi.URLParam(r, "name")
+		window, months, err := ownershipParams(r)
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+		key := fmt.Sprintf("repositories:ownership:%s:%d:%d", repoID, window, months)
+		cache.Serve(w, r, key, []apicache.Domain{apicache.GitHub, apicache.Clock}, func() (any, error) {
+			if err := db.First(&models.Repository{}, "id = ?", repoID).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, apierror.NotFound("repository %q not found", repoID)
+			} else if err != nil {
+				return nil, err
+			}
+			return computeOwnership(db, repoID, window, months, time.Now().UTC())
+		})
+	}
+}
+
+// HandleListOwnership serves GET /repositories/ownership: the bus factor of
+// every tracked repository, with the same ?window= and ?months= as
+// HandleGetOwnership, so the ones that dropped to 1 stand out.
+func HandleListOwnership(db *gorm.DB, cache *apicache.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		w.Header().Set("Content-Type", "application/json")
+		window, months, err := ownershipParams(r)
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+		key := fmt.Sprintf("repositories:ownership:all:%d:%d", window, months)
+		cache.Serve(w, r, key, []apicache.Domain{apicache.GitHub, apicache.Clock}, func() (any, error) {
+			var repos []models.Repository
+			if err := db.Order("id").Find(&repos).Error; err != nil {
+				return nil, fmt.Errorf("repositories query: %w", err)
+			}
+			now := time.Now().UTC()
+			out := make([]RepoOwnership, 0, len(repos))
+			for _, repo := range repos {
+				o, err := computeOwnership(db, repo.ID, window, months, now)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, RepoOwnership{RepositoryID: repo.ID, BusFactor: o.BusFactor, DroppedToOne: o.DroppedToOne})
+			}
+			return out, nil
+		})
+	}
+}
+
+func ownershipParams(r *http.Request) (window, months int, err error) {
+	window, months = defaultWindowDays, defaultTrendMonths
+	if s := r.URL.Query().Get("window"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > maxWindowDays {
+			return 0, 0, apierror.InvalidInput("invalid window %q, use 1 to %d days", s, maxWindowDays)
+		}
+		window = n
+	}
+	if s := r.URL.Query().Get("months"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 || n > maxTrendMonths {
+			return 0, 0, apierror.InvalidInput("invalid months %q, use 0 to %d", s, maxTrendMonths)
+		}
+		months = n
+	}
+	return window, months, nil
+}
+
+// ownershipRow is what one contributor did to the repository on one day.
+type ownershipRow struct {
+	UserID    string `gorm:"column:user_id"`
+	Login     string `gorm:"column:login"`
+	AvatarURL string `gorm:"column:avatar_url"`
+	Day       string `gorm:"column:day"`
+	Commits   int    `gorm:"column:commits"`
+	PRsMerged int    `gorm:"column:prs_merged"`
+}
+
+// computeOwnership reads the repository's daily_contributions rows once
+// and sums them over every window in memory.
+func computeOwnership(db *gorm.DB, repoID string, window, months int, now time.Time) (ownershipResponse, error) {
+	var rows []ownershipRow
+	err := db.Model(&models.DailyContribution{}).
+		Select("daily_contributions.user_id AS user_id, users.login AS login, users.avatar_url AS avatar_url, daily_contributions.day AS day, daily_contributions.commits AS commits, daily_contributions.prs_merged AS prs_merged").
+		Joins("LEFT JOIN users ON users.id = daily_contributions.user_id").
+		Where("daily_contributions.repo_id = ?", repoID).
+		Where("(daily_contributions.commits > 0 OR daily_contributions.prs_merged > 0)").
+		Scan(&rows).Error
+	if err != nil {
+		return ownershipResponse{}, fmt.Errorf("ownership query: %w", err)
+	}
+
+	today := now.Truncate(24 * time.Hour)
+	end := contributions.Day(today.AddDate(0, 0, 1))
+	resp := ownershipResponse{
+		SchemaVersion: ownershipSchemaVer,
+		LastSyncedAt:  handler.LastSyncedAt(db),
+		RepositoryID:  repoID,
+		WindowDays:    window,
+		Windows:       make([]OwnershipWindow, len(ownershipWindows)),
+		Trend:         make([]OwnershipPoint, 0, months),
+	}
+	for i, days := range ownershipWindows {
+		from := ""
+		if days > 0 {
+			from = contributions.Day(today.AddDate(0, 0, 1-days))
+		}
+		prs, commits := sumOwners(rows, from, end)
+		resp.Windows[i] = OwnershipWindow{Days: days, MergedPRs: concentration(prs), Commits: concentration(commits)}
+	}
+
+	prs, commits := sumOwners(rows, contributions.Day(today.AddDate(0, 0, 1-window)), end)
+	resp.BusFactor = concentration(prs).BusFactor50
+	resp.TopOwners = topOwners(rows, prs, commits)
+
+	month := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for i := months - 1; i >= 0; i-- {
+		at := month.AddDate(0, -i, 0)
+		prs, commits := sumOwners(rows, contributions.Day(at.AddDate(0, 0, -window)), contributions.Day(at))
+		point := OwnershipPoint{Date: contributions.Day(at), MergedPRs: concentration(prs), Commits: concentration(commits)}
+		resp.Trend = append(resp.Trend, point)
+		if resp.BusFactor == 1 && point.MergedPRs.BusFactor50 > 1 {
+			resp.DroppedToOne = true
+		}
+	}
+	return resp, nil
+}
+
+// sumOwners adds up the merged PRs and commits of each contributor on the
+// days in [from, to); an empty from means since always.
+func sumOwners(rows []ownershipRow, from, to string) (prs, commits map[string]int) {
+	prs, commits = map[string]int{}, map[string]int{}
+	for _, r := range rows {
+		if r.Day < from || r.Day >= to {
+			continue
+		}
+		if r.PRsMerged > 0 {
+			prs[r.UserID] += r.PRsMerged
+		}
+		if r.Commits > 0 {
+			commits[r.UserID] += r.Commits
+		}
+	}
+	return prs, commits
+}
+
+func concentration(byUser map[string]int) Concentration {
+	counts := make([]int, 0, len(byUser))
+	c := Concentration{Contributors: len(byUser)}
+	for _, n := range byUser {
+		counts = append(counts, n)
+		c.Total += n
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(counts)))
+	c.BusFactor50 = cover(counts, c.Total, 0.5)
+	c.BusFactor80 = cover(counts, c.Total, 0.8)
+	return c
+}
+
+// cover returns how many of counts, sorted in decreasing order, it takes to
+// reach share of total.
+func cover(counts []int, total int, share float64) int {
+	covered := 0
+	for i, n := range counts {
+		if float64(covered) >= share*float64(total) {
+			return i
+		}
+		covered += n
+	}
+	return len(counts)
+}
+
+// topOwners ranks the contributors of the window by merged PRs, then
+// commits.
+func topOwners(rows []ownershipRow, prs, commits map[string]int) []Owner {
+	totalPRs, totalCommits := 0, 0
+	for _, n := range prs {
+		totalPRs += n
+	}
+	for _, n := range commits {
+		totalCommits += n
+	}
+	owners := map[string]*Owner{}
+	for _, r := range rows {
+		if owners[r.UserID] != nil || (prs[r.UserID] == 0 && commits[r.UserID] == 0) {
+			continue
+		}
+		o := &Owner{Login: r.Login, AvatarURL: r.AvatarURL, MergedPRs: prs[r.UserID], Commits: commits[r.UserID]}
+		if o.Login == "" {
+			o.Login = r.UserID // commit authors that aren't synced users
+		}
+		if totalPRs > 0 {
+			o.PRShare = float64(o.MergedPRs) / float64(totalPRs)
+		}
+		if totalCommits > 0 {
+			o.CommitShare = float64(o.Commits) / float64(totalCommits)
+		}
+		owners[r.UserID] = o
+	}
+	out := make([]Owner, 0, len(owners))
+	for _, o := range owners {
+		out = append(out, *o)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].MergedPRs != out[j].MergedPRs {
+			return out[i].MergedPRs > out[j].MergedPRs
+		}
+		if out[i].Commits != out[j].Commits {
+			return out[i].Commits > out[j].Commits
+		}
+		return out[i].Login < out[j].Login
+	})
+	return out[:min(len(out), maxTopOwners)]
+}
@@ -0,0 +1,126 @@
+package repositories
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/samouraiworld/topofgnomes/server/db/dbtest"
+	"github.com/samouraiworld/topofgnomes/server/models"
+	"gorm.io/gorm"
+)
+
+func seedOwnership(t *testing.T) *gorm.DB {
+	t.Helper()
+	db := dbtest.Open(t, &models.Repository{}, &models.User{}, &models.DailyContribution{}, &models.SyncStatus{})
+	for _, repo := range []models.Repository{{ID: "o/r", Owner: "o", Name: "r"}, {ID: "o/quiet", Owner: "o", Name: "quiet"}} {
+		if err := db.Create(&repo).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, login := range []string{"alice", "bob", "carol"} {
+		if err := db.Create(&models.User{ID: "u-" + login, Login: login, AvatarUrl: "https://avatars/" + login}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	rows := []models.DailyContribution{
+		{UserID: "u-alice", Day: "2026-06-10", PRsMerged: 5, Commits: 10},
+		{UserID: "u-bob", Day: "2026-06-01", PRsMerged: 1},
+		{UserID: "u-alice", Day: "2026-03-10", PRsMerged: 2},
+		{UserID: "u-bob", Day: "2026-03-11", PRsMerged: 2},
+		{UserID: "u-carol", Day: "2026-03-12", PRsMerged: 2, Reviews: 4},
+		{UserID: "u-carol", Day: "2024-01-01", PRsMerged: 3, Commits: 4},
+		{UserID: "u-dave", Day: "2024-01-01", Commits: 1}, // not a synced user
+		{UserID: "u-carol", Day: "2026-06-12", Reviews: 3},
+	}
+	for _, row := range rows {
+		row.RepoID = "o/r"
+		if err := db.Create(&row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func TestComputeOwnership(t *testing.T) {
+	db := seedOwnership(t)
+	now := time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	resp, err := computeOwnership(db, "o/r", 90, 4, now)
+	if err != nil {
+		t.Fatalf("computeOwnership: %v", err)
+	}
+	if resp.BusFactor != 1 || !resp.DroppedToOne {
+		t.Errorf("bus factor = %d, dropped = %v, want 1, true", resp.BusFactor, resp.DroppedToOne)
+	}
+	wantWindows := []OwnershipWindow{
+		{Days: 30, MergedPRs: Concentration{Total: 6, Contributors: 2, BusFactor50: 1, BusFactor80: 1}, Commits: Concentration{Total: 10, Contributors: 1, BusFactor50: 1, BusFactor80: 1}},
+		{Days: 90, MergedPRs: Concentration{Total: 6, Contributors: 2, BusFactor50: 1, BusFactor80: 1}, Commits: Concentration{Total: 10, Contributors: 1, BusFactor50: 1, BusFactor80: 1}},
+		{Days: 365, MergedPRs: Concentration{Total: 12, Contributors: 3, BusFactor50: 1, BusFactor80: 2}, Commits: Concentration{Total: 10, Contributors: 1, BusFactor50: 1, BusFactor80: 1}},
+		{Days: 0, MergedPRs: Concentration{Total: 15, Contributors: 3, BusFactor50: 2, BusFactor80: 2}, Commits: Concentration{Total: 15, Contributors: 3, BusFactor50: 1, BusFactor80: 2}},
+	}
+	if !reflect.DeepEqual(resp.Windows, wantWindows) {
+		t.Errorf("windows = %+v, want %+v", resp.Windows, wantWindows)
+	}
+	wantOwners := []Owner{
+		{Login: "alice", AvatarURL: "https://avatars/alice", MergedPRs: 5, Commits: 10, PRShare: 5.0 / 6, CommitShare: 1},
+		{Login: "bob", AvatarURL: "https://avatars/bob", MergedPRs: 1, PRShare: 1.0 / 6},
+	}
+	if !reflect.DeepEqual(resp.TopOwners, wantOwners) {
+		t.Errorf("top owners = %+v, want %+v", resp.TopOwners, wantOwners)
+	}
+	var trend []string
+	for _, p := range resp.Trend {
+		trend = append(trend, fmt.Sprintf("%s=%d", p.Date, p.MergedPRs.BusFactor50))
+	}
+	// The window before each point: March's PRs count from April to June.
+	if want := []string{"2026-03-01=0", "2026-04-01=2", "2026-05-01=2", "2026-06-01=2"}; !reflect.DeepEqual(trend, want) {
+		t.Errorf("trend = %v, want %v", trend, want)
+	}
+
+	resp, err = computeOwnership(db, "o/r", 365, 0, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.BusFactor != 1 || resp.DroppedToOne || len(resp.Trend) != 0 || len(resp.TopOwners) != 3 {
+		t.Errorf("365-day window = %+v", resp)
+	}
+}
+
+func TestHandleOwnership(t *testing.T) {
+	db := seedOwnership(t)
+	r := chi.NewRouter()
+	r.Get("/repositories/ownership", HandleListOwnership(db, nil))
+	r.Get("/repositories/{owner}/{name}/ownership", HandleGetOwnership(db, nil))
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	if rec := get("/repositories/o/r/ownership?window=30&months=2"); rec.Code != http.StatusOK {
+		t.Errorf("status = %d body=%s", rec.Code, rec.Body)
+	}
+	if rec := get("/repositories/o/nope/ownership"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown repository: status = %d", rec.Code)
+	}
+	for _, q := range []string{"window=0", "window=x", "months=37"} {
+		if rec := get("/repositories/o/r/ownership?" + q); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d", q, rec.Code)
+		}
+	}
+
+	rec := get("/repositories/ownership")
+	var list []RepoOwnership
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatalf("unmarshal: %v body=%s", err, rec.Body)
+	}
+	if len(list) != 2 || list[0].RepositoryID != "o/quiet" || list[0].BusFactor != 0 || list[1].RepositoryID != "o/r" {
+		t.Errorf("list = %+v", list)
+	}
+}
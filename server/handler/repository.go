@@ -1,20 +1,38 @@
 package handler
 
 import (
-	"encoding/json"
 	"net/http"
 
+	"github.com/samouraiworld/topofgnomes/server/handler/apierror"
+	"github.com/samouraiworld/topofgnomes/server/handler/listquery"
 	"github.com/samouraiworld/topofgnomes/server/models"
 	"gorm.io/gorm"
 )
 
+var repositoriesList = listquery.Spec{
+	Model:       &models.Repository{},
+	Sorts:       map[string]string{"id": "id", "name": "name", "owner": "owner"},
+	DefaultSort: "id",
+	Keys:        []string{"id"},
+}
+
 func HandleGetRepository(db *gorm.DB) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
 		w.Header().Set("Content-Type", "application/json")
 
-		var repositories []models.Repository
+		params, err := listquery.Parse(r.URL.Query(), repositoriesList)
+		if err != nil {
+			apierror.Write(w, r, apierror.InvalidInput("%v", err))
+			return
+		}
 
-		db.Model(&models.Repository{}).Find(&repositories)
-		json.NewEncoder(w).Encode(repositories)
+		var repositories []models.Repository
+		page, err := listquery.Find(db.Model(&models.Repository{}), params, repositoriesList, &repositories)
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+		listquery.Write(w, r, params, page, repositories)
 	}
 }
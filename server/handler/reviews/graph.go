@@ -0,0 +1,319 @@
+// Package reviews wires the reviewer-network endpoint: who reviews whose
+// pull requests, contributor by contributor, with the metrics maintainers
+// use to spot review bottlenecks.
+package reviews
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/samouraiworld/topofgnomes/server/apicache"
+	"github.com/samouraiworld/topofgnomes/server/contributions"
+	"github.com/samouraiworld/topofgnomes/server/handler"
+	"github.com/samouraiworld/topofgnomes/server/models"
+	"github.com/samouraiworld/topofgnomes/server/teams"
+	"gorm.io/gorm"
+)
+
+const (
+	graphSchemaVer = 1
+	// busFactorShare is the share of a repository's reviews its bus factor
+	// reviewers cover.
+	busFactorShare = 0.8
+	// botLogin is left out of the graph, like in the team collaboration
+	// matrix. Case-insensitive.
+	botLogin = "dependabot"
+)
+
+// GraphNode is a contributor active in the period.
+type GraphNode struct {
+	Login     string `json:"login"`
+	AvatarURL string `json:"avatarURL"`
+	// Team is the team the contributor is in today, if any.
+	Team            string  `json:"team,omitempty"`
+	Score           float64 `json:"score"`
+	ReviewsGiven    int     `json:"reviewsGiven"`
+	ReviewsReceived int     `json:"reviewsReceived"`
+	// Betweenness is the share of shortest paths between two other
+	// contributors that go through this one, reviews taken both ways: high
+	// for those bridging groups that don't review each other otherwise.
+	Betweenness float64 `json:"betweenness"`
+}
+
+// GraphEdge counts the reviews Reviewer left on pull requests by Author.
+type GraphEdge struct {
+	Author   string `json:"author"`
+	Reviewer string `json:"reviewer"`
+	Reviews  int    `json:"reviews"`
+}
+
+// RepoBusFactor is how concentrated a repository's reviewing is.
+type RepoBusFactor struct {
+	Repo      string `json:"repo"`
+	Reviews   int    `json:"reviews"`
+	Reviewers int    `json:"reviewers"`
+	// BusFactor is the fewest reviewers doing 80% of the reviews, the
+	// busiest first in TopReviewers.
+	BusFactor    int      `json:"busFactor"`
+	TopReviewers []string `json:"topReviewers"`
+}
+
+type graphResponse struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	LastSyncedAt  *time.Time      `json:"lastSyncedAt"`
+	Period        string          `json:"period"`
+	Repos         []string        `json:"repos"`
+	Nodes         []GraphNode     `json:"nodes"`
+	Edges         []GraphEdge     `json:"edges"`
+	BusFactor     []RepoBusFactor `json:"busFactor"`
+	// Isolated are the contributors who neither reviewed nor were reviewed
+	// by anyone else in the period.
+	Isolated []string `json:"isolated"`
+}
+
+// HandleGetGraph returns the reviewer network over ?period= (daily,
+// weekly, monthly or yearly; all time by default), optionally narrowed to
+// pull requests of ?repos=. Self-reviews and dependabot are left out.
+// Cached until the next sync or teams config reload, five minutes at most.
+func HandleGetGraph(db *gorm.DB, roster teams.Provider, cache *apicache.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		w.Header().Set("Content-Type", "application/json")
+		period := r.URL.Query().Get("period")
+		repos := slices.Sorted(slices.Values(r.URL.Query()["repos"]))
+		key := fmt.Sprintf("graph:reviews:%s:%s", period, strings.Join(repos, ","))
+		cache.Serve(w, r, key, []apicache.Domain{apicache.GitHub, apicache.TeamsConfig, apicache.Clock}, func() (any, error) {
+			return computeGraph(db, roster(), period, repos)
+		})
+	}
+}
+
+func computeGraph(db *gorm.DB, cfg *teams.Config, period string, repos []string) (graphResponse, error) {
+	start := handler.PeriodStart(period)
+	var reviews []struct {
+		Repo     string `gorm:"column:repo"`
+		Author   string `gorm:"column:author"`
+		Reviewer string `gorm:"column:reviewer"`
+		Reviews  int    `gorm:"column:reviews"`
+	}
+	q := db.Table("reviews").
+		Select("pull_requests.repository_id AS repo, author_users.login AS author, reviewer_users.login AS reviewer, COUNT(*) AS reviews").
+		Joins("JOIN pull_requests ON pull_requests.id = reviews.pull_request_id").
+		Joins("JOIN users AS author_users ON author_users.id = pull_requests.author_id").
+		Joins("JOIN users AS reviewer_users ON reviewer_users.id = reviews.author_id").
+		Where("author_users.id <> reviewer_users.id").
+		Where("LOWER(author_users.login) <> ?", botLogin).
+		Where("LOWER(reviewer_users.login) <> ?", botLogin).
+		Group("pull_requests.repository_id, author_users.login, reviewer_users.login")
+	if !start.IsZero() {
+		q = q.Where("reviews.created_at >= ?", start)
+	}
+	if len(repos) > 0 {
+		q = q.Where("pull_requests.repository_id IN ?", repos)
+	}
+	if err := q.Scan(&reviews).Error; err != nil {
+		return graphResponse{}, fmt.Errorf("review-graph query: %w", err)
+	}
+
+	var activity []struct {
+		Login     string `gorm:"column:login"`
+		AvatarURL string `gorm:"column:avatar_url"`
+		Commits   int64  `gorm:"column:commits"`
+		PRs       int64  `gorm:"column:prs"`
+		Issues    int64  `gorm:"column:issues"`
+		Reviews   int64  `gorm:"column:reviews"`
+	}
+	q = db.Model(&models.DailyContribution{}).
+		Select("users.login AS login, users.avatar_url AS avatar_url, SUM(daily_contributions.commits) AS commits, SUM(daily_contributions.prs_merged) AS prs, SUM(daily_contributions.issues) AS issues, SUM(daily_contributions.reviews) AS reviews").
+		Joins("JOIN users ON users.id = daily_contributions.user_id").
+		Where("LOWER(users.login) <> ?", botLogin).
+		Where("(daily_contributions.commits > 0 OR daily_contributions.prs_merged > 0 OR daily_contributions.issues > 0 OR daily_contributions.reviews > 0)").
+		Group("users.login, users.avatar_url")
+	if !start.IsZero() {
+		q = q.Where("daily_contributions.day >= ?", contributions.Day(start))
+	}
+	if len(repos) > 0 {
+		q = q.Where("daily_contributions.repo_id IN ?", repos)
+	}
+	if err := q.Scan(&activity).Error; err != nil {
+		return graphResponse{}, fmt.Errorf("review-graph activity query: %w", err)
+	}
+
+	today := contributions.Day(time.Now())
+	nodes := map[string]*GraphNode{}
+	node := func(login string) *GraphNode {
+		n := nodes[login]
+		if n == nil {
+			n = &GraphNode{Login: login}
+			if slug, ok := cfg.TeamOn(login, today); ok {
+				n.Team = slug
+			}
+			nodes[login] = n
+		}
+		return n
+	}
+	for _, a := range activity {
+		n := node(a.Login)
+		n.AvatarURL = a.AvatarURL
+		n.Score = handler.CalculateScore(a.Commits, a.Issues, a.PRs, a.Reviews)
+	}
+	type pair struct{ author, reviewer string }
+	edges := map[pair]int{}
+	byRepo := map[string]map[string]int{} // repo -> reviewer -> reviews
+	for _, r := range reviews {
+		edges[pair{r.Author, r.Reviewer}] += r.Reviews
+		node(r.Author).ReviewsReceived += r.Reviews
+		node(r.Reviewer).ReviewsGiven += r.Reviews
+		if byRepo[r.Repo] == nil {
+			byRepo[r.Repo] = map[string]int{}
+		}
+		byRepo[r.Repo][r.Reviewer] += r.Reviews
+	}
+
+	resp := graphResponse{
+		SchemaVersion: graphSchemaVer,
+		LastSyncedAt:  handler.LastSyncedAt(db),
+		Period:        period,
+		Repos:         repos,
+		Nodes:         make([]GraphNode, 0, len(nodes)),
+		Edges:         make([]GraphEdge, 0, len(edges)),
+		BusFactor:     make([]RepoBusFactor, 0, len(byRepo)),
+		Isolated:      []string{},
+	}
+	if resp.Repos == nil {
+		resp.Repos = []string{}
+	}
+	for _, n := range nodes {
+		resp.Nodes = append(resp.Nodes, *n)
+	}
+	sort.Slice(resp.Nodes, func(i, j int) bool { return resp.Nodes[i].Login < resp.Nodes[j].Login })
+	for p, count := range edges {
+		resp.Edges = append(resp.Edges, GraphEdge{Author: p.author, Reviewer: p.reviewer, Reviews: count})
+	}
+	sort.Slice(resp.Edges, func(i, j int) bool {
+		a, b := resp.Edges[i], resp.Edges[j]
+		if a.Reviews != b.Reviews {
+			return a.Reviews > b.Reviews
+		}
+		if a.Author != b.Author {
+			return a.Author < b.Author
+		}
+		return a.Reviewer < b.Reviewer
+	})
+
+	index := make(map[string]int, len(resp.Nodes))
+	for i, n := range resp.Nodes {
+		index[n.Login] = i
+	}
+	adj := make([][]int, len(resp.Nodes))
+	linked := map[pair]bool{}
+	for p := range edges {
+		a, b := index[p.author], index[p.reviewer]
+		if a > b {
+			a, b = b, a
+		}
+		key := pair{resp.Nodes[a].Login, resp.Nodes[b].Login}
+		if !linked[key] {
+			linked[key] = true
+			adj[a] = append(adj[a], b)
+			adj[b] = append(adj[b], a)
+		}
+	}
+	for i, c := range betweenness(adj) {
+		resp.Nodes[i].Betweenness = c
+		if len(adj[i]) == 0 {
+			resp.Isolated = append(resp.Isolated, resp.Nodes[i].Login)
+		}
+	}
+
+	for repo, reviewers := range byRepo {
+		resp.BusFactor = append(resp.BusFactor, busFactor(repo, reviewers))
+	}
+	sort.Slice(resp.BusFactor, func(i, j int) bool { return resp.BusFactor[i].Repo < resp.BusFactor[j].Repo })
+	return resp, nil
+}
+
+// busFactor finds the fewest reviewers of a repository covering
+// busFactorShare of its reviews.
+func busFactor(repo string, reviews map[string]int) RepoBusFactor {
+	logins := make([]string, 0, len(reviews))
+	total := 0
+	for login, n := range reviews {
+		logins = append(logins, login)
+		total += n
+	}
+	sort.Slice(logins, func(i, j int) bool {
+		if reviews[logins[i]] != reviews[logins[j]] {
+			return reviews[logins[i]] > reviews[logins[j]]
+		}
+		return logins[i] < logins[j]
+	})
+	out := RepoBusFactor{Repo: repo, Reviews: total, Reviewers: len(logins)}
+	covered := 0
+	for _, login := range logins {
+		if float64(covered) >= busFactorShare*float64(total) {
+			break
+		}
+		covered += reviews[login]
+		out.BusFactor++
+		out.TopReviewers = append(out.TopReviewers, login)
+	}
+	return out
+}
+
+// betweenness computes the normalized betweenness centrality of every node
+// of an unweighted, undirected graph with Brandes' algorithm.
+func betweenness(adj [][]int) []float64 {
+	n := len(adj)
+	cb := make([]float64, n)
+	for s := range adj {
+		var stack []int
+		pred := make([][]int, n)
+		sigma := make([]float64, n)
+		dist := make([]int, n)
+		for i := range dist {
+			dist[i] = -1
+		}
+		sigma[s], dist[s] = 1, 0
+		queue := []int{s}
+		for len(queue) > 0 {
+			v := queue[0]
+			queue = queue[1:]
+			stack = append(stack, v)
+			for _, w := range adj[v] {
+				if dist[w] < 0 {
+					dist[w] = dist[v] + 1
+					queue = append(queue, w)
+				}
+				if dist[w] == dist[v]+1 {
+					sigma[w] += sigma[v]
+					pred[w] = append(pred[w], v)
+				}
+			}
+		}
+		delta := make([]float64, n)
+		for i := len(stack) - 1; i >= 0; i-- {
+			w := stack[i]
+			for _, v := range pred[w] {
+				delta[v] += sigma[v] / sigma[w] * (1 + delta[w])
+			}
+			if w != s {
+				cb[w] += delta[w]
+			}
+		}
+	}
+	// Every pair was counted from both ends.
+	pairs := float64(n-1) * float64(n-2)
+	for i := range cb {
+		if pairs > 0 {
+			cb[i] /= pairs
+		}
+		cb[i] = math.Round(cb[i]*1e4) / 1e4
+	}
+	return cb
+}
@@ -0,0 +1,122 @@
+package reviews
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/samouraiworld/topofgnomes/server/contributions"
+	"github.com/samouraiworld/topofgnomes/server/db/dbtest"
+	"github.com/samouraiworld/topofgnomes/server/models"
+	"github.com/samouraiworld/topofgnomes/server/teams"
+	"gorm.io/gorm"
+)
+
+// seedGraph stores, with e—a—b—c as the review network and d alone:
+//
+//	r1: b reviews a's pr-1 twice and pr-2 once, and c's pr-3 once; e
+//	    reviews pr-2; a reviews their own pr-1 and dependabot reviews pr-3
+//	r2: c reviews b's pr-4; d's pr-5 gets no review
+func seedGraph(t *testing.T) *gorm.DB {
+	t.Helper()
+	db := dbtest.Open(t, &models.User{}, &models.PullRequest{}, &models.Review{}, &models.DailyContribution{},
+		&models.Commit{}, &models.Issue{}, &models.SyncStatus{})
+	for _, login := range []string{"a", "b", "c", "d", "e", "dependabot"} {
+		if err := db.Create(&models.User{ID: "u-" + login, Login: login, AvatarUrl: "https://avatars/" + login}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	at := time.Now().UTC().Add(-48 * time.Hour)
+	for i, pr := range []struct{ repo, author string }{
+		{"r1", "a"}, {"r1", "a"}, {"r1", "c"}, {"r2", "b"}, {"r2", "d"},
+	} {
+		row := models.PullRequest{ID: fmt.Sprintf("pr-%d", i+1), RepositoryID: pr.repo, AuthorID: "u-" + pr.author,
+			State: "MERGED", CreatedAt: at, MergedAt: &at}
+		if err := db.Create(&row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, rv := range []struct{ pr, repo, reviewer string }{
+		{"pr-1", "r1", "b"}, {"pr-1", "r1", "b"}, {"pr-2", "r1", "b"}, {"pr-3", "r1", "b"},
+		{"pr-2", "r1", "e"}, {"pr-1", "r1", "a"}, {"pr-3", "r1", "dependabot"},
+		{"pr-4", "r2", "c"},
+	} {
+		row := models.Review{ID: fmt.Sprintf("rv-%d", i), PullRequestID: rv.pr, RepositoryID: rv.repo,
+			AuthorID: "u-" + rv.reviewer, CreatedAt: at}
+		if err := db.Create(&row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := contributions.Backfill(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestComputeGraph(t *testing.T) {
+	db := seedGraph(t)
+	roster := &teams.Config{Teams: []teams.Team{{Slug: "x", Members: []string{"a", "b"}}}}
+
+	resp, err := computeGraph(db, roster, "weekly", nil)
+	if err != nil {
+		t.Fatalf("computeGraph: %v", err)
+	}
+	wantEdges := []GraphEdge{
+		{Author: "a", Reviewer: "b", Reviews: 3},
+		{Author: "a", Reviewer: "e", Reviews: 1},
+		{Author: "b", Reviewer: "c", Reviews: 1},
+		{Author: "c", Reviewer: "b", Reviews: 1},
+	}
+	if !reflect.DeepEqual(resp.Edges, wantEdges) {
+		t.Errorf("edges = %+v, want %+v", resp.Edges, wantEdges)
+	}
+	var logins []string
+	for _, n := range resp.Nodes {
+		logins = append(logins, n.Login)
+	}
+	if !reflect.DeepEqual(logins, []string{"a", "b", "c", "d", "e"}) {
+		t.Fatalf("nodes = %v", logins)
+	}
+	a, b, c := resp.Nodes[0], resp.Nodes[1], resp.Nodes[2]
+	if a.Team != "x" || a.ReviewsReceived != 4 || a.Score == 0 || a.AvatarURL != "https://avatars/a" {
+		t.Errorf("a = %+v", a)
+	}
+	if b.ReviewsGiven != 4 || b.ReviewsReceived != 1 || c.Team != "" {
+		t.Errorf("b = %+v, c = %+v", b, c)
+	}
+	// a and b each sit on 2 of the 6 shortest paths between two others.
+	for i, want := range []float64{0.3333, 0.3333, 0, 0, 0} {
+		if got := resp.Nodes[i].Betweenness; got != want {
+			t.Errorf("%s betweenness = %v, want %v", resp.Nodes[i].Login, got, want)
+		}
+	}
+	wantBus := []RepoBusFactor{
+		{Repo: "r1", Reviews: 5, Reviewers: 2, BusFactor: 1, TopReviewers: []string{"b"}},
+		{Repo: "r2", Reviews: 1, Reviewers: 1, BusFactor: 1, TopReviewers: []string{"c"}},
+	}
+	if !reflect.DeepEqual(resp.BusFactor, wantBus) {
+		t.Errorf("bus factor = %+v, want %+v", resp.BusFactor, wantBus)
+	}
+	if !reflect.DeepEqual(resp.Isolated, []string{"d"}) {
+		t.Errorf("isolated = %v, want [d]", resp.Isolated)
+	}
+
+	resp, err = computeGraph(db, roster, "", []string{"r2"})
+	if err != nil {
+		t.Fatalf("computeGraph r2: %v", err)
+	}
+	if len(resp.Edges) != 1 || resp.Edges[0] != (GraphEdge{Author: "b", Reviewer: "c", Reviews: 1}) {
+		t.Errorf("r2 edges = %+v", resp.Edges)
+	}
+	if !reflect.DeepEqual(resp.Isolated, []string{"d"}) {
+		t.Errorf("r2 isolated = %v, want [d]", resp.Isolated)
+	}
+}
+
+func TestBusFactor(t *testing.T) {
+	got := busFactor("r", map[string]int{"a": 5, "b": 3, "c": 1, "d": 1})
+	if got.BusFactor != 2 || !reflect.DeepEqual(got.TopReviewers, []string{"a", "b"}) {
+		t.Errorf("busFactor = %+v, want a and b", got)
+	}
+}
@@ -0,0 +1,24 @@
+package reviews
+
+import (
+	"net/http"
+
+	"github.com/samouraiworld/topofgnomes/server/openapi"
+)
+
+// OpenAPIRoutes describes the routes served by this package.
+func OpenAPIRoutes() []openapi.Route {
+	return []openapi.Route{
+		{
+			Method: http.MethodGet, Path: "/graph/reviews", Tag: "reviews",
+			Summary:     "Reviewer network between contributors",
+			Description: "Who reviews whose pull requests, with betweenness centrality per contributor, the bus factor of each repository's reviewing and the contributors outside the network. Self-reviews and dependabot are left out.",
+			Params: []openapi.Param{
+				openapi.QueryParam("period", "string", "Period: daily, weekly, monthly or yearly; all time when omitted"),
+				openapi.QueryParam("repos", "string", "Repository id (owner/name); repeat to include several, all when omitted"),
+			},
+			Response: graphResponse{},
+			Cached:   true,
+		},
+	}
+}
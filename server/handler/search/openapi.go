@@ -0,0 +1,26 @@
+package search
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/samouraiworld/topofgnomes/server/openapi"
+	"github.com/samouraiworld/topofgnomes/server/search"
+)
+
+// OpenAPIRoutes describes the routes served by this package.
+func OpenAPIRoutes() []openapi.Route {
+	return []openapi.Route{
+		{
+			Method: http.MethodGet, Path: "/search", Tag: "search",
+			Summary: "Ranked search across GitHub and on-chain data",
+			Params: []openapi.Param{
+				{Name: "q", In: "query", Type: "string", Description: "Search text", Required: true},
+				openapi.QueryParam("types", "string", "Comma-separated subset of "+strings.Join(search.Kinds, ", ")),
+				openapi.QueryParam("repositories", "string", "Comma-separated owner/name list; narrows GitHub kinds only"),
+				openapi.QueryParam("limit", "integer", "Max results"),
+			},
+			Response: response{},
+		},
+	}
+}
@@ -0,0 +1,85 @@
+// Package search wires GET /search: one ranked, typed result list across
+// PRs, issues, commits, GovDAO proposals and published gno packages.
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	dbpkg "github.com/samouraiworld/topofgnomes/server/db"
+	"github.com/samouraiworld/topofgnomes/server/handler/apierror"
+	"github.com/samouraiworld/topofgnomes/server/search"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+type response struct {
+	Query   string          `json:"query"`
+	Results []search.Result `json:"results"`
+}
+
+// HandleSearch serves GET /search?q=. Optional filters: `types` (comma list
+// of pull_request, issue, commit, proposal, package), `repositories` (only
+// narrows GitHub kinds; on-chain documents have no repository) and `limit`.
+func HandleSearch(db *gorm.DB) http.HandlerFunc {
+	useFTS := db.Migrator().HasTable(dbpkg.SearchFTSTable)
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		w.Header().Set("Content-Type", "application/json")
+		q, err := parseQuery(r.URL.Query())
+		if err != nil {
+			apierror.Write(w, r, apierror.InvalidInput("%v", err))
+			return
+		}
+		results, err := search.Search(db, q, useFTS)
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(response{Query: q.Text, Results: results})
+	}
+}
+
+func parseQuery(v url.Values) (search.Query, error) {
+	q := search.Query{
+		Text:         strings.TrimSpace(v.Get("q")),
+		Kinds:        splitList(v.Get("types")),
+		Repositories: splitList(v.Get("repositories")),
+		Limit:        defaultLimit,
+	}
+	if q.Text == "" {
+		return q, fmt.Errorf("missing q")
+	}
+	for _, k := range q.Kinds {
+		if !slices.Contains(search.Kinds, k) {
+			return q, fmt.Errorf("invalid type %q (want one of %s)", k, strings.Join(search.Kinds, ", "))
+		}
+	}
+	if s := v.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			return q, fmt.Errorf("invalid limit %q", s)
+		}
+		q.Limit = min(n, maxLimit)
+	}
+	return q, nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,117 @@
+package search
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	dbpkg "github.com/samouraiworld/topofgnomes/server/db"
+	"github.com/samouraiworld/topofgnomes/server/db/dbtest"
+	"github.com/samouraiworld/topofgnomes/server/models"
+	"github.com/samouraiworld/topofgnomes/server/search"
+	"gorm.io/gorm"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db := dbtest.Open(t, &models.PullRequest{}, &models.Issue{}, &models.Commit{},
+		&models.GnoProposal{}, &models.GnoPackage{}, &models.SearchDocument{})
+	if _, err := dbpkg.EnsureSearchFTS(db); err != nil {
+		t.Fatalf("fts: %v", err)
+	}
+	return db
+}
+
+func get(t *testing.T, db *gorm.DB, path string) response {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	HandleSearch(db).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("%s: status = %d body=%s", path, rec.Code, rec.Body.String())
+	}
+	var got response
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	return got
+}
+
+func TestHandleSearch_AcrossKinds(t *testing.T) {
+	db := newTestDB(t)
+	ts := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := db.Create(&models.PullRequest{ID: "pr-1", RepositoryID: "gnolang/gno", Title: "feat: reduce storage deposit", CreatedAt: ts}).Error; err != nil {
+		t.Fatalf("seed pr: %v", err)
+	}
+	if err := db.Create(&models.Issue{ID: "is-1", RepositoryID: "gnolang/gno", Title: "gnovm: parser panic", CreatedAt: ts}).Error; err != nil {
+		t.Fatalf("seed issue: %v", err)
+	}
+	// The PR and issue are picked up by the backfill, the proposal by Index,
+	// like rows synced before and after the index existed.
+	if err := search.Backfill(db); err != nil {
+		t.Fatalf("backfill: %v", err)
+	}
+	prop := models.GnoProposal{ID: "7", Title: "Lower fees", Description: "Halve the storage deposit for <realms>"}
+	if err := search.Index(db, search.FromProposal(prop)); err != nil {
+		t.Fatalf("index: %v", err)
+	}
+
+	got := get(t, db, "/search?q=storage")
+	if len(got.Results) != 2 {
+		t.Fatalf("results = %+v, want the PR and the proposal", got.Results)
+	}
+	types := map[string]search.Result{}
+	for _, r := range got.Results {
+		types[r.Type] = r
+	}
+	if _, ok := types[search.KindPullRequest]; !ok {
+		t.Errorf("missing pull_request result in %+v", got.Results)
+	}
+	p, ok := types[search.KindProposal]
+	if !ok {
+		t.Fatalf("missing proposal result in %+v", got.Results)
+	}
+	if !strings.Contains(p.Snippet, "<mark>") || strings.Contains(p.Snippet, "<realms>") {
+		t.Errorf("snippet = %q, want highlighted and escaped", p.Snippet)
+	}
+
+	got = get(t, db, "/search?q=storage&types=proposal")
+	if len(got.Results) != 1 || got.Results[0].ID != "7" {
+		t.Errorf("types filter: got %+v, want only proposal 7", got.Results)
+	}
+}
+
+func TestHandleSearch_ReindexUpdatesTitle(t *testing.T) {
+	db := newTestDB(t)
+	pr := models.PullRequest{ID: "pr-1", RepositoryID: "gnolang/gno", Title: "wip"}
+	if err := search.Index(db, search.FromPullRequest(pr)); err != nil {
+		t.Fatalf("index: %v", err)
+	}
+	pr.Title = "feat: add banker module"
+	if err := search.Index(db, search.FromPullRequest(pr)); err != nil {
+		t.Fatalf("reindex: %v", err)
+	}
+	if got := get(t, db, "/search?q=banker"); len(got.Results) != 1 {
+		t.Errorf("after reindex: got %+v, want one result", got.Results)
+	}
+	if got := get(t, db, "/search?q=wip"); len(got.Results) != 0 {
+		t.Errorf("stale title still matches: %+v", got.Results)
+	}
+}
+
+func TestHandleSearch_RejectsBadParams(t *testing.T) {
+	db := newTestDB(t)
+	for _, path := range []string{
+		"/search",
+		"/search?q=x&types=wiki",
+		"/search?q=x&limit=0",
+	} {
+		rec := httptest.NewRecorder()
+		HandleSearch(db).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", path, rec.Code)
+		}
+	}
+}
@@ -9,8 +9,9 @@ import (
 	"strings"
 	"time"
 
-	"github.com/dgraph-io/ristretto"
-
+	"github.com/samouraiworld/topofgnomes/server/apicache"
+	"github.com/samouraiworld/topofgnomes/server/contributions"
+	"github.com/samouraiworld/topofgnomes/server/handler/apierror"
 	"github.com/samouraiworld/topofgnomes/server/models"
 	"gorm.io/gorm"
 )
@@ -25,65 +26,44 @@ func getUserStats(db *gorm.DB, startTime time.Time, exclude, repositories []stri
 		returnedTime = &time
 	}
 
-	users := make([]models.User, 0)
-	query := db.Model(&models.User{})
-
-	if len(exclude) > 0 {
-		for i, login := range exclude {
-			exclude[i] = strings.ToLower(login)
-		}
-
-		query = query.Where("LOWER(login) NOT IN ?", exclude)
+	totals, err := contributions.Totals(db, contributions.Filter{
+		Since:         startTime,
+		Repositories:  repositories,
+		ExcludeLogins: exclude,
+	})
+	if err != nil {
+		return nil, returnedTime, err
 	}
-
-	err := query.
-		Preload("Commits", func(db *gorm.DB) *gorm.DB {
-			return db.Where("created_at > ? AND repository_id IN (?)", startTime, repositories).
-				Order("created_at DESC")
-		}).
-		Preload("PullRequests", func(db *gorm.DB) *gorm.DB {
-			return db.Where("created_at > ? AND repository_id IN (?)", startTime, repositories).
-				Order("created_at DESC")
-		}).
-		Preload("Reviews", func(db *gorm.DB) *gorm.DB {
-			return db.Where("created_at > ? AND repository_id IN (?)", startTime, repositories).
-				Order("created_at DESC")
-		}).
-		Preload("Issues", func(db *gorm.DB) *gorm.DB {
-			return db.Where("created_at > ? AND repository_id IN (?)", startTime, repositories).
-				Order("created_at DESC")
-		}).
-		Preload("Reviews.PullRequest").
-		Find(&users).Error
+	userIDs := make([]string, 0, len(totals))
+	for _, t := range totals {
+		userIDs = append(userIDs, t.UserID)
+	}
+	last, err := getLastContributions(db, startTime, repositories, userIDs)
 	if err != nil {
 		return nil, returnedTime, err
 	}
-	res := make([]UserWithStats, 0, len(users))
 
-	for _, user := range users {
-		user.Reviews = slices.DeleteFunc(user.Reviews, func(review models.Review) bool {
-			return review.PullRequest.State != "MERGED" || review.PullRequest.AuthorID == review.AuthorID
-		})
-		if getLastContribution(user) == nil {
+	res := make([]UserWithStats, 0, len(totals))
+	for _, t := range totals {
+		// Like before the rollup: anyone who committed, opened an issue or a
+		// PR in the period is listed, and merged PRs count by creation date.
+		if t.Commits+t.PRsOpened+t.Issues == 0 {
 			continue
 		}
-
-		user.PullRequests = getPrByState(user.PullRequests, "MERGED")
-
-		score := CalculateScore(int64(len(user.Commits)), int64(len(user.Issues)), int64(len(user.PullRequests)), int64(len(user.Reviews)))
+		score := CalculateScore(int64(t.Commits), int64(t.Issues), int64(t.PRsOpenedMerged), int64(t.Reviews))
 		res = append(res, UserWithStats{
 			User: models.User{
-				Login:     user.Login,
-				ID:        user.ID,
-				AvatarUrl: user.AvatarUrl,
-				URL:       user.URL,
-				Name:      user.Name,
+				Login:     t.Login,
+				ID:        t.UserID,
+				AvatarUrl: t.AvatarUrl,
+				URL:       t.URL,
+				Name:      t.Name,
 			},
-			TotalCommits:              len(user.Commits),
-			TotalPrs:                  len(user.PullRequests),
-			TotalIssues:               len(user.Issues),
-			TotalReviewedPullRequests: len(user.Reviews),
-			LastContribution:          getLastContribution(user),
+			TotalCommits:              t.Commits,
+			TotalPrs:                  t.PRsOpenedMerged,
+			TotalIssues:               t.Issues,
+			TotalReviewedPullRequests: t.Reviews,
+			LastContribution:          last[t.UserID],
 			Score:                     score,
 		})
 	}
@@ -96,13 +76,6 @@ func getUserStats(db *gorm.DB, startTime time.Time, exclude, repositories []stri
 	return res, returnedTime, nil
 }
 
-func getPrByState(prs []models.PullRequest, state string) []models.PullRequest {
-	prs = slices.DeleteFunc(prs, func(pr models.PullRequest) bool {
-		return pr.State != state
-	})
-	return prs
-}
-
 type UserWithStats struct {
 	models.User
 	TotalCommits              int
@@ -118,97 +91,58 @@ type UserStatsResponse struct {
 	Users        []UserWithStats `json:"users"`
 }
 
-func HandleGetUserStats(db *gorm.DB, cache *ristretto.Cache) func(w http.ResponseWriter, r *http.Request) {
+func HandleGetUserStats(db *gorm.DB, cache *apicache.Cache) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
 		w.Header().Set("Content-Type", "application/json")
 
-		var startTime time.Time
-		switch r.URL.Query().Get("time") {
-		case "daily":
-			startTime = time.Now().AddDate(0, 0, -1)
-		case "weekly":
-			startTime = time.Now().AddDate(0, 0, -7)
-		case "monthly":
-			startTime = time.Now().AddDate(0, -1, 0)
-		case "yearly":
-			startTime = time.Now().AddDate(-1, 0, 0)
-		}
-
+		startTime := PeriodStart(r.URL.Query().Get("time"))
 		exclude := r.URL.Query()["exclude"]
 		repositories := getRepositoriesWithRequest(r)
 
 		cacheKey := fmt.Sprintf("stats:%s:%s:%s", strings.Join(repositories, ","), strings.Join(exclude, ","), r.URL.Query().Get("time"))
-		data, ok := cache.Get(cacheKey)
-		if ok {
-			json.NewEncoder(w).Encode(data.(UserStatsResponse))
-		} else {
+		cache.Serve(w, r, cacheKey, []apicache.Domain{apicache.GitHub, apicache.Clock}, func() (any, error) {
 			stats, lastSyncedAt, err := getUserStats(db, startTime, exclude, repositories)
 			if err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
-				w.Write([]byte(err.Error()))
-
-				return
+				return nil, err
 			}
-
-			resp := UserStatsResponse{
+			return UserStatsResponse{
 				LastSyncedAt: lastSyncedAt,
 				Users:        stats,
-			}
-			cache.SetWithTTL(cacheKey, resp, 0, time.Minute*5)
-			json.NewEncoder(w).Encode(resp)
-		}
-
+			}, nil
+		})
 	}
 }
 
-func HandleGetLastPrs(db *gorm.DB, cache *ristretto.Cache) func(w http.ResponseWriter, r *http.Request) {
+func HandleGetLastPrs(db *gorm.DB, cache *apicache.Cache) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
 		w.Header().Set("Content-Type", "application/json")
 		repositories := getRepositoriesWithRequest(r)
 
 		cacheKey := fmt.Sprintf("lastprs:%s:%s", strings.Join(repositories, ","), r.URL.Query().Get("time"))
-		data, ok := cache.Get(cacheKey)
-		if ok {
-			json.NewEncoder(w).Encode(data.([]*models.PullRequest))
-		} else {
-			lastPRs, err := getLastPrs(db, repositories)
-			if err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
-				w.Write([]byte(err.Error()))
-
-				return
-			}
-
-			cache.SetWithTTL(cacheKey, lastPRs, 0, time.Minute*5)
-			json.NewEncoder(w).Encode(lastPRs)
-		}
-
+		cache.Serve(w, r, cacheKey, []apicache.Domain{apicache.GitHub}, func() (any, error) {
+			return getLastPrs(db, repositories)
+		})
 	}
 }
 
 func HandleGetNewestContributors(db *gorm.DB) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
 		w.Header().Set("Content-Type", "application/json")
 		number := r.URL.Query().Get("number")
 		if number == "" {
 			number = "5"
 		}
-		_, err := strconv.Atoi(number)
+		limit, err := strconv.Atoi(number)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte(err.Error()))
+			apierror.Write(w, r, apierror.InvalidInput("%v", err))
 			return
 		}
 
 		repositories := getRepositoriesWithRequest(r)
-		placeholders := make([]string, len(repositories))
-		args := make([]interface{}, len(repositories))
-
-		for i, repository := range repositories {
-			placeholders[i] = fmt.Sprintf("$%d", i+1)
-			args[i] = repository
-		}
-		query := fmt.Sprintf(`
+		query := `
 		with users_with_oldest_contribution as (
 			select
 				min(
@@ -222,23 +156,21 @@ func HandleGetNewestContributors(db *gorm.DB) func(w http.ResponseWriter, r *htt
 				left join issues i on i.author_id =u.id
 				left join pull_requests pr on pr.author_id =u.id
 				where (pr.created_at is not null OR i.created_at is not null) 
-				AND i.repository_id in (%s) AND pr.repository_id in (%s)
+				AND i.repository_id in ? AND pr.repository_id in ?
 				group by u.id
 				order by oldest_contribution desc
-				limit %s
+				limit ?
 		)
-		select * from users u
+		select u.* from users u
 		inner join users_with_oldest_contribution uc on uc.id =u.id
 		order by uc.oldest_contribution desc
-		`, strings.Join(placeholders, ","), strings.Join(placeholders, ","), number)
+		`
 		var users []models.User
 
-		err = db.Model(&models.User{}).Raw(query, args...).
+		err = db.Model(&models.User{}).Raw(query, repositories, repositories, limit).
 			Find(&users).Error
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte(err.Error()))
-
+			apierror.Write(w, r, err)
 			return
 		}
 
@@ -246,24 +178,55 @@ func HandleGetNewestContributors(db *gorm.DB) func(w http.ResponseWriter, r *htt
 	}
 }
 
-func getLastContribution(user models.User) interface{} {
-	var newest interface{}
-	var lastTime time.Time
-	if len(user.Commits) > 0 {
-		newest = user.Commits[0]
-		lastTime = user.Commits[0].CreatedAt
+// getLastContributions returns, per user, their newest commit, issue or
+// merged pull request since startTime in repositories.
+func getLastContributions(db *gorm.DB, startTime time.Time, repositories, userIDs []string) (map[string]interface{}, error) {
+	out := map[string]interface{}{}
+	if len(userIDs) == 0 {
+		return out, nil
 	}
-
-	if len(user.Issues) > 0 && user.Issues[0].CreatedAt.After(lastTime) {
-		newest = user.Issues[0]
-		lastTime = user.Issues[0].CreatedAt
+	newest := map[string]time.Time{}
+	keep := func(authorID string, createdAt time.Time, v interface{}) {
+		if t, ok := newest[authorID]; !ok || createdAt.After(t) {
+			newest[authorID] = createdAt
+			out[authorID] = v
+		}
 	}
 
-	if len(user.PullRequests) > 0 && user.PullRequests[0].CreatedAt.After(lastTime) {
-		newest = user.PullRequests[0]
+	window := func(table string) *gorm.DB {
+		return db.Table(table).Where(table+".created_at > ? AND "+table+".repository_id IN ?", startTime, repositories)
+	}
+	commits, err := newestPerAuthor[models.Commit](db, window("commits"), "commits", userIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range commits {
+		keep(c.AuthorID, c.CreatedAt, c)
+	}
+	issues, err := newestPerAuthor[models.Issue](db, window("issues"), "issues", userIDs)
+	if err != nil {
+		return nil, err
 	}
+	for _, i := range issues {
+		keep(i.AuthorID, i.CreatedAt, i)
+	}
+	prs, err := newestPerAuthor[models.PullRequest](db, window("pull_requests").Where("pull_requests.state = ?", "MERGED"), "pull_requests", userIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, pr := range prs {
+		keep(pr.AuthorID, pr.CreatedAt, pr)
+	}
+	return out, nil
+}
 
-	return newest
+// newestPerAuthor loads the newest row of q per author in one query.
+func newestPerAuthor[T any](db *gorm.DB, q *gorm.DB, table string, authorIDs []string) ([]T, error) {
+	ranked := q.Select(table+".*, ROW_NUMBER() OVER (PARTITION BY "+table+".author_id ORDER BY "+table+".created_at DESC) AS rn").
+		Where(table+".author_id IN ?", authorIDs)
+	var rows []T
+	err := db.Session(&gorm.Session{NewDB: true}).Table("(?) AS ranked", ranked).Where("rn = 1").Find(&rows).Error
+	return rows, err
 }
 
 func getRepositoriesWithRequest(r *http.Request) []string {
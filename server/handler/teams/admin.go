@@ -0,0 +1,233 @@
+package teams
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/samouraiworld/topofgnomes/server/handler/adminauth"
+	"github.com/samouraiworld/topofgnomes/server/handler/apierror"
+	"github.com/samouraiworld/topofgnomes/server/teams"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+	// maxImportSize bounds an imported roster; teams.yaml is a few KB.
+	maxImportSize = 1 << 20
+)
+
+type createTeamRequest struct {
+	teams.Team
+	// JoinedAt is when the members join; now when omitted.
+	JoinedAt *time.Time `json:"joinedAt,omitempty"`
+}
+
+type addMemberRequest struct {
+	Login string `json:"login"`
+	// JoinedAt is when the member joins; now when omitted.
+	JoinedAt *time.Time `json:"joinedAt,omitempty"`
+}
+
+// fileManaged is the answer to changes while the roster comes from
+// teams.yaml.
+var fileManaged = apierror.Conflict("the roster is read from teams.yaml; set TEAMS_SOURCE=db to manage it through the API")
+
+// storeError maps a change the Store refused to its API error; anything
+// else is an internal error.
+func storeError(err error) error {
+	switch {
+	case errors.Is(err, teams.ErrNotFound):
+		return apierror.NotFound("%v", err)
+	case errors.Is(err, teams.ErrConflict):
+		return apierror.Conflict("%v", err)
+	case errors.Is(err, teams.ErrInvalid):
+		return apierror.InvalidInput("%v", err)
+	}
+	return err
+}
+
+// effective returns *t, or now when t is nil.
+func effective(t *time.Time) time.Time {
+	if t == nil {
+		return time.Now()
+	}
+	return *t
+}
+
+func writeTeam(w http.ResponseWriter, store *teams.Store, team teams.Team, status int) {
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(teamResponse{
+		SchemaVersion: teams.SchemaVersion,
+		LastSyncedAt:  store.Current().LastSyncedAt,
+		Team:          team,
+	})
+}
+
+// HandleCreateTeam adds a team to the database roster.
+func HandleCreateTeam(store *teams.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if store == nil {
+			apierror.Write(w, r, fileManaged)
+			return
+		}
+		var req createTeamRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Write(w, r, apierror.InvalidInput("%v", err))
+			return
+		}
+		team, err := store.CreateTeam(adminauth.Actor(r.Context()), req.Team, effective(req.JoinedAt))
+		if err != nil {
+			apierror.Write(w, r, storeError(err))
+			return
+		}
+		writeTeam(w, store, team, http.StatusCreated)
+	}
+}
+
+// HandleUpdateTeam renames, recolors or redescribes a team.
+func HandleUpdateTeam(store *teams.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if store == nil {
+			apierror.Write(w, r, fileManaged)
+			return
+		}
+		var req teams.TeamUpdate
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Write(w, r, apierror.InvalidInput("%v", err))
+			return
+		}
+		team, err := store.UpdateTeam(adminauth.Actor(r.Context()), chi.URLParam(r, "slug"), req)
+		if err != nil {
+			apierror.Write(w, r, storeError(err))
+			return
+		}
+		writeTeam(w, store, team, http.StatusOK)
+	}
+}
+
+// HandleAddMember adds a member to a team, from now or from joinedAt.
+func HandleAddMember(store *teams.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if store == nil {
+			apierror.Write(w, r, fileManaged)
+			return
+		}
+		var req addMemberRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Write(w, r, apierror.InvalidInput("%v", err))
+			return
+		}
+		team, err := store.AddMember(adminauth.Actor(r.Context()), chi.URLParam(r, "slug"), req.Login, effective(req.JoinedAt))
+		if err != nil {
+			apierror.Write(w, r, storeError(err))
+			return
+		}
+		writeTeam(w, store, team, http.StatusCreated)
+	}
+}
+
+// HandleRemoveMember ends a membership, now or at ?leftAt=. Contributions
+// made before that date stay the team's.
+func HandleRemoveMember(store *teams.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if store == nil {
+			apierror.Write(w, r, fileManaged)
+			return
+		}
+		var leftAt *time.Time
+		if s := r.URL.Query().Get("leftAt"); s != "" {
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				apierror.Write(w, r, apierror.InvalidInput("invalid leftAt %q, use RFC3339", s))
+				return
+			}
+			leftAt = &t
+		}
+		team, err := store.RemoveMember(adminauth.Actor(r.Context()), chi.URLParam(r, "slug"), chi.URLParam(r, "login"), effective(leftAt))
+		if err != nil {
+			apierror.Write(w, r, storeError(err))
+			return
+		}
+		writeTeam(w, store, team, http.StatusOK)
+	}
+}
+
+// HandleGetAudit lists roster changes, newest first.
+func HandleGetAudit(store *teams.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if store == nil {
+			apierror.Write(w, r, fileManaged)
+			return
+		}
+		limit := defaultAuditLimit
+		if s := r.URL.Query().Get("limit"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n <= 0 {
+				apierror.Write(w, r, apierror.InvalidInput("invalid limit %q", s))
+				return
+			}
+			limit = min(n, maxAuditLimit)
+		}
+		events, err := store.Audit(r.URL.Query().Get("team"), limit)
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(events)
+	}
+}
+
+// HandleExport serves the current roster in the teams.yaml format, from
+// either source.
+func HandleExport(roster teams.Provider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		raw, err := teams.Marshal(roster())
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/yaml")
+		_, _ = w.Write(raw)
+	}
+}
+
+// HandleImport replaces the database roster with a teams.yaml document.
+func HandleImport(store *teams.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if store == nil {
+			apierror.Write(w, r, fileManaged)
+			return
+		}
+		raw, err := io.ReadAll(io.LimitReader(r.Body, maxImportSize))
+		if err != nil {
+			apierror.Write(w, r, apierror.InvalidInput("%v", err))
+			return
+		}
+		cfg, err := teams.Parse(raw)
+		if err != nil {
+			apierror.Write(w, r, apierror.InvalidInput("%v", err))
+			return
+		}
+		if err := store.Import(adminauth.Actor(r.Context()), cfg); err != nil {
+			apierror.Write(w, r, storeError(err))
+			return
+		}
+		cfg = store.Current()
+		_ = json.NewEncoder(w).Encode(teamsResponse{
+			SchemaVersion: cfg.SchemaVersion,
+			LastSyncedAt:  cfg.LastSyncedAt,
+			Teams:         cfg.Teams,
+		})
+	}
+}
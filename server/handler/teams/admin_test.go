@@ -0,0 +1,143 @@
+package teams
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/clerk/clerk-sdk-go/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/samouraiworld/topofgnomes/server/db/dbtest"
+	"github.com/samouraiworld/topofgnomes/server/models"
+	"github.com/samouraiworld/topofgnomes/server/teams"
+)
+
+// adminRouter serves the admin routes as user_admin, without the Clerk and
+// admin checks routes.go puts in front of them.
+func adminRouter(store *teams.Store, roster teams.Provider) http.Handler {
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			claims := &clerk.SessionClaims{RegisteredClaims: clerk.RegisteredClaims{Subject: "user_admin"}}
+			next.ServeHTTP(w, req.WithContext(clerk.ContextWithSessionClaims(req.Context(), claims)))
+		})
+	})
+	r.Post("/admin/teams", HandleCreateTeam(store))
+	r.Patch("/admin/teams/{slug}", HandleUpdateTeam(store))
+	r.Post("/admin/teams/{slug}/members", HandleAddMember(store))
+	r.Delete("/admin/teams/{slug}/members/{login}", HandleRemoveMember(store))
+	r.Get("/admin/teams/audit", HandleGetAudit(store))
+	r.Get("/admin/teams/export", HandleExport(roster))
+	r.Put("/admin/teams/import", HandleImport(store))
+	return r
+}
+
+func do(t *testing.T, h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return rec
+}
+
+func newTestStore(t *testing.T) *teams.Store {
+	t.Helper()
+	db := dbtest.Open(t, &models.Team{}, &models.TeamMembership{}, &models.TeamAuditEvent{})
+	store, err := teams.NewStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Import("system", fixtureConfig()); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestAdminFileManagedRoster(t *testing.T) {
+	h := adminRouter(nil, teams.Static(fixtureConfig()))
+	if rec := do(t, h, http.MethodPost, "/admin/teams/onbloc/members", `{"login":"x"}`); rec.Code != http.StatusConflict {
+		t.Errorf("add member: status %d, want 409", rec.Code)
+	}
+	rec := do(t, h, http.MethodGet, "/admin/teams/export", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "slug: onbloc") {
+		t.Errorf("export: status %d, body %s", rec.Code, rec.Body)
+	}
+}
+
+func TestAdminMembers(t *testing.T) {
+	store := newTestStore(t)
+	h := adminRouter(store, store.Current)
+
+	rec := do(t, h, http.MethodPost, "/admin/teams/onbloc/members", `{"login":"newhire"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("add member: status %d, body %s", rec.Code, rec.Body)
+	}
+	var resp teamResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(resp.Team.Members, ","); got != "notJoon,r3v4s,newhire" {
+		t.Errorf("members = %s", got)
+	}
+
+	// zxxma is in samouraiworld: double attribution.
+	if rec := do(t, h, http.MethodPost, "/admin/teams/onbloc/members", `{"login":"zxxma"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("double attribution: status %d, want 400", rec.Code)
+	}
+	if rec := do(t, h, http.MethodDelete, "/admin/teams/onbloc/members/r3v4s?leftAt=yesterday", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad leftAt: status %d, want 400", rec.Code)
+	}
+	if rec := do(t, h, http.MethodDelete, "/admin/teams/onbloc/members/r3v4s", ""); rec.Code != http.StatusOK {
+		t.Errorf("remove member: status %d, body %s", rec.Code, rec.Body)
+	}
+	if got := strings.Join(store.Current().MembersOf("onbloc"), ","); got != "notJoon,newhire" {
+		t.Errorf("roster members = %s", got)
+	}
+
+	rec = do(t, h, http.MethodGet, "/admin/teams/audit?team=onbloc", "")
+	var events []models.TeamAuditEvent
+	if err := json.NewDecoder(rec.Body).Decode(&events); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Action != "remove_member" || events[0].Actor != "user_admin" {
+		t.Errorf("audit = %+v", events)
+	}
+}
+
+func TestAdminCreateUpdateImport(t *testing.T) {
+	store := newTestStore(t)
+	h := adminRouter(store, store.Current)
+
+	rec := do(t, h, http.MethodPost, "/admin/teams", `{"slug":"gnocore","name":"Gno Core","color":"blue","members":["moul"]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: status %d, body %s", rec.Code, rec.Body)
+	}
+	if rec := do(t, h, http.MethodPost, "/admin/teams", `{"slug":"gnocore","name":"Again","color":"green","members":["moul"]}`); rec.Code != http.StatusConflict {
+		t.Errorf("duplicate slug: status %d, want 409", rec.Code)
+	}
+	if rec := do(t, h, http.MethodPatch, "/admin/teams/nope", `{"name":"Core"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown team: status %d, want 404", rec.Code)
+	}
+	if rec := do(t, h, http.MethodPatch, "/admin/teams/gnocore", `{"color":"teal"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid color: status %d, want 400", rec.Code)
+	}
+	if rec := do(t, h, http.MethodPatch, "/admin/teams/gnocore", `{"name":"Core"}`); rec.Code != http.StatusOK {
+		t.Errorf("rename: status %d, body %s", rec.Code, rec.Body)
+	}
+
+	// Export, then import what was exported: nothing changes.
+	exported := do(t, h, http.MethodGet, "/admin/teams/export", "").Body.String()
+	if !strings.Contains(exported, "name: Core") {
+		t.Fatalf("export = %s", exported)
+	}
+	if rec := do(t, h, http.MethodPut, "/admin/teams/import", exported); rec.Code != http.StatusOK {
+		t.Fatalf("import: status %d, body %s", rec.Code, rec.Body)
+	}
+	if got := len(store.Current().Teams); got != 3 {
+		t.Errorf("teams after re-import = %d, want 3", got)
+	}
+	if rec := do(t, h, http.MethodPut, "/admin/teams/import", "schemaVersion: 1\nteams: []\n"); rec.Code != http.StatusBadRequest {
+		t.Errorf("empty import: status %d, want 400", rec.Code)
+	}
+}
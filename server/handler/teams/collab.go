@@ -1,20 +1,19 @@
 package teams
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"sort"
-	"strings"
 	"time"
 
-	"github.com/dgraph-io/ristretto"
+	"github.com/samouraiworld/topofgnomes/server/apicache"
+	dbpkg "github.com/samouraiworld/topofgnomes/server/db"
+	"github.com/samouraiworld/topofgnomes/server/handler"
 	"github.com/samouraiworld/topofgnomes/server/teams"
 	"gorm.io/gorm"
 )
 
 const (
-	collabCacheTTL  = 5 * time.Minute
 	collabSchemaVer = 1
 	// Hide noise contributors that pollute the matrix (review bots).
 	// Case-insensitive match on the GitHub login.
@@ -53,28 +52,17 @@ type collabResponse struct {
 // author) are excluded too.
 //
 // `?time=` accepts `daily|weekly|monthly|yearly|""`(all-time).
-// Cached 5 min via ristretto keyed on the period.
-func HandleGetTeamCollab(db *gorm.DB, cfg *teams.Config, cache *ristretto.Cache) http.HandlerFunc {
+// Cached per period until the next sync or teams config reload.
+func HandleGetTeamCollab(db *gorm.DB, roster teams.Provider, cache *apicache.Cache) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		cfg := roster()
 		w.Header().Set("Content-Type", "application/json")
 		period := r.URL.Query().Get("time")
 		key := fmt.Sprintf("team-collab:%s", period)
-		if cache != nil {
-			if cached, ok := cache.Get(key); ok {
-				_ = json.NewEncoder(w).Encode(cached.(collabResponse))
-				return
-			}
-		}
-
-		resp, err := computeTeamCollab(db, cfg, period)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		if cache != nil {
-			cache.SetWithTTL(key, resp, 0, collabCacheTTL)
-		}
-		_ = json.NewEncoder(w).Encode(resp)
+		cache.Serve(w, r, key, []apicache.Domain{apicache.GitHub, apicache.TeamsConfig, apicache.Clock}, func() (any, error) {
+			return computeTeamCollab(db, cfg, period)
+		})
 	}
 }
 
@@ -87,19 +75,23 @@ func computeTeamCollab(db *gorm.DB, cfg *teams.Config, period string) (collabRes
 		return collabResponse{}, fmt.Errorf("teams config is nil")
 	}
 
-	startTime := periodStart(period)
+	startTime := handler.PeriodStart(period)
 
 	type row struct {
 		AuthorLogin   string `gorm:"column:author_login"`
 		ReviewerLogin string `gorm:"column:reviewer_login"`
+		Day           string `gorm:"column:day"`
 		Reviews       int    `gorm:"column:reviews"`
 	}
 
 	// Join reviews → pull_requests (to get PR author) → users for both sides.
-	// Exclude self-reviews and the dependabot bot account.
+	// Exclude self-reviews and the dependabot bot account. Counts are per
+	// review day: both sides are attributed to the team they were in then.
+	day := dbpkg.DayExpr(db, "reviews.created_at")
 	q := db.Table("reviews").
 		Select(`author_users.login AS author_login,
 		        reviewer_users.login AS reviewer_login,
+		        `+day+` AS day,
 		        COUNT(*) AS reviews`).
 		Joins("JOIN pull_requests ON pull_requests.id = reviews.pull_request_id").
 		Joins("JOIN users AS author_users ON author_users.id = pull_requests.author_id").
@@ -108,7 +100,7 @@ func computeTeamCollab(db *gorm.DB, cfg *teams.Config, period string) (collabRes
 		Where("author_users.id <> reviewer_users.id").
 		Where("LOWER(author_users.login) <> ?", collabBotLogin).
 		Where("LOWER(reviewer_users.login) <> ?", collabBotLogin).
-		Group("author_users.login, reviewer_users.login")
+		Group("author_users.login, reviewer_users.login, " + day)
 	if !startTime.IsZero() {
 		q = q.Where("reviews.created_at >= ?", startTime)
 	}
@@ -118,14 +110,9 @@ func computeTeamCollab(db *gorm.DB, cfg *teams.Config, period string) (collabRes
 		return collabResponse{}, fmt.Errorf("team-collab query: %w", err)
 	}
 
-	// Build a case-insensitive login→team lookup once.
-	loginToTeam := map[string]string{} // lowercased login -> slug
 	teamSlugs := make([]string, 0, len(cfg.Teams))
 	for _, t := range cfg.Teams {
 		teamSlugs = append(teamSlugs, t.Slug)
-		for _, m := range t.Members {
-			loginToTeam[strings.ToLower(m)] = t.Slug
-		}
 	}
 	sort.Strings(teamSlugs)
 
@@ -135,8 +122,8 @@ func computeTeamCollab(db *gorm.DB, cfg *teams.Config, period string) (collabRes
 	outsiderByAuthor := map[string]int{}
 	outsiderByReviewer := map[string]int{}
 	for _, rr := range rows {
-		aTeam, aOk := loginToTeam[strings.ToLower(rr.AuthorLogin)]
-		rTeam, rOk := loginToTeam[strings.ToLower(rr.ReviewerLogin)]
+		aTeam, aOk := cfg.TeamOn(rr.AuthorLogin, rr.Day)
+		rTeam, rOk := cfg.TeamOn(rr.ReviewerLogin, rr.Day)
 		switch {
 		case aOk && rOk:
 			matrix[pairKey{a: aTeam, r: rTeam}] += rr.Reviews
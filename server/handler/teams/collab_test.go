@@ -9,7 +9,10 @@ import (
 	"time"
 
 	"github.com/dgraph-io/ristretto"
+	"github.com/samouraiworld/topofgnomes/server/apicache"
+	"github.com/samouraiworld/topofgnomes/server/contributions"
 	"github.com/samouraiworld/topofgnomes/server/models"
+	"github.com/samouraiworld/topofgnomes/server/teams"
 	"gorm.io/gorm"
 )
 
@@ -49,6 +52,9 @@ func seedMergedPRReturnID(t *testing.T, db *gorm.DB, repoID, authorID string, me
 	if err := db.Create(&pr).Error; err != nil {
 		t.Fatalf("seed pr: %v", err)
 	}
+	if err := contributions.Refresh(db, repoID, []string{contributions.Day(pr.CreatedAt), contributions.Day(mergedAt)}); err != nil {
+		t.Fatalf("refresh rollup: %v", err)
+	}
 	return id
 }
 
@@ -147,13 +153,41 @@ func TestComputeTeamCollab_ExcludesDependabotAndSelfReviews(t *testing.T) {
 	}
 }
 
+func TestComputeTeamCollab_MemberMovedTeams(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Now().UTC()
+	cfg := movedConfig(now.AddDate(0, 0, -5))
+
+	notJoon := seedUser(t, db, "notJoon")
+	r3v4s := seedUser(t, db, "r3v4s")
+
+	// r3v4s reviews notJoon as a teammate, then from samouraiworld.
+	before := seedMergedPRReturnID(t, db, "gnolang/gno", notJoon, now.AddDate(0, 0, -10))
+	seedReview(t, db, before, r3v4s, "gnolang/gno", now.AddDate(0, 0, -10))
+	after := seedMergedPRReturnID(t, db, "gnolang/gno", notJoon, now.AddDate(0, 0, -2))
+	seedReview(t, db, after, r3v4s, "gnolang/gno", now.AddDate(0, 0, -2))
+
+	resp, err := computeTeamCollab(db, cfg, "monthly")
+	if err != nil {
+		t.Fatalf("computeTeamCollab: %v", err)
+	}
+	cellMap := map[string]int{}
+	for _, c := range resp.Cells {
+		cellMap[c.AuthorTeam+":"+c.ReviewerTeam] = c.Reviews
+	}
+	if cellMap["onbloc:onbloc"] != 1 || cellMap["onbloc:samouraiworld"] != 1 {
+		t.Errorf("cells = %+v, want one onbloc review and one samouraiworld review", resp.Cells)
+	}
+}
+
 func TestHandleGetTeamCollab_CachesResponse(t *testing.T) {
 	db := newTestDB(t)
 	if err := db.AutoMigrate(&models.Review{}); err != nil {
 		t.Fatalf("migrate Review: %v", err)
 	}
 	cfg := fixtureConfig()
-	cache, _ := ristretto.NewCache(&ristretto.Config{NumCounters: 1000, MaxCost: 1 << 20, BufferItems: 64})
+	store, _ := ristretto.NewCache(&ristretto.Config{NumCounters: 1000, MaxCost: 1 << 20, BufferItems: 64})
+	cache := apicache.New(store)
 
 	notJoon := seedUser(t, db, "notJoon")
 	zxxma := seedUser(t, db, "zxxma")
@@ -162,7 +196,7 @@ func TestHandleGetTeamCollab_CachesResponse(t *testing.T) {
 	pr := seedMergedPRReturnID(t, db, "gnolang/gno", notJoon, mergedAt)
 	seedReview(t, db, pr, zxxma, "gnolang/gno", mergedAt)
 
-	h := HandleGetTeamCollab(db, cfg, cache)
+	h := HandleGetTeamCollab(db, teams.Static(cfg), cache)
 
 	rec1 := httptest.NewRecorder()
 	h.ServeHTTP(rec1, httptest.NewRequest(http.MethodGet, "/team-collab", nil))
@@ -0,0 +1,328 @@
+package teams
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/samouraiworld/topofgnomes/server/apicache"
+	"github.com/samouraiworld/topofgnomes/server/contributions"
+	"github.com/samouraiworld/topofgnomes/server/handler"
+	"github.com/samouraiworld/topofgnomes/server/handler/apierror"
+	"github.com/samouraiworld/topofgnomes/server/models"
+	"github.com/samouraiworld/topofgnomes/server/teams"
+	"gorm.io/gorm"
+)
+
+// maxCompareTeams bounds /teams/compare: each team is one rollup query.
+const maxCompareTeams = 8
+
+// PerCapita is a team's figures divided by its headcount over the period.
+type PerCapita struct {
+	MergedPRs       float64 `json:"mergedPRs"`
+	ReviewsGiven    float64 `json:"reviewsGiven"`
+	ReviewsReceived float64 `json:"reviewsReceived"`
+	Issues          float64 `json:"issues"`
+	Score           float64 `json:"score"`
+}
+
+// LeaderboardEntry is one team's row of /teams/leaderboard.
+type LeaderboardEntry struct {
+	Rank  int    `json:"rank"`
+	Slug  string `json:"slug"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+	// Members counts everyone in the team at some point in the period.
+	Members      int `json:"members"`
+	MergedPRs    int `json:"mergedPRs"`
+	ReviewsGiven int `json:"reviewsGiven"`
+	// ReviewsReceived counts reviews of the team's PRs by members of other
+	// teams, as in the /team-collab matrix.
+	ReviewsReceived    int       `json:"reviewsReceived"`
+	Issues             int       `json:"issues"`
+	Commits            int       `json:"commits"`
+	Score              float64   `json:"score"`
+	ActiveContributors int       `json:"activeContributors"`
+	ActiveRepos        int       `json:"activeRepos"`
+	PerCapita          PerCapita `json:"perCapita"`
+}
+
+type leaderboardResponse struct {
+	SchemaVersion int                `json:"schemaVersion"`
+	LastSyncedAt  *time.Time         `json:"lastSyncedAt"`
+	Period        string             `json:"period"`
+	Teams         []LeaderboardEntry `json:"teams"`
+}
+
+// ComparePoint is one team's week in /teams/compare.
+type ComparePoint struct {
+	// Week is the Monday the week starts on, YYYY-MM-DD (UTC).
+	Week               string  `json:"week"`
+	MergedPRs          int     `json:"mergedPRs"`
+	Reviews            int     `json:"reviews"`
+	Issues             int     `json:"issues"`
+	Commits            int     `json:"commits"`
+	Score              float64 `json:"score"`
+	ActiveContributors int     `json:"activeContributors"`
+}
+
+type compareSeries struct {
+	Slug   string         `json:"slug"`
+	Name   string         `json:"name"`
+	Color  string         `json:"color"`
+	Points []ComparePoint `json:"points"`
+}
+
+type compareResponse struct {
+	SchemaVersion int        `json:"schemaVersion"`
+	LastSyncedAt  *time.Time `json:"lastSyncedAt"`
+	Period        string     `json:"period"`
+	// Weeks is the shared axis: every series has one point per week.
+	Weeks []string        `json:"weeks"`
+	Teams []compareSeries `json:"teams"`
+}
+
+// activityRow is what one member did on one day while in the team.
+type activityRow struct {
+	Day       string `gorm:"column:day"`
+	UserID    string `gorm:"column:user_id"`
+	Commits   int    `gorm:"column:commits"`
+	PRsMerged int    `gorm:"column:prs_merged"`
+	Issues    int    `gorm:"column:issues"`
+	Reviews   int    `gorm:"column:reviews"`
+}
+
+// HandleGetLeaderboard ranks the teams by score over a period. Cached until
+// the next sync or teams config reload.
+func HandleGetLeaderboard(db *gorm.DB, roster teams.Provider, cache *apicache.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		cfg := roster()
+		w.Header().Set("Content-Type", "application/json")
+		period := r.URL.Query().Get("time")
+		key := fmt.Sprintf("teams:leaderboard:%s", period)
+		cache.Serve(w, r, key, []apicache.Domain{apicache.GitHub, apicache.TeamsConfig, apicache.Clock}, func() (any, error) {
+			return computeLeaderboard(db, cfg, period)
+		})
+	}
+}
+
+// computeLeaderboard builds one entry per team from queryTeamStats (merged
+// PRs and who/where they landed), queryTeamActivity (the other rollup
+// columns) and the collab matrix (reviews received).
+func computeLeaderboard(db *gorm.DB, cfg *teams.Config, period string) (leaderboardResponse, error) {
+	startTime := handler.PeriodStart(period)
+	collab, err := computeTeamCollab(db, cfg, period)
+	if err != nil {
+		return leaderboardResponse{}, err
+	}
+	received := map[string]int{}
+	for _, c := range collab.Cells {
+		if c.AuthorTeam != c.ReviewerTeam {
+			received[c.AuthorTeam] += c.Reviews
+		}
+	}
+
+	resp := leaderboardResponse{
+		SchemaVersion: cfg.SchemaVersion,
+		LastSyncedAt:  collab.LastSyncedAt,
+		Period:        period,
+		Teams:         make([]LeaderboardEntry, 0, len(cfg.Teams)),
+	}
+	now := time.Now()
+	for _, team := range cfg.Teams {
+		stats, _, err := queryTeamStats(db, team, startTime, nil)
+		if err != nil {
+			return leaderboardResponse{}, err
+		}
+		activity, err := queryTeamActivity(db, team, startTime, time.Time{})
+		if err != nil {
+			return leaderboardResponse{}, err
+		}
+		totals := rollUp(stats)
+		e := LeaderboardEntry{
+			Slug:               team.Slug,
+			Name:               team.Name,
+			Color:              team.Color,
+			Members:            team.Headcount(startTime, now),
+			MergedPRs:          totals.MergedPRs,
+			ReviewsReceived:    received[team.Slug],
+			ActiveContributors: totals.ActiveContributors,
+			ActiveRepos:        totals.ActiveRepos,
+		}
+		for _, a := range activity {
+			e.ReviewsGiven += a.Reviews
+			e.Issues += a.Issues
+			e.Commits += a.Commits
+		}
+		e.Score = handler.CalculateScore(int64(e.Commits), int64(e.Issues), int64(e.MergedPRs), int64(e.ReviewsGiven))
+		if n := float64(e.Members); n > 0 {
+			e.PerCapita = PerCapita{
+				MergedPRs:       float64(e.MergedPRs) / n,
+				ReviewsGiven:    float64(e.ReviewsGiven) / n,
+				ReviewsReceived: float64(e.ReviewsReceived) / n,
+				Issues:          float64(e.Issues) / n,
+				Score:           e.Score / n,
+			}
+		}
+		resp.Teams = append(resp.Teams, e)
+	}
+
+	sort.SliceStable(resp.Teams, func(i, j int) bool {
+		if resp.Teams[i].Score != resp.Teams[j].Score {
+			return resp.Teams[i].Score > resp.Teams[j].Score
+		}
+		return resp.Teams[i].Slug < resp.Teams[j].Slug
+	})
+	for i := range resp.Teams {
+		resp.Teams[i].Rank = i + 1
+	}
+	return resp, nil
+}
+
+// HandleGetCompare returns weekly series for ?slugs=a,b, aligned on the
+// same weeks. Cached until the next sync or teams config reload.
+func HandleGetCompare(db *gorm.DB, roster teams.Provider, cache *apicache.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		cfg := roster()
+		w.Header().Set("Content-Type", "application/json")
+		var selected []teams.Team
+		seen := map[string]bool{}
+		for _, slug := range strings.Split(r.URL.Query().Get("slugs"), ",") {
+			slug = strings.TrimSpace(slug)
+			if slug == "" || seen[strings.ToLower(slug)] {
+				continue
+			}
+			seen[strings.ToLower(slug)] = true
+			team, ok := cfg.FindBySlug(slug)
+			if !ok {
+				apierror.Write(w, r, apierror.NotFound("team %q not found", slug))
+				return
+			}
+			selected = append(selected, team)
+		}
+		if len(selected) < 2 || len(selected) > maxCompareTeams {
+			apierror.Write(w, r, apierror.InvalidInput("slugs must list 2 to %d teams", maxCompareTeams))
+			return
+		}
+		period := r.URL.Query().Get("time")
+		slugs := make([]string, len(selected))
+		for i, t := range selected {
+			slugs[i] = strings.ToLower(t.Slug)
+		}
+		key := fmt.Sprintf("teams:compare:%s:%s", strings.Join(slugs, ","), period)
+		cache.Serve(w, r, key, []apicache.Domain{apicache.GitHub, apicache.TeamsConfig, apicache.Clock}, func() (any, error) {
+			return computeCompare(db, cfg, selected, period)
+		})
+	}
+}
+
+func computeCompare(db *gorm.DB, cfg *teams.Config, selected []teams.Team, period string) (compareResponse, error) {
+	startTime := handler.PeriodStart(period)
+	byTeam := make([]map[string]*ComparePoint, len(selected))
+	first := ""
+	if !startTime.IsZero() {
+		first = bucketOf(bucketWeek, contributions.Day(startTime))
+	}
+	for i, team := range selected {
+		activity, err := queryTeamActivity(db, team, startTime, time.Time{})
+		if err != nil {
+			return compareResponse{}, err
+		}
+		points := map[string]*ComparePoint{}
+		for week, b := range sumActivity(bucketWeek, activity) {
+			points[week] = &ComparePoint{
+				Week:               week,
+				MergedPRs:          b.mergedPRs,
+				Reviews:            b.reviews,
+				Issues:             b.issues,
+				Commits:            b.commits,
+				Score:              handler.CalculateScore(int64(b.commits), int64(b.issues), int64(b.mergedPRs), int64(b.reviews)),
+				ActiveContributors: len(b.authors),
+			}
+			if first == "" || week < first {
+				first = week
+			}
+		}
+		byTeam[i] = points
+	}
+
+	weeks := bucketsBetween(bucketWeek, first, bucketOf(bucketWeek, contributions.Day(time.Now())))
+	resp := compareResponse{
+		SchemaVersion: cfg.SchemaVersion,
+		LastSyncedAt:  handler.LastSyncedAt(db),
+		Period:        period,
+		Weeks:         weeks,
+		Teams:         make([]compareSeries, len(selected)),
+	}
+	for i, team := range selected {
+		series := compareSeries{Slug: team.Slug, Name: team.Name, Color: team.Color, Points: make([]ComparePoint, len(weeks))}
+		for j, week := range weeks {
+			if p, ok := byTeam[i][week]; ok {
+				series.Points[j] = *p
+			} else {
+				series.Points[j] = ComparePoint{Week: week}
+			}
+		}
+		resp.Teams[i] = series
+	}
+	return resp, nil
+}
+
+// queryTeamActivity sums the rollup per day and member, over the days each
+// member was in the team (see memberDays), from the day of from to the day
+// before to. A zero from or to leaves that side open.
+func queryTeamActivity(db *gorm.DB, team teams.Team, from, to time.Time) ([]activityRow, error) {
+	clause, args := memberDays(team, "daily_contributions.day")
+	q := db.Model(&models.DailyContribution{}).
+		Select(`daily_contributions.day AS day, daily_contributions.user_id AS user_id,
+			SUM(daily_contributions.commits) AS commits, SUM(daily_contributions.prs_merged) AS prs_merged,
+			SUM(daily_contributions.issues) AS issues, SUM(daily_contributions.reviews) AS reviews`).
+		Joins("JOIN users ON users.id = daily_contributions.user_id").
+		Where(clause, args...).
+		Where("(daily_contributions.commits > 0 OR daily_contributions.prs_merged > 0 OR daily_contributions.issues > 0 OR daily_contributions.reviews > 0)").
+		Group("daily_contributions.day, daily_contributions.user_id").
+		Order("daily_contributions.day")
+	if !from.IsZero() {
+		q = q.Where("daily_contributions.day >= ?", contributions.Day(from))
+	}
+	if !to.IsZero() {
+		q = q.Where("daily_contributions.day < ?", contributions.Day(to))
+	}
+	var rows []activityRow
+	if err := q.Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("team-activity query: %w", err)
+	}
+	return rows, nil
+}
+
+// bucketTotals is a team's activity over one bucket.
+type bucketTotals struct {
+	mergedPRs, reviews, issues, commits int
+	// authors are the members with a merged PR, as in rollUp.
+	authors map[string]struct{}
+}
+
+// sumActivity groups rows by the bucket their day falls in.
+func sumActivity(bucket string, rows []activityRow) map[string]*bucketTotals {
+	out := map[string]*bucketTotals{}
+	for _, a := range rows {
+		start := bucketOf(bucket, a.Day)
+		b, ok := out[start]
+		if !ok {
+			b = &bucketTotals{authors: map[string]struct{}{}}
+			out[start] = b
+		}
+		b.mergedPRs += a.PRsMerged
+		b.reviews += a.Reviews
+		b.issues += a.Issues
+		b.commits += a.Commits
+		if a.PRsMerged > 0 {
+			b.authors[a.UserID] = struct{}{}
+		}
+	}
+	return out
+}
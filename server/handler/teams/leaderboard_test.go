@@ -0,0 +1,117 @@
+package teams
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/samouraiworld/topofgnomes/server/contributions"
+	"github.com/samouraiworld/topofgnomes/server/handler"
+	"github.com/samouraiworld/topofgnomes/server/teams"
+)
+
+func TestComputeLeaderboard(t *testing.T) {
+	db := newTestDB(t)
+	cfg := fixtureConfig()
+	notJoon := seedUser(t, db, "notJoon")
+	zxxma := seedUser(t, db, "zxxma")
+	mergedAt := time.Now().UTC().Add(-time.Hour)
+
+	pr := seedMergedPRReturnID(t, db, "gnolang/gno", notJoon, mergedAt)
+	seedMergedPR(t, db, "gnolang/gno", notJoon, mergedAt)
+	seedReview(t, db, pr, zxxma, "gnolang/gno", mergedAt)
+	if err := contributions.Refresh(db, "gnolang/gno", []string{contributions.Day(mergedAt)}); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := computeLeaderboard(db, cfg, "")
+	if err != nil {
+		t.Fatalf("computeLeaderboard: %v", err)
+	}
+	if len(resp.Teams) != 2 {
+		t.Fatalf("teams = %+v", resp.Teams)
+	}
+	onbloc, samourai := resp.Teams[0], resp.Teams[1]
+	if onbloc.Slug != "onbloc" || onbloc.Rank != 1 || samourai.Rank != 2 {
+		t.Fatalf("ranking = %+v", resp.Teams)
+	}
+	if onbloc.MergedPRs != 2 || onbloc.ReviewsReceived != 1 || onbloc.ActiveContributors != 1 || onbloc.Members != 2 {
+		t.Errorf("onbloc = %+v", onbloc)
+	}
+	if want := handler.CalculateScore(0, 0, 2, 0); onbloc.Score != want || onbloc.PerCapita.Score != want/2 {
+		t.Errorf("onbloc score = %v (per capita %v), want %v", onbloc.Score, onbloc.PerCapita.Score, want)
+	}
+	if samourai.ReviewsGiven != 1 || samourai.ReviewsReceived != 0 || samourai.PerCapita.ReviewsGiven != 0.5 {
+		t.Errorf("samouraiworld = %+v", samourai)
+	}
+}
+
+func TestHandleGetCompare(t *testing.T) {
+	db := newTestDB(t)
+	cfg := fixtureConfig()
+	notJoon := seedUser(t, db, "notJoon")
+	zxxma := seedUser(t, db, "zxxma")
+	now := time.Now().UTC()
+	seedMergedPR(t, db, "gnolang/gno", notJoon, now.AddDate(0, 0, -21))
+	seedMergedPR(t, db, "gnolang/gno", zxxma, now.Add(-time.Hour))
+
+	h := HandleGetCompare(db, teams.Static(cfg), nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/teams/compare?slugs=samouraiworld,ONBLOC", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", rec.Code, rec.Body)
+	}
+	var got compareResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Weeks) < 4 || len(got.Teams) != 2 || got.Teams[0].Slug != "samouraiworld" {
+		t.Fatalf("weeks = %v, teams = %+v", got.Weeks, got.Teams)
+	}
+	last := len(got.Weeks) - 1
+	for _, s := range got.Teams {
+		if len(s.Points) != len(got.Weeks) {
+			t.Fatalf("%s has %d points for %d weeks", s.Slug, len(s.Points), len(got.Weeks))
+		}
+	}
+	if p := got.Teams[1].Points[0]; p.MergedPRs != 1 || p.ActiveContributors != 1 {
+		t.Errorf("onbloc first week = %+v", p)
+	}
+	if p := got.Teams[0].Points[last]; p.MergedPRs != 1 || p.Week != got.Weeks[last] {
+		t.Errorf("samouraiworld last week = %+v", p)
+	}
+
+	for query, status := range map[string]int{
+		"slugs=onbloc":         http.StatusBadRequest,
+		"slugs=onbloc,onbloc":  http.StatusBadRequest,
+		"slugs=onbloc,missing": http.StatusNotFound,
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/teams/compare?"+query, nil))
+		if rec.Code != status {
+			t.Errorf("%s: status = %d, want %d", query, rec.Code, status)
+		}
+	}
+}
+
+func TestBuckets(t *testing.T) {
+	for _, tt := range []struct{ bucket, day, want string }{
+		{bucketWeek, "2026-10-19", "2026-10-19"}, // Monday
+		{bucketWeek, "2026-10-25", "2026-10-19"}, // Sunday
+		{bucketWeek, "2026-11-01", "2026-10-26"},
+		{bucketMonth, "2026-10-25", "2026-10-01"},
+		{bucketDay, "2026-10-25", "2026-10-25"},
+	} {
+		if got := bucketOf(tt.bucket, tt.day); got != tt.want {
+			t.Errorf("bucketOf(%s, %s) = %s, want %s", tt.bucket, tt.day, got, tt.want)
+		}
+	}
+	if got := bucketsBetween(bucketWeek, "2026-10-05", "2026-10-19"); len(got) != 3 {
+		t.Errorf("weeks = %v", got)
+	}
+	if got := bucketsBetween(bucketMonth, "2025-11-01", "2026-02-01"); len(got) != 4 || got[3] != "2026-02-01" {
+		t.Errorf("months = %v", got)
+	}
+}
@@ -0,0 +1,140 @@
+package teams
+
+import (
+	"net/http"
+
+	"github.com/samouraiworld/topofgnomes/server/models"
+	"github.com/samouraiworld/topofgnomes/server/openapi"
+	"github.com/samouraiworld/topofgnomes/server/teams"
+)
+
+// OpenAPIRoutes describes the routes served by this package.
+func OpenAPIRoutes() []openapi.Route {
+	slug := openapi.PathParam("slug", "Team slug from teams.yaml")
+	period := openapi.QueryParam("time", "string", "Period: daily, weekly, monthly or yearly; all time when omitted")
+	return []openapi.Route{
+		{
+			Method: http.MethodGet, Path: "/teams", Tag: "teams",
+			Summary:  "Team roster",
+			Response: teamsResponse{},
+		},
+		{
+			Method: http.MethodGet, Path: "/teams/{slug}", Tag: "teams",
+			Summary:  "One team",
+			Params:   []openapi.Param{slug},
+			Response: teamResponse{},
+		},
+		{
+			Method: http.MethodGet, Path: "/teams/{slug}/active-repos", Tag: "teams",
+			Summary:  "Repositories a team merged into, split primary/secondary",
+			Params:   []openapi.Param{slug, period},
+			Response: activeReposResponse{},
+			Cached:   true,
+		},
+		{
+			Method: http.MethodGet, Path: "/teams/{slug}/team-stats", Tag: "teams",
+			Summary: "Merged pull requests per repository and member",
+			Params: []openapi.Param{
+				slug,
+				period,
+				openapi.QueryParam("repos", "string", "Repository to restrict to; repeatable"),
+			},
+			Response: teamStatsResponse{},
+			Cached:   true,
+		},
+		{
+			Method: http.MethodGet, Path: "/teams/{slug}/timeseries", Tag: "teams",
+			Summary:     "A team's activity per day, week or month",
+			Description: "One point per bucket from from to to, zero-filled. Topics counts merged PRs per topic, as classified by topics.yaml.",
+			Params: []openapi.Param{
+				slug,
+				openapi.QueryParam("bucket", "string", "day, week (default, starting Monday) or month"),
+				openapi.QueryParam("from", "string", "First day, YYYY-MM-DD; a year before to by default"),
+				openapi.QueryParam("to", "string", "Last day, YYYY-MM-DD; today by default"),
+			},
+			Response: timeseriesResponse{},
+			Cached:   true,
+		},
+		{
+			Method: http.MethodGet, Path: "/teams/leaderboard", Tag: "teams",
+			Summary:     "Teams ranked by score",
+			Description: "Contributions count for the team their author was in on the day they were made. Per-capita figures divide by everyone in the team at some point in the period.",
+			Params:      []openapi.Param{period},
+			Response:    leaderboardResponse{},
+			Cached:      true,
+		},
+		{
+			Method: http.MethodGet, Path: "/teams/compare", Tag: "teams",
+			Summary:     "Weekly activity of several teams, side by side",
+			Description: "Every series has one point per week of the shared weeks axis, zero-filled.",
+			Params: []openapi.Param{
+				openapi.QueryParam("slugs", "string", "Comma-separated team slugs, 2 to 8"),
+				period,
+			},
+			Response: compareResponse{},
+			Cached:   true,
+		},
+		{
+			Method: http.MethodGet, Path: "/team-collab", Tag: "teams",
+			Summary:  "Cross-team review matrix",
+			Params:   []openapi.Param{period},
+			Response: collabResponse{},
+			Cached:   true,
+		},
+		{
+			Method: http.MethodPost, Path: "/admin/teams", Tag: "teams-admin", Auth: true,
+			Summary:     "Create a team",
+			Description: "Administrators only, with TEAMS_SOURCE=db. Members join at joinedAt, now when omitted.",
+			Body:        createTeamRequest{},
+			Response:    teamResponse{},
+			Status:      http.StatusCreated,
+		},
+		{
+			Method: http.MethodPatch, Path: "/admin/teams/{slug}", Tag: "teams-admin", Auth: true,
+			Summary:  "Rename, recolor or redescribe a team",
+			Params:   []openapi.Param{slug},
+			Body:     teams.TeamUpdate{},
+			Response: teamResponse{},
+		},
+		{
+			Method: http.MethodPost, Path: "/admin/teams/{slug}/members", Tag: "teams-admin", Auth: true,
+			Summary:  "Add a member to a team",
+			Params:   []openapi.Param{slug},
+			Body:     addMemberRequest{},
+			Response: teamResponse{},
+			Status:   http.StatusCreated,
+		},
+		{
+			Method: http.MethodDelete, Path: "/admin/teams/{slug}/members/{login}", Tag: "teams-admin", Auth: true,
+			Summary:     "Remove a member from a team",
+			Description: "Ends the membership; contributions made before leftAt stay the team's.",
+			Params: []openapi.Param{
+				slug,
+				openapi.PathParam("login", "GitHub login"),
+				openapi.QueryParam("leftAt", "string", "RFC3339 date the member leaves; now when omitted"),
+			},
+			Response: teamResponse{},
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/teams/audit", Tag: "teams-admin", Auth: true,
+			Summary: "Roster changes, newest first",
+			Params: []openapi.Param{
+				openapi.QueryParam("team", "string", "Team slug to restrict to"),
+				openapi.QueryParam("limit", "integer", "Max events, default 100"),
+			},
+			Response: []models.TeamAuditEvent{},
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/teams/export", Tag: "teams-admin", Auth: true,
+			Summary:     "Current roster as teams.yaml",
+			Description: "Served as application/yaml, whichever the roster source.",
+		},
+		{
+			Method: http.MethodPut, Path: "/admin/teams/import", Tag: "teams-admin", Auth: true,
+			Summary:     "Replace the roster with a teams.yaml document",
+			Description: "YAML or JSON. Teams and members missing from the document leave now; history is kept.",
+			Body:        teams.Config{},
+			Response:    teamsResponse{},
+		},
+	}
+}
@@ -1,21 +1,21 @@
 package teams
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
-	"github.com/dgraph-io/ristretto"
 	"github.com/go-chi/chi/v5"
+	"github.com/samouraiworld/topofgnomes/server/apicache"
+	"github.com/samouraiworld/topofgnomes/server/contributions"
+	"github.com/samouraiworld/topofgnomes/server/handler"
+	"github.com/samouraiworld/topofgnomes/server/handler/apierror"
 	"github.com/samouraiworld/topofgnomes/server/models"
 	"github.com/samouraiworld/topofgnomes/server/teams"
 	"gorm.io/gorm"
 )
 
-const teamStatsCacheTTL = 5 * time.Minute
-
 // TeamStatRow is one (repo, author) cell of the GROUP BY result.
 type TeamStatRow struct {
 	RepoID    string `gorm:"column:repo_id"    json:"repoId"`
@@ -43,74 +43,66 @@ type teamStatsResponse struct {
 }
 
 // HandleGetTeamStats returns merged-PR counts grouped by (repository_id,
-// author_id) for one team in one period. 5-minute ristretto cache.
-func HandleGetTeamStats(db *gorm.DB, cfg *teams.Config, cache *ristretto.Cache) http.HandlerFunc {
+// author_id) for one team in one period. Cached until the next sync or
+// teams config reload.
+func HandleGetTeamStats(db *gorm.DB, roster teams.Provider, cache *apicache.Cache) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		cfg := roster()
 		w.Header().Set("Content-Type", "application/json")
 		slug := chi.URLParam(r, "slug")
 		team, ok := cfg.FindBySlug(slug)
 		if !ok {
-			http.Error(w, fmt.Sprintf("team %q not found", slug), http.StatusNotFound)
+			apierror.Write(w, r, apierror.NotFound("team %q not found", slug))
 			return
 		}
 		period := r.URL.Query().Get("time")
 		repos := r.URL.Query()["repos"]
 		key := fmt.Sprintf("teams:stats:%s:%s:%s", strings.ToLower(team.Slug), period, strings.Join(repos, ","))
-		if cache != nil {
-			if cached, ok := cache.Get(key); ok {
-				_ = json.NewEncoder(w).Encode(cached.(teamStatsResponse))
-				return
+		cache.Serve(w, r, key, []apicache.Domain{apicache.GitHub, apicache.TeamsConfig, apicache.Clock}, func() (any, error) {
+			stats, lastSyncedAt, err := queryTeamStats(db, team, handler.PeriodStart(period), repos)
+			if err != nil {
+				return nil, err
 			}
-		}
-		stats, lastSyncedAt, err := queryTeamStats(db, team.Members, periodStart(period), repos)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		resp := teamStatsResponse{
-			SchemaVersion: cfg.SchemaVersion,
-			LastSyncedAt:  lastSyncedAt,
-			Slug:          team.Slug,
-			Period:        period,
-			Repos:         repos,
-			Stats:         stats,
-			Totals:        rollUp(stats),
-		}
-		if cache != nil {
-			cache.SetWithTTL(key, resp, 0, teamStatsCacheTTL)
-		}
-		_ = json.NewEncoder(w).Encode(resp)
+			return teamStatsResponse{
+				SchemaVersion: cfg.SchemaVersion,
+				LastSyncedAt:  lastSyncedAt,
+				Slug:          team.Slug,
+				Period:        period,
+				Repos:         repos,
+				Stats:         stats,
+				Totals:        rollUp(stats),
+			}, nil
+		})
 	}
 }
 
-// queryTeamStats runs the single GROUP BY query the plan calls for:
+// queryTeamStats runs a single GROUP BY over the daily_contributions rollup,
+// counting each member's days in the team only (see memberDays):
 //
-//	SELECT repository_id, author_id, users.login, COUNT(*) AS merged_prs
-//	FROM pull_requests
-//	JOIN users ON users.id = pull_requests.author_id
-//	WHERE state = 'MERGED'
-//	  AND LOWER(users.login) IN (...members)
-//	  [AND merged_at >= startTime]
-//	  [AND repository_id IN (...repos)]
-//	GROUP BY repository_id, author_id, users.login
+//	SELECT repo_id, user_id AS author_id, users.login, SUM(prs_merged) AS merged_prs
+//	FROM daily_contributions
+//	JOIN users ON users.id = daily_contributions.user_id
+//	WHERE (LOWER(users.login) = ? AND day >= ? AND day < ?) OR ...
+//	  [AND day >= Day(startTime)]
+//	  [AND repo_id IN (...repos)]
+//	GROUP BY repo_id, user_id, users.login
+//	HAVING SUM(prs_merged) > 0
 //	ORDER BY merged_prs DESC
-func queryTeamStats(db *gorm.DB, members []string, startTime time.Time, repos []string) ([]TeamStatRow, *time.Time, error) {
-	lowered := make([]string, len(members))
-	for i, m := range members {
-		lowered[i] = strings.ToLower(m)
-	}
-	q := db.Model(&models.PullRequest{}).
-		Select("pull_requests.repository_id AS repo_id, pull_requests.author_id AS author_id, users.login AS login, COUNT(*) AS merged_prs").
-		Joins("JOIN users ON users.id = pull_requests.author_id").
-		Where("pull_requests.state = ?", "MERGED").
-		Where("LOWER(users.login) IN ?", lowered).
-		Group("pull_requests.repository_id, pull_requests.author_id, users.login").
+func queryTeamStats(db *gorm.DB, team teams.Team, startTime time.Time, repos []string) ([]TeamStatRow, *time.Time, error) {
+	clause, args := memberDays(team, "daily_contributions.day")
+	q := db.Model(&models.DailyContribution{}).
+		Select("daily_contributions.repo_id AS repo_id, daily_contributions.user_id AS author_id, users.login AS login, SUM(daily_contributions.prs_merged) AS merged_prs").
+		Joins("JOIN users ON users.id = daily_contributions.user_id").
+		Where(clause, args...).
+		Group("daily_contributions.repo_id, daily_contributions.user_id, users.login").
+		Having("SUM(daily_contributions.prs_merged) > 0").
 		Order("merged_prs DESC")
 	if !startTime.IsZero() {
-		q = q.Where("pull_requests.merged_at >= ?", startTime)
+		q = q.Where("daily_contributions.day >= ?", contributions.Day(startTime))
 	}
 	if len(repos) > 0 {
-		q = q.Where("pull_requests.repository_id IN ?", repos)
+		q = q.Where("daily_contributions.repo_id IN ?", repos)
 	}
 	var rows []TeamStatRow
 	if err := q.Scan(&rows).Error; err != nil {
@@ -9,12 +9,15 @@ import (
 
 	"github.com/dgraph-io/ristretto"
 	"github.com/go-chi/chi/v5"
+	"github.com/samouraiworld/topofgnomes/server/apicache"
+	"github.com/samouraiworld/topofgnomes/server/teams"
 )
 
 func TestHandleGetTeamStats_GroupByRepoAndAuthor(t *testing.T) {
 	db := newTestDB(t)
 	cfg := fixtureConfig()
-	cache, _ := ristretto.NewCache(&ristretto.Config{NumCounters: 1000, MaxCost: 1 << 20, BufferItems: 64})
+	store, _ := ristretto.NewCache(&ristretto.Config{NumCounters: 1000, MaxCost: 1 << 20, BufferItems: 64})
+	cache := apicache.New(store)
 
 	notJoon := seedUser(t, db, "notJoon")
 	r3v4s := seedUser(t, db, "r3v4s")
@@ -33,7 +36,7 @@ func TestHandleGetTeamStats_GroupByRepoAndAuthor(t *testing.T) {
 	}
 
 	r := chi.NewRouter()
-	r.Get("/teams/{slug}/team-stats", HandleGetTeamStats(db, cfg, cache))
+	r.Get("/teams/{slug}/team-stats", HandleGetTeamStats(db, teams.Static(cfg), cache))
 
 	rec := httptest.NewRecorder()
 	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/teams/onbloc/team-stats", nil))
@@ -75,7 +78,7 @@ func TestHandleGetTeamStats_RepoFilter(t *testing.T) {
 	seedMergedPR(t, db, "onbloc/gnoscan", notJoon, mergedAt)
 
 	r := chi.NewRouter()
-	r.Get("/teams/{slug}/team-stats", HandleGetTeamStats(db, cfg, nil))
+	r.Get("/teams/{slug}/team-stats", HandleGetTeamStats(db, teams.Static(cfg), nil))
 
 	rec := httptest.NewRecorder()
 	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/teams/onbloc/team-stats?repos=onbloc/gnoscan", nil))
@@ -91,16 +94,44 @@ func TestHandleGetTeamStats_RepoFilter(t *testing.T) {
 	}
 }
 
+func TestHandleGetTeamStats_MemberMovedTeams(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Now().UTC()
+	cfg := movedConfig(now.AddDate(0, 0, -5))
+
+	r3v4s := seedUser(t, db, "r3v4s")
+	seedMergedPR(t, db, "gnolang/gno", r3v4s, now.AddDate(0, 0, -10))
+	seedMergedPR(t, db, "gnolang/gno", r3v4s, now.AddDate(0, 0, -2))
+	seedMergedPR(t, db, "gnolang/gno", r3v4s, now.AddDate(0, 0, -1))
+
+	r := chi.NewRouter()
+	r.Get("/teams/{slug}/team-stats", HandleGetTeamStats(db, teams.Static(cfg), nil))
+
+	// Each team keeps the PRs merged while r3v4s was a member.
+	for slug, want := range map[string]int{"onbloc": 1, "samouraiworld": 2} {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/teams/"+slug+"/team-stats?time=monthly", nil))
+		var got teamStatsResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("%s: unmarshal: %v (status %d)", slug, err, rec.Code)
+		}
+		if got.Totals.MergedPRs != want {
+			t.Errorf("%s mergedPRs = %d, want %d", slug, got.Totals.MergedPRs, want)
+		}
+	}
+}
+
 func TestHandleGetTeamStats_CachesResponse(t *testing.T) {
 	db := newTestDB(t)
 	cfg := fixtureConfig()
-	cache, _ := ristretto.NewCache(&ristretto.Config{NumCounters: 1000, MaxCost: 1 << 20, BufferItems: 64})
+	store, _ := ristretto.NewCache(&ristretto.Config{NumCounters: 1000, MaxCost: 1 << 20, BufferItems: 64})
+	cache := apicache.New(store)
 
 	notJoon := seedUser(t, db, "notJoon")
 	seedMergedPR(t, db, "gnolang/gno", notJoon, time.Now().UTC().Add(-time.Hour))
 
 	r := chi.NewRouter()
-	r.Get("/teams/{slug}/team-stats", HandleGetTeamStats(db, cfg, cache))
+	r.Get("/teams/{slug}/team-stats", HandleGetTeamStats(db, teams.Static(cfg), cache))
 
 	rec1 := httptest.NewRecorder()
 	r.ServeHTTP(rec1, httptest.NewRequest(http.MethodGet, "/teams/onbloc/team-stats", nil))
@@ -122,7 +153,7 @@ func TestHandleGetTeamStats_UnknownSlug(t *testing.T) {
 	db := newTestDB(t)
 	cfg := fixtureConfig()
 	r := chi.NewRouter()
-	r.Get("/teams/{slug}/team-stats", HandleGetTeamStats(db, cfg, nil))
+	r.Get("/teams/{slug}/team-stats", HandleGetTeamStats(db, teams.Static(cfg), nil))
 	rec := httptest.NewRecorder()
 	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/teams/missing/team-stats", nil))
 	if rec.Code != http.StatusNotFound {
@@ -9,19 +9,16 @@ import (
 	"strings"
 	"time"
 
-	"github.com/dgraph-io/ristretto"
 	"github.com/go-chi/chi/v5"
+	"github.com/samouraiworld/topofgnomes/server/apicache"
+	"github.com/samouraiworld/topofgnomes/server/contributions"
+	"github.com/samouraiworld/topofgnomes/server/handler"
+	"github.com/samouraiworld/topofgnomes/server/handler/apierror"
 	"github.com/samouraiworld/topofgnomes/server/models"
 	"github.com/samouraiworld/topofgnomes/server/teams"
 	"gorm.io/gorm"
 )
 
-// activeReposCacheTTL is the ristretto TTL for /teams/:slug/active-repos.
-// Five minutes is the same window we use for /stats — keeps the team hub
-// fresh enough during EU business hours without re-running aggregations
-// on every page hop.
-const activeReposCacheTTL = 5 * time.Minute
-
 type teamsResponse struct {
 	SchemaVersion int          `json:"schemaVersion"`
 	LastSyncedAt  time.Time    `json:"lastSyncedAt"`
@@ -44,8 +41,9 @@ type activeReposResponse struct {
 }
 
 // HandleGetAll returns the full roster + schemaVersion + lastSyncedAt.
-func HandleGetAll(cfg *teams.Config) http.HandlerFunc {
+func HandleGetAll(roster teams.Provider) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		cfg := roster()
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(teamsResponse{
 			SchemaVersion: cfg.SchemaVersion,
@@ -56,13 +54,14 @@ func HandleGetAll(cfg *teams.Config) http.HandlerFunc {
 }
 
 // HandleGetBySlug returns a single team or 404.
-func HandleGetBySlug(cfg *teams.Config) http.HandlerFunc {
+func HandleGetBySlug(roster teams.Provider) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		cfg := roster()
 		w.Header().Set("Content-Type", "application/json")
 		slug := chi.URLParam(r, "slug")
 		team, ok := cfg.FindBySlug(slug)
 		if !ok {
-			http.Error(w, fmt.Sprintf("team %q not found", slug), http.StatusNotFound)
+			apierror.Write(w, r, apierror.NotFound("team %q not found", slug))
 			return
 		}
 		_ = json.NewEncoder(w).Encode(teamResponse{
@@ -74,90 +73,90 @@ func HandleGetBySlug(cfg *teams.Config) http.HandlerFunc {
 }
 
 // HandleGetActiveRepos returns Primary/Secondary repos for a team using the
-// dual-threshold rule. Cached 5 minutes per (slug, period).
-func HandleGetActiveRepos(db *gorm.DB, cfg *teams.Config, cache *ristretto.Cache) http.HandlerFunc {
+// dual-threshold rule. Cached per (slug, period) until the next sync or
+// teams config reload.
+func HandleGetActiveRepos(db *gorm.DB, roster teams.Provider, cache *apicache.Cache) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		cfg := roster()
 		w.Header().Set("Content-Type", "application/json")
 		slug := chi.URLParam(r, "slug")
 		team, ok := cfg.FindBySlug(slug)
 		if !ok {
-			http.Error(w, fmt.Sprintf("team %q not found", slug), http.StatusNotFound)
+			apierror.Write(w, r, apierror.NotFound("team %q not found", slug))
 			return
 		}
 		period := r.URL.Query().Get("time")
 		key := fmt.Sprintf("teams:active-repos:%s:%s", strings.ToLower(team.Slug), period)
-		if cache != nil {
-			if cached, ok := cache.Get(key); ok {
-				_ = json.NewEncoder(w).Encode(cached.(activeReposResponse))
-				return
+		cache.Serve(w, r, key, []apicache.Domain{apicache.GitHub, apicache.TeamsConfig, apicache.Clock}, func() (any, error) {
+			teamPRs, repoTotals, lastSyncedAt, err := AggregatePRs(db, team, handler.PeriodStart(period))
+			if err != nil {
+				return nil, err
 			}
-		}
-		startTime := periodStart(period)
-		teamPRs, repoTotals, lastSyncedAt, err := AggregatePRs(db, team.Members, startTime)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		result := teams.ComputeActiveRepos(teamPRs, repoTotals)
-		resp := activeReposResponse{
-			SchemaVersion: cfg.SchemaVersion,
-			LastSyncedAt:  lastSyncedAt,
-			Slug:          team.Slug,
-			Period:        period,
-			Primary:       result.Primary,
-			Secondary:     result.Secondary,
-		}
-		if cache != nil {
-			cache.SetWithTTL(key, resp, 0, activeReposCacheTTL)
-		}
-		_ = json.NewEncoder(w).Encode(resp)
+			result := teams.ComputeActiveRepos(teamPRs, repoTotals)
+			return activeReposResponse{
+				SchemaVersion: cfg.SchemaVersion,
+				LastSyncedAt:  lastSyncedAt,
+				Slug:          team.Slug,
+				Period:        period,
+				Primary:       result.Primary,
+				Secondary:     result.Secondary,
+			}, nil
+		})
 	}
 }
 
-func periodStart(period string) time.Time {
-	now := time.Now().UTC()
-	switch period {
-	case "daily":
-		return now.AddDate(0, 0, -1)
-	case "weekly":
-		return now.AddDate(0, 0, -7)
-	case "monthly":
-		return now.AddDate(0, -1, 0)
-	case "yearly":
-		return now.AddDate(-1, 0, 0)
-	default:
-		return time.Time{} // all-time
+// memberDays returns a condition matching the rows of users joined with a
+// table whose UTC day is dayCol, on the days their user was in team: one
+// term per stint, by lowercased login and teams.Member.ActiveOn's range.
+// dayCol is inlined into the query and must be a trusted identifier.
+func memberDays(team teams.Team, dayCol string) (string, []any) {
+	var terms []string
+	var args []any
+	for _, m := range team.Stints() {
+		term := "(LOWER(users.login) = ?"
+		args = append(args, strings.ToLower(m.Login))
+		if m.Joined != nil {
+			term += " AND " + dayCol + " >= ?"
+			args = append(args, contributions.Day(*m.Joined))
+		}
+		if m.Left != nil {
+			term += " AND " + dayCol + " < ?"
+			args = append(args, contributions.Day(*m.Left))
+		}
+		terms = append(terms, term+")")
+	}
+	if len(terms) == 0 {
+		return "1 = 0", nil
 	}
+	return "(" + strings.Join(terms, " OR ") + ")", args
 }
 
 // AggregatePRs returns:
-//   - teamPRs   : map[repoID]merged-PR-count by members (case-insensitive match on users.login)
+//   - teamPRs   : map[repoID]merged-PR-count by members while in the team (see memberDays)
 //   - repoTotals: map[repoID]merged-PR-count across all authors
 //   - lastSyncedAt: from the global sync_status row (nil if unset)
 //
 // Exposed for the team-stats handler (Commit 3) to reuse the team filter.
-func AggregatePRs(db *gorm.DB, members []string, startTime time.Time) (map[string]int, map[string]int, *time.Time, error) {
+func AggregatePRs(db *gorm.DB, team teams.Team, startTime time.Time) (map[string]int, map[string]int, *time.Time, error) {
 	if db == nil {
 		return nil, nil, nil, errors.New("db is nil")
 	}
-	lowered := make([]string, len(members))
-	for i, m := range members {
-		lowered[i] = strings.ToLower(m)
-	}
+	clause, args := memberDays(team, "daily_contributions.day")
 
 	type row struct {
 		RepositoryID string
 		Cnt          int
 	}
 
-	teamQuery := db.Model(&models.PullRequest{}).
-		Select("repository_id, COUNT(*) AS cnt").
-		Joins("JOIN users ON users.id = pull_requests.author_id").
-		Where("pull_requests.state = ?", "MERGED").
-		Where("LOWER(users.login) IN ?", lowered).
-		Group("repository_id")
+	teamQuery := db.Model(&models.DailyContribution{}).
+		Select("repo_id AS repository_id, SUM(prs_merged) AS cnt").
+		Joins("JOIN users ON users.id = daily_contributions.user_id").
+		Where(clause, args...).
+		Group("repo_id").
+		Having("SUM(prs_merged) > 0")
 	if !startTime.IsZero() {
-		teamQuery = teamQuery.Where("pull_requests.merged_at >= ?", startTime)
+		teamQuery = teamQuery.Where("daily_contributions.day >= ?", contributions.Day(startTime))
 	}
 	var teamRows []row
 	if err := teamQuery.Scan(&teamRows).Error; err != nil {
@@ -168,12 +167,12 @@ func AggregatePRs(db *gorm.DB, members []string, startTime time.Time) (map[strin
 		teamPRs[r.RepositoryID] = r.Cnt
 	}
 
-	totalsQuery := db.Model(&models.PullRequest{}).
-		Select("repository_id, COUNT(*) AS cnt").
-		Where("state = ?", "MERGED").
-		Group("repository_id")
+	totalsQuery := db.Model(&models.DailyContribution{}).
+		Select("repo_id AS repository_id, SUM(prs_merged) AS cnt").
+		Group("repo_id").
+		Having("SUM(prs_merged) > 0")
 	if !startTime.IsZero() {
-		totalsQuery = totalsQuery.Where("merged_at >= ?", startTime)
+		totalsQuery = totalsQuery.Where("day >= ?", contributions.Day(startTime))
 	}
 	var totalRows []row
 	if err := totalsQuery.Scan(&totalRows).Error; err != nil {
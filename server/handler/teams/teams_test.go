@@ -10,22 +10,18 @@ import (
 
 	"github.com/dgraph-io/ristretto"
 	"github.com/go-chi/chi/v5"
+	"github.com/samouraiworld/topofgnomes/server/apicache"
+	"github.com/samouraiworld/topofgnomes/server/contributions"
+	"github.com/samouraiworld/topofgnomes/server/db/dbtest"
 	"github.com/samouraiworld/topofgnomes/server/models"
 	"github.com/samouraiworld/topofgnomes/server/teams"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
 func newTestDB(t *testing.T) *gorm.DB {
 	t.Helper()
-	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
-	if err != nil {
-		t.Fatalf("open sqlite: %v", err)
-	}
-	if err := db.AutoMigrate(&models.User{}, &models.PullRequest{}, &models.SyncStatus{}); err != nil {
-		t.Fatalf("migrate: %v", err)
-	}
+	db := dbtest.Open(t, &models.User{}, &models.PullRequest{}, &models.Commit{}, &models.Issue{},
+		&models.Review{}, &models.DailyContribution{}, &models.SyncStatus{})
 	return db
 }
 
@@ -55,6 +51,9 @@ func seedMergedPR(t *testing.T, db *gorm.DB, repoID, authorID string, mergedAt t
 	if err := db.Create(&pr).Error; err != nil {
 		t.Fatalf("seed pr: %v", err)
 	}
+	if err := contributions.Refresh(db, repoID, []string{contributions.Day(pr.CreatedAt), contributions.Day(mergedAt)}); err != nil {
+		t.Fatalf("refresh rollup: %v", err)
+	}
 }
 
 func fixtureConfig() *teams.Config {
@@ -68,11 +67,20 @@ func fixtureConfig() *teams.Config {
 	}
 }
 
+// movedConfig is fixtureConfig with r3v4s moving from onbloc to
+// samouraiworld at moved.
+func movedConfig(moved time.Time) *teams.Config {
+	cfg := fixtureConfig()
+	cfg.Teams[0].History = []teams.Member{{Login: "notJoon"}, {Login: "r3v4s", Left: &moved}}
+	cfg.Teams[1].History = []teams.Member{{Login: "n0izn0iz"}, {Login: "zxxma"}, {Login: "r3v4s", Joined: &moved}}
+	return cfg
+}
+
 func TestHandleGetAll(t *testing.T) {
 	cfg := fixtureConfig()
 	rec := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodGet, "/teams", nil)
-	HandleGetAll(cfg).ServeHTTP(rec, req)
+	HandleGetAll(teams.Static(cfg)).ServeHTTP(rec, req)
 
 	if rec.Code != http.StatusOK {
 		t.Fatalf("status = %d, want 200", rec.Code)
@@ -95,7 +103,7 @@ func TestHandleGetAll(t *testing.T) {
 func TestHandleGetBySlug_FoundAndNotFound(t *testing.T) {
 	cfg := fixtureConfig()
 	r := chi.NewRouter()
-	r.Get("/teams/{slug}", HandleGetBySlug(cfg))
+	r.Get("/teams/{slug}", HandleGetBySlug(teams.Static(cfg)))
 
 	t.Run("hit", func(t *testing.T) {
 		rec := httptest.NewRecorder()
@@ -125,7 +133,8 @@ func TestHandleGetBySlug_FoundAndNotFound(t *testing.T) {
 func TestHandleGetActiveRepos_DualThresholdEnd2End(t *testing.T) {
 	db := newTestDB(t)
 	cfg := fixtureConfig()
-	cache, _ := ristretto.NewCache(&ristretto.Config{NumCounters: 1000, MaxCost: 1 << 20, BufferItems: 64})
+	store, _ := ristretto.NewCache(&ristretto.Config{NumCounters: 1000, MaxCost: 1 << 20, BufferItems: 64})
+	cache := apicache.New(store)
 
 	// Onbloc members are notJoon + r3v4s. Seed:
 	//   - gnolang/gno    : team = 10, total = 100  → primary
@@ -154,7 +163,7 @@ func TestHandleGetActiveRepos_DualThresholdEnd2End(t *testing.T) {
 	}
 
 	r := chi.NewRouter()
-	r.Get("/teams/{slug}/active-repos", HandleGetActiveRepos(db, cfg, cache))
+	r.Get("/teams/{slug}/active-repos", HandleGetActiveRepos(db, teams.Static(cfg), cache))
 
 	rec := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodGet, "/teams/onbloc/active-repos", nil)
@@ -195,13 +204,14 @@ func TestHandleGetActiveRepos_DualThresholdEnd2End(t *testing.T) {
 func TestHandleGetActiveRepos_CachesResponse(t *testing.T) {
 	db := newTestDB(t)
 	cfg := fixtureConfig()
-	cache, _ := ristretto.NewCache(&ristretto.Config{NumCounters: 1000, MaxCost: 1 << 20, BufferItems: 64})
+	store, _ := ristretto.NewCache(&ristretto.Config{NumCounters: 1000, MaxCost: 1 << 20, BufferItems: 64})
+	cache := apicache.New(store)
 
 	notJoon := seedUser(t, db, "notJoon")
 	seedMergedPR(t, db, "gnolang/gno", notJoon, time.Now().UTC().Add(-time.Hour))
 
 	r := chi.NewRouter()
-	r.Get("/teams/{slug}/active-repos", HandleGetActiveRepos(db, cfg, cache))
+	r.Get("/teams/{slug}/active-repos", HandleGetActiveRepos(db, teams.Static(cfg), cache))
 
 	rec1 := httptest.NewRecorder()
 	r.ServeHTTP(rec1, httptest.NewRequest(http.MethodGet, "/teams/onbloc/active-repos", nil))
@@ -228,7 +238,7 @@ func TestHandleGetActiveRepos_UnknownSlug(t *testing.T) {
 	db := newTestDB(t)
 	cfg := fixtureConfig()
 	r := chi.NewRouter()
-	r.Get("/teams/{slug}/active-repos", HandleGetActiveRepos(db, cfg, nil))
+	r.Get("/teams/{slug}/active-repos", HandleGetActiveRepos(db, teams.Static(cfg), nil))
 	rec := httptest.NewRecorder()
 	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/teams/missing/active-repos", nil))
 	if rec.Code != http.StatusNotFound {
@@ -0,0 +1,217 @@
+package teams
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/samouraiworld/topofgnomes/server/apicache"
+	dbpkg "github.com/samouraiworld/topofgnomes/server/db"
+	"github.com/samouraiworld/topofgnomes/server/handler"
+	"github.com/samouraiworld/topofgnomes/server/handler/apierror"
+	"github.com/samouraiworld/topofgnomes/server/teams"
+	"gorm.io/gorm"
+)
+
+// Buckets a series can be split into. Weeks start on Monday; all are UTC.
+const (
+	bucketDay   = "day"
+	bucketWeek  = "week"
+	bucketMonth = "month"
+)
+
+// maxBuckets bounds a time series: a little over a year of days.
+const maxBuckets = 420
+
+// TimeseriesPoint is a team's activity over one bucket.
+type TimeseriesPoint struct {
+	// Start is the first day of the bucket, YYYY-MM-DD.
+	Start              string `json:"start"`
+	MergedPRs          int    `json:"mergedPRs"`
+	Reviews            int    `json:"reviews"`
+	Issues             int    `json:"issues"`
+	ActiveContributors int    `json:"activeContributors"`
+	// Topics counts the bucket's merged PRs per topic slug.
+	Topics map[string]int `json:"topics"`
+}
+
+type timeseriesResponse struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	LastSyncedAt  *time.Time        `json:"lastSyncedAt"`
+	Slug          string            `json:"slug"`
+	Bucket        string            `json:"bucket"`
+	From          string            `json:"from"`
+	To            string            `json:"to"`
+	Points        []TimeseriesPoint `json:"points"`
+}
+
+// HandleGetTimeseries returns a team's activity per day, week or month
+// between ?from= and ?to= (YYYY-MM-DD, both included; the year up to today
+// by default). Cached until the next sync or teams or topics config reload.
+func HandleGetTimeseries(db *gorm.DB, roster teams.Provider, cache *apicache.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		cfg := roster()
+		w.Header().Set("Content-Type", "application/json")
+		slug := chi.URLParam(r, "slug")
+		team, ok := cfg.FindBySlug(slug)
+		if !ok {
+			apierror.Write(w, r, apierror.NotFound("team %q not found", slug))
+			return
+		}
+		q := r.URL.Query()
+		bucket := q.Get("bucket")
+		switch bucket {
+		case "":
+			bucket = bucketWeek
+		case bucketDay, bucketWeek, bucketMonth:
+		default:
+			apierror.Write(w, r, apierror.InvalidInput("invalid bucket %q, use day, week or month", bucket))
+			return
+		}
+		to := time.Now().UTC().Truncate(24 * time.Hour)
+		if s := q.Get("to"); s != "" {
+			t, err := time.Parse(time.DateOnly, s)
+			if err != nil {
+				apierror.Write(w, r, apierror.InvalidInput("invalid to %q, use YYYY-MM-DD", s))
+				return
+			}
+			to = t
+		}
+		from := to.AddDate(-1, 0, 1)
+		if s := q.Get("from"); s != "" {
+			t, err := time.Parse(time.DateOnly, s)
+			if err != nil {
+				apierror.Write(w, r, apierror.InvalidInput("invalid from %q, use YYYY-MM-DD", s))
+				return
+			}
+			from = t
+		}
+		if from.After(to) {
+			apierror.Write(w, r, apierror.InvalidInput("from is after to"))
+			return
+		}
+		starts := bucketsBetween(bucket, bucketOf(bucket, from.Format(time.DateOnly)), bucketOf(bucket, to.Format(time.DateOnly)))
+		if len(starts) > maxBuckets {
+			apierror.Write(w, r, apierror.InvalidInput("%d buckets requested, at most %d", len(starts), maxBuckets))
+			return
+		}
+
+		key := fmt.Sprintf("teams:timeseries:%s:%s:%s:%s", strings.ToLower(team.Slug), bucket, from.Format(time.DateOnly), to.Format(time.DateOnly))
+		domains := []apicache.Domain{apicache.GitHub, apicache.TeamsConfig, apicache.TopicsConfig}
+		cache.Serve(w, r, key, domains, func() (any, error) {
+			points, err := computeTimeseries(db, team, bucket, starts, from, to.AddDate(0, 0, 1))
+			if err != nil {
+				return nil, err
+			}
+			return timeseriesResponse{
+				SchemaVersion: cfg.SchemaVersion,
+				LastSyncedAt:  handler.LastSyncedAt(db),
+				Slug:          team.Slug,
+				Bucket:        bucket,
+				From:          from.Format(time.DateOnly),
+				To:            to.Format(time.DateOnly),
+				Points:        points,
+			}, nil
+		})
+	}
+}
+
+// computeTimeseries fills one point per bucket start from the rollup over
+// [from, to), and counts the merged PRs of the same days per stored topic
+// for the topic mix.
+func computeTimeseries(db *gorm.DB, team teams.Team, bucket string, starts []string, from, to time.Time) ([]TimeseriesPoint, error) {
+	activity, err := queryTeamActivity(db, team, from, to)
+	if err != nil {
+		return nil, err
+	}
+	totals := sumActivity(bucket, activity)
+
+	day := dbpkg.DayExpr(db, "pull_requests.merged_at")
+	clause, args := memberDays(team, day)
+	var rows []struct {
+		Topic string `gorm:"column:topic"`
+		Day   string `gorm:"column:day"`
+		PRs   int    `gorm:"column:prs"`
+	}
+	err = db.Table("pull_requests").
+		Select("pull_requests.topic AS topic, "+day+" AS day, COUNT(*) AS prs").
+		Joins("JOIN users ON users.id = pull_requests.author_id").
+		Where("pull_requests.state = ?", "MERGED").
+		Where("pull_requests.merged_at >= ? AND pull_requests.merged_at < ?", from, to).
+		Where(clause, args...).
+		Group("pull_requests.topic, " + day).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("team-timeseries topics query: %w", err)
+	}
+	mix := map[string]map[string]int{}
+	for _, row := range rows {
+		start := bucketOf(bucket, row.Day)
+		if mix[start] == nil {
+			mix[start] = map[string]int{}
+		}
+		mix[start][row.Topic] += row.PRs
+	}
+
+	points := make([]TimeseriesPoint, len(starts))
+	for i, start := range starts {
+		p := TimeseriesPoint{Start: start, Topics: mix[start]}
+		if p.Topics == nil {
+			p.Topics = map[string]int{}
+		}
+		if b, ok := totals[start]; ok {
+			p.MergedPRs = b.mergedPRs
+			p.Reviews = b.reviews
+			p.Issues = b.issues
+			p.ActiveContributors = len(b.authors)
+		}
+		points[i] = p
+	}
+	return points, nil
+}
+
+// bucketOf returns the first day of the bucket day falls in (both
+// YYYY-MM-DD).
+func bucketOf(bucket, day string) string {
+	t, err := time.Parse(time.DateOnly, day)
+	if err != nil {
+		return day
+	}
+	switch bucket {
+	case bucketWeek:
+		offset := (int(t.Weekday()) + 6) % 7 // days since Monday
+		t = t.AddDate(0, 0, -offset)
+	case bucketMonth:
+		t = t.AddDate(0, 0, 1-t.Day())
+	}
+	return t.Format(time.DateOnly)
+}
+
+// bucketsBetween lists the bucket starts from first to last, both
+// included. An empty first means no data: only last is listed.
+func bucketsBetween(bucket, first, last string) []string {
+	if first == "" || first > last {
+		return []string{last}
+	}
+	start, _ := time.Parse(time.DateOnly, first)
+	end, _ := time.Parse(time.DateOnly, last)
+	var out []string
+	for t := start; !t.After(end); t = nextBucket(bucket, t) {
+		out = append(out, t.Format(time.DateOnly))
+	}
+	return out
+}
+
+func nextBucket(bucket string, t time.Time) time.Time {
+	switch bucket {
+	case bucketWeek:
+		return t.AddDate(0, 0, 7)
+	case bucketMonth:
+		return t.AddDate(0, 1, 0)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
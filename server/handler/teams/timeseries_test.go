@@ -0,0 +1,82 @@
+package teams
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/samouraiworld/topofgnomes/server/contributions"
+	"github.com/samouraiworld/topofgnomes/server/models"
+	"github.com/samouraiworld/topofgnomes/server/teams"
+)
+
+func TestHandleGetTimeseries(t *testing.T) {
+	db := newTestDB(t)
+	notJoon := seedUser(t, db, "notJoon")
+	for i, at := range []time.Time{
+		time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC),  // Monday
+		time.Date(2026, 3, 8, 10, 0, 0, 0, time.UTC),  // Sunday, same week
+		time.Date(2026, 3, 17, 10, 0, 0, 0, time.UTC), // two weeks later
+	} {
+		topic := "other"
+		if i == 0 {
+			topic = "wallet"
+		}
+		pr := models.PullRequest{ID: fmt.Sprintf("ts-%d", i), RepositoryID: "gnolang/gno", State: "MERGED",
+			AuthorID: notJoon, Topic: topic, MergedAt: &at, CreatedAt: at.Add(-time.Hour)}
+		if err := db.Create(&pr).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := contributions.Backfill(db); err != nil {
+		t.Fatal(err)
+	}
+
+	r := chi.NewRouter()
+	r.Get("/teams/{slug}/timeseries", HandleGetTimeseries(db, teams.Static(fixtureConfig()), nil))
+	get := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/teams/onbloc/timeseries?"+query, nil))
+		return rec
+	}
+
+	rec := get("from=2026-03-04&to=2026-03-20")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", rec.Code, rec.Body)
+	}
+	var got timeseriesResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Bucket != "week" || len(got.Points) != 3 || got.Points[0].Start != "2026-03-02" {
+		t.Fatalf("points = %+v", got.Points)
+	}
+	// The Monday PR is before from; the Sunday one counts.
+	if p := got.Points[0]; p.MergedPRs != 1 || p.ActiveContributors != 1 || p.Topics["other"] != 1 || p.Topics["wallet"] != 0 {
+		t.Errorf("first week = %+v", p)
+	}
+	if p := got.Points[1]; p.MergedPRs != 0 || len(p.Topics) != 0 {
+		t.Errorf("empty week = %+v", p)
+	}
+	if p := got.Points[2]; p.MergedPRs != 1 {
+		t.Errorf("last week = %+v", p)
+	}
+
+	rec = get("bucket=month&from=2026-03-01&to=2026-03-31")
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Points) != 1 || got.Points[0].MergedPRs != 3 || got.Points[0].Topics["wallet"] != 1 {
+		t.Errorf("month = %+v", got.Points)
+	}
+
+	for _, query := range []string{"bucket=hour", "from=2026-04-01&to=2026-03-01", "from=march", "bucket=day&from=2020-01-01&to=2026-01-01"} {
+		if rec := get(query); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, rec.Code)
+		}
+	}
+}
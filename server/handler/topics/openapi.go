@@ -0,0 +1,49 @@
+package topics
+
+import (
+	"net/http"
+
+	"github.com/samouraiworld/topofgnomes/server/handler/listquery"
+	"github.com/samouraiworld/topofgnomes/server/models"
+	"github.com/samouraiworld/topofgnomes/server/openapi"
+)
+
+// OpenAPIRoutes describes the routes served by this package.
+func OpenAPIRoutes() []openapi.Route {
+	return []openapi.Route{
+		{
+			Method: http.MethodGet, Path: "/topics", Tag: "topics",
+			Summary:  "Focus-area taxonomy",
+			Response: topicsResponse{},
+		},
+		{
+			Method: http.MethodGet, Path: "/topics/stats", Tag: "topics",
+			Summary:     "Merged pull requests per topic, overall and per team",
+			Description: "Each PR counts for the team its author was in on the day it was merged.",
+			Params:      []openapi.Param{openapi.QueryParam("time", "string", "Period: daily, weekly, monthly or yearly; all time when omitted")},
+			Response:    statsResponse{},
+			Cached:      true,
+		},
+		{
+			Method: http.MethodGet, Path: "/topics/unclassified/tokens", Tag: "topics",
+			Summary:     "Most frequent words in the titles of unclassified pull requests",
+			Description: "Counts the pull requests classified as other whose title has each word, to help write new topics.yaml patterns.",
+			Params: []openapi.Param{
+				openapi.QueryParam("time", "string", "Period: daily, weekly, monthly or yearly; all time when omitted"),
+				openapi.QueryParam("limit", "integer", "Max tokens, default 50"),
+			},
+			Response: tokensResponse{},
+			Cached:   true,
+		},
+		{
+			Method: http.MethodGet, Path: "/topics/{slug}/prs", Tag: "topics",
+			Summary: "Pull requests classified under a topic",
+			Params: append([]openapi.Param{
+				openapi.PathParam("slug", "Topic slug from topics.yaml, or other"),
+				openapi.QueryParam("state", "string", "OPEN, MERGED or CLOSED"),
+				openapi.QueryParam("match", "string", "primary (default): PRs where the topic ranks first; any: every PR it matches"),
+			}, listquery.OpenAPIParams(topicPRsList)...),
+			Response: []models.PullRequest{},
+		},
+	}
+}
@@ -0,0 +1,201 @@
+package topics
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/samouraiworld/topofgnomes/server/apicache"
+	dbpkg "github.com/samouraiworld/topofgnomes/server/db"
+	"github.com/samouraiworld/topofgnomes/server/handler"
+	"github.com/samouraiworld/topofgnomes/server/handler/apierror"
+	"github.com/samouraiworld/topofgnomes/server/handler/listquery"
+	"github.com/samouraiworld/topofgnomes/server/models"
+	"github.com/samouraiworld/topofgnomes/server/teams"
+	"github.com/samouraiworld/topofgnomes/server/topics"
+	"gorm.io/gorm"
+)
+
+// TopicCount is one topic's merged pull requests.
+type TopicCount struct {
+	Slug      string `json:"slug"`
+	Label     string `json:"label"`
+	MergedPRs int    `json:"mergedPRs"`
+}
+
+// TeamTopics is one team's merged pull requests per topic slug.
+type TeamTopics struct {
+	Slug      string         `json:"slug"`
+	MergedPRs int            `json:"mergedPRs"`
+	Topics    map[string]int `json:"topics"`
+}
+
+type statsResponse struct {
+	SchemaVersion int        `json:"schemaVersion"`
+	LastSyncedAt  *time.Time `json:"lastSyncedAt"`
+	Period        string     `json:"period"`
+	// Topics follows topics.yaml, then "other"; every author counts.
+	Topics []TopicCount `json:"topics"`
+	// Teams counts each PR for the team its author was in on the merge day.
+	Teams []TeamTopics `json:"teams"`
+}
+
+// HandleGetStats counts merged pull requests per topic over ?time=, overall
+// and per team. Cached until the next sync, teams config reload or topics
+// reclassification.
+func HandleGetStats(db *gorm.DB, taxonomy topics.Provider, roster teams.Provider, cache *apicache.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		w.Header().Set("Content-Type", "application/json")
+		period := r.URL.Query().Get("time")
+		domains := []apicache.Domain{apicache.GitHub, apicache.TeamsConfig, apicache.TopicsConfig, apicache.Clock}
+		cache.Serve(w, r, "topics:stats:"+period, domains, func() (any, error) {
+			return computeStats(db, taxonomy(), roster(), period)
+		})
+	}
+}
+
+func computeStats(db *gorm.DB, taxonomy *topics.Config, roster *teams.Config, period string) (statsResponse, error) {
+	day := dbpkg.DayExpr(db, "pull_requests.merged_at")
+	q := db.Table("pull_requests").
+		Select("pull_requests.topic AS topic, users.login AS login, "+day+" AS day, COUNT(*) AS prs").
+		Joins("LEFT JOIN users ON users.id = pull_requests.author_id").
+		Where("pull_requests.state = ?", "MERGED").
+		Group("pull_requests.topic, users.login, " + day)
+	if start := handler.PeriodStart(period); !start.IsZero() {
+		q = q.Where("pull_requests.merged_at >= ?", start)
+	}
+	var rows []struct {
+		Topic string `gorm:"column:topic"`
+		Login string `gorm:"column:login"`
+		Day   string `gorm:"column:day"`
+		PRs   int    `gorm:"column:prs"`
+	}
+	if err := q.Scan(&rows).Error; err != nil {
+		return statsResponse{}, fmt.Errorf("topic-stats query: %w", err)
+	}
+
+	resp := statsResponse{
+		SchemaVersion: taxonomy.SchemaVersion,
+		LastSyncedAt:  handler.LastSyncedAt(db),
+		Period:        period,
+		Topics:        make([]TopicCount, 0, len(taxonomy.Topics)+1),
+		Teams:         make([]TeamTopics, len(roster.Teams)),
+	}
+	index := map[string]int{}
+	for _, t := range taxonomy.Topics {
+		index[t.Slug] = len(resp.Topics)
+		resp.Topics = append(resp.Topics, TopicCount{Slug: t.Slug, Label: t.Label})
+	}
+	index[topics.OtherSlug] = len(resp.Topics)
+	resp.Topics = append(resp.Topics, TopicCount{Slug: topics.OtherSlug, Label: "Other"})
+	teamIndex := map[string]int{}
+	for i, t := range roster.Teams {
+		teamIndex[t.Slug] = i
+		resp.Teams[i] = TeamTopics{Slug: t.Slug, Topics: map[string]int{}}
+	}
+
+	for _, row := range rows {
+		// Rows classified under a topic removed since are counted as
+		// other until their reclassification lands.
+		i, ok := index[row.Topic]
+		if !ok {
+			i = index[topics.OtherSlug]
+		}
+		resp.Topics[i].MergedPRs += row.PRs
+		if slug, ok := roster.TeamOn(row.Login, row.Day); ok {
+			team := &resp.Teams[teamIndex[slug]]
+			team.MergedPRs += row.PRs
+			team.Topics[resp.Topics[i].Slug] += row.PRs
+		}
+	}
+	return resp, nil
+}
+
+var topicPRsList = listquery.Spec{
+	Model:       &models.PullRequest{},
+	Sorts:       map[string]string{"createdAt": "created_at", "updatedAt": "updated_at"},
+	DefaultSort: "createdAt",
+	DefaultDesc: true,
+	Keys:        []string{"id"},
+}
+
+// HandleGetTopicPRs lists the pull requests classified under a topic, one
+// page at a time (see listquery), optionally in one ?state=. With
+// ?match=any it lists every PR the topic matches, not only those where it
+// ranks first. Each PR comes with its ranked topics.
+func HandleGetTopicPRs(db *gorm.DB, taxonomy topics.Provider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		w.Header().Set("Content-Type", "application/json")
+		slug := chi.URLParam(r, "slug")
+		if topic, ok := taxonomy().FindBySlug(slug); ok {
+			slug = topic.Slug
+		} else if slug != topics.OtherSlug {
+			apierror.Write(w, r, apierror.NotFound("topic %q not found", slug))
+			return
+		}
+		params, err := listquery.Parse(r.URL.Query(), topicPRsList)
+		if err != nil {
+			apierror.Write(w, r, apierror.InvalidInput("%v", err))
+			return
+		}
+		tx := db.Model(&models.PullRequest{})
+		switch match := r.URL.Query().Get("match"); match {
+		case "", "primary":
+			tx = tx.Where("topic = ?", slug)
+		case "any":
+			// Only matched topics have rows; other never does.
+			if slug == topics.OtherSlug {
+				tx = tx.Where("topic = ?", slug)
+			} else {
+				tx = tx.Where("id IN (?)", db.Model(&models.PullRequestTopic{}).Select("pull_request_id").Where("topic = ?", slug))
+			}
+		default:
+			apierror.Write(w, r, apierror.InvalidInput("invalid match %q, use primary or any", match))
+			return
+		}
+		if state := r.URL.Query().Get("state"); state != "" {
+			tx = tx.Where("state = ?", state)
+		}
+		var preloads []string
+		if params.Wants("author") {
+			preloads = append(preloads, "Author")
+		}
+		var prs []models.PullRequest
+		page, err := listquery.Find(tx, params, topicPRsList, &prs, preloads...)
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+		if err := loadRankedTopics(db, prs); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+		listquery.Write(w, r, params, page, prs)
+	}
+}
+
+// loadRankedTopics fills the Topics of prs, best first.
+func loadRankedTopics(db *gorm.DB, prs []models.PullRequest) error {
+	if len(prs) == 0 {
+		return nil
+	}
+	ids := make([]string, len(prs))
+	for i, pr := range prs {
+		ids[i] = pr.ID
+	}
+	var rows []models.PullRequestTopic
+	if err := db.Where("pull_request_id IN ?", ids).Order("pull_request_id, rank").Find(&rows).Error; err != nil {
+		return fmt.Errorf("topic-prs ranked topics query: %w", err)
+	}
+	byPR := map[string][]models.PullRequestTopic{}
+	for _, row := range rows {
+		byPR[row.PullRequestID] = append(byPR[row.PullRequestID], row)
+	}
+	for i := range prs {
+		prs[i].Topics = byPR[prs[i].ID]
+	}
+	return nil
+}
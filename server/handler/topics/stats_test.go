@@ -0,0 +1,133 @@
+package topics
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/samouraiworld/topofgnomes/server/db/dbtest"
+	"github.com/samouraiworld/topofgnomes/server/models"
+	"github.com/samouraiworld/topofgnomes/server/teams"
+	"github.com/samouraiworld/topofgnomes/server/topics"
+	"gorm.io/gorm"
+)
+
+func seedPRs(t *testing.T) *gorm.DB {
+	t.Helper()
+	db := dbtest.Open(t, &models.User{}, &models.PullRequest{}, &models.PullRequestTopic{}, &models.SyncStatus{})
+	for _, login := range []string{"alice", "bob"} {
+		if err := db.Create(&models.User{ID: "u-" + login, Login: login}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	merged := time.Now().UTC().Add(-time.Hour)
+	for i, pr := range []struct{ author, topic, state string }{
+		{"alice", "wallet", "MERGED"},
+		{"alice", "wallet", "MERGED"},
+		{"bob", "indexer", "MERGED"},
+		{"carol", "other", "MERGED"}, // not synced as a user, in no team
+		{"bob", "wallet", "OPEN"},
+	} {
+		row := models.PullRequest{ID: fmt.Sprintf("pr-%d", i), AuthorID: "u-" + pr.author, Topic: pr.topic, State: pr.state,
+			RepositoryID: "gnolang/gno", CreatedAt: merged.Add(time.Duration(i) * time.Minute)}
+		if pr.state == "MERGED" {
+			row.MergedAt = &merged
+		}
+		if err := db.Create(&row).Error; err != nil {
+			t.Fatal(err)
+		}
+		if pr.topic != topics.OtherSlug {
+			if err := topics.SaveMatches(db, row.ID, []topics.Match{{Slug: pr.topic, Score: 1, Confidence: 1}}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	// bob's merged indexer PR touches the wallet too.
+	err := topics.SaveMatches(db, "pr-2", []topics.Match{{Slug: "indexer", Score: 2, Confidence: 2.0 / 3}, {Slug: "wallet", Score: 1, Confidence: 1.0 / 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestComputeStats(t *testing.T) {
+	db := seedPRs(t)
+	roster := &teams.Config{Teams: []teams.Team{
+		{Slug: "a", Members: []string{"alice"}},
+		{Slug: "b", Members: []string{"bob"}},
+	}}
+
+	resp, err := computeStats(db, fixtureConfig(t), roster, "")
+	if err != nil {
+		t.Fatalf("computeStats: %v", err)
+	}
+	got := map[string]int{}
+	for _, c := range resp.Topics {
+		got[c.Slug] = c.MergedPRs
+	}
+	if len(resp.Topics) != 3 || resp.Topics[2].Slug != topics.OtherSlug || got["wallet"] != 2 || got["indexer"] != 1 || got["other"] != 1 {
+		t.Errorf("topics = %+v", resp.Topics)
+	}
+	if a := resp.Teams[0]; a.MergedPRs != 2 || a.Topics["wallet"] != 2 {
+		t.Errorf("team a = %+v", a)
+	}
+	if b := resp.Teams[1]; b.MergedPRs != 1 || b.Topics["indexer"] != 1 || b.Topics["wallet"] != 0 {
+		t.Errorf("team b = %+v", b)
+	}
+}
+
+func TestHandleGetTopicPRs(t *testing.T) {
+	db := seedPRs(t)
+	r := chi.NewRouter()
+	r.Get("/topics/{slug}/prs", HandleGetTopicPRs(db, topics.Static(fixtureConfig(t))))
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/topics/WALLET/prs")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", rec.Code, rec.Body)
+	}
+	var prs []models.PullRequest
+	if err := json.Unmarshal(rec.Body.Bytes(), &prs); err != nil {
+		t.Fatal(err)
+	}
+	if len(prs) != 3 || prs[0].ID != "pr-4" || prs[0].Author == nil || prs[0].Author.Login != "bob" {
+		t.Errorf("wallet prs = %+v", prs)
+	}
+	if got := rec.Header().Get("X-Total-Count"); got != "3" {
+		t.Errorf("X-Total-Count = %q", got)
+	}
+
+	rec = get("/topics/wallet/prs?state=MERGED")
+	if got := rec.Header().Get("X-Total-Count"); got != "2" {
+		t.Errorf("merged X-Total-Count = %q", got)
+	}
+
+	rec = get("/topics/wallet/prs?match=any&state=MERGED")
+	prs = nil
+	if err := json.Unmarshal(rec.Body.Bytes(), &prs); err != nil {
+		t.Fatal(err)
+	}
+	if len(prs) != 3 || prs[0].ID != "pr-2" {
+		t.Fatalf("wallet prs, any match = %+v", prs)
+	}
+	if ts := prs[0].Topics; len(ts) != 2 || ts[0].Topic != "indexer" || ts[1].Topic != "wallet" || ts[1].Rank != 2 {
+		t.Errorf("pr-2 topics = %+v", ts)
+	}
+	if rec := get("/topics/wallet/prs?match=some"); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad match: status = %d", rec.Code)
+	}
+	if rec := get("/topics/other/prs"); rec.Code != http.StatusOK {
+		t.Errorf("other: status = %d", rec.Code)
+	}
+	if rec := get("/topics/nope/prs"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown topic: status = %d", rec.Code)
+	}
+}
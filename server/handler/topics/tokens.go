@@ -0,0 +1,138 @@
+package topics
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samouraiworld/topofgnomes/server/apicache"
+	"github.com/samouraiworld/topofgnomes/server/handler"
+	"github.com/samouraiworld/topofgnomes/server/handler/apierror"
+	"github.com/samouraiworld/topofgnomes/server/models"
+	"github.com/samouraiworld/topofgnomes/server/topics"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultTokenLimit = 50
+	maxTokenLimit     = 500
+	// tokenExamples is how many titles each token comes with.
+	tokenExamples = 3
+)
+
+// TokenCount is a word of unclassified pull request titles.
+type TokenCount struct {
+	Token string `json:"token"`
+	// PullRequests counts the unclassified PRs whose title has the token.
+	PullRequests int `json:"pullRequests"`
+	// Examples are a few of those titles, newest first.
+	Examples []string `json:"examples"`
+}
+
+type tokensResponse struct {
+	LastSyncedAt *time.Time   `json:"lastSyncedAt"`
+	Period       string       `json:"period"`
+	Unclassified int          `json:"unclassified"`
+	Tokens       []TokenCount `json:"tokens"`
+}
+
+// HandleGetUnclassifiedTokens lists the words that come up most in the
+// titles of pull requests classified as other, created over ?time=, as
+// leads for new topics.yaml patterns. Cached until the next sync or topics
+// reclassification.
+func HandleGetUnclassifiedTokens(db *gorm.DB, cache *apicache.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		w.Header().Set("Content-Type", "application/json")
+		period := r.URL.Query().Get("time")
+		limit := defaultTokenLimit
+		if s := r.URL.Query().Get("limit"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n <= 0 {
+				apierror.Write(w, r, apierror.InvalidInput("invalid limit %q", s))
+				return
+			}
+			limit = min(n, maxTokenLimit)
+		}
+		key := fmt.Sprintf("topics:unclassified-tokens:%s:%d", period, limit)
+		domains := []apicache.Domain{apicache.GitHub, apicache.TopicsConfig, apicache.Clock}
+		cache.Serve(w, r, key, domains, func() (any, error) {
+			return computeTokens(db, period, limit)
+		})
+	}
+}
+
+func computeTokens(db *gorm.DB, period string, limit int) (tokensResponse, error) {
+	q := db.Model(&models.PullRequest{}).Where("topic = ?", topics.OtherSlug).Order("created_at DESC")
+	if start := handler.PeriodStart(period); !start.IsZero() {
+		q = q.Where("created_at >= ?", start)
+	}
+	var titles []string
+	if err := q.Pluck("title", &titles).Error; err != nil {
+		return tokensResponse{}, fmt.Errorf("unclassified-tokens query: %w", err)
+	}
+
+	counts := map[string]*TokenCount{}
+	for _, title := range titles {
+		for _, token := range titleTokens(title) {
+			c := counts[token]
+			if c == nil {
+				c = &TokenCount{Token: token, Examples: []string{}}
+				counts[token] = c
+			}
+			c.PullRequests++
+			if len(c.Examples) < tokenExamples {
+				c.Examples = append(c.Examples, title)
+			}
+		}
+	}
+	tokens := make([]TokenCount, 0, len(counts))
+	for _, c := range counts {
+		tokens = append(tokens, *c)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		if tokens[i].PullRequests != tokens[j].PullRequests {
+			return tokens[i].PullRequests > tokens[j].PullRequests
+		}
+		return tokens[i].Token < tokens[j].Token
+	})
+	return tokensResponse{
+		LastSyncedAt: handler.LastSyncedAt(db),
+		Period:       period,
+		Unclassified: len(titles),
+		Tokens:       tokens[:min(limit, len(tokens))],
+	}, nil
+}
+
+var tokenPattern = regexp.MustCompile(`[a-z][a-z0-9]*(?:[-_.][a-z0-9]+)*`)
+
+// stopTokens are words that say nothing about a PR's area: English filler
+// and conventional-commit types.
+var stopTokens = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"by": true, "for": true, "from": true, "in": true, "into": true, "is": true, "it": true,
+	"not": true, "of": true, "on": true, "or": true, "the": true, "to": true, "when": true,
+	"with": true, "without": true, "this": true, "that": true, "use": true, "all": true,
+	"add": true, "adds": true, "added": true, "update": true, "updates": true, "remove": true,
+	"feat": true, "fix": true, "fixes": true, "chore": true, "refactor": true, "perf": true,
+	"style": true, "revert": true, "wip": true, "draft": true, "new": true, "more": true,
+}
+
+// titleTokens returns the distinct words of a title worth a pattern,
+// lowercased like the classifier's haystack.
+func titleTokens(title string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, token := range tokenPattern.FindAllString(strings.ToLower(title), -1) {
+		if len(token) < 3 || stopTokens[token] || seen[token] {
+			continue
+		}
+		seen[token] = true
+		out = append(out, token)
+	}
+	return out
+}
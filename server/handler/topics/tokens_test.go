@@ -0,0 +1,58 @@
+package topics
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/samouraiworld/topofgnomes/server/db/dbtest"
+	"github.com/samouraiworld/topofgnomes/server/models"
+)
+
+func TestComputeTokens(t *testing.T) {
+	db := dbtest.Open(t, &models.PullRequest{}, &models.SyncStatus{})
+	now := time.Now().UTC()
+	for i, pr := range []struct {
+		title, topic string
+		age          time.Duration
+	}{
+		{"feat: add Telemetry exporter", "other", time.Hour},
+		{"fix(telemetry): flaky exporter", "other", 2 * time.Hour},
+		{"chore: telemetry telemetry", "other", 3 * time.Hour},
+		{"feat: wallet telemetry", "wallet", time.Hour},
+		{"old telemetry", "other", 30 * 24 * time.Hour},
+	} {
+		row := models.PullRequest{ID: fmt.Sprintf("pr-%d", i), Title: pr.title, Topic: pr.topic, CreatedAt: now.Add(-pr.age)}
+		if err := db.Create(&row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resp, err := computeTokens(db, "weekly", 2)
+	if err != nil {
+		t.Fatalf("computeTokens: %v", err)
+	}
+	if resp.Unclassified != 3 {
+		t.Errorf("unclassified = %d, want 3", resp.Unclassified)
+	}
+	want := []TokenCount{
+		{Token: "telemetry", PullRequests: 3, Examples: []string{
+			"feat: add Telemetry exporter", "fix(telemetry): flaky exporter", "chore: telemetry telemetry",
+		}},
+		{Token: "exporter", PullRequests: 2, Examples: []string{
+			"feat: add Telemetry exporter", "fix(telemetry): flaky exporter",
+		}},
+	}
+	if !reflect.DeepEqual(resp.Tokens, want) {
+		t.Errorf("tokens = %+v, want %+v", resp.Tokens, want)
+	}
+}
+
+func TestTitleTokens(t *testing.T) {
+	got := titleTokens("feat(gno.land): Add tx-indexer to the r/demo v2 for CI")
+	want := []string{"gno.land", "tx-indexer", "demo"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("titleTokens = %q, want %q", got, want)
+	}
+}
@@ -18,8 +18,9 @@ type topicsResponse struct {
 
 // HandleGetAll serves the full topic taxonomy with the file mtime so the
 // frontend can bust its query cache when ops re-deploys the YAML.
-func HandleGetAll(cfg *topics.Config) http.HandlerFunc {
+func HandleGetAll(roster topics.Provider) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		cfg := roster()
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(topicsResponse{
 			SchemaVersion: cfg.SchemaVersion,
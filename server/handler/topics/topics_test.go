@@ -34,7 +34,7 @@ topics:
 
 func TestHandleGetAll_ReturnsTopicsWithSchemaAndSyncedAt(t *testing.T) {
 	cfg := fixtureConfig(t)
-	h := HandleGetAll(cfg)
+	h := HandleGetAll(topics.Static(cfg))
 
 	rec := httptest.NewRecorder()
 	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/topics", nil))
@@ -50,8 +50,8 @@ func TestHandleGetAll_ReturnsTopicsWithSchemaAndSyncedAt(t *testing.T) {
 	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
 		t.Fatalf("unmarshal: %v", err)
 	}
-	if body.SchemaVersion != 1 {
-		t.Errorf("schemaVersion = %d, want 1", body.SchemaVersion)
+	if body.SchemaVersion != topics.SchemaVersion {
+		t.Errorf("schemaVersion = %d, want %d", body.SchemaVersion, topics.SchemaVersion)
 	}
 	if body.LastSyncedAt.IsZero() {
 		t.Error("lastSyncedAt missing")
@@ -73,7 +73,7 @@ func TestHandleGetAll_ReturnsTopicsWithSchemaAndSyncedAt(t *testing.T) {
 func TestHandleGetAll_PreservesYAMLOrder(t *testing.T) {
 	// Order matters for first-match-wins classification on the frontend.
 	cfg := fixtureConfig(t)
-	h := HandleGetAll(cfg)
+	h := HandleGetAll(topics.Static(cfg))
 
 	rec := httptest.NewRecorder()
 	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/topics", nil))
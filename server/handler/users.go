@@ -2,58 +2,79 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 
+	"github.com/samouraiworld/topofgnomes/server/handler/apierror"
+	"github.com/samouraiworld/topofgnomes/server/handler/listquery"
 	"github.com/samouraiworld/topofgnomes/server/models"
 	"gorm.io/gorm"
 )
 
-// Get all users. Optionally filtered by addresses (comma-separated)
+var usersList = listquery.Spec{
+	Model: &models.User{},
+	Sorts: map[string]string{
+		"login":      "login",
+		"joinDate":   "join_date",
+		"followers":  "followers",
+		"totalStars": "total_stars",
+		"id":         "id",
+	},
+	DefaultSort: "login",
+	Keys:        []string{"id"},
+}
+
+// Get one page of users. Optionally filtered by addresses (comma-separated)
 func HandleGetUsers(db *gorm.DB) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
 		w.Header().Set("Content-Type", "application/json")
 
-		var users []models.User
+		params, err := listquery.Parse(r.URL.Query(), usersList)
+		if err != nil {
+			apierror.Write(w, r, apierror.InvalidInput("%v", err))
+			return
+		}
+
+		query := db.Model(&models.User{})
 		// Get users depending on the addresses query parameter
 		wallets := r.URL.Query().Get("addresses")
 		if wallets != "" {
 			addresses := strings.Split(wallets, ",")
-			err := db.Model(&models.User{}).Where("wallet IN ?", addresses).Find(&users).Error
-			if err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
-				w.Write([]byte(err.Error()))
-				return
-			}
-		} else {
-			err := db.Model(&models.User{}).Find(&users).Error
-			if err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
-				w.Write([]byte(err.Error()))
-				return
-			}
+			query = query.Where("wallet IN ?", addresses)
 		}
-		json.NewEncoder(w).Encode(users)
+
+		var users []models.User
+		page, err := listquery.Find(query, params, usersList, &users)
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+		listquery.Write(w, r, params, page, users)
 	}
 }
 
 // Get a user based on their address
 func HandleGetUser(db *gorm.DB) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
 		w.Header().Set("Content-Type", "application/json")
 
 		address := r.PathValue("address")
 		if address == "" {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte("address is required"))
+			apierror.Write(w, r, apierror.InvalidInput("address is required"))
 			return
 		}
 
 		var user models.User
 		err := db.Model(&models.User{}).Where("wallet = ?", address).First(&user).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			apierror.Write(w, r, apierror.NotFound("no user with wallet %q", address))
+			return
+		}
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte(err.Error()))
+			apierror.Write(w, r, err)
 			return
 		}
 		json.NewEncoder(w).Encode(user)
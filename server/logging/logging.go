@@ -0,0 +1,127 @@
+// Package logging configures the server's zap logger and carries a
+// request-scoped logger through context.Context.
+//
+// New builds the process logger from LOG_FORMAT and LOG_LEVEL and installs
+// it as zap's global one, so packages without a logger of their own (db,
+// providers) log through zap.S(). Middleware tags every request with an ID
+// (the caller's X-Request-ID, or a fresh one) and stores a logger carrying
+// it, plus the trace and span IDs, in the request context; handlers and
+// whatever they call log through FromContext(ctx) so lines can be
+// correlated to the request that produced them.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/samouraiworld/topofgnomes/server/tracing"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// RequestIDHeader is read from requests and echoed on responses.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds caller-supplied IDs so a client can't bloat
+// every log line of its request.
+const maxRequestIDLength = 128
+
+// New builds the process logger. LOG_FORMAT is "json" (the default, one
+// object per line for log shippers) or "console" for development; LOG_LEVEL
+// is debug, info (the default), warn or error.
+func New() (*zap.SugaredLogger, error) {
+	var cfg zap.Config
+	switch format := strings.ToLower(os.Getenv("LOG_FORMAT")); format {
+	case "", "json":
+		cfg = zap.NewProductionConfig()
+		cfg.EncoderConfig.TimeKey = "time"
+		cfg.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	case "console":
+		cfg = zap.NewDevelopmentConfig()
+	default:
+		return nil, fmt.Errorf("LOG_FORMAT must be json or console, got %q", format)
+	}
+	if lvl := os.Getenv("LOG_LEVEL"); lvl != "" {
+		level, err := zap.ParseAtomicLevel(lvl)
+		if err != nil {
+			return nil, fmt.Errorf("LOG_LEVEL: %w", err)
+		}
+		cfg.Level = level
+	}
+	l, err := cfg.Build()
+	if err != nil {
+		return nil, err
+	}
+	zap.ReplaceGlobals(l)
+	return l.Sugar(), nil
+}
+
+type ctxKey struct{}
+
+// WithLogger returns a copy of ctx carrying l.
+func WithLogger(ctx context.Context, l *zap.SugaredLogger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext returns the logger stored in ctx by Middleware or
+// WithLogger, or the global logger when there is none.
+func FromContext(ctx context.Context) *zap.SugaredLogger {
+	return FromContextOr(ctx, zap.S())
+}
+
+// FromContextOr is FromContext for components with a logger of their own
+// (Syncer, Signer): work done for a request logs with the request's logger,
+// background work with fallback, annotated with the trace IDs of ctx.
+func FromContextOr(ctx context.Context, fallback *zap.SugaredLogger) *zap.SugaredLogger {
+	if l, ok := ctx.Value(ctxKey{}).(*zap.SugaredLogger); ok {
+		return l
+	}
+	return tracing.Logger(ctx, fallback)
+}
+
+type requestIDKey struct{}
+
+// RequestID returns the ID Middleware assigned to the request, or "".
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Middleware assigns the request ID, stores a logger derived from base in
+// the context and writes one access log line per request. Install it after
+// tracing.Middleware so the trace IDs are known.
+func Middleware(base *zap.SugaredLogger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			id := r.Header.Get(RequestIDHeader)
+			if id == "" || len(id) > maxRequestIDLength {
+				id = uuid.NewString()
+			}
+			w.Header().Set(RequestIDHeader, id)
+
+			l := tracing.Logger(r.Context(), base).With("request_id", id)
+			ctx := context.WithValue(WithLogger(r.Context(), l), requestIDKey{}, id)
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			l.Infow("request",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", status,
+				"bytes", ww.BytesWritten(),
+				"duration", time.Since(start),
+			)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package logging
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestMiddleware(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	base := zap.New(core).Sugar()
+
+	var seen string
+	handler := Middleware(base)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestID(r.Context())
+		FromContext(r.Context()).Infow("inside", "k", "v")
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	tests := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{"caller id is kept", "abc-123", true},
+		{"missing id is generated", "", false},
+		{"oversized id is replaced", strings.Repeat("x", maxRequestIDLength+1), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.TakeAll()
+			req := httptest.NewRequest(http.MethodGet, "/things/1", nil)
+			if tt.header != "" {
+				req.Header.Set(RequestIDHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			id := rec.Header().Get(RequestIDHeader)
+			if id == "" || id != seen {
+				t.Fatalf("response id %q, handler saw %q", id, seen)
+			}
+			if (id == tt.header) != tt.keep {
+				t.Errorf("id = %q, header %q", id, tt.header)
+			}
+
+			entries := logs.All()
+			if len(entries) != 2 {
+				t.Fatalf("got %d log lines, want the handler's and the access line", len(entries))
+			}
+			for _, e := range entries {
+				if e.ContextMap()["request_id"] != id {
+					t.Errorf("%q logged without the request id: %v", e.Message, e.ContextMap())
+				}
+			}
+			access := entries[1].ContextMap()
+			if access["status"] != int64(http.StatusTeapot) || access["path"] != "/things/1" {
+				t.Errorf("access line = %v", access)
+			}
+		})
+	}
+}
+
+func TestFromContextOr(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	fallback := zap.New(core).Sugar().With("component", "sync")
+	request := zap.New(core).Sugar().With("request_id", "r1")
+
+	FromContextOr(context.Background(), fallback).Info("background")
+	FromContextOr(WithLogger(context.Background(), request), fallback).Info("for request")
+
+	entries := logs.All()
+	if entries[0].ContextMap()["component"] != "sync" {
+		t.Errorf("background work should use the fallback: %v", entries[0].ContextMap())
+	}
+	if entries[1].ContextMap()["request_id"] != "r1" {
+		t.Errorf("request work should use the request logger: %v", entries[1].ContextMap())
+	}
+}
+
+func TestNewRejectsUnknownSettings(t *testing.T) {
+	t.Setenv("LOG_FORMAT", "xml")
+	if _, err := New(); err == nil {
+		t.Error("LOG_FORMAT=xml accepted")
+	}
+	t.Setenv("LOG_FORMAT", "json")
+	t.Setenv("LOG_LEVEL", "loud")
+	if _, err := New(); err == nil {
+		t.Error("LOG_LEVEL=loud accepted")
+	}
+	t.Setenv("LOG_LEVEL", "warn")
+	l, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Desugar().Core().Enabled(zap.InfoLevel) {
+		t.Error("LOG_LEVEL=warn should disable info")
+	}
+}
@@ -3,10 +3,10 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -16,50 +16,50 @@ import (
 
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/rs/cors"
+	"github.com/samouraiworld/topofgnomes/server/apicache"
+	"github.com/samouraiworld/topofgnomes/server/configwatch"
 	"github.com/samouraiworld/topofgnomes/server/db"
 	"github.com/samouraiworld/topofgnomes/server/handler"
-	"github.com/samouraiworld/topofgnomes/server/handler/ai"
-	"github.com/samouraiworld/topofgnomes/server/handler/contributor"
-	teamshandler "github.com/samouraiworld/topofgnomes/server/handler/teams"
-	topicshandler "github.com/samouraiworld/topofgnomes/server/handler/topics"
+	"github.com/samouraiworld/topofgnomes/server/handler/adminauth"
 	infrarepo "github.com/samouraiworld/topofgnomes/server/infra/repository"
+	"github.com/samouraiworld/topofgnomes/server/logging"
+	"github.com/samouraiworld/topofgnomes/server/metrics"
 	"github.com/samouraiworld/topofgnomes/server/models"
 	"github.com/samouraiworld/topofgnomes/server/signer"
 	"github.com/samouraiworld/topofgnomes/server/sync"
 	"github.com/samouraiworld/topofgnomes/server/teams"
 	"github.com/samouraiworld/topofgnomes/server/topics"
+	"github.com/samouraiworld/topofgnomes/server/tracing"
 	"github.com/subosito/gotenv"
-	"go.uber.org/zap"
 	"gorm.io/gorm"
 
 	"github.com/clerk/clerk-sdk-go/v2"
-	clerkhttp "github.com/clerk/clerk-sdk-go/v2/http"
 )
 
 var database *gorm.DB
 
 const port = 3333
 
-var logger *zap.SugaredLogger
-
-// LoggingMiddleware logs the duration of each request
-func LoggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		next.ServeHTTP(w, r)
-		duration := time.Since(start)
-		logger.Infof("[%s] %s %s took %s", time.Now().Format("2006-01-02 15:04:05"), r.Method, r.URL.Path, duration)
-	})
-}
-
 func main() {
 	gotenv.Load()
-	zapLogger, err := zap.NewDevelopment()
+	logger, err := logging.New()
 	if err != nil {
 		panic(err)
 	}
+	defer logger.Sync()
 
-	logger = zapLogger.Sugar()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Stdout, os.Args[2:]); err != nil {
+			logger.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "topics" {
+		if err := runTopics(os.Stdout, os.Args[2:]); err != nil {
+			logger.Fatal(err)
+		}
+		return
+	}
 
 	repositories, err := models.GetRepositoriesFromConfig()
 	if err != nil {
@@ -70,25 +70,27 @@ func main() {
 	if teamsConfigPath == "" {
 		teamsConfigPath = "config/teams.yaml"
 	}
-	teamsCfg, err := teams.Load(teamsConfigPath)
+	teamsWatch, err := configwatch.New("teams", teamsConfigPath, teams.Load)
 	if err != nil {
 		panic(fmt.Errorf("load teams config: %w", err))
 	}
+	teamsCfg := teamsWatch.Current()
 	logger.Infof("loaded %d teams from %s (mtime=%s)", len(teamsCfg.Teams), teamsConfigPath, teamsCfg.LastSyncedAt.Format(time.RFC3339))
 
 	topicsConfigPath := os.Getenv("TOPICS_CONFIG_PATH")
 	if topicsConfigPath == "" {
 		topicsConfigPath = "config/topics.yaml"
 	}
-	topicsCfg, err := topics.Load(topicsConfigPath)
+	topicsWatch, err := configwatch.New("topics", topicsConfigPath, topics.Load)
 	if err != nil {
 		panic(fmt.Errorf("load topics config: %w", err))
 	}
+	topicsCfg := topicsWatch.Current()
 	logger.Infof("loaded %d topics from %s (mtime=%s)", len(topicsCfg.Topics), topicsConfigPath, topicsCfg.LastSyncedAt.Format(time.RFC3339))
 
 	database, err = db.InitDB()
 	if err != nil {
-		log.Fatal(err)
+		logger.Fatal(err)
 	}
 	if os.Getenv("GITHUB_OAUTH_CLIENT_ID") == "" {
 		panic("GITHUB_OAUTH_CLIENT_ID is not set")
@@ -123,11 +125,73 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	shutdownTracing, err := tracing.Init(ctx)
+	if err != nil {
+		panic(fmt.Errorf("init tracing: %w", err))
+	}
+
 	// Listen for termination signals
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
-	syncer := sync.NewSyncer(database, repositories, logger)
+	store, err := ristretto.NewCache(&ristretto.Config{
+		NumCounters: 100000,    // number of keys to track frequency of (10M).
+		MaxCost:     100000000, // maximum cost of cache (1GB).
+		BufferItems: 64,        // number of keys per Get buffer.
+		Metrics:     true,      // hit/miss counters exported at /metrics.
+	})
+	if err != nil {
+		panic(err)
+	}
+	metrics.RegisterCache("api", store)
+	// Entries live until the syncers report new data; see apicache.
+	cache := apicache.New(store)
+
+	// The roster comes from teams.yaml, or with TEAMS_SOURCE=db from the
+	// database, seeded from teams.yaml on first start and then edited
+	// through /admin/teams.
+	roster := teams.Provider(teamsWatch.Current)
+	configs := []configwatch.Reporter{teamsWatch, topicsWatch}
+	var teamStore *teams.Store
+	switch source := os.Getenv("TEAMS_SOURCE"); source {
+	case "", "file":
+		teamsWatch.OnReload(func(*teams.Config) { cache.Bump(apicache.TeamsConfig) })
+	case "db":
+		teamStore, err = teams.NewStore(database)
+		if err != nil {
+			panic(fmt.Errorf("load teams roster: %w", err))
+		}
+		empty, err := teamStore.Empty()
+		if err != nil {
+			panic(fmt.Errorf("load teams roster: %w", err))
+		}
+		if empty {
+			if err := teamStore.Import("system", teamsWatch.Current()); err != nil {
+				panic(fmt.Errorf("seed teams roster from %s: %w", teamsConfigPath, err))
+			}
+			logger.Infof("seeded the teams roster from %s", teamsConfigPath)
+		}
+		teamStore.OnChange(func(*teams.Config) { cache.Bump(apicache.TeamsConfig) })
+		roster = teamStore.Current
+		configs = []configwatch.Reporter{topicsWatch}
+	default:
+		panic(fmt.Errorf("invalid TEAMS_SOURCE %q, want file or db", source))
+	}
+
+	syncer := sync.NewSyncer(database, repositories, topicsWatch.Current, cache, logger)
+	// Topic stats read the topic stored on each pull request: serve them
+	// again once those are all reclassified. A run that fails partway
+	// leaves the cache alone; the next reload or restart finishes it.
+	topicsWatch.OnReload(func(*topics.Config) {
+		go func() {
+			n, err := syncer.ReclassifyTopics(ctx)
+			if err != nil {
+				logger.Errorf("error while reclassifying pull requests, %d reclassified before it: %v", n, err)
+				return
+			}
+			cache.Bump(apicache.TopicsConfig)
+		}()
+	})
 
 	// Start data synchronization first
 	err = syncer.StartSynchonizing(ctx)
@@ -147,16 +211,10 @@ func main() {
 		logger.Warn("DISCORD_WEBHOOK_URL not set, skipping leaderboard notifier")
 	}
 
-	cache, err := ristretto.NewCache(&ristretto.Config{
-		NumCounters: 100000,    // number of keys to track frequency of (10M).
-		MaxCost:     100000000, // maximum cost of cache (1GB).
-		BufferItems: 64,        // number of keys per Get buffer.
-	})
-	if err != nil {
-		panic(err)
-	}
 	router := chi.NewRouter()
-	router.Use(LoggingMiddleware)
+	router.Use(tracing.Middleware)
+	router.Use(metrics.Middleware)
+	router.Use(logging.Middleware(logger))
 	router.Use(Compress())
 
 	// CORS — allow Memba and gnolove.world to access the API.
@@ -167,70 +225,52 @@ func main() {
 	}
 	router.Use(cors.New(cors.Options{
 		AllowedOrigins:   strings.Split(corsOrigins, ","),
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Authorization", "Content-Type"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Authorization", "Content-Type", "If-None-Match"},
+		ExposedHeaders:   []string{"Link", "X-Next-Cursor", "X-Total-Count", "X-Request-ID", "ETag"},
 		AllowCredentials: true,
 		MaxAge:           600, // 10 min — conservative during migration
 	}).Handler)
 
-	// repositories
-	prRepo := infrarepo.NewPullRequestRepository(database)
-
 	// Start triggering leaderboard webhooks
 	go handler.LoopTriggerLeaderboardWebhooks(ctx, database, logger)
 
-	router.Get("/teams", teamshandler.HandleGetAll(teamsCfg))
-	router.Get("/teams/{slug}", teamshandler.HandleGetBySlug(teamsCfg))
-	router.Get("/teams/{slug}/active-repos", teamshandler.HandleGetActiveRepos(database, teamsCfg, cache))
-	router.Get("/teams/{slug}/team-stats", teamshandler.HandleGetTeamStats(database, teamsCfg, cache))
-	router.Get("/team-collab", teamshandler.HandleGetTeamCollab(database, teamsCfg, cache))
-
-	router.Get("/topics", topicshandler.HandleGetAll(topicsCfg))
-	router.Get("/contributors/cohorts", contributor.HandleGetCohorts(database, cache))
-
-	router.HandleFunc("/repositories", handler.HandleGetRepository(database))
-	router.HandleFunc("/stats", handler.HandleGetUserStats(database, cache))
-	router.HandleFunc("/last-prs", handler.HandleGetLastPrs(database, cache))
-	router.HandleFunc("/users", handler.HandleGetUsers(database))
-	router.HandleFunc("/users/{address}", handler.HandleGetUser(database))
-	router.HandleFunc("/issues", handler.GetIssues(database))
-	router.HandleFunc("/pull-requests/report", handler.GetPullrequestsReportByDate(prRepo))
-	router.HandleFunc("/score-factors", handler.HandleGetScoreFactors)
-	router.HandleFunc("/milestones/{number}", handler.GetMilestone(database))
-	router.HandleFunc("/contributors/newest", handler.HandleGetNewestContributors(database))
-	router.HandleFunc("/github/verify", handler.HandleVerifyGithubAccount(signer, database))
-	router.HandleFunc("/github/oauth/exchange", handler.HandleGetGithubUserAndTokenByCode(signer, database))
-	router.HandleFunc("/contributors/{login}", contributor.HandleGetContributor(database))
-	router.Post("/github/link", handler.HandleLink(database))
-
-	// Leaderboard webhook endpoints
-	router.Group(func(r chi.Router) {
-		r.Use(clerkhttp.WithHeaderAuthorization())
-		r.Get("/leaderboard-webhooks", handler.HandleGetLeaderboardWebhooks(database))
-		r.Post("/leaderboard-webhooks", handler.HandleCreateLeaderboardWebhook(database))
-		r.Put("/leaderboard-webhooks/{id}", handler.HandleUpdateLeaderboardWebhook(database))
-		r.Delete("/leaderboard-webhooks/{id}", handler.HandleDeleteLeaderboardWebhook(database))
-	})
+	// Pick up changes to the teams and topics config, and membership dates
+	// coming due in a database roster; 0 disables.
+	reloadInterval := 10 * time.Second
+	if v := os.Getenv("CONFIG_RELOAD_INTERVAL"); v != "" {
+		reloadInterval, err = time.ParseDuration(v)
+		if err != nil {
+			panic(fmt.Errorf("invalid CONFIG_RELOAD_INTERVAL: %w", err))
+		}
+	}
+	if reloadInterval > 0 {
+		if teamStore != nil {
+			go teamStore.Run(ctx, reloadInterval, logger)
+		} else {
+			go teamsWatch.Run(ctx, reloadInterval, logger)
+		}
+		go topicsWatch.Run(ctx, reloadInterval, logger)
+	}
 
-	// ai endpoints
-	router.HandleFunc("/ai/report", ai.HandleGetLastReport(database))
-	router.HandleFunc("/ai/report/weekly", ai.HandleGetReportByWeek(database))
-	router.Post("/ai/report/generate", ai.HandleGenerateReport(database))
-	router.Post("/ai/report/regenerate", ai.HandleRegenerateReport(database))
-	router.HandleFunc("/ai/reports", ai.HandleGetAllReports(database))
-
-	// Onchain package contributions endpoints
-	router.HandleFunc("/onchain/packages", handler.HandleGetAllPackages(database))
-	router.HandleFunc("/onchain/packages/{address}", handler.HandleGetPackagesByUser(database))
-
-	// Onchain namespace contributions endpoints
-	router.HandleFunc("/onchain/namespaces", handler.HandleGetAllNamespaces(database))
-	router.HandleFunc("/onchain/namespaces/{address}", handler.HandleGetNamespacesByUser(database))
-	router.HandleFunc("/onchain/proposals", handler.HandleGetAllProposals(database))
-	router.HandleFunc("/onchain/proposals/{id}", handler.HandleGetProposal(database))
-	router.HandleFunc("/onchain/govdao-members", handler.HandleGetGovdaoMembers(database))
-	router.HandleFunc("/onchain/votes/{address}", handler.HandleGetVotesByUser(database))
-	router.Put("/on-chain/votes", handler.HandleSynchronizeVotes(syncer))
+	// Unset or invalid falls back to graph.DefaultMaxCost.
+	graphqlMaxCost, _ := strconv.Atoi(os.Getenv("GRAPHQL_MAX_COST"))
+
+	registerRoutes(router, routeDeps{
+		db:     database,
+		cache:  cache,
+		teams:  roster,
+		topics: topicsWatch.Current,
+		signer: signer,
+		syncer: syncer,
+		prRepo: infrarepo.NewPullRequestRepository(database),
+
+		configs:   configs,
+		teamStore: teamStore,
+		adminIDs:  adminauth.ParseIDs(os.Getenv("ADMIN_USER_IDS")),
+
+		graphqlMaxCost: graphqlMaxCost,
+	})
 
 	srv := &http.Server{
 		Addr:    fmt.Sprintf(":%d", port),
@@ -257,6 +297,9 @@ func main() {
 	if err := srv.Shutdown(shutdownCtx); err != nil {
 		logger.Errorf("HTTP server shutdown error: %v", err)
 	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Errorf("tracing shutdown error: %v", err)
+	}
 	logger.Info("Server stopped.")
 }
 
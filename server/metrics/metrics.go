@@ -0,0 +1,208 @@
+// Package metrics is the server's Prometheus instrumentation, served in the
+// text exposition format at /metrics.
+//
+// Everything is registered on a package-level Registry rather than the
+// client library's global one, so the endpoint only exposes what is listed
+// here plus the Go runtime and process collectors. Callers record through
+// the small helpers below (ObserveSyncStep, ObserveLLMCall, ...) so label
+// names and values stay in one place.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dgraph-io/ristretto"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "gnolove"
+
+// Registry holds every collector exposed at /metrics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests served, by chi route pattern and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency, by chi route pattern.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	syncStepDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "sync_step_duration_seconds",
+		Help:      "Duration of one per-repository GitHub sync step, backoff retries included.",
+		Buckets:   []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600},
+	}, []string{"step"})
+
+	syncErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sync_errors_total",
+		Help:      "Per-repository GitHub sync steps that failed after retries.",
+	}, []string{"step"})
+
+	chainHeight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "onchain_chain_height",
+		Help:      "Latest block height reported by the gno RPC node.",
+	})
+
+	chainLastBlock = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "onchain_sync_last_block",
+		Help:      "Block height of the newest synced on-chain event, by stream.",
+	}, []string{"stream"})
+
+	chainLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "onchain_sync_lag_blocks",
+		Help:      "Blocks between the chain head and the newest synced event, by stream. Quiet streams (few proposals) lag by design.",
+	}, []string{"stream"})
+
+	llmDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "llm_request_duration_seconds",
+		Help:      "LLM completion latency, by provider, model and result.",
+		Buckets:   []float64{1, 2.5, 5, 10, 20, 30, 60, 120, 300},
+	}, []string{"provider", "model", "result"})
+
+	llmTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_tokens_total",
+		Help:      "Tokens reported by LLM providers, by kind (prompt or completion).",
+	}, []string{"provider", "model", "kind"})
+
+	webhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Leaderboard webhook deliveries, by webhook type and result.",
+	}, []string{"type", "result"})
+
+	configReloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "config_reloads_total",
+		Help:      "Reloads of the teams and topics YAML files, by config and result.",
+	}, []string{"config", "result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration,
+		syncStepDuration, syncErrors,
+		chainHeight, chainLastBlock, chainLag,
+		llmDuration, llmTokens,
+		webhookDeliveries,
+		configReloads,
+	)
+}
+
+// Handler serves Registry in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// Middleware counts and times requests. Routes are labelled with their chi
+// pattern (/users/{address}), never the raw path, so label cardinality is
+// bounded by the route table; requests no route matched share "unmatched".
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			if p := rctx.RoutePattern(); p != "" {
+				route = p
+			}
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		httpRequests.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+		httpDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// RegisterCache exposes hit and miss counters and the hit ratio of a
+// ristretto cache created with Config.Metrics set; name labels the series.
+func RegisterCache(name string, cache *ristretto.Cache) {
+	labels := prometheus.Labels{"cache": name}
+	Registry.MustRegister(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace, Name: "cache_hits_total", ConstLabels: labels,
+			Help: "Cache lookups that found a value.",
+		}, func() float64 { return float64(cache.Metrics.Hits()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace, Name: "cache_misses_total", ConstLabels: labels,
+			Help: "Cache lookups that found nothing.",
+		}, func() float64 { return float64(cache.Metrics.Misses()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace, Name: "cache_hit_ratio", ConstLabels: labels,
+			Help: "Hits over lookups since the cache was created.",
+		}, func() float64 { return cache.Metrics.Ratio() }),
+	)
+}
+
+// ObserveSyncStep records one per-repository sync step.
+func ObserveSyncStep(step string, took time.Duration, err error) {
+	syncStepDuration.WithLabelValues(step).Observe(took.Seconds())
+	if err != nil {
+		syncErrors.WithLabelValues(step).Inc()
+	}
+}
+
+// SetChainHeight records the chain head seen by the on-chain syncer.
+func SetChainHeight(height int64) {
+	chainHeight.Set(float64(height))
+}
+
+// SetChainSyncBlock records the newest synced block of an on-chain stream
+// and its lag behind head.
+func SetChainSyncBlock(stream string, lastBlock, head int64) {
+	chainLastBlock.WithLabelValues(stream).Set(float64(lastBlock))
+	chainLag.WithLabelValues(stream).Set(float64(head - lastBlock))
+}
+
+// ObserveLLMCall records one completion request and, on success, the
+// tokens the provider billed for it.
+func ObserveLLMCall(provider, model string, took time.Duration, promptTokens, completionTokens int, err error) {
+	llmDuration.WithLabelValues(provider, model, result(err)).Observe(took.Seconds())
+	if err != nil {
+		return
+	}
+	llmTokens.WithLabelValues(provider, model, "prompt").Add(float64(promptTokens))
+	llmTokens.WithLabelValues(provider, model, "completion").Add(float64(completionTokens))
+}
+
+// ObserveWebhookDelivery records one leaderboard webhook delivery.
+func ObserveWebhookDelivery(webhookType string, err error) {
+	webhookDeliveries.WithLabelValues(webhookType, result(err)).Inc()
+}
+
+// ObserveConfigReload records one attempt at reloading a config file.
+func ObserveConfigReload(config string, err error) {
+	configReloads.WithLabelValues(config, result(err)).Inc()
+}
+
+func result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/ristretto"
+	"github.com/go-chi/chi/v5"
+)
+
+func scrape(t *testing.T) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("scrape status = %d", rec.Code)
+	}
+	return rec.Body.String()
+}
+
+func assertContains(t *testing.T, body string, lines ...string) {
+	t.Helper()
+	for _, l := range lines {
+		if !strings.Contains(body, l) {
+			t.Errorf("metrics output is missing %q", l)
+		}
+	}
+}
+
+func TestMiddlewareLabelsRoutePattern(t *testing.T) {
+	router := chi.NewRouter()
+	router.Use(Middleware)
+	router.Get("/things/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if chi.URLParam(r, "id") == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	for _, path := range []string{"/things/1", "/things/2", "/things/missing", "/nowhere"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	body := scrape(t)
+	assertContains(t, body,
+		`gnolove_http_requests_total{method="GET",route="/things/{id}",status="200"} 2`,
+		`gnolove_http_requests_total{method="GET",route="/things/{id}",status="404"} 1`,
+		`gnolove_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`gnolove_http_request_duration_seconds_count{method="GET",route="/things/{id}"} 3`,
+	)
+	if strings.Contains(body, `route="/things/1"`) {
+		t.Error("raw paths must not be used as route labels")
+	}
+}
+
+func TestRegisterCache(t *testing.T) {
+	cache, err := ristretto.NewCache(&ristretto.Config{NumCounters: 1000, MaxCost: 1 << 20, BufferItems: 64, Metrics: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	RegisterCache("test", cache)
+
+	cache.Get("k")
+	cache.Set("k", "v", 1)
+	cache.Wait()
+	cache.Get("k")
+	cache.Get("k")
+
+	assertContains(t, scrape(t),
+		`gnolove_cache_hits_total{cache="test"} 2`,
+		`gnolove_cache_misses_total{cache="test"} 1`,
+		`gnolove_cache_hit_ratio{cache="test"} 0.6666666666666666`,
+	)
+}
+
+func TestRecorders(t *testing.T) {
+	ObserveSyncStep("prs", time.Second, nil)
+	ObserveSyncStep("prs", time.Second, errors.New("boom"))
+	SetChainHeight(120)
+	SetChainSyncBlock("packages", 100, 120)
+	ObserveLLMCall("mistral", "small", time.Second, 30, 12, nil)
+	ObserveLLMCall("mistral", "small", time.Second, 0, 0, errors.New("rate limited"))
+	ObserveWebhookDelivery("discord", nil)
+	ObserveWebhookDelivery("discord", errors.New("410"))
+	ObserveConfigReload("teams", errors.New("duplicate slug"))
+
+	assertContains(t, scrape(t),
+		`gnolove_sync_step_duration_seconds_count{step="prs"} 2`,
+		`gnolove_sync_errors_total{step="prs"} 1`,
+		`gnolove_onchain_chain_height 120`,
+		`gnolove_onchain_sync_last_block{stream="packages"} 100`,
+		`gnolove_onchain_sync_lag_blocks{stream="packages"} 20`,
+		`gnolove_llm_request_duration_seconds_count{model="small",provider="mistral",result="error"} 1`,
+		`gnolove_llm_tokens_total{kind="prompt",model="small",provider="mistral"} 30`,
+		`gnolove_llm_tokens_total{kind="completion",model="small",provider="mistral"} 12`,
+		`gnolove_webhook_deliveries_total{result="success",type="discord"} 1`,
+		`gnolove_webhook_deliveries_total{result="error",type="discord"} 1`,
+		`gnolove_config_reloads_total{config="teams",result="error"} 1`,
+	)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/samouraiworld/topofgnomes/server/db"
+	"github.com/samouraiworld/topofgnomes/server/db/migrations"
+)
+
+const migrateUsage = "usage: migrate up | down [steps] | status"
+
+// runMigrate implements the `migrate` subcommand against the database
+// configured by DATABASE_URL / DATABASE_PATH:
+//
+//	migrate up            apply every pending migration
+//	migrate down [steps]  revert the newest applied migrations (default 1)
+//	migrate status        list migrations and when they were applied
+func runMigrate(out io.Writer, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	conn, err := db.Open()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		ran, err := migrations.Up(conn)
+		for _, m := range ran {
+			fmt.Fprintf(out, "applied %d %s\n", m.Version, m.Name)
+		}
+		if err == nil && len(ran) == 0 {
+			fmt.Fprintln(out, "schema is up to date")
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("down: steps must be a positive number, got %q", args[1])
+			}
+		}
+		ran, err := migrations.Rollback(conn, steps)
+		for _, m := range ran {
+			fmt.Fprintf(out, "reverted %d %s\n", m.Version, m.Name)
+		}
+		return err
+	case "status":
+		statuses, err := migrations.Statuses(conn)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED")
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return tw.Flush()
+	default:
+		return errors.New(migrateUsage)
+	}
+}
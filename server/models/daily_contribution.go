@@ -0,0 +1,20 @@
+package models
+
+// DailyContribution is one row of the daily_contributions rollup: what a
+// user contributed to one repository on one UTC day. It's derived from the
+// commits, pull_requests, issues and reviews tables by the syncer (see
+// package contributions) so read paths can sum a few rows per user instead
+// of loading every contribution.
+type DailyContribution struct {
+	UserID    string `gorm:"primaryKey" json:"userID"`
+	RepoID    string `gorm:"primaryKey;index:idx_daily_contributions_repo_day,priority:1" json:"repoID"`
+	Day       string `gorm:"primaryKey;size:10;index:idx_daily_contributions_repo_day,priority:2;index" json:"day"` // YYYY-MM-DD
+	Commits   int    `gorm:"not null;default:0" json:"commits"`
+	PRsMerged int    `gorm:"column:prs_merged;not null;default:0" json:"prsMerged"`
+	// PRsOpened counts the PRs opened that day, PRsOpenedMerged those of
+	// them merged since.
+	PRsOpened       int `gorm:"column:prs_opened;not null;default:0" json:"prsOpened"`
+	PRsOpenedMerged int `gorm:"column:prs_opened_merged;not null;default:0" json:"prsOpenedMerged"`
+	Issues          int `gorm:"not null;default:0" json:"issues"`
+	Reviews         int `gorm:"not null;default:0" json:"reviews"`
+}
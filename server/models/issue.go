@@ -15,8 +15,9 @@ type Issue struct {
 	AuthorID     string     `json:"authorID" gorm:"index;index:idx_issues_author_created,priority:1"`
 	Author       *User      `json:"author"`
 	Labels       []Label    `gorm:"many2many:issue_labels" json:"labels"`
-	MilestoneID  string     `json:"milestoneID"`
+	MilestoneID  string     `json:"milestoneID" gorm:"index"`
 	URL          string     `json:"URL"`
+	ClosedAt     *time.Time `json:"closedAt"`
 	Assignees    []Assignee `gorm:"many2many:issue_assignees" json:"assignees"`
 }
 
@@ -13,11 +13,40 @@ type PullRequest struct {
 	AuthorID         string     `json:"authorID" gorm:"index;index:idx_pull_requests_author_created,priority:1"`
 	Author           *User      `json:"author"`
 	Reviews          []Review   `json:"reviews"`
-	MilestoneID      string     `json:"milestoneID"`
+	MilestoneID      string     `json:"milestoneID" gorm:"index"`
 	URL              string     `json:"URL"`
 	ReviewDecision   string     `json:"reviewDecision"`
 	Mergeable        string     `json:"mergeable"`
 	MergeStateStatus string     `json:"mergeStateStatus"`
 	MergedAt         *time.Time `json:"mergedAt"`
 	IsDraft          bool       `json:"isDraft"`
+	// Topic is the slug topics.yaml classifies the PR under, or "other";
+	// kept up to date by the syncer when the taxonomy changes.
+	Topic string `json:"topic" gorm:"index"`
+	// Topics is every topic the PR matches, best first; Topic is the first.
+	Topics []PullRequestTopic `json:"topics,omitempty"`
+	Labels []string           `json:"labels" gorm:"type:text;serializer:json"`
+	Files  []PullRequestFile  `json:"files,omitempty"`
+	// FilesSynced is false for PRs synced before files and labels were,
+	// until the syncer backfills them.
+	FilesSynced bool `json:"-" gorm:"index"`
+}
+
+// PullRequestFile is a file a pull request changes. GitHub lists at most
+// the first 100.
+type PullRequestFile struct {
+	PullRequestID string `json:"-" gorm:"primaryKey"`
+	Path          string `json:"path" gorm:"primaryKey"`
+	Additions     int    `json:"additions"`
+	Deletions     int    `json:"deletions"`
+}
+
+// PullRequestTopic is one of the topics a pull request matches; see
+// topics.Match.
+type PullRequestTopic struct {
+	PullRequestID string  `json:"-" gorm:"primaryKey"`
+	Topic         string  `json:"slug" gorm:"primaryKey;index"`
+	Rank          int     `json:"rank"`
+	Score         float64 `json:"score"`
+	Confidence    float64 `json:"confidence"`
 }
@@ -6,7 +6,7 @@ import "time"
 // Data field contains the JSON report generated by the AI.
 //
 // PromptVersion identifies which prompt + schema produced this row.
-// Legacy rows (pre-prompt-v2) have value 1 — backfilled at startup.
+// Legacy rows (pre-prompt-v2) have value 1 — backfilled by schema migration 2.
 // Prompt v2 adds summary_short / summary_long / team fields per project.
 type Report struct {
 	ID            string    `gorm:"primaryKey" json:"id"`
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 )
 
 type Repository struct {
@@ -11,6 +12,14 @@ type Repository struct {
 	Name       string `json:"name"`
 	Owner      string `json:"owner"`
 	BaseBranch string `json:"baseBranch"`
+	// LatestReleaseTag and LatestReleaseAt describe the repository's latest
+	// GitHub release, synced with the rest; empty when it has none.
+	LatestReleaseTag string     `json:"latestReleaseTag,omitempty"`
+	LatestReleaseAt  *time.Time `json:"latestReleaseAt,omitempty"`
+	// IssuesClosedAtSynced and MilestonesClosedAtSynced are set once a full
+	// issue or milestone pass has filled closed_at.
+	IssuesClosedAtSynced     bool `json:"-"`
+	MilestonesClosedAtSynced bool `json:"-"`
 }
 
 // GetRepositoriesFromConfig parses GITHUB_REPOSITORIES from the environment.
@@ -0,0 +1,10 @@
+package models
+
+// RollupDirtyDay is a repository and UTC day whose daily_contributions rows
+// are behind the source tables. The syncer records it in the transaction
+// that saves the contribution and deletes it once the rollup has caught up,
+// so a pass interrupted before its rollup step leaves it for the next one.
+type RollupDirtyDay struct {
+	RepoID string `gorm:"primaryKey" json:"repoID"`
+	Day    string `gorm:"primaryKey;size:10" json:"day"` // YYYY-MM-DD
+}
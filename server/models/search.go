@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// SearchDocument is one row of the unified search index. The syncers upsert
+// a row per PR, issue, commit, proposal and package; the FTS5 table
+// `search_index` mirrors Title/Body through triggers (see db.EnsureSearchFTS).
+type SearchDocument struct {
+	ID           uint       `gorm:"primaryKey;autoIncrement" json:"-"`
+	Kind         string     `gorm:"uniqueIndex:idx_search_documents_kind_ref;not null" json:"kind"`
+	RefID        string     `gorm:"uniqueIndex:idx_search_documents_kind_ref;not null" json:"refID"`
+	RepositoryID string     `gorm:"index" json:"repositoryID"`
+	Title        string     `json:"title"`
+	Body         string     `json:"body"`
+	URL          string     `json:"url"`
+	CreatedAt    *time.Time `json:"createdAt"`
+}
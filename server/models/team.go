@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Team is a team of the database-backed roster (TEAMS_SOURCE=db). Its
+// members are TeamMemberships; see teams.Store.
+type Team struct {
+	ID          uint      `gorm:"primarykey;autoIncrement" json:"id"`
+	Slug        string    `gorm:"uniqueIndex;not null" json:"slug"`
+	Name        string    `gorm:"not null" json:"name"`
+	Color       string    `gorm:"not null" json:"color"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// TeamMembership is one stint of a GitHub login in a team: from JoinedAt
+// (nil: since always) until LeftAt (nil: still a member). Removing a
+// member closes the stint instead of deleting it.
+type TeamMembership struct {
+	ID       uint       `gorm:"primarykey;autoIncrement" json:"id"`
+	TeamID   uint       `gorm:"index;not null" json:"teamId"`
+	Login    string     `gorm:"index;not null" json:"login"`
+	JoinedAt *time.Time `json:"joinedAt"`
+	LeftAt   *time.Time `json:"leftAt"`
+}
+
+// TeamAuditEvent records one change to the database-backed roster.
+type TeamAuditEvent struct {
+	ID     uint      `gorm:"primarykey;autoIncrement" json:"id"`
+	At     time.Time `gorm:"index;not null" json:"at"`
+	Actor  string    `gorm:"not null" json:"actor"` // Clerk user id, or "system" for the startup seed
+	Action string    `gorm:"not null" json:"action"`
+	// Team is the slug of the team changed; empty for imports.
+	Team   string         `gorm:"index" json:"team,omitempty"`
+	Detail map[string]any `gorm:"type:text;serializer:json" json:"detail,omitempty"`
+}
@@ -8,6 +8,8 @@ import (
 	"github.com/gnolang/gno/gno.land/pkg/gnoclient"
 	rpcclient "github.com/gnolang/gno/tm2/pkg/bft/rpc/client"
 	"github.com/gnolang/gno/tm2/pkg/crypto"
+	"github.com/samouraiworld/topofgnomes/server/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // GetGnoBalance fetches the GNO balance for a given wallet address from the Gno blockchain.
@@ -34,7 +36,9 @@ func GetGnoBalance(ctx context.Context, wallet string) (string, error) {
 	if err != nil {
 		return "0", fmt.Errorf("failed to parse address: %w", err)
 	}
+	_, span := tracing.Start(ctx, "gno.ABCIQuery", attribute.String("gno.query.path", "auth/accounts/"+wallet))
 	account, _, err := gnocl.QueryAccount(arr)
+	tracing.End(span, err)
 	if err != nil {
 		return "0", fmt.Errorf("failed to query account: %w", err)
 	}
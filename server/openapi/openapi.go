@@ -0,0 +1,254 @@
+// Package openapi builds the OpenAPI 3.1 document served at /openapi.json.
+//
+// Each handler package describes the routes it serves as a []Route next to
+// its handlers (see the openapi.go files under handler/); response and body
+// schemas are reflected from the Go types the handlers actually encode, so
+// renaming a json tag updates the spec. main.go assembles the document and a
+// test walks the chi router to make sure every registered route has an entry.
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/samouraiworld/topofgnomes/server/handler/apierror"
+)
+
+// Version is the API document version (info.version). Bump the minor when
+// routes or fields are added, the major when something is removed.
+const Version = "1.14.0"
+
+const specVersion = "3.1.0"
+
+// Route describes one operation. Body and Response are zero values of the
+// request/response types (e.g. models.User{} or []models.User{}); nil means
+// no body.
+type Route struct {
+	Method      string
+	Path        string
+	Summary     string
+	Description string
+	Tag         string
+	Params      []Param
+	Body        any
+	Response    any
+	// Status is the success status code, 200 when zero.
+	Status int
+	// Auth marks routes behind the Clerk bearer token.
+	Auth bool
+	// Cached marks routes served through apicache: they send an ETag and
+	// answer a matching If-None-Match with 304 Not Modified.
+	Cached bool
+}
+
+// Param is a path or query parameter.
+type Param struct {
+	Name        string
+	In          string
+	Type        string
+	Description string
+	Required    bool
+}
+
+// PathParam declares a required string path segment.
+func PathParam(name, description string) Param {
+	return Param{Name: name, In: "path", Type: "string", Description: description, Required: true}
+}
+
+// QueryParam declares an optional query parameter of the given JSON type
+// (string, integer, number, boolean).
+func QueryParam(name, typ, description string) Param {
+	return Param{Name: name, In: "query", Type: typ, Description: description}
+}
+
+// Info is the document's info object.
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Document is the subset of OpenAPI 3.1 this API needs.
+type Document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       Info                            `json:"info"`
+	Paths      map[string]map[string]Operation `json:"paths"`
+	Components Components                      `json:"components"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme"`
+}
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Build assembles the document from every package's routes. It panics on a
+// duplicate (method, path): that's a programming error caught by tests.
+func Build(info Info, groups ...[]Route) *Document {
+	doc := &Document{
+		OpenAPI: specVersion,
+		Info:    info,
+		Paths:   map[string]map[string]Operation{},
+		Components: Components{
+			Schemas:         map[string]*Schema{},
+			SecuritySchemes: map[string]SecurityScheme{"clerk": {Type: "http", Scheme: "bearer"}},
+		},
+	}
+	reg := newRegistry(doc.Components.Schemas)
+	for _, routes := range groups {
+		for _, rt := range routes {
+			method := strings.ToLower(rt.Method)
+			if doc.Paths[rt.Path] == nil {
+				doc.Paths[rt.Path] = map[string]Operation{}
+			}
+			if _, dup := doc.Paths[rt.Path][method]; dup {
+				panic("openapi: duplicate route " + rt.Method + " " + rt.Path)
+			}
+			doc.Paths[rt.Path][method] = reg.operation(rt)
+		}
+	}
+	return doc
+}
+
+// Has reports whether the document describes method on path (chi pattern).
+func (d *Document) Has(method, path string) bool {
+	_, ok := d.Paths[path][strings.ToLower(method)]
+	return ok
+}
+
+// Operations lists "METHOD path" for every documented operation, sorted.
+func (d *Document) Operations() []string {
+	var out []string
+	for path, ops := range d.Paths {
+		for method := range ops {
+			out = append(out, strings.ToUpper(method)+" "+path)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Handler serves the document. It's marshalled once, up front.
+func Handler(doc *Document) http.HandlerFunc {
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		panic("openapi: marshal document: " + err.Error())
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(raw)
+	}
+}
+
+func (reg *registry) operation(rt Route) Operation {
+	op := Operation{
+		OperationID: operationID(rt.Method, rt.Path),
+		Summary:     rt.Summary,
+		Description: rt.Description,
+		Responses:   map[string]Response{},
+	}
+	if rt.Tag != "" {
+		op.Tags = []string{rt.Tag}
+	}
+	for _, p := range rt.Params {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:        p.Name,
+			In:          p.In,
+			Description: p.Description,
+			Required:    p.Required,
+			Schema:      &Schema{Type: p.Type},
+		})
+	}
+	if rt.Cached {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:        "If-None-Match",
+			In:          "header",
+			Description: "ETag of a previous response; unchanged data then gets a 304.",
+			Schema:      &Schema{Type: "string"},
+		})
+	}
+	if rt.Body != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{"application/json": {Schema: reg.schemaOf(rt.Body)}},
+		}
+	}
+	status := rt.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	resp := Response{Description: http.StatusText(status)}
+	if rt.Response != nil {
+		resp.Content = map[string]MediaType{"application/json": {Schema: reg.schemaOf(rt.Response)}}
+	}
+	op.Responses[strconv.Itoa(status)] = resp
+	if rt.Cached {
+		op.Responses["304"] = Response{Description: http.StatusText(http.StatusNotModified)}
+	}
+	problem := map[string]MediaType{"application/problem+json": {Schema: reg.schemaOf(apierror.Problem{})}}
+	op.Responses["default"] = Response{Description: "Error", Content: problem}
+	if rt.Auth {
+		op.Security = []map[string][]string{{"clerk": {}}}
+		op.Responses["401"] = Response{Description: http.StatusText(http.StatusUnauthorized), Content: problem}
+	}
+	return op
+}
+
+// operationID derives a stable id such as getTeamsBySlugActiveRepos from
+// the method and path, which client generators use as function names.
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, seg := range strings.Split(path, "/") {
+		if seg == "" {
+			continue
+		}
+		if strings.HasPrefix(seg, "{") {
+			seg = "by-" + strings.Trim(seg, "{}")
+		}
+		for _, word := range strings.FieldsFunc(seg, func(r rune) bool { return r == '-' || r == '_' || r == '.' }) {
+			b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,110 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type node struct {
+	Name     string     `json:"name"`
+	Parent   *node      `json:"parent"`
+	Children []node     `json:"children,omitempty"`
+	Seen     *time.Time `json:"seen"`
+	Secret   string     `json:"-"`
+}
+
+type envelope struct {
+	node
+	Count int `json:"count"`
+}
+
+func TestBuild_ReflectsSchemas(t *testing.T) {
+	doc := Build(Info{Title: "t", Version: Version}, []Route{
+		{Method: http.MethodGet, Path: "/nodes/{id}", Params: []Param{PathParam("id", "")}, Response: envelope{}, Cached: true},
+		{Method: http.MethodPost, Path: "/nodes", Body: node{}, Response: node{}, Status: http.StatusCreated, Auth: true},
+	})
+
+	env := doc.Components.Schemas["OpenapiEnvelope"]
+	if env == nil {
+		t.Fatalf("envelope schema missing, have %v", keys(doc.Components.Schemas))
+	}
+	// The embedded struct is flattened, json:"-" is dropped.
+	for _, want := range []string{"name", "parent", "children", "seen", "count"} {
+		if env.Properties[want] == nil {
+			t.Errorf("envelope.%s missing", want)
+		}
+	}
+	if env.Properties["Secret"] != nil {
+		t.Error("json:\"-\" field leaked into the schema")
+	}
+	// Recursive pointer resolves to a nullable $ref instead of looping.
+	n := doc.Components.Schemas["OpenapiNode"]
+	if n == nil || len(n.Properties["parent"].OneOf) != 2 || n.Properties["parent"].OneOf[0].Ref != "#/components/schemas/OpenapiNode" {
+		t.Errorf("parent schema = %+v, want nullable ref to OpenapiNode", n.Properties["parent"])
+	}
+	if got := n.Properties["seen"].Type; !equalTypes(got, []string{"string", "null"}) {
+		t.Errorf("seen type = %v, want [string null]", got)
+	}
+
+	post := doc.Paths["/nodes"]["post"]
+	if post.RequestBody == nil || post.Responses["201"].Content == nil || len(post.Security) != 1 {
+		t.Errorf("post op = %+v", post)
+	}
+	get := doc.Paths["/nodes/{id}"]["get"]
+	if get.OperationID != "getNodesById" {
+		t.Errorf("operationId = %q", get.OperationID)
+	}
+	if _, ok := get.Responses["304"]; !ok || get.Parameters[len(get.Parameters)-1].Name != "If-None-Match" {
+		t.Errorf("cached op = %+v, want If-None-Match and a 304", get)
+	}
+	if _, ok := post.Responses["304"]; ok {
+		t.Error("uncached op documents a 304")
+	}
+}
+
+func TestBuild_PanicsOnDuplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic on duplicate route")
+		}
+	}()
+	r := Route{Method: http.MethodGet, Path: "/x"}
+	Build(Info{}, []Route{r}, []Route{r})
+}
+
+func TestHandler_ServesJSON(t *testing.T) {
+	doc := Build(Info{Title: "t", Version: Version}, []Route{{Method: http.MethodGet, Path: "/x", Response: []string{}}})
+	rec := httptest.NewRecorder()
+	Handler(doc).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	var got map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got["openapi"] != "3.1.0" {
+		t.Errorf("openapi = %v", got["openapi"])
+	}
+}
+
+func keys(m map[string]*Schema) []string {
+	var out []string
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
+
+func equalTypes(got any, want []string) bool {
+	g, ok := got.([]string)
+	if !ok || len(g) != len(want) {
+		return false
+	}
+	for i := range g {
+		if g[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
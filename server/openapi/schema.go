@@ -0,0 +1,175 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Schema is a JSON Schema (2020-12, as used by OpenAPI 3.1). Type is a
+// string or, for nullable values, a [type, "null"] pair.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 any                `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+)
+
+// registry turns Go types into schemas, registering named structs once
+// under components/schemas and referencing them with $ref, which also
+// terminates recursive types (User -> PullRequest -> User).
+type registry struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+	owners  map[string]reflect.Type
+}
+
+func newRegistry(schemas map[string]*Schema) *registry {
+	return &registry{schemas: schemas, names: map[reflect.Type]string{}, owners: map[string]reflect.Type{}}
+}
+
+func (reg *registry) schemaOf(v any) *Schema {
+	return reg.schemaFor(reflect.TypeOf(v))
+}
+
+func (reg *registry) schemaFor(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Pointer {
+		return nullable(reg.schemaFor(t.Elem()))
+	}
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawMessageType:
+		return &Schema{}
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		// Custom encoding (e.g. go-github's Timestamp): we can't know the
+		// shape, so accept anything rather than describe it wrongly.
+		return &Schema{}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: reg.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: reg.schemaFor(t.Elem())}
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return reg.structSchema(t)
+		}
+		name := reg.nameFor(t)
+		if _, seen := reg.schemas[name]; !seen {
+			// Placeholder first so self-references resolve to the $ref.
+			reg.schemas[name] = &Schema{}
+			*reg.schemas[name] = *reg.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	return &Schema{}
+}
+
+func (reg *registry) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	reg.addFields(s, t)
+	return s
+}
+
+// addFields follows encoding/json's rules closely enough for our types:
+// json:"-" is skipped, untagged embedded structs are flattened, and fields
+// without omitempty are listed as required.
+func (reg *registry) addFields(s *Schema, t reflect.Type) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				reg.addFields(s, ft)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s.Properties[name] = reg.schemaFor(f.Type)
+		if !strings.Contains(opts, "omitempty") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// nameFor picks a component name: PackageType (ModelsUser, TeamsTeam). When
+// two packages share a name, the parent directory disambiguates
+// (HandlerTeamsTeamsResponse).
+func (reg *registry) nameFor(t reflect.Type) string {
+	if name, ok := reg.names[t]; ok {
+		return name
+	}
+	pkg := t.PkgPath()
+	name := capitalize(path.Base(pkg)) + capitalize(t.Name())
+	if owner, taken := reg.owners[name]; taken && owner != t {
+		name = capitalize(path.Base(path.Dir(pkg))) + name
+	}
+	reg.names[t] = name
+	reg.owners[name] = t
+	return name
+}
+
+func nullable(s *Schema) *Schema {
+	switch typ := s.Type.(type) {
+	case string:
+		return &Schema{Type: []string{typ, "null"}, Format: s.Format, Items: s.Items, Properties: s.Properties,
+			Required: s.Required, AdditionalProperties: s.AdditionalProperties}
+	case nil:
+		if s.Ref != "" {
+			return &Schema{OneOf: []*Schema{s, {Type: "null"}}}
+		}
+	}
+	return s
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
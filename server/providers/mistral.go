@@ -2,11 +2,18 @@ package providers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"time"
+
+	"github.com/samouraiworld/topofgnomes/server/metrics"
+	"github.com/samouraiworld/topofgnomes/server/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
 )
 
 // ChatMessage represents a message in the OpenAI-compatible response format.
@@ -48,7 +55,19 @@ func setHeaders(req *http.Request, apiKey string) {
 	req.Header.Set(contentTypeHeader, applicationJSON)
 }
 
-func callMistralAPI(apiKey, systemPrompt, userPrompt string, outputFormatSchema map[string]interface{}) (string, error) {
+func callMistralAPI(apiKey, systemPrompt, userPrompt string, outputFormatSchema map[string]interface{}) (content string, err error) {
+	var mistralResp MistralResponse
+	start := time.Now()
+	_, span := tracing.Start(context.Background(), "llm.mistral", attribute.String("llm.model", mistralModel))
+	defer func() {
+		metrics.ObserveLLMCall("mistral", mistralModel, time.Since(start), mistralResp.Usage.PromptTokens, mistralResp.Usage.CompletionTokens, err)
+		span.SetAttributes(
+			attribute.Int("llm.usage.prompt_tokens", mistralResp.Usage.PromptTokens),
+			attribute.Int("llm.usage.completion_tokens", mistralResp.Usage.CompletionTokens),
+		)
+		tracing.End(span, err)
+	}()
+
 	url := fmt.Sprintf("%schat/completions", mistralBaseURL)
 
 	body := map[string]interface{}{
@@ -94,11 +113,10 @@ func callMistralAPI(apiKey, systemPrompt, userPrompt string, outputFormatSchema
 	if resp.StatusCode != http.StatusOK {
 		var responseBody bytes.Buffer
 		_, _ = responseBody.ReadFrom(resp.Body)
-		fmt.Printf("Response Body: %s\n", responseBody.String())
+		zap.S().Named("llm").Warnw("unexpected response", "provider", "mistral", "status", resp.StatusCode, "body", responseBody.String())
 		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
-	var mistralResp MistralResponse
 	if err := json.NewDecoder(resp.Body).Decode(&mistralResp); err != nil {
 		return "", fmt.Errorf("failed to decode response: %w", err)
 	}
@@ -107,9 +125,7 @@ func callMistralAPI(apiKey, systemPrompt, userPrompt string, outputFormatSchema
 		return "", errors.New("no valid mistral response message found")
 	}
 
-	content := mistralResp.Choices[0].Message.Content
-
-	return content, nil
+	return mistralResp.Choices[0].Message.Content, nil
 }
 
 func AskMistral(systemPrompt, userPrompt string, outputFormatSchema map[string]interface{}) (string, error) {
@@ -2,11 +2,18 @@ package providers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"time"
+
+	"github.com/samouraiworld/topofgnomes/server/metrics"
+	"github.com/samouraiworld/topofgnomes/server/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
 )
 
 // OpenRouter uses the OpenAI-compatible /chat/completions endpoint.
@@ -26,7 +33,19 @@ var openrouterFreeModels = []string{
 // A single weekly report costs ~$0.001.
 const openrouterPaidModel = "qwen/qwen3-30b-a3b"
 
-func callOpenRouterWithModel(apiKey, model, systemPrompt, userPrompt string, outputFormatSchema map[string]interface{}) (string, error) {
+func callOpenRouterWithModel(apiKey, model, systemPrompt, userPrompt string, outputFormatSchema map[string]interface{}) (content string, err error) {
+	var result MistralResponse
+	start := time.Now()
+	_, span := tracing.Start(context.Background(), "llm.openrouter", attribute.String("llm.model", model))
+	defer func() {
+		metrics.ObserveLLMCall("openrouter", model, time.Since(start), result.Usage.PromptTokens, result.Usage.CompletionTokens, err)
+		span.SetAttributes(
+			attribute.Int("llm.usage.prompt_tokens", result.Usage.PromptTokens),
+			attribute.Int("llm.usage.completion_tokens", result.Usage.CompletionTokens),
+		)
+		tracing.End(span, err)
+	}()
+
 	url := fmt.Sprintf("%schat/completions", openrouterBaseURL)
 
 	body := map[string]interface{}{
@@ -81,7 +100,6 @@ func callOpenRouterWithModel(apiKey, model, systemPrompt, userPrompt string, out
 	}
 
 	// OpenRouter uses the same response format as OpenAI/Mistral
-	var result MistralResponse
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return "", fmt.Errorf("failed to decode response: %w", err)
 	}
@@ -100,18 +118,18 @@ func callOpenRouterAPI(apiKey, systemPrompt, userPrompt string, outputFormatSche
 	for _, model := range openrouterFreeModels {
 		result, err := callOpenRouterWithModel(apiKey, model, systemPrompt, userPrompt, outputFormatSchema)
 		if err == nil {
-			fmt.Printf("[OpenRouter] report generated with free model %s\n", model)
+			zap.S().Named("llm").Infow("report generated", "provider", "openrouter", "model", model)
 			return result, nil
 		}
 		lastErr = err
-		fmt.Printf("[OpenRouter] free model %s failed: %v, trying next...\n", model, err)
+		zap.S().Named("llm").Warnw("free model failed, trying next", "provider", "openrouter", "model", model, "error", err)
 	}
 
 	// Final fallback: low-cost paid model
-	fmt.Printf("[OpenRouter] all free models exhausted, trying paid fallback %s\n", openrouterPaidModel)
+	zap.S().Named("llm").Infow("free models exhausted, trying paid fallback", "provider", "openrouter", "model", openrouterPaidModel)
 	result, err := callOpenRouterWithModel(apiKey, openrouterPaidModel, systemPrompt, userPrompt, outputFormatSchema)
 	if err == nil {
-		fmt.Printf("[OpenRouter] report generated with paid model %s\n", openrouterPaidModel)
+		zap.S().Named("llm").Infow("report generated", "provider", "openrouter", "model", openrouterPaidModel)
 		return result, nil
 	}
 	lastErr = err
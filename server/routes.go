@@ -0,0 +1,174 @@
+package main
+
+import (
+	"net/http"
+
+	clerkhttp "github.com/clerk/clerk-sdk-go/v2/http"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+
+	"github.com/samouraiworld/topofgnomes/server/apicache"
+	"github.com/samouraiworld/topofgnomes/server/configwatch"
+	"github.com/samouraiworld/topofgnomes/server/graph"
+	"github.com/samouraiworld/topofgnomes/server/handler"
+	"github.com/samouraiworld/topofgnomes/server/handler/adminauth"
+	"github.com/samouraiworld/topofgnomes/server/handler/ai"
+	"github.com/samouraiworld/topofgnomes/server/handler/contributor"
+	issueshandler "github.com/samouraiworld/topofgnomes/server/handler/issues"
+	milestoneshandler "github.com/samouraiworld/topofgnomes/server/handler/milestones"
+	repositorieshandler "github.com/samouraiworld/topofgnomes/server/handler/repositories"
+	reviewshandler "github.com/samouraiworld/topofgnomes/server/handler/reviews"
+	searchhandler "github.com/samouraiworld/topofgnomes/server/handler/search"
+	teamshandler "github.com/samouraiworld/topofgnomes/server/handler/teams"
+	topicshandler "github.com/samouraiworld/topofgnomes/server/handler/topics"
+	"github.com/samouraiworld/topofgnomes/server/metrics"
+	"github.com/samouraiworld/topofgnomes/server/openapi"
+	"github.com/samouraiworld/topofgnomes/server/repository"
+	"github.com/samouraiworld/topofgnomes/server/signer"
+	"github.com/samouraiworld/topofgnomes/server/sync"
+	"github.com/samouraiworld/topofgnomes/server/teams"
+	"github.com/samouraiworld/topofgnomes/server/topics"
+)
+
+// routeDeps is everything the HTTP handlers are built from.
+type routeDeps struct {
+	db     *gorm.DB
+	cache  *apicache.Cache
+	teams  teams.Provider
+	topics topics.Provider
+	signer *signer.Signer
+	syncer *sync.Syncer
+	prRepo repository.PullRequestRepository
+	// graphqlMaxCost is the per-query cost limit; <= 0 uses graph.DefaultMaxCost.
+	graphqlMaxCost int
+	// configs are the hot-reloaded config files reported at /config/status.
+	configs []configwatch.Reporter
+	// teamStore is the database roster; nil when teams.yaml is the source.
+	teamStore *teams.Store
+	// adminIDs are the Clerk users allowed on /admin routes.
+	adminIDs []string
+}
+
+// apiSpec is the OpenAPI document served at /openapi.json. Every route
+// registered below must have an entry here; TestRoutesAreDocumented fails
+// otherwise.
+func apiSpec() *openapi.Document {
+	return openapi.Build(
+		openapi.Info{
+			Title:       "gnolove API",
+			Version:     openapi.Version,
+			Description: "Contribution analytics for the gno.land ecosystem.",
+		},
+		handler.OpenAPIRoutes(),
+		ai.OpenAPIRoutes(),
+		contributor.OpenAPIRoutes(),
+		issueshandler.OpenAPIRoutes(),
+		milestoneshandler.OpenAPIRoutes(),
+		repositorieshandler.OpenAPIRoutes(),
+		reviewshandler.OpenAPIRoutes(),
+		searchhandler.OpenAPIRoutes(),
+		teamshandler.OpenAPIRoutes(),
+		topicshandler.OpenAPIRoutes(),
+		graph.OpenAPIRoutes(),
+		[]openapi.Route{{
+			Method: http.MethodGet, Path: "/openapi.json", Tag: "meta",
+			Summary: "This document",
+		}, {
+			Method: http.MethodGet, Path: "/metrics", Tag: "meta",
+			Summary:     "Prometheus metrics",
+			Description: "Server, cache, sync, LLM and webhook metrics in the Prometheus text exposition format.",
+		}, {
+			Method: http.MethodGet, Path: "/config/status", Tag: "meta",
+			Summary:     "Hot-reloaded config files",
+			Description: "When each config file was last loaded, and why its newest version was rejected if it was.",
+			Response:    []configwatch.Status{},
+		}},
+	)
+}
+
+func registerRoutes(router chi.Router, d routeDeps) {
+	router.Get("/openapi.json", openapi.Handler(apiSpec()))
+	router.Method(http.MethodGet, "/metrics", metrics.Handler())
+	router.Get("/config/status", configwatch.Handler(d.configs...))
+
+	router.Get("/teams", teamshandler.HandleGetAll(d.teams))
+	router.Get("/teams/leaderboard", teamshandler.HandleGetLeaderboard(d.db, d.teams, d.cache))
+	router.Get("/teams/compare", teamshandler.HandleGetCompare(d.db, d.teams, d.cache))
+	router.Get("/teams/{slug}", teamshandler.HandleGetBySlug(d.teams))
+	router.Get("/teams/{slug}/active-repos", teamshandler.HandleGetActiveRepos(d.db, d.teams, d.cache))
+	router.Get("/teams/{slug}/team-stats", teamshandler.HandleGetTeamStats(d.db, d.teams, d.cache))
+	router.Get("/teams/{slug}/timeseries", teamshandler.HandleGetTimeseries(d.db, d.teams, d.cache))
+	router.Get("/team-collab", teamshandler.HandleGetTeamCollab(d.db, d.teams, d.cache))
+	router.Get("/graph/reviews", reviewshandler.HandleGetGraph(d.db, d.teams, d.cache))
+
+	router.Group(func(r chi.Router) {
+		r.Use(clerkhttp.WithHeaderAuthorization())
+		r.Use(adminauth.Require(d.adminIDs))
+		r.Post("/admin/teams", teamshandler.HandleCreateTeam(d.teamStore))
+		r.Patch("/admin/teams/{slug}", teamshandler.HandleUpdateTeam(d.teamStore))
+		r.Post("/admin/teams/{slug}/members", teamshandler.HandleAddMember(d.teamStore))
+		r.Delete("/admin/teams/{slug}/members/{login}", teamshandler.HandleRemoveMember(d.teamStore))
+		r.Get("/admin/teams/audit", teamshandler.HandleGetAudit(d.teamStore))
+		r.Get("/admin/teams/export", teamshandler.HandleExport(d.teams))
+		r.Put("/admin/teams/import", teamshandler.HandleImport(d.teamStore))
+	})
+
+	router.Get("/topics", topicshandler.HandleGetAll(d.topics))
+	router.Get("/topics/stats", topicshandler.HandleGetStats(d.db, d.topics, d.teams, d.cache))
+	router.Get("/topics/unclassified/tokens", topicshandler.HandleGetUnclassifiedTokens(d.db, d.cache))
+	router.Get("/topics/{slug}/prs", topicshandler.HandleGetTopicPRs(d.db, d.topics))
+	router.Get("/contributors/cohorts", contributor.HandleGetCohorts(d.db, d.cache))
+
+	router.Get("/repositories", handler.HandleGetRepository(d.db))
+	router.Get("/repositories/ownership", repositorieshandler.HandleListOwnership(d.db, d.cache))
+	router.Get("/repositories/{owner}/{name}/ownership", repositorieshandler.HandleGetOwnership(d.db, d.cache))
+	router.Get("/repositories/{owner}/{name}/health", repositorieshandler.HandleGetHealth(d.db, d.cache))
+	router.Get("/stats", handler.HandleGetUserStats(d.db, d.cache))
+	router.Get("/last-prs", handler.HandleGetLastPrs(d.db, d.cache))
+	router.Get("/users", handler.HandleGetUsers(d.db))
+	router.Get("/users/{address}", handler.HandleGetUser(d.db))
+	router.Get("/issues", issueshandler.HandleGetIssues(d.db))
+	router.Get("/search", searchhandler.HandleSearch(d.db))
+	router.Get("/pull-requests/report", handler.GetPullrequestsReportByDate(d.prRepo))
+	router.Get("/score-factors", handler.HandleGetScoreFactors)
+	router.Get("/milestones/{id}", milestoneshandler.HandleGetByID(d.db, d.cache))
+	router.Get("/repositories/{owner}/{name}/milestones", milestoneshandler.HandleListByRepository(d.db, d.cache))
+	router.Get("/contributors/newest", handler.HandleGetNewestContributors(d.db))
+	router.Get("/github/verify", handler.HandleVerifyGithubAccount(d.signer, d.db))
+	router.Get("/github/oauth/exchange", handler.HandleGetGithubUserAndTokenByCode(d.signer, d.db))
+	router.Get("/contributors/{login}", contributor.HandleGetContributor(d.db))
+	router.Post("/github/link", handler.HandleLink(d.db))
+
+	// Leaderboard webhook endpoints
+	router.Group(func(r chi.Router) {
+		r.Use(clerkhttp.WithHeaderAuthorization())
+		r.Get("/leaderboard-webhooks", handler.HandleGetLeaderboardWebhooks(d.db))
+		r.Post("/leaderboard-webhooks", handler.HandleCreateLeaderboardWebhook(d.db))
+		r.Put("/leaderboard-webhooks/{id}", handler.HandleUpdateLeaderboardWebhook(d.db))
+		r.Delete("/leaderboard-webhooks/{id}", handler.HandleDeleteLeaderboardWebhook(d.db))
+	})
+
+	// ai endpoints
+	router.Get("/ai/report", ai.HandleGetLastReport(d.db, d.cache))
+	router.Get("/ai/report/weekly", ai.HandleGetReportByWeek(d.db, d.cache))
+	router.Post("/ai/report/generate", ai.HandleGenerateReport(d.db, d.cache))
+	router.Post("/ai/report/regenerate", ai.HandleRegenerateReport(d.db, d.cache))
+	router.Get("/ai/reports", ai.HandleGetAllReports(d.db))
+
+	// Onchain package contributions endpoints
+	router.Get("/onchain/packages", handler.HandleGetAllPackages(d.db))
+	router.Get("/onchain/packages/{address}", handler.HandleGetPackagesByUser(d.db, d.cache))
+
+	// Onchain namespace contributions endpoints
+	router.Get("/onchain/namespaces", handler.HandleGetAllNamespaces(d.db))
+	router.Get("/onchain/namespaces/{address}", handler.HandleGetNamespacesByUser(d.db, d.cache))
+	router.Get("/onchain/proposals", handler.HandleGetAllProposals(d.db))
+	router.Get("/onchain/proposals/{id}", handler.HandleGetProposal(d.db, d.cache))
+	router.Get("/onchain/govdao-members", handler.HandleGetGovdaoMembers(d.db, d.cache))
+	router.Get("/onchain/votes/{address}", handler.HandleGetVotesByUser(d.db, d.cache))
+	router.Put("/on-chain/votes", handler.HandleSynchronizeVotes(d.syncer))
+
+	graphqlHandler := graph.Handle(d.db, d.teams, d.topics, d.graphqlMaxCost)
+	router.Get("/graphql", graphqlHandler)
+	router.Post("/graphql", graphqlHandler)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/samouraiworld/topofgnomes/server/db/dbtest"
+	"github.com/samouraiworld/topofgnomes/server/handler/apierror"
+	"github.com/samouraiworld/topofgnomes/server/models"
+	"github.com/samouraiworld/topofgnomes/server/tracing"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newTestRouter(t *testing.T) chi.Router {
+	t.Helper()
+	db := dbtest.Open(t, &models.User{}, &models.Report{})
+	router := chi.NewRouter()
+	registerRoutes(router, routeDeps{db: db})
+	return router
+}
+
+// TestRoutesAreDocumented fails when a route is registered without an
+// OpenAPI entry, or documented without being registered.
+func TestRoutesAreDocumented(t *testing.T) {
+	router := newTestRouter(t)
+	doc := apiSpec()
+
+	registered := map[string]bool{}
+	err := chi.Walk(router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		registered[method+" "+route] = true
+		if !doc.Has(method, route) {
+			t.Errorf("%s %s is registered but missing from the OpenAPI spec", method, route)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk: %v", err)
+	}
+	for _, op := range doc.Operations() {
+		if !registered[op] {
+			t.Errorf("%s is in the OpenAPI spec but not registered", op)
+		}
+	}
+}
+
+func TestOpenAPIEndpoint(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newTestRouter(t).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	var doc struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if doc.OpenAPI != "3.1.0" || doc.Paths["/stats"]["get"] == nil {
+		t.Errorf("unexpected document: openapi=%q, %d paths", doc.OpenAPI, len(doc.Paths))
+	}
+}
+
+// TestErrorsAreProblemJSON pins the error envelope on a few routes that used
+// to answer with a bare 500 or a plain-text body.
+func TestErrorsAreProblemJSON(t *testing.T) {
+	router := newTestRouter(t)
+	tests := []struct {
+		path   string
+		status int
+		code   apierror.Code
+	}{
+		{"/users/g1unknown", http.StatusNotFound, apierror.CodeNotFound},
+		{"/ai/report", http.StatusNotFound, apierror.CodeNotFound},
+		{"/users?limit=abc", http.StatusBadRequest, apierror.CodeInvalidInput},
+		{"/ai/report/weekly?start=yesterday&end=today", http.StatusBadRequest, apierror.CodeInvalidInput},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d (body %s)", tt.path, rec.Code, tt.status, rec.Body.String())
+			continue
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+			t.Errorf("%s: Content-Type = %q", tt.path, ct)
+		}
+		var p apierror.Problem
+		if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+			t.Fatalf("%s: unmarshal %s: %v", tt.path, rec.Body.String(), err)
+		}
+		if p.Code != tt.code || p.Status != tt.status || p.Detail == "" {
+			t.Errorf("%s: problem = %+v", tt.path, p)
+		}
+	}
+}
+
+// TestHandlersTraceQueries checks that handlers run their queries with the
+// request context, so the GORM plugin records them under the request span.
+func TestHandlersTraceQueries(t *testing.T) {
+	exp := tracetest.NewInMemoryExporter()
+	tp := tracing.Install(sdktrace.WithSyncer(exp))
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+
+	db := dbtest.Open(t, &models.User{}, &models.Repository{}, &models.Issue{}, &models.Milestone{},
+		&models.PullRequest{}, &models.Commit{}, &models.Review{}, &models.GnoProposal{}, &models.File{},
+		&models.GnoVote{}, &models.SearchDocument{}, &models.DailyContribution{}, &models.SyncStatus{})
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		t.Fatal(err)
+	}
+	router := chi.NewRouter()
+	router.Use(tracing.Middleware)
+	registerRoutes(router, routeDeps{db: db})
+
+	for _, path := range []string{
+		"/stats",
+		"/issues",
+		"/onchain/proposals",
+		"/repositories",
+		"/search?q=gno",
+		"/milestones/m1",
+		"/repositories/gnolang/gno/health",
+	} {
+		exp.Reset()
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+
+		spans := exp.GetSpans()
+		var server *tracetest.SpanStub
+		for i := range spans {
+			if strings.HasPrefix(spans[i].Name, "GET ") {
+				server = &spans[i]
+			}
+		}
+		if server == nil {
+			t.Errorf("%s: no request span", path)
+			continue
+		}
+		queries := 0
+		for _, s := range spans {
+			if strings.HasPrefix(s.Name, "gorm.") && s.SpanContext.TraceID() == server.SpanContext.TraceID() {
+				queries++
+			}
+		}
+		if queries == 0 {
+			t.Errorf("%s: no query spans in the request trace", path)
+		}
+	}
+}
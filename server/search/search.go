@@ -0,0 +1,268 @@
+// Package search maintains the unified full-text index over PRs, issues,
+// commits, GovDAO proposals and gno packages, and answers ranked queries
+// against it. The syncers call Index after every save so results follow the
+// GitHub and on-chain sync cadence without a separate indexing job.
+package search
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	dbpkg "github.com/samouraiworld/topofgnomes/server/db"
+	"github.com/samouraiworld/topofgnomes/server/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Document kinds, also the `type` of each search result.
+const (
+	KindPullRequest = "pull_request"
+	KindIssue       = "issue"
+	KindCommit      = "commit"
+	KindProposal    = "proposal"
+	KindPackage     = "package"
+)
+
+// Kinds lists every indexed kind in the order /search documents them.
+var Kinds = []string{KindPullRequest, KindIssue, KindCommit, KindProposal, KindPackage}
+
+// Highlight markers wrapped around matched terms in Result.Snippet. The
+// snippet is HTML-escaped first, so these are the only tags it contains.
+const (
+	markOpen  = "<mark>"
+	markClose = "</mark>"
+	// Private-use sentinels handed to FTS5 snippet(), swapped for the real
+	// tags after escaping so titles containing `<` can't inject markup.
+	sentinelOpen  = "\x02"
+	sentinelClose = "\x03"
+	snippetTokens = 16
+	// Title hits weigh 10x body hits in bm25 so a PR titled "storage
+	// deposit" beats a proposal that mentions it once in its description.
+	titleWeight = 10.0
+	bodyWeight  = 1.0
+)
+
+// Result is one ranked hit.
+type Result struct {
+	Type         string     `json:"type"`
+	ID           string     `json:"id"`
+	RepositoryID string     `json:"repositoryID,omitempty"`
+	Title        string     `json:"title"`
+	Snippet      string     `json:"snippet"`
+	URL          string     `json:"url,omitempty"`
+	CreatedAt    *time.Time `json:"createdAt"`
+	Score        float64    `json:"score"`
+}
+
+// Query is a parsed /search request.
+type Query struct {
+	Text         string
+	Kinds        []string
+	Repositories []string
+	Limit        int
+}
+
+func FromPullRequest(pr models.PullRequest) models.SearchDocument {
+	createdAt := pr.CreatedAt
+	return models.SearchDocument{Kind: KindPullRequest, RefID: pr.ID, RepositoryID: pr.RepositoryID, Title: pr.Title, URL: pr.URL, CreatedAt: &createdAt}
+}
+
+func FromIssue(is models.Issue) models.SearchDocument {
+	createdAt := is.CreatedAt
+	return models.SearchDocument{Kind: KindIssue, RefID: is.ID, RepositoryID: is.RepositoryID, Title: is.Title, URL: is.URL, CreatedAt: &createdAt}
+}
+
+func FromCommit(c models.Commit) models.SearchDocument {
+	createdAt := c.CreatedAt
+	return models.SearchDocument{Kind: KindCommit, RefID: c.ID, RepositoryID: c.RepositoryID, Title: c.Title, URL: c.URL, CreatedAt: &createdAt}
+}
+
+func FromProposal(p models.GnoProposal) models.SearchDocument {
+	return models.SearchDocument{Kind: KindProposal, RefID: p.ID, Title: p.Title, Body: p.Description}
+}
+
+func FromPackage(p models.GnoPackage) models.SearchDocument {
+	return models.SearchDocument{Kind: KindPackage, RefID: p.Path, Title: p.Path, URL: "https://" + p.Path}
+}
+
+// Index upserts documents keyed on (kind, ref_id). The FTS triggers pick up
+// the insert/update, so this is the only call the syncers need.
+func Index(db *gorm.DB, docs ...models.SearchDocument) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "kind"}, {Name: "ref_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"repository_id", "title", "body", "url", "created_at"}),
+	}).Create(&docs).Error
+	if err != nil {
+		return fmt.Errorf("index %d search documents: %w", len(docs), err)
+	}
+	return nil
+}
+
+// Backfill indexes every row already present in the source tables. It's
+// idempotent (existing documents are left alone) and runs once when the
+// syncer starts, so databases populated before the index existed catch up.
+func Backfill(db *gorm.DB) error {
+	statements := []string{
+		`INSERT INTO search_documents (kind, ref_id, repository_id, title, body, url, created_at)
+			SELECT '` + KindPullRequest + `', id, repository_id, title, '', url, created_at FROM pull_requests WHERE true
+			ON CONFLICT (kind, ref_id) DO NOTHING`,
+		`INSERT INTO search_documents (kind, ref_id, repository_id, title, body, url, created_at)
+			SELECT '` + KindIssue + `', id, repository_id, title, '', url, created_at FROM issues WHERE true
+			ON CONFLICT (kind, ref_id) DO NOTHING`,
+		`INSERT INTO search_documents (kind, ref_id, repository_id, title, body, url, created_at)
+			SELECT '` + KindCommit + `', id, repository_id, title, '', url, created_at FROM commits WHERE true
+			ON CONFLICT (kind, ref_id) DO NOTHING`,
+		`INSERT INTO search_documents (kind, ref_id, repository_id, title, body, url, created_at)
+			SELECT '` + KindProposal + `', id, '', title, description, '', NULL FROM gno_proposals WHERE true
+			ON CONFLICT (kind, ref_id) DO NOTHING`,
+		`INSERT INTO search_documents (kind, ref_id, repository_id, title, body, url, created_at)
+			SELECT DISTINCT '` + KindPackage + `', path, '', path, '', 'https://' || path, NULL FROM gno_packages WHERE true
+			ON CONFLICT (kind, ref_id) DO NOTHING`,
+	}
+	for _, stmt := range statements {
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("search backfill: %w", err)
+		}
+	}
+	return nil
+}
+
+// Search runs q against the FTS5 index (bm25-ranked, title-weighted) or,
+// when useFTS is false, a LIKE scan ordered by recency.
+func Search(db *gorm.DB, q Query, useFTS bool) ([]Result, error) {
+	type row struct {
+		Kind         string     `gorm:"column:kind"`
+		RefID        string     `gorm:"column:ref_id"`
+		RepositoryID string     `gorm:"column:repository_id"`
+		Title        string     `gorm:"column:title"`
+		Body         string     `gorm:"column:body"`
+		URL          string     `gorm:"column:url"`
+		CreatedAt    *time.Time `gorm:"column:created_at"`
+		Snippet      string     `gorm:"column:snippet"`
+		Rank         float64    `gorm:"column:rank"`
+	}
+
+	var tx *gorm.DB
+	if useFTS {
+		tx = db.Table(dbpkg.SearchFTSTable).
+			Select(fmt.Sprintf(`d.kind, d.ref_id, d.repository_id, d.title, d.body, d.url, d.created_at,
+				snippet(%[1]s, -1, '%[2]s', '%[3]s', '…', %[4]d) AS snippet,
+				bm25(%[1]s, %[5]f, %[6]f) AS rank`,
+				dbpkg.SearchFTSTable, sentinelOpen, sentinelClose, snippetTokens, titleWeight, bodyWeight)).
+			Joins("JOIN search_documents d ON d.id = "+dbpkg.SearchFTSTable+".rowid").
+			Where(dbpkg.SearchFTSTable+" MATCH ?", dbpkg.FTSQuery(q.Text)).
+			Order("rank")
+	} else {
+		like := "%" + strings.ToLower(q.Text) + "%"
+		tx = db.Table("search_documents d").
+			Select("d.kind, d.ref_id, d.repository_id, d.title, d.body, d.url, d.created_at").
+			Where("LOWER(d.title) LIKE ? OR LOWER(d.body) LIKE ?", like, like).
+			Order("d.created_at DESC")
+	}
+	if len(q.Kinds) > 0 {
+		tx = tx.Where("d.kind IN ?", q.Kinds)
+	}
+	if len(q.Repositories) > 0 {
+		tx = tx.Where("d.repository_id IN ?", q.Repositories)
+	}
+
+	var rows []row
+	if err := tx.Limit(q.Limit).Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("search query: %w", err)
+	}
+
+	terms := strings.Fields(strings.ToLower(q.Text))
+	out := make([]Result, 0, len(rows))
+	for _, r := range rows {
+		snippet := r.Snippet
+		if !useFTS {
+			snippet = likeSnippet(r.Title, r.Body, terms)
+		}
+		out = append(out, Result{
+			Type:         r.Kind,
+			ID:           r.RefID,
+			RepositoryID: r.RepositoryID,
+			Title:        r.Title,
+			Snippet:      markup(snippet),
+			URL:          r.URL,
+			CreatedAt:    r.CreatedAt,
+			// bm25 is "lower is better"; flip it so clients sort descending.
+			Score: -r.Rank,
+		})
+	}
+	return out, nil
+}
+
+// markup escapes a sentinel-delimited snippet and turns the sentinels into
+// <mark> tags.
+func markup(s string) string {
+	s = html.EscapeString(s)
+	s = strings.ReplaceAll(s, sentinelOpen, markOpen)
+	return strings.ReplaceAll(s, sentinelClose, markClose)
+}
+
+// likeSnippet is the FTS-less stand-in for snippet(): it picks the title
+// when it matches (the body otherwise), trims it around the first hit and
+// wraps every term occurrence in sentinels.
+func likeSnippet(title, body string, terms []string) string {
+	text := title
+	if !containsAny(strings.ToLower(title), terms) && body != "" {
+		text = body
+	}
+	const window = 120
+	if len(text) > window {
+		lower := strings.ToLower(text)
+		start := 0
+		for _, t := range terms {
+			if i := strings.Index(lower, t); i >= 0 {
+				start = max(0, i-window/4)
+				break
+			}
+		}
+		end := min(len(text), start+window)
+		prefix, suffix := "", ""
+		if start > 0 {
+			prefix = "…"
+		}
+		if end < len(text) {
+			suffix = "…"
+		}
+		text = prefix + strings.ToValidUTF8(text[start:end], "") + suffix
+	}
+	return wrapTerms(text, terms)
+}
+
+func containsAny(s string, terms []string) bool {
+	for _, t := range terms {
+		if strings.Contains(s, t) {
+			return true
+		}
+	}
+	return false
+}
+
+func wrapTerms(text string, terms []string) string {
+	lower := strings.ToLower(text)
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		matched := 0
+		for _, t := range terms {
+			if t != "" && strings.HasPrefix(lower[i:], t) && len(t) > matched {
+				matched = len(t)
+			}
+		}
+		if matched > 0 {
+			b.WriteString(sentinelOpen + text[i:i+matched] + sentinelClose)
+			i += matched
+			continue
+		}
+		b.WriteByte(text[i])
+		i++
+	}
+	return b.String()
+}
@@ -1,12 +1,14 @@
 package signer
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/gnolang/gno/gno.land/pkg/gnoclient"
 	"github.com/gnolang/gno/gno.land/pkg/sdk/vm"
 	rpcclient "github.com/gnolang/gno/tm2/pkg/bft/rpc/client"
 	"github.com/gnolang/gno/tm2/pkg/crypto/keys"
+	"github.com/samouraiworld/topofgnomes/server/logging"
 	"github.com/samouraiworld/topofgnomes/server/models"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
@@ -54,7 +56,7 @@ func New(
 
 	return &Signer{
 		db:                db,
-		logger:            logger,
+		logger:            logger.Named("signer"),
 		keyInfo:           keyInfo,
 		ghVerifyRealmPath: ghVerifyRealmPath,
 		govDAORealmPath:   govDAORealmPath,
@@ -62,7 +64,13 @@ func New(
 	}
 }
 
-func (s *Signer) CallVerify(address string, login string) error {
+// log is the logger for work done under ctx: the request's logger when
+// called from a handler, s.logger otherwise.
+func (s *Signer) log(ctx context.Context) *zap.SugaredLogger {
+	return logging.FromContextOr(ctx, s.logger)
+}
+
+func (s *Signer) CallVerify(ctx context.Context, address string, login string) error {
 	acc, _, err := s.gnoclient.QueryAccount(s.keyInfo.GetAddress())
 	if err != nil {
 		return fmt.Errorf("failed to query account: %w", err)
@@ -77,7 +85,7 @@ func (s *Signer) CallVerify(address string, login string) error {
 	}
 
 	arg := "ingest," + address + ",OK"
-	s.logger.Infof("Calling gnoclient withPath: %s and address %s", s.ghVerifyRealmPath, address)
+	s.log(ctx).Infow("calling realm", "path", s.ghVerifyRealmPath, "address", address)
 	_, err = s.gnoclient.Call(baseCfg, vm.MsgCall{
 		Caller:  s.keyInfo.GetAddress(),
 		Send:    nil,
@@ -92,12 +100,12 @@ func (s *Signer) CallVerify(address string, login string) error {
 	return s.db.Model(&models.User{}).Where("login = ?", login).Update("wallet", address).Error
 }
 
-func (s *Signer) ClaimTier(login string) error {
+func (s *Signer) ClaimTier(ctx context.Context, login string) error {
 	acc, _, err := s.gnoclient.QueryAccount(s.keyInfo.GetAddress())
 	if err != nil {
 		return fmt.Errorf("failed to query account: %w", err)
 	}
-	s.logger.Infof("Calling gnoclient withPath: %s and address %s", s.govDAORealmPath)
+	s.log(ctx).Infow("calling realm", "path", s.govDAORealmPath, "login", login)
 	baseCfg := gnoclient.BaseTxCfg{
 		GasFee:         "1000000ugnot",
 		GasWanted:      50000000,
@@ -115,7 +123,7 @@ func (s *Signer) ClaimTier(login string) error {
 	})
 	if err != nil {
 		//Just log the error can be just that the user does not have a tier
-		s.logger.Errorf("failed to call gnoclient: %s", err.Error())
+		s.log(ctx).Warnw("ClaimTier failed", "login", login, "error", err)
 	}
 
 	return nil
@@ -12,7 +12,8 @@ import (
 // defaultSyncWorkers is the per-cycle concurrent repository count when the
 // SYNC_WORKERS env var is unset. Tuned to four — high enough to amortise
 // GitHub round-trip latency across ~50 repos, low enough to keep sqlite
-// writer contention bounded.
+// writer contention bounded. PostgreSQL (DATABASE_URL) has no single
+// writer, so deployments on it can raise SYNC_WORKERS.
 const defaultSyncWorkers = 4
 
 // syncWorkerCount reads SYNC_WORKERS, clamped to [1, 16]. Out-of-range or
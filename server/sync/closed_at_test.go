@@ -0,0 +1,40 @@
+package sync
+
+import (
+	"testing"
+	"time"
+
+	"github.com/samouraiworld/topofgnomes/server/db/dbtest"
+	"github.com/samouraiworld/topofgnomes/server/models"
+)
+
+func TestGetLastUpdatedIssue_BackfillsOnce(t *testing.T) {
+	db := dbtest.Open(t, &models.Repository{}, &models.Issue{})
+	updated := time.Date(2026, 5, 18, 0, 0, 0, 0, time.UTC)
+	if err := db.Create(&models.Repository{ID: "o/r", Owner: "o", Name: "r"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	// Closed before closed_at was synced, and gone from GitHub since.
+	if err := db.Create(&models.Issue{ID: "i1", RepositoryID: "o/r", State: "CLOSED", UpdatedAt: updated}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if got := getLastUpdatedIssue(*db, "o/r"); !got.IsZero() {
+		t.Fatalf("before the full pass: %v, want zero", got)
+	}
+	s := &Syncer{db: db}
+	if err := s.markClosedAtSynced("o/r", "issues_closed_at_synced"); err != nil {
+		t.Fatal(err)
+	}
+	if got := getLastUpdatedIssue(*db, "o/r"); !got.Equal(updated) {
+		t.Errorf("after the full pass: %v, want %v", got, updated)
+	}
+
+	// The config save at start keeps the flag.
+	if err := db.Omit(syncedRepositoryColumns...).Save(&models.Repository{ID: "o/r", Owner: "o", Name: "r"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !closedAtSynced(*db, "o/r", "issues_closed_at_synced") {
+		t.Error("saving the configured repository cleared the flag")
+	}
+}
@@ -7,13 +7,20 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/samouraiworld/topofgnomes/server/apicache"
 	"github.com/samouraiworld/topofgnomes/server/gnoindexerql"
+	"github.com/samouraiworld/topofgnomes/server/metrics"
 	"github.com/samouraiworld/topofgnomes/server/models"
+	"github.com/samouraiworld/topofgnomes/server/search"
+	"github.com/samouraiworld/topofgnomes/server/tracing"
+	"go.opentelemetry.io/otel/attribute"
 	"gorm.io/gorm"
+
+	ctypes "github.com/gnolang/gno/tm2/pkg/bft/rpc/core/types"
 )
 
 func (s *Syncer) syncGnoUserRegistrations(ctx context.Context) error {
-	s.logger.Info("Syncing GnoUserRegistrations")
+	s.log(ctx).Info("Syncing GnoUserRegistrations")
 	lastBlock := getRegistrationsLastBlock(s.db)
 	response, err := gnoindexerql.GetUserRegistrations(ctx, s.graphqlClient, int(lastBlock))
 	if err != nil {
@@ -26,7 +33,7 @@ func (s *Syncer) syncGnoUserRegistrations(ctx context.Context) error {
 				continue
 			}
 			if len(msgCall.Args) < 1 {
-				s.logger.Warnf("invalid args %s", msg.Value)
+				s.log(ctx).Warnf("invalid args %s", msg.Value)
 				continue
 			}
 			namespace := &models.GnoNamespace{
@@ -45,6 +52,26 @@ func (s *Syncer) syncGnoUserRegistrations(ctx context.Context) error {
 	return nil
 }
 
+// recordChainLag publishes how far each on-chain stream is behind the
+// chain head. Streams are tracked by their newest stored event, so a stream
+// with no recent activity (proposals, votes) shows a growing lag even when
+// fully synced.
+func (s *Syncer) recordChainLag(ctx context.Context) {
+	_, span := tracing.Start(ctx, "gno.Status")
+	status, err := s.rpcClient.Status()
+	tracing.End(span, err)
+	if err != nil {
+		s.log(ctx).Warnf("failed to get chain status: %v", err)
+		return
+	}
+	head := status.SyncInfo.LatestBlockHeight
+	metrics.SetChainHeight(head)
+	metrics.SetChainSyncBlock("registrations", getRegistrationsLastBlock(s.db), head)
+	metrics.SetChainSyncBlock("packages", getPublishedPackagesLastBlock(s.db), head)
+	metrics.SetChainSyncBlock("proposals", getProposalsLastBlock(s.db), head)
+	metrics.SetChainSyncBlock("votes", getVotesLastBlock(s.db), head)
+}
+
 func getRegistrationsLastBlock(db *gorm.DB) int64 {
 	var lastRegistration models.GnoNamespace
 	db.Model(&lastRegistration).Order("block_height desc").First(&lastRegistration)
@@ -52,7 +79,7 @@ func getRegistrationsLastBlock(db *gorm.DB) int64 {
 }
 
 func (s *Syncer) syncPublishedPackages(ctx context.Context) error {
-	s.logger.Info("Syncing PublishedPackages")
+	s.log(ctx).Info("Syncing PublishedPackages")
 	lastBlock := getPublishedPackagesLastBlock(s.db)
 	response, err := gnoindexerql.GetPublishedPackages(ctx, s.graphqlClient, int(lastBlock))
 	if err != nil {
@@ -64,7 +91,7 @@ func (s *Syncer) syncPublishedPackages(ctx context.Context) error {
 			addPkg := msg.Value.(*gnoindexerql.GetPublishedPackagesTransactionsTransactionMessagesTransactionMessageValueMsgAddPackage)
 			pathParts := strings.Split(addPkg.Package.Path, "/")
 			if len(pathParts) < 2 {
-				s.logger.Warnf("invalid path %s", addPkg.Package.Path)
+				s.log(ctx).Warnf("invalid path %s", addPkg.Package.Path)
 				continue
 			}
 			namespace := &models.GnoPackage{
@@ -78,6 +105,9 @@ func (s *Syncer) syncPublishedPackages(ctx context.Context) error {
 			if err != nil {
 				return err
 			}
+			if err := search.Index(s.db, search.FromPackage(*namespace)); err != nil {
+				s.log(ctx).Errorf("error while indexing package %s for search: %s", namespace.Path, err.Error())
+			}
 		}
 	}
 	return nil
@@ -90,7 +120,7 @@ func getPublishedPackagesLastBlock(db *gorm.DB) int64 {
 }
 
 func (s *Syncer) syncProposals(ctx context.Context) error {
-	s.logger.Info("Syncing Proposals")
+	s.log(ctx).Info("Syncing Proposals")
 	lastBlock := getProposalsLastBlock(s.db)
 	response, err := gnoindexerql.GetGovDAOProposals(ctx, s.graphqlClient, int(lastBlock))
 	if err != nil {
@@ -120,7 +150,7 @@ func (s *Syncer) syncProposals(ctx context.Context) error {
 					GnoProposalID: proposalID,
 				})
 			}
-			title, description, err := s.getProposalTitleAndDescription(proposalID)
+			title, description, err := s.getProposalTitleAndDescription(ctx, proposalID)
 			if err != nil {
 				return fmt.Errorf("failed to get proposal title and description: %w", err)
 			}
@@ -139,6 +169,9 @@ func (s *Syncer) syncProposals(ctx context.Context) error {
 			if err != nil {
 				return err
 			}
+			if err := search.Index(s.db, search.FromProposal(*proposal)); err != nil {
+				s.log(ctx).Errorf("error while indexing proposal %s for search: %s", proposal.ID, err.Error())
+			}
 		}
 	}
 
@@ -155,7 +188,7 @@ func (s *Syncer) syncProposals(ctx context.Context) error {
 				continue
 			}
 			if len(msgRunValue.Args) != 1 {
-				s.logger.Errorf("invalid args length %d for tx %s and block %d", len(msgRunValue.Args), execution.Hash, execution.Block_height)
+				s.log(ctx).Errorf("invalid args length %d for tx %s and block %d", len(msgRunValue.Args), execution.Hash, execution.Block_height)
 				continue
 			}
 			err = s.db.Model(&models.GnoProposal{}).Where("id = ?", msgRunValue.Args[0]).Updates(map[string]interface{}{
@@ -172,8 +205,8 @@ func (s *Syncer) syncProposals(ctx context.Context) error {
 	return nil
 }
 
-func (s *Syncer) getProposalTitleAndDescription(proposalID string) (string, string, error) {
-	titleData, err := s.rpcClient.ABCIQuery("vm/qeval", []byte(fmt.Sprintf("gno.land/r/gov/dao.MustGetProposal(cross,%s).Title()", proposalID)))
+func (s *Syncer) getProposalTitleAndDescription(ctx context.Context, proposalID string) (string, string, error) {
+	titleData, err := s.abciQuery(ctx, "vm/qeval", []byte(fmt.Sprintf("gno.land/r/gov/dao.MustGetProposal(cross,%s).Title()", proposalID)))
 	if err != nil {
 		return "", "", err
 	}
@@ -183,7 +216,7 @@ func (s *Syncer) getProposalTitleAndDescription(proposalID string) (string, stri
 		return "", "", err
 	}
 
-	descriptionData, err := s.rpcClient.ABCIQuery("vm/qeval", []byte(fmt.Sprintf("gno.land/r/gov/dao.MustGetProposal(cross,%s).Description()", proposalID)))
+	descriptionData, err := s.abciQuery(ctx, "vm/qeval", []byte(fmt.Sprintf("gno.land/r/gov/dao.MustGetProposal(cross,%s).Description()", proposalID)))
 	if err != nil {
 		return "", "", err
 	}
@@ -196,6 +229,17 @@ func (s *Syncer) getProposalTitleAndDescription(proposalID string) (string, stri
 	return title, description, nil
 }
 
+// abciQuery is rpcClient.ABCIQuery wrapped in a span.
+func (s *Syncer) abciQuery(ctx context.Context, path string, data []byte) (*ctypes.ResultABCIQuery, error) {
+	_, span := tracing.Start(ctx, "gno.ABCIQuery",
+		attribute.String("gno.query.path", path),
+		attribute.String("gno.query.data", string(data)),
+	)
+	res, err := s.rpcClient.ABCIQuery(path, data)
+	tracing.End(span, err)
+	return res, err
+}
+
 func extractGnoStringResponse(res string) (string, error) {
 	// Remove '(' and 'string)' from effective response
 	res = strings.TrimPrefix(res, "(")
@@ -205,7 +249,7 @@ func extractGnoStringResponse(res string) (string, error) {
 }
 
 func (s *Syncer) SyncVotesOnProposals(ctx context.Context) (bool, error) {
-	s.logger.Info("Syncing Votes on Proposals")
+	s.log(ctx).Info("Syncing Votes on Proposals")
 	lastBlock := getVotesLastBlock(s.db)
 	response, err := gnoindexerql.GetGovDAOProposalsVotes(ctx, s.graphqlClient, int(lastBlock+1))
 	if err != nil {
@@ -221,7 +265,7 @@ func (s *Syncer) SyncVotesOnProposals(ctx context.Context) (bool, error) {
 			}
 
 			if len(msgRunValue.Args) != 2 {
-				s.logger.Errorf("invalid args length %d for tx %s and block %d", len(msgRunValue.Args), transaction.Hash, transaction.Block_height)
+				s.log(ctx).Errorf("invalid args length %d for tx %s and block %d", len(msgRunValue.Args), transaction.Hash, transaction.Block_height)
 				continue
 			}
 
@@ -240,7 +284,11 @@ func (s *Syncer) SyncVotesOnProposals(ctx context.Context) (bool, error) {
 		}
 	}
 
-	return len(response.GetTransactions) > 0, nil
+	if len(response.GetTransactions) == 0 {
+		return false, nil
+	}
+	s.cache.Bump(apicache.Onchain)
+	return true, nil
 }
 func getProposalCreatedEvent(events []gnoindexerql.GetGovDAOProposalsGetTransactionsTransactionResponseEventsEvent) (*gnoindexerql.GetGovDAOProposalsGetTransactionsTransactionResponseEventsGnoEvent, error) {
 	for _, event := range events {
@@ -282,8 +330,8 @@ func getAttrKey(attributs []gnoindexerql.GetGovDAOProposalsGetTransactionsTransa
 	return ""
 }
 
-func (s *Syncer) syncGovDaoMembers() error {
-	s.logger.Info("Syncing Gov Dao Members")
+func (s *Syncer) syncGovDaoMembers(ctx context.Context) error {
+	s.log(ctx).Info("Syncing Gov Dao Members")
 	allMembers := []models.GovDaoMember{}
 	err := s.db.Model(&models.GovDaoMember{}).Find(&allMembers).Error
 	if err != nil {
@@ -302,7 +350,7 @@ func (s *Syncer) syncGovDaoMembers() error {
 
 	page := 1
 	for {
-		membersData, err := s.rpcClient.ABCIQuery("vm/qeval", []byte(fmt.Sprintf(`gno.land/r/gov/dao/v3/memberstore.Render("members?page=%d")`, page)))
+		membersData, err := s.abciQuery(ctx, "vm/qeval", []byte(fmt.Sprintf(`gno.land/r/gov/dao/v3/memberstore.Render("members?page=%d")`, page)))
 		if err != nil {
 			return err
 		}
@@ -310,7 +358,7 @@ func (s *Syncer) syncGovDaoMembers() error {
 		members := regex.FindAllStringSubmatch(string(membersData.Response.Data), -1)
 		for _, member := range members {
 			if len(member) < 3 {
-				s.logger.Errorf("invalid member %s", member)
+				s.log(ctx).Errorf("invalid member %s", member)
 				continue
 			}
 
@@ -5,12 +5,19 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	stdsync "sync"
 	"time"
 
 	"github.com/Khan/genqlient/graphql"
 	"github.com/robfig/cron/v3"
+	"github.com/samouraiworld/topofgnomes/server/apicache"
+	"github.com/samouraiworld/topofgnomes/server/contributions"
 	"github.com/samouraiworld/topofgnomes/server/handler/ai"
+	"github.com/samouraiworld/topofgnomes/server/logging"
 	"github.com/samouraiworld/topofgnomes/server/models"
+	"github.com/samouraiworld/topofgnomes/server/search"
+	"github.com/samouraiworld/topofgnomes/server/topics"
+	"github.com/samouraiworld/topofgnomes/server/tracing"
 	"github.com/shurcooL/githubv4"
 	"go.uber.org/zap"
 	"golang.org/x/oauth2"
@@ -27,13 +34,20 @@ type Syncer struct {
 	logger        *zap.SugaredLogger
 	graphqlClient graphql.Client
 	rpcClient     *rpcclient.RPCClient
+	// cache is told when a pass has written new data; nil in tests.
+	cache *apicache.Cache
+	// topics classifies the pull requests as they're saved.
+	topics topics.Provider
+	// reclassifying serializes ReclassifyTopics runs.
+	reclassifying stdsync.Mutex
 }
 
-func NewSyncer(db *gorm.DB, repositories []models.Repository, logger *zap.SugaredLogger) *Syncer {
+func NewSyncer(db *gorm.DB, repositories []models.Repository, taxonomy topics.Provider, cache *apicache.Cache, logger *zap.SugaredLogger) *Syncer {
 	src := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: os.Getenv("GITHUB_API_TOKEN")},
 	)
 	httpClient := oauth2.NewClient(context.Background(), src)
+	httpClient.Transport = tracing.Transport("github.graphql", httpClient.Transport)
 	client := githubv4.NewClient(httpClient)
 	gqlClient := graphql.NewClient(os.Getenv("GNO_GRAPHQL_ENDPOINT"), nil)
 	rpcClient, err := rpcclient.NewHTTPClient(os.Getenv("GNO_RPC_ENDPOINT"))
@@ -45,12 +59,38 @@ func NewSyncer(db *gorm.DB, repositories []models.Repository, logger *zap.Sugare
 		db:            db,
 		client:        client,
 		repositories:  repositories,
-		logger:        logger,
+		logger:        logger.Named("sync"),
 		graphqlClient: gqlClient,
 		rpcClient:     rpcClient,
+		cache:         cache,
+		topics:        taxonomy,
 	}
 }
 
+// log is the logger for work done under ctx: the request's logger when a
+// handler triggered it, s.logger otherwise.
+func (s *Syncer) log(ctx context.Context) *zap.SugaredLogger {
+	return logging.FromContextOr(ctx, s.logger)
+}
+
+// syncedRepositoryColumns are the repositories columns the syncer fills,
+// which saving the configured repositories must leave alone.
+var syncedRepositoryColumns = []string{"LatestReleaseTag", "LatestReleaseAt", "IssuesClosedAtSynced", "MilestonesClosedAtSynced"}
+
+// closedAtSynced reads one of the repository's closed_at backfill flags.
+// Rows GitHub no longer returns keep a NULL closed_at, so the flag, not the
+// rows, records that the full pass ran.
+func closedAtSynced(db gorm.DB, repositoryID, column string) bool {
+	var synced []bool
+	db.Model(&models.Repository{}).Where("id = ?", repositoryID).Pluck(column, &synced)
+	return len(synced) == 0 || synced[0]
+}
+
+// markClosedAtSynced sets a flag closedAtSynced reads, after a full pass.
+func (s *Syncer) markClosedAtSynced(repositoryID, column string) error {
+	return s.db.Model(&models.Repository{}).Where("id = ?", repositoryID).Update(column, true).Error
+}
+
 func getLastUpdatedPR(db gorm.DB, repositoryID string) time.Time {
 	var lastPR models.PullRequest
 	db.Model(&lastPR).Where("repository_id = ?", repositoryID).Order("updated_at desc").First(&lastPR)
@@ -58,12 +98,24 @@ func getLastUpdatedPR(db gorm.DB, repositoryID string) time.Time {
 }
 
 func getLastUpdatedIssue(db gorm.DB, repositoryID string) time.Time {
+	// Closed issues synced before closed_at existed need one full pass so the
+	// milestone burndown can place them on the right day.
+	if !closedAtSynced(db, repositoryID, "issues_closed_at_synced") {
+		return time.Time{}
+	}
+
 	var lastIssue models.Issue
 	db.Model(&lastIssue).Where("repository_id = ?", repositoryID).Order("updated_at desc").First(&lastIssue)
 	return lastIssue.UpdatedAt
 }
 
 func getLastUpdatedMilestone(db gorm.DB, repositoryID string) time.Time {
+	// Same backfill rule as issues: closed milestones without closed_at were
+	// synced before dueOn/closedAt were fetched.
+	if !closedAtSynced(db, repositoryID, "milestones_closed_at_synced") {
+		return time.Time{}
+	}
+
 	var lastMilestone models.Milestone
 	db.Model(&lastMilestone).Where("repository_id = ?", repositoryID).Order("updated_at desc").First(&lastMilestone)
 	return lastMilestone.UpdatedAt
@@ -71,17 +123,33 @@ func getLastUpdatedMilestone(db gorm.DB, repositoryID string) time.Time {
 
 func (s *Syncer) StartSynchonizing(ctx context.Context) error {
 	for _, repository := range s.repositories {
-		err := s.db.Save(&repository).Error
+		err := s.db.Omit(syncedRepositoryColumns...).Save(&repository).Error
 		if err != nil {
 			return err
 		}
 	}
+	// Rows synced before the search index existed are only picked up here;
+	// from then on every save below re-indexes its own document.
+	if err := search.Backfill(s.db); err != nil {
+		s.logger.Errorf("error while backfilling search index %s", err.Error())
+	}
+	if err := contributions.Backfill(s.db); err != nil {
+		s.logger.Errorf("error while backfilling daily contributions %s", err.Error())
+	}
+	// topics.yaml may have changed while the server was down.
+	if n, err := s.ReclassifyTopics(ctx); err != nil {
+		s.logger.Errorf("error while classifying pull requests, %d classified before it %s", n, err.Error())
+	}
 	go func() {
 		ticker := time.NewTicker(2 * time.Hour)
 		defer ticker.Stop()
 		for {
 			s.syncRepositoriesConcurrently(ctx)
 
+			if err := s.backfillPRFiles(ctx); err != nil {
+				s.logger.Errorf("error while backfilling pull request files %s", err.Error())
+			}
+
 			// For some reason github api doesn't return all users. so we have to sync them manually
 			// by taking the ids from pull requests and issues without a corresponding ID on users table
 			err := s.syncRemaningUsers()
@@ -104,6 +172,7 @@ func (s *Syncer) StartSynchonizing(ctx context.Context) error {
 			if err != nil {
 				s.logger.Errorf("Failed to persist last synced time: %v", err)
 			}
+			s.cache.Bump(apicache.GitHub)
 
 			select {
 			case <-ctx.Done():
@@ -146,36 +215,158 @@ func (s *Syncer) StartSynchonizing(ctx context.Context) error {
 	return nil
 }
 
+// syncRelease stores the repository's latest release.
+func (s *Syncer) syncRelease(ctx context.Context, repository models.Repository) error {
+	var q struct {
+		Repository struct {
+			LatestRelease *struct {
+				TagName     string
+				PublishedAt *time.Time
+			}
+		} `graphql:"repository(owner: $owner, name: $name)"`
+	}
+	variables := map[string]interface{}{
+		"owner": githubv4.String(repository.Owner),
+		"name":  githubv4.String(repository.Name),
+	}
+	if err := s.client.Query(ctx, &q, variables); err != nil {
+		return err
+	}
+	repository.LatestReleaseTag, repository.LatestReleaseAt = "", nil
+	if r := q.Repository.LatestRelease; r != nil {
+		repository.LatestReleaseTag, repository.LatestReleaseAt = r.TagName, r.PublishedAt
+	}
+	return s.db.Model(&repository).Select("LatestReleaseTag", "LatestReleaseAt").Updates(&repository).Error
+}
+
+// filesBackfillPerPass bounds how many pull requests synced before their
+// files and labels were get them in one sync pass.
+const filesBackfillPerPass = 2000
+
+// backfillPRFiles fetches the files and labels of pull requests synced
+// before they were, which syncPRs won't revisit until they change, and
+// classifies them again with them.
+func (s *Syncer) backfillPRFiles(ctx context.Context) error {
+	for done := 0; done < filesBackfillPerPass; {
+		var prs []models.PullRequest
+		err := s.db.Select("id, repository_id, title").
+			Where("files_synced = ?", false).
+			Order("id").
+			Limit(50).
+			Find(&prs).Error
+		if err != nil {
+			return err
+		}
+		if len(prs) == 0 {
+			return nil
+		}
+		ids := make([]githubv4.ID, len(prs))
+		for i, pr := range prs {
+			ids[i] = githubv4.ID(pr.ID)
+		}
+		var q struct {
+			Nodes []struct {
+				PullRequest struct {
+					ID string
+					pullRequestFiles
+				} `graphql:"... on PullRequest"`
+			} `graphql:"nodes(ids: $ids)"`
+		}
+		if err := s.client.Query(ctx, &q, map[string]interface{}{"ids": ids}); err != nil {
+			return err
+		}
+		fetched := map[string]pullRequestFiles{}
+		for _, n := range q.Nodes {
+			fetched[n.PullRequest.ID] = n.PullRequest.pullRequestFiles
+		}
+
+		// PRs GitHub no longer returns are marked synced too, without files,
+		// so they aren't asked for again.
+		cfg := s.topics()
+		for _, pr := range prs {
+			files, labels := fetched[pr.ID].models(pr.ID)
+			pr.Labels = labels
+			matches := cfg.Rank(topics.SignalsOf(pr, paths(files)))
+			pr.Topic = topics.Primary(matches)
+			pr.FilesSynced = true
+			err := s.db.Transaction(func(tx *gorm.DB) error {
+				err := tx.Model(&pr).Select("labels", "files_synced", "topic").UpdateColumns(pr).Error
+				if err != nil {
+					return err
+				}
+				if err := replaceFiles(tx, pr.ID, files); err != nil {
+					return err
+				}
+				return topics.SaveMatches(tx, pr.ID, matches)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		done += len(prs)
+		s.logger.Infof("backfilled files of %d pull requests", len(prs))
+	}
+	return nil
+}
+
+func replaceFiles(tx *gorm.DB, prID string, files []models.PullRequestFile) error {
+	if err := tx.Where("pull_request_id = ?", prID).Delete(&models.PullRequestFile{}).Error; err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	return tx.Create(files).Error
+}
+
+// ReclassifyTopics brings the stored topic of every pull request in line
+// with the current topics.yaml. It returns how many pull requests changed;
+// on error, those of the batches committed before it.
+func (s *Syncer) ReclassifyTopics(ctx context.Context) (int, error) {
+	s.reclassifying.Lock()
+	defer s.reclassifying.Unlock()
+	n, err := topics.Reclassify(s.db.WithContext(ctx), s.topics())
+	if err == nil && n > 0 {
+		s.logger.Infof("reclassified %d pull requests", n)
+	}
+	return n, err
+}
+
 func (s *Syncer) StartSynchonizingChain(ctx context.Context) error {
 	go func() {
 		ticker := time.NewTicker(time.Minute)
 		defer ticker.Stop()
 		for {
-			err := s.syncGnoUserRegistrations(ctx)
+			passCtx, span := tracing.Start(ctx, "sync.onchain")
+			err := s.syncGnoUserRegistrations(passCtx)
 			if err != nil {
 				s.logger.Errorf("error while syncing gno user registrations %s", err.Error())
 			}
 
-			err = s.syncPublishedPackages(ctx)
+			err = s.syncPublishedPackages(passCtx)
 			if err != nil {
 				s.logger.Errorf("error while syncing gno published packages %s", err.Error())
 			}
 
-			err = s.syncProposals(ctx)
+			err = s.syncProposals(passCtx)
 			if err != nil {
 				s.logger.Errorf("error while syncing proposals %s", err.Error())
 			}
 
-			_, err = s.SyncVotesOnProposals(ctx)
+			_, err = s.SyncVotesOnProposals(passCtx)
 			if err != nil {
 				s.logger.Errorf("error while syncing votes on proposals %s", err.Error())
 			}
 
-			err = s.syncGovDaoMembers()
+			err = s.syncGovDaoMembers(passCtx)
 			if err != nil {
 				s.logger.Errorf("error while syncing GovDao members %s", err.Error())
 			}
 
+			s.recordChainLag(passCtx)
+			span.End()
+			s.cache.Bump(apicache.Onchain)
+
 			s.logger.Info("Onchain Sync finished.")
 
 			select {
@@ -198,6 +389,7 @@ func (s *Syncer) syncReports() error {
 	}
 
 	s.logger.Infof("Report generated successfully: %s", report.ID)
+	s.cache.Bump(apicache.Reports)
 
 	// Broadcast to Discord if webhook is configured
 	webhookURL := os.Getenv("DISCORD_REPORT_WEBHOOK_URL")
@@ -221,7 +413,7 @@ func (s *Syncer) syncReports() error {
 	return nil
 }
 
-func (s *Syncer) syncPRs(repository models.Repository) error {
+func (s *Syncer) syncPRs(ctx context.Context, repository models.Repository) error {
 	lastUpdatedTime := getLastUpdatedPR(*s.db, repository.ID)
 
 	var q struct {
@@ -244,7 +436,7 @@ func (s *Syncer) syncPRs(repository models.Repository) error {
 	}
 
 	for hasNextPage {
-		err := s.client.Query(context.Background(), &q, variables)
+		err := s.client.Query(ctx, &q, variables)
 		if err != nil {
 			return err
 		}
@@ -272,6 +464,8 @@ func (s *Syncer) syncPRs(repository models.Repository) error {
 				}
 			}
 
+			files, labels := pr.pullRequestFiles.models(pr.ID)
+			matches := s.topics().Rank(topics.Signals{Repo: repository.ID, Title: pr.Title, Paths: paths(files), Labels: labels})
 			pr := models.PullRequest{
 				CreatedAt:        pr.CreatedAt,
 				UpdatedAt:        pr.UpdatedAt,
@@ -289,11 +483,35 @@ func (s *Syncer) syncPRs(repository models.Repository) error {
 				MergeStateStatus: pr.MergeStateStatus,
 				MergedAt:         pr.MergedAt,
 				IsDraft:          pr.IsDraft,
+				Topic:            topics.Primary(matches),
+				Labels:           labels,
+				FilesSynced:      true,
+			}
+			counted := []time.Time{pr.CreatedAt}
+			if pr.MergedAt != nil {
+				counted = append(counted, *pr.MergedAt)
 			}
-			err = s.db.Save(pr).Error
+			for _, review := range reviews {
+				counted = append(counted, review.CreatedAt)
+			}
+			err = s.db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Save(pr).Error; err != nil {
+					return err
+				}
+				if err := replaceFiles(tx, pr.ID, files); err != nil {
+					return err
+				}
+				if err := topics.SaveMatches(tx, pr.ID, matches); err != nil {
+					return err
+				}
+				return contributions.MarkDirty(tx, repository.ID, counted...)
+			})
 			if err != nil {
 				return err
 			}
+			if err := search.Index(s.db, search.FromPullRequest(pr)); err != nil {
+				s.logger.Errorf("error while indexing pr %s for search: %s", pr.ID, err.Error())
+			}
 
 		}
 		hasNextPage = q.Repository.PullRequests.PageInfo.HasNextPage
@@ -303,7 +521,7 @@ func (s *Syncer) syncPRs(repository models.Repository) error {
 	return nil
 }
 
-func (s *Syncer) syncUsers(repository models.Repository) error {
+func (s *Syncer) syncUsers(ctx context.Context, repository models.Repository) error {
 	variables := map[string]interface{}{
 		"cursor": (*githubv4.String)(nil), // Null after argument to get first page.
 		"owner":  githubv4.String(repository.Owner),
@@ -326,7 +544,7 @@ func (s *Syncer) syncUsers(repository models.Repository) error {
 			} `graphql:"repository(owner: $owner, name: $name)"`
 		}
 
-		err := s.client.Query(context.Background(), &q, variables)
+		err := s.client.Query(ctx, &q, variables)
 		if err != nil {
 			return err
 		}
@@ -356,7 +574,7 @@ func (s *Syncer) syncUsers(repository models.Repository) error {
 	return nil
 }
 
-func (s *Syncer) syncIssues(repository models.Repository) error {
+func (s *Syncer) syncIssues(ctx context.Context, repository models.Repository) error {
 	lastUpdatedTime := getLastUpdatedIssue(*s.db, repository.ID)
 
 	var q struct {
@@ -379,7 +597,7 @@ func (s *Syncer) syncIssues(repository models.Repository) error {
 	}
 	for hasNextPage {
 
-		err := s.client.Query(context.Background(), &q, variables)
+		err := s.client.Query(ctx, &q, variables)
 		if err != nil {
 			return err
 		}
@@ -423,22 +641,34 @@ func (s *Syncer) syncIssues(repository models.Repository) error {
 				Labels:       labels,
 				MilestoneID:  issue.Milestone.ID,
 				URL:          issue.Url,
+				ClosedAt:     issue.ClosedAt,
 				Assignees:    assignees,
 			}
-			err = s.db.Save(issue).Error
+			err = s.db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Save(issue).Error; err != nil {
+					return err
+				}
+				return contributions.MarkDirty(tx, repository.ID, issue.CreatedAt)
+			})
 			if err != nil {
 				return err
 			}
+			if err := search.Index(s.db, search.FromIssue(issue)); err != nil {
+				s.logger.Errorf("error while indexing issue %s for search: %s", issue.ID, err.Error())
+			}
 		}
 
 		hasNextPage = q.Repository.Issues.PageInfo.HasNextPage
 		variables["cursor"] = githubv4.NewString(q.Repository.Issues.PageInfo.EndCursor)
 	}
 
+	if lastUpdatedTime.IsZero() {
+		return s.markClosedAtSynced(repository.ID, "issues_closed_at_synced")
+	}
 	return nil
 }
 
-func (s *Syncer) syncMilestones(repository models.Repository) error {
+func (s *Syncer) syncMilestones(ctx context.Context, repository models.Repository) error {
 	lastUpdatedTime := getLastUpdatedMilestone(*s.db, repository.ID)
 
 	var q struct {
@@ -461,7 +691,7 @@ func (s *Syncer) syncMilestones(repository models.Repository) error {
 	}
 	for hasNextPage {
 
-		err := s.client.Query(context.Background(), &q, variables)
+		err := s.client.Query(ctx, &q, variables)
 		if err != nil {
 			return err
 		}
@@ -483,6 +713,8 @@ func (s *Syncer) syncMilestones(repository models.Repository) error {
 				AuthorID:     milestone.Creator.User.ID,
 				Description:  milestone.Description,
 				Url:          milestone.Url,
+				DueOn:        milestone.DueOn,
+				ClosedAt:     milestone.ClosedAt,
 			}
 			err = s.db.Save(issue).Error
 			if err != nil {
@@ -494,10 +726,13 @@ func (s *Syncer) syncMilestones(repository models.Repository) error {
 		variables["cursor"] = githubv4.NewString(q.Repository.Milestones.PageInfo.EndCursor)
 	}
 
+	if lastUpdatedTime.IsZero() {
+		return s.markClosedAtSynced(repository.ID, "milestones_closed_at_synced")
+	}
 	return nil
 }
 
-func (s *Syncer) syncCommits(repository models.Repository) error {
+func (s *Syncer) syncCommits(ctx context.Context, repository models.Repository) error {
 	var q struct {
 		Repository struct {
 			Ref struct {
@@ -525,7 +760,7 @@ func (s *Syncer) syncCommits(repository models.Repository) error {
 	}
 	for hasNextPage {
 
-		err := s.client.Query(context.Background(), &q, variables)
+		err := s.client.Query(ctx, &q, variables)
 		if err != nil {
 			return err
 		}
@@ -540,10 +775,18 @@ func (s *Syncer) syncCommits(repository models.Repository) error {
 				UpdatedAt:    c.CommittedDate,
 				Title:        c.MessageHeadline,
 			}
-			err = s.db.Save(commit).Error
+			err = s.db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Save(commit).Error; err != nil {
+					return err
+				}
+				return contributions.MarkDirty(tx, repository.ID, commit.CreatedAt)
+			})
 			if err != nil {
 				return err
 			}
+			if err := search.Index(s.db, search.FromCommit(commit)); err != nil {
+				s.logger.Errorf("error while indexing commit %s for search: %s", commit.ID, err.Error())
+			}
 		}
 
 		hasNextPage = q.Repository.Ref.Target.Commit.History.PageInfo.HasNextPage
@@ -694,12 +937,15 @@ type milestone struct {
 	State       string
 	Description string
 	Url         string
+	DueOn       *time.Time
+	ClosedAt    *time.Time
 	Creator     Author
 }
 
 type issue struct {
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	ClosedAt  *time.Time
 	ID        string
 	Number    int
 	State     string
@@ -743,6 +989,44 @@ type pullRequest struct {
 	MergeStateStatus string     `graphql:"mergeStateStatus"`
 	MergedAt         *time.Time `graphql:"mergedAt"`
 	IsDraft          bool       `graphql:"isDraft"`
+	pullRequestFiles
+}
+
+// pullRequestFiles is what the topic rules match a pull request on besides
+// its title.
+type pullRequestFiles struct {
+	Files struct {
+		Nodes []struct {
+			Path      string
+			Additions int
+			Deletions int
+		}
+	} `graphql:"files(first: 100)"`
+	Labels struct {
+		Nodes []struct {
+			Name string
+		}
+	} `graphql:"labels(first: 20)"`
+}
+
+func (f pullRequestFiles) models(prID string) ([]models.PullRequestFile, []string) {
+	files := make([]models.PullRequestFile, len(f.Files.Nodes))
+	for i, n := range f.Files.Nodes {
+		files[i] = models.PullRequestFile{PullRequestID: prID, Path: n.Path, Additions: n.Additions, Deletions: n.Deletions}
+	}
+	labels := make([]string, len(f.Labels.Nodes))
+	for i, n := range f.Labels.Nodes {
+		labels[i] = n.Name
+	}
+	return files, labels
+}
+
+func paths(files []models.PullRequestFile) []string {
+	out := make([]string, len(files))
+	for i, f := range files {
+		out[i] = f.Path
+	}
+	return out
 }
 
 type review struct {
@@ -785,7 +1069,7 @@ func (s *Syncer) syncRemaningUsers() error {
 		select pr.author_id from pull_requests pr 
 		UNION
 		select i.author_id  from issues i
-	) where author_id != '' and author_id not in (select id from users)
+	) as authors where author_id != '' and author_id not in (select id from users)
 	`)
 	if err != nil {
 		return err
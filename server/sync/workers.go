@@ -3,8 +3,13 @@ package sync
 import (
 	"context"
 	stdsync "sync"
+	"time"
 
+	"github.com/samouraiworld/topofgnomes/server/contributions"
+	"github.com/samouraiworld/topofgnomes/server/metrics"
 	"github.com/samouraiworld/topofgnomes/server/models"
+	"github.com/samouraiworld/topofgnomes/server/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // syncRepositoriesConcurrently fans out repository-level work across a pool
@@ -45,7 +50,7 @@ func (s *Syncer) syncRepositoriesConcurrently(ctx context.Context) {
 	wg.Wait()
 }
 
-// syncOneRepo runs the five per-repository sync passes for a single repo,
+// syncOneRepo runs the per-repository sync passes for a single repo,
 // each wrapped in rate-limit backoff. A failure in one pass is logged but
 // doesn't skip the rest — partial progress is better than none.
 func (s *Syncer) syncOneRepo(ctx context.Context, repo models.Repository, workerID int) {
@@ -53,21 +58,36 @@ func (s *Syncer) syncOneRepo(ctx context.Context, repo models.Repository, worker
 
 	steps := []struct {
 		name string
-		fn   func() error
+		fn   func(context.Context, models.Repository) error
 	}{
-		{"users", func() error { return s.syncUsers(repo) }},
-		{"issues", func() error { return s.syncIssues(repo) }},
-		{"prs", func() error { return s.syncPRs(repo) }},
-		{"milestones", func() error { return s.syncMilestones(repo) }},
-		{"commits", func() error { return s.syncCommits(repo) }},
+		{"users", s.syncUsers},
+		{"issues", s.syncIssues},
+		{"prs", s.syncPRs},
+		{"milestones", s.syncMilestones},
+		{"commits", s.syncCommits},
+		{"release", s.syncRelease},
+		{"rollup", s.refreshRollup},
 	}
 	for _, step := range steps {
 		if ctx.Err() != nil {
 			return
 		}
-		err := backoffRetry(ctx, defaultBackoffAttempts, defaultBackoffBase, isRateLimitErr, step.fn)
+		stepCtx, span := tracing.Start(ctx, "sync."+step.name, attribute.String("repository", repo.ID))
+		start := time.Now()
+		err := backoffRetry(ctx, defaultBackoffAttempts, defaultBackoffBase, isRateLimitErr, func() error {
+			return step.fn(stepCtx, repo)
+		})
+		metrics.ObserveSyncStep(step.name, time.Since(start), err)
+		tracing.End(span, err)
 		if err != nil {
-			s.logger.Errorf("[worker %d] %s sync %s failed: %v", workerID, repo.ID, step.name, err)
+			s.log(stepCtx).Errorf("[worker %d] %s sync %s failed: %v", workerID, repo.ID, step.name, err)
 		}
 	}
 }
+
+// refreshRollup rewrites the repository's daily_contributions rows on the
+// days marked dirty by this pass's steps, or by earlier passes that stopped
+// before getting here.
+func (s *Syncer) refreshRollup(ctx context.Context, repo models.Repository) error {
+	return contributions.RefreshDirty(s.db.WithContext(ctx), repo.ID)
+}
@@ -0,0 +1,427 @@
+package teams
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/samouraiworld/topofgnomes/server/models"
+)
+
+// Errors of the Store's changes, to tell apart with errors.Is. Their
+// messages are meant for the admin who asked for the change.
+var (
+	ErrNotFound = errors.New("not found") // no such team, or no such member in it
+	ErrConflict = errors.New("conflict")  // clashes with the roster as it is, e.g. a taken slug
+	ErrInvalid  = errors.New("invalid")   // breaks the rules of the config file
+)
+
+// changeError is a refused change: msg is what went wrong, kind which of
+// the errors above it is.
+type changeError struct {
+	kind error
+	msg  string
+}
+
+func (e *changeError) Error() string { return e.msg }
+func (e *changeError) Unwrap() error { return e.kind }
+
+func refuse(kind error, format string, args ...any) error {
+	return &changeError{kind: kind, msg: fmt.Sprintf(format, args...)}
+}
+
+// Store keeps the roster in the database, for deployments that manage it
+// through the admin API (TEAMS_SOURCE=db) rather than teams.yaml.
+//
+// Members are stints with a join and a leave date, so a removal or a move
+// between teams takes effect at a given time instead of rewriting history.
+// Every change is checked with the rules of the config file and recorded
+// in team_audit_events.
+type Store struct {
+	db  *gorm.DB
+	now func() time.Time
+
+	current atomic.Pointer[Config]
+
+	// mu serialises changes, so each one is checked against the roster
+	// the previous one committed.
+	mu       sync.Mutex
+	onChange []func(*Config)
+}
+
+// TeamUpdate is a partial edit of a team; nil fields are left as they are.
+type TeamUpdate struct {
+	Name        *string `json:"name,omitempty"`
+	Color       *string `json:"color,omitempty"`
+	Description *string `json:"description,omitempty"`
+}
+
+// NewStore loads the roster from db.
+func NewStore(db *gorm.DB) (*Store, error) {
+	// Microseconds are what PostgreSQL keeps; a stint must compare the
+	// same before and after a round trip.
+	s := &Store{db: db, now: func() time.Time { return time.Now().UTC().Truncate(time.Microsecond) }}
+	if err := s.Refresh(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Current returns the roster as of its last refresh. It is a Provider.
+func (s *Store) Current() *Config {
+	return s.current.Load()
+}
+
+// OnChange registers fn to run with the new roster whenever it changes.
+func (s *Store) OnChange(fn func(*Config)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onChange = append(s.onChange, fn)
+}
+
+// Empty reports whether no team was ever stored.
+func (s *Store) Empty() (bool, error) {
+	var n int64
+	err := s.db.Model(&models.Team{}).Count(&n).Error
+	return n == 0, err
+}
+
+// Refresh reloads the roster. Besides picking up changes made by other
+// replicas, this applies stints whose dates have come since the last one.
+func (s *Store) Refresh() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.refreshLocked()
+}
+
+func (s *Store) refreshLocked() error {
+	r, err := loadRoster(s.db)
+	if err != nil {
+		return err
+	}
+	cfg := &Config{SchemaVersion: SchemaVersion, Teams: r.at(s.now()), LastSyncedAt: r.changedAt}
+	prev := s.current.Swap(cfg)
+	if prev == nil || reflect.DeepEqual(prev, cfg) {
+		return nil
+	}
+	for _, fn := range s.onChange {
+		fn(cfg)
+	}
+	return nil
+}
+
+// Run refreshes the roster every interval until ctx is done.
+func (s *Store) Run(ctx context.Context, interval time.Duration, logger *zap.SugaredLogger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := s.Refresh(); err != nil {
+			logger.Errorf("refresh teams roster: %v", err)
+		}
+	}
+}
+
+// Audit returns the newest limit changes, of one team when team is set.
+func (s *Store) Audit(team string, limit int) ([]models.TeamAuditEvent, error) {
+	q := s.db.Order("id DESC").Limit(limit)
+	if team != "" {
+		q = q.Where("LOWER(team) = ?", strings.ToLower(team))
+	}
+	events := []models.TeamAuditEvent{}
+	err := q.Find(&events).Error
+	return events, err
+}
+
+// CreateTeam adds t, its members joining at joined.
+func (s *Store) CreateTeam(actor string, t Team, joined time.Time) (Team, error) {
+	if err := validate([]Team{t}); err != nil {
+		return Team{}, refuse(ErrInvalid, "%v", err)
+	}
+	joined = joined.UTC()
+	ev := models.TeamAuditEvent{Actor: actor, Action: "create_team", Team: t.Slug, Detail: map[string]any{
+		"name": t.Name, "color": t.Color, "description": t.Description, "members": t.Members, "joinedAt": joined,
+	}}
+	err := s.change(&ev, func(tx *gorm.DB, r *roster) error {
+		if _, ok := r.find(t.Slug); ok {
+			return refuse(ErrConflict, "team %q already exists", t.Slug)
+		}
+		row := models.Team{Slug: t.Slug, Name: t.Name, Color: t.Color, Description: t.Description}
+		if err := tx.Create(&row).Error; err != nil {
+			return err
+		}
+		for _, login := range t.Members {
+			if err := tx.Create(&models.TeamMembership{TeamID: row.ID, Login: login, JoinedAt: &joined}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return s.result(t.Slug, err)
+}
+
+// UpdateTeam renames, recolors or redescribes the team slug. Slugs are
+// stable identifiers and can't be changed.
+func (s *Store) UpdateTeam(actor, slug string, u TeamUpdate) (Team, error) {
+	ev := models.TeamAuditEvent{Actor: actor, Action: "update_team", Detail: map[string]any{}}
+	err := s.change(&ev, func(tx *gorm.DB, r *roster) error {
+		row, ok := r.find(slug)
+		if !ok {
+			return refuse(ErrNotFound, "team %q not found", slug)
+		}
+		ev.Team = row.Slug
+		set := func(field string, to *string, cur *string) {
+			if to != nil && *to != *cur {
+				ev.Detail[field] = map[string]string{"from": *cur, "to": *to}
+				*cur = *to
+			}
+		}
+		set("name", u.Name, &row.Name)
+		set("color", u.Color, &row.Color)
+		set("description", u.Description, &row.Description)
+		return tx.Save(row).Error
+	})
+	return s.result(slug, err)
+}
+
+// AddMember starts a stint of login in the team slug at joined.
+func (s *Store) AddMember(actor, slug, login string, joined time.Time) (Team, error) {
+	if err := checkWhitespace("member", login); err != nil || login == "" {
+		return Team{}, refuse(ErrInvalid, "invalid login %q", login)
+	}
+	joined = joined.UTC()
+	ev := models.TeamAuditEvent{Actor: actor, Action: "add_member", Detail: map[string]any{"login": login, "joinedAt": joined}}
+	err := s.change(&ev, func(tx *gorm.DB, r *roster) error {
+		row, ok := r.find(slug)
+		if !ok {
+			return refuse(ErrNotFound, "team %q not found", slug)
+		}
+		ev.Team = row.Slug
+		for _, m := range r.members {
+			if m.TeamID == row.ID && strings.EqualFold(m.Login, login) && (m.LeftAt == nil || m.LeftAt.After(joined)) {
+				return refuse(ErrConflict, "%s is already a member of %q at that date", login, row.Slug)
+			}
+		}
+		return tx.Create(&models.TeamMembership{TeamID: row.ID, Login: login, JoinedAt: &joined}).Error
+	})
+	return s.result(slug, err)
+}
+
+// RemoveMember ends the current stint of login in the team slug at left.
+func (s *Store) RemoveMember(actor, slug, login string, left time.Time) (Team, error) {
+	left = left.UTC()
+	ev := models.TeamAuditEvent{Actor: actor, Action: "remove_member", Detail: map[string]any{"login": login, "leftAt": left}}
+	err := s.change(&ev, func(tx *gorm.DB, r *roster) error {
+		row, ok := r.find(slug)
+		if !ok {
+			return refuse(ErrNotFound, "team %q not found", slug)
+		}
+		ev.Team = row.Slug
+		for i := range r.members {
+			m := &r.members[i]
+			if m.TeamID != row.ID || !strings.EqualFold(m.Login, login) || m.LeftAt != nil {
+				continue
+			}
+			if m.JoinedAt != nil && !left.After(*m.JoinedAt) {
+				return refuse(ErrInvalid, "%s joined %q on %s, after %s", login, row.Slug, m.JoinedAt.Format(time.RFC3339), left.Format(time.RFC3339))
+			}
+			m.LeftAt = &left
+			return tx.Save(m).Error
+		}
+		return refuse(ErrNotFound, "%s is not a member of %q", login, row.Slug)
+	})
+	return s.result(slug, err)
+}
+
+// result returns the team slug after a change.
+func (s *Store) result(slug string, err error) (Team, error) {
+	if err != nil {
+		return Team{}, err
+	}
+	t, _ := s.Current().FindBySlug(slug)
+	return t, nil
+}
+
+// Import makes the roster match cfg, as replacing teams.yaml would: teams
+// are created or updated by slug and their memberships replaced by cfg's,
+// dates included. Teams missing from cfg are kept for their history, but
+// their current members leave now.
+func (s *Store) Import(actor string, cfg *Config) error {
+	if err := validate(cfg.Teams); err != nil {
+		return refuse(ErrInvalid, "%v", err)
+	}
+	var created, updated, retired []string
+	ev := models.TeamAuditEvent{Actor: actor, Action: "import"}
+	err := s.change(&ev, func(tx *gorm.DB, r *roster) error {
+		now := s.now()
+		kept := map[uint]bool{}
+		for _, t := range cfg.Teams {
+			row, ok := r.find(t.Slug)
+			changed := false
+			if !ok {
+				row = &models.Team{Slug: t.Slug, Name: t.Name, Color: t.Color, Description: t.Description}
+				if err := tx.Create(row).Error; err != nil {
+					return err
+				}
+				created = append(created, t.Slug)
+			} else if row.Slug != t.Slug || row.Name != t.Name || row.Color != t.Color || row.Description != t.Description {
+				row.Slug, row.Name, row.Color, row.Description = t.Slug, t.Name, t.Color, t.Description
+				if err := tx.Save(row).Error; err != nil {
+					return err
+				}
+				changed = true
+			}
+			kept[row.ID] = true
+
+			want := append([]Member(nil), t.Stints()...)
+			for i := range want {
+				want[i].Joined, want[i].Left = utc(want[i].Joined), utc(want[i].Left)
+			}
+			if ok && !reflect.DeepEqual(r.team(row, now).History, want) {
+				if err := tx.Where("team_id = ?", row.ID).Delete(&models.TeamMembership{}).Error; err != nil {
+					return err
+				}
+				changed = true
+			}
+			if !ok || changed {
+				for _, m := range want {
+					stint := models.TeamMembership{TeamID: row.ID, Login: m.Login, JoinedAt: utc(m.Joined), LeftAt: utc(m.Left)}
+					if err := tx.Create(&stint).Error; err != nil {
+						return err
+					}
+				}
+			}
+			if ok && changed {
+				updated = append(updated, t.Slug)
+			}
+		}
+		for i := range r.teams {
+			row := &r.teams[i]
+			if kept[row.ID] {
+				continue
+			}
+			res := tx.Model(&models.TeamMembership{}).
+				Where("team_id = ? AND (left_at IS NULL OR left_at > ?)", row.ID, now).
+				Update("left_at", now)
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected > 0 {
+				retired = append(retired, row.Slug)
+			}
+		}
+		ev.Detail = map[string]any{"created": created, "updated": updated, "retired": retired}
+		return nil
+	})
+	return err
+}
+
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+// change runs fn in a transaction, then checks the roster it leaves and
+// records ev. Errors from fn are returned as they are; a roster breaking
+// the rules is an InvalidInput.
+func (s *Store) change(ev *models.TeamAuditEvent, fn func(tx *gorm.DB, r *roster) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		r, err := loadRoster(tx)
+		if err != nil {
+			return err
+		}
+		if err := fn(tx, r); err != nil {
+			return err
+		}
+		if r, err = loadRoster(tx); err != nil {
+			return err
+		}
+		if err := r.check(s.now()); err != nil {
+			return refuse(ErrInvalid, "%v", err)
+		}
+		ev.At = s.now()
+		return tx.Create(ev).Error
+	})
+	if err != nil {
+		return err
+	}
+	return s.refreshLocked()
+}
+
+// roster is the content of the teams and team_memberships tables.
+type roster struct {
+	teams     []models.Team
+	members   []models.TeamMembership
+	changedAt time.Time
+}
+
+func loadRoster(db *gorm.DB) (*roster, error) {
+	r := &roster{}
+	if err := db.Order("id").Find(&r.teams).Error; err != nil {
+		return nil, fmt.Errorf("load teams: %w", err)
+	}
+	if err := db.Order("id").Find(&r.members).Error; err != nil {
+		return nil, fmt.Errorf("load team memberships: %w", err)
+	}
+	var last models.TeamAuditEvent
+	if err := db.Order("id DESC").Limit(1).Find(&last).Error; err != nil {
+		return nil, fmt.Errorf("load team audit: %w", err)
+	}
+	r.changedAt = last.At.UTC()
+	return r, nil
+}
+
+func (r *roster) find(slug string) (*models.Team, bool) {
+	for i := range r.teams {
+		if strings.EqualFold(r.teams[i].Slug, slug) {
+			return &r.teams[i], true
+		}
+	}
+	return nil, false
+}
+
+func (r *roster) team(row *models.Team, t time.Time) Team {
+	out := Team{Slug: row.Slug, Name: row.Name, Color: row.Color, Description: row.Description, History: []Member{}}
+	for _, m := range r.members {
+		if m.TeamID == row.ID {
+			out.History = append(out.History, Member{Login: m.Login, Joined: utc(m.JoinedAt), Left: utc(m.LeftAt)})
+		}
+	}
+	out.Members = membersAt(out.History, t)
+	return out
+}
+
+// at returns the roster with Members as of t. Teams whose members all
+// left are kept for their history.
+func (r *roster) at(t time.Time) []Team {
+	out := []Team{}
+	for i := range r.teams {
+		out = append(out, r.team(&r.teams[i], t))
+	}
+	return out
+}
+
+// check applies the rules of the config file.
+func (r *roster) check(now time.Time) error {
+	if len(r.teams) == 0 {
+		return nil
+	}
+	return validate(r.at(now))
+}
@@ -0,0 +1,263 @@
+package teams
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/samouraiworld/topofgnomes/server/db/dbtest"
+	"github.com/samouraiworld/topofgnomes/server/models"
+)
+
+var storeNow = time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func newStore(t *testing.T) *Store {
+	t.Helper()
+	db := dbtest.Open(t, &models.Team{}, &models.TeamMembership{}, &models.TeamAuditEvent{})
+	s, err := NewStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = func() time.Time { return storeNow }
+	return s
+}
+
+// seeded returns a store imported from validYAML.
+func seeded(t *testing.T) *Store {
+	t.Helper()
+	s := newStore(t)
+	cfg, err := Parse([]byte(validYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Import("system", cfg); err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	return s
+}
+
+func members(t *testing.T, s *Store, slug string) string {
+	t.Helper()
+	return strings.Join(s.Current().MembersOf(slug), ",")
+}
+
+func wantErr(t *testing.T, err, want error) {
+	t.Helper()
+	if !errors.Is(err, want) {
+		t.Fatalf("error = %v, want %v", err, want)
+	}
+}
+
+func TestStoreImportSeedsFromConfig(t *testing.T) {
+	s := seeded(t)
+	if got := len(s.Current().Teams); got != 2 {
+		t.Fatalf("teams = %d, want 2", got)
+	}
+	if got := members(t, s, "onbloc"); got != "notJoon,r3v4s" {
+		t.Errorf("onbloc members = %s", got)
+	}
+	// Seeded members belong to the team since always.
+	var m models.TeamMembership
+	if err := s.db.First(&m, "login = ?", "notJoon").Error; err != nil {
+		t.Fatal(err)
+	}
+	if m.JoinedAt != nil {
+		t.Errorf("seeded member joined at %v, want since always", m.JoinedAt)
+	}
+	if !s.Current().LastSyncedAt.Equal(storeNow) {
+		t.Errorf("LastSyncedAt = %v, want the import time", s.Current().LastSyncedAt)
+	}
+}
+
+func TestStoreMoveMember(t *testing.T) {
+	s := seeded(t)
+	var changes int
+	s.OnChange(func(*Config) { changes++ })
+
+	// Adding r3v4s to samouraiworld while still in onbloc double-attributes.
+	_, err := s.AddMember("admin", "samouraiworld", "r3v4s", storeNow)
+	wantErr(t, err, ErrInvalid)
+	if !strings.Contains(err.Error(), "double-attributed") {
+		t.Errorf("error = %v", err)
+	}
+
+	if _, err := s.RemoveMember("admin", "onbloc", "R3V4S", storeNow); err != nil {
+		t.Fatalf("RemoveMember: %v", err)
+	}
+	team, err := s.AddMember("admin", "samouraiworld", "r3v4s", storeNow)
+	if err != nil {
+		t.Fatalf("AddMember: %v", err)
+	}
+	if got := strings.Join(team.Members, ","); got != "n0izn0iz,zxxma,r3v4s" {
+		t.Errorf("returned members = %s", got)
+	}
+	if got := members(t, s, "onbloc"); got != "notJoon" {
+		t.Errorf("onbloc members = %s", got)
+	}
+	if changes != 2 {
+		t.Errorf("OnChange ran %d times, want 2", changes)
+	}
+
+	// The stint in onbloc is closed, not deleted.
+	var stints []models.TeamMembership
+	s.db.Where("login = ?", "r3v4s").Order("id").Find(&stints)
+	if len(stints) != 2 || stints[0].LeftAt == nil || !stints[0].LeftAt.Equal(storeNow) {
+		t.Errorf("stints = %+v", stints)
+	}
+}
+
+func TestStoreRejectsOverlapInThePast(t *testing.T) {
+	s := seeded(t)
+	// zxxma left samouraiworld on April 1st...
+	if _, err := s.RemoveMember("admin", "samouraiworld", "zxxma", storeNow.AddDate(0, -1, 0)); err != nil {
+		t.Fatal(err)
+	}
+	// ...so joining onbloc on March 1st overlaps, though both are over now.
+	_, err := s.AddMember("admin", "onbloc", "zxxma", storeNow.AddDate(0, -2, 0))
+	wantErr(t, err, ErrInvalid)
+	if _, err := s.AddMember("admin", "onbloc", "zxxma", storeNow.AddDate(0, -1, 0)); err != nil {
+		t.Errorf("joining the day the other stint ended: %v", err)
+	}
+}
+
+func TestStoreFutureDates(t *testing.T) {
+	s := seeded(t)
+	later := storeNow.Add(24 * time.Hour)
+	if _, err := s.AddMember("admin", "onbloc", "newhire", later); err != nil {
+		t.Fatal(err)
+	}
+	if got := members(t, s, "onbloc"); got != "notJoon,r3v4s" {
+		t.Errorf("before the join date: %s", got)
+	}
+	s.now = func() time.Time { return later }
+	if err := s.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if got := members(t, s, "onbloc"); got != "notJoon,r3v4s,newhire" {
+		t.Errorf("after the join date: %s", got)
+	}
+}
+
+func TestStoreCreateAndUpdateTeam(t *testing.T) {
+	s := seeded(t)
+	_, err := s.CreateTeam("admin", Team{Slug: "gnocore", Name: "Gno Core", Color: "teal", Members: []string{"moul"}}, storeNow)
+	wantErr(t, err, ErrInvalid)
+	_, err = s.CreateTeam("admin", Team{Slug: "OnBloc", Name: "Other", Color: "blue", Members: []string{"moul"}}, storeNow)
+	wantErr(t, err, ErrConflict)
+
+	if _, err := s.CreateTeam("admin", Team{Slug: "gnocore", Name: "Gno Core", Color: "blue", Members: []string{"moul"}}, storeNow); err != nil {
+		t.Fatalf("CreateTeam: %v", err)
+	}
+	if got := members(t, s, "gnocore"); got != "moul" {
+		t.Errorf("gnocore members = %s", got)
+	}
+
+	name, color := "Onbloc Inc.", "green"
+	team, err := s.UpdateTeam("admin", "onbloc", TeamUpdate{Name: &name, Color: &color})
+	if err != nil {
+		t.Fatalf("UpdateTeam: %v", err)
+	}
+	if team.Name != name || team.Color != color || len(team.Members) != 2 {
+		t.Errorf("updated team = %+v", team)
+	}
+	dup := "Gno Core"
+	_, err = s.UpdateTeam("admin", "onbloc", TeamUpdate{Name: &dup})
+	wantErr(t, err, ErrInvalid)
+	_, err = s.UpdateTeam("admin", "nope", TeamUpdate{Name: &name})
+	wantErr(t, err, ErrNotFound)
+}
+
+func TestStoreReimport(t *testing.T) {
+	s := seeded(t)
+	cfg, err := Parse([]byte(`
+schemaVersion: 2
+teams:
+  - slug: onbloc
+    name: Onbloc
+    color: purple
+    members:
+      - notJoon
+      - {login: r3v4s, left: 2026-04-01}
+      - {login: newhire, joined: 2026-04-15}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Import("admin", cfg); err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	// samouraiworld is kept for its history, without members.
+	if got := len(s.Current().Teams); got != 2 {
+		t.Errorf("teams = %d, want 2", got)
+	}
+	if got := members(t, s, "samouraiworld"); got != "" {
+		t.Errorf("samouraiworld members = %s", got)
+	}
+	if got := members(t, s, "onbloc"); got != "notJoon,newhire" {
+		t.Errorf("onbloc members = %s", got)
+	}
+	onbloc, _ := s.Current().FindBySlug("onbloc")
+	if h := onbloc.History; len(h) != 3 || h[1].Left == nil || !h[1].Left.Equal(time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("onbloc history = %+v", h)
+	}
+
+	events, err := s.Audit("", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Actor != "admin" || events[0].Action != "import" {
+		t.Fatalf("audit = %+v", events)
+	}
+	d := events[0].Detail
+	if got := strings.Join(toStrings(d["updated"]), ","); got != "onbloc" {
+		t.Errorf("updated = %s", got)
+	}
+	if got := strings.Join(toStrings(d["retired"]), ","); got != "samouraiworld" {
+		t.Errorf("retired = %s", got)
+	}
+
+	// Importing the export changes nothing.
+	raw, err := Marshal(s.Current())
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := Parse(raw)
+	if err != nil {
+		t.Fatalf("Parse(export): %v\n%s", err, raw)
+	}
+	if err := s.Import("admin", again); err != nil {
+		t.Fatal(err)
+	}
+	events, _ = s.Audit("", 1)
+	if d := events[0].Detail; len(toStrings(d["updated"])) != 0 || len(toStrings(d["retired"])) != 0 {
+		t.Errorf("re-importing the export changed %+v", d)
+	}
+}
+
+func TestStoreAudit(t *testing.T) {
+	s := seeded(t)
+	if _, err := s.RemoveMember("lead", "onbloc", "r3v4s", storeNow); err != nil {
+		t.Fatal(err)
+	}
+	_, err := s.RemoveMember("lead", "onbloc", "r3v4s", storeNow)
+	wantErr(t, err, ErrNotFound)
+
+	events, err := s.Audit("ONBLOC", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Actor != "lead" || events[0].Action != "remove_member" || events[0].Detail["login"] != "r3v4s" {
+		t.Errorf("onbloc audit = %+v", events)
+	}
+}
+
+func toStrings(v any) []string {
+	list, _ := v.([]any)
+	out := make([]string, 0, len(list))
+	for _, x := range list {
+		s, _ := x.(string)
+		out = append(out, s)
+	}
+	return out
+}
@@ -1,6 +1,7 @@
 // Package teams loads and validates the gnolove team roster from a YAML
-// config file. The roster is the source of truth for which GitHub login
-// belongs to which team and powers the /teams and /teams/:slug endpoints.
+// config file, or keeps it in the database (see Store). The roster is the
+// source of truth for which GitHub login belongs to which team and powers
+// the /teams and /teams/:slug endpoints.
 package teams
 
 import (
@@ -12,8 +13,10 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
-// SchemaVersion is the YAML shape this loader understands.
-const SchemaVersion = 1
+// SchemaVersion is the YAML shape this loader writes. Version 2 added
+// membership dates; version 1 files, where every member is a plain login,
+// read as version 2 files without dates.
+const SchemaVersion = 2
 
 // validColors mirrors the TeamColor union used by the Memba frontend.
 var validColors = map[string]struct{}{
@@ -22,11 +25,80 @@ var validColors = map[string]struct{}{
 }
 
 type Team struct {
-	Slug        string   `yaml:"slug" json:"slug"`
-	Name        string   `yaml:"name" json:"name"`
-	Color       string   `yaml:"color" json:"color"`
-	Description string   `yaml:"description,omitempty" json:"description,omitempty"`
-	Members     []string `yaml:"members" json:"members"`
+	Slug        string `yaml:"slug" json:"slug"`
+	Name        string `yaml:"name" json:"name"`
+	Color       string `yaml:"color" json:"color"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	// Members are the logins in the team when the roster was loaded.
+	Members []string `yaml:"-" json:"members"`
+	// History is every membership, past, current or scheduled; it is the
+	// members list of teams.yaml.
+	History []Member `yaml:"members" json:"history"`
+}
+
+// Member is one stint of a GitHub login in a team, from Joined (nil: since
+// the roster began) until Left (nil: still a member). In teams.yaml a stint
+// without dates is written as the bare login.
+type Member struct {
+	Login  string     `yaml:"login" json:"login"`
+	Joined *time.Time `yaml:"joined,omitempty" json:"joined,omitempty"`
+	Left   *time.Time `yaml:"left,omitempty" json:"left,omitempty"`
+}
+
+func (m *Member) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		*m = Member{}
+		return n.Decode(&m.Login)
+	}
+	type plain Member
+	return n.Decode((*plain)(m))
+}
+
+func (m Member) MarshalYAML() (any, error) {
+	if m.Joined == nil && m.Left == nil {
+		return m.Login, nil
+	}
+	type plain Member
+	return plain(m), nil
+}
+
+// ActiveAt reports whether the stint covers the instant t.
+func (m Member) ActiveAt(t time.Time) bool {
+	return (m.Joined == nil || !m.Joined.After(t)) && (m.Left == nil || m.Left.After(t))
+}
+
+// ActiveOn reports whether the stint covers the UTC day ("YYYY-MM-DD"),
+// which is how stats built on the daily rollup attribute contributions: a
+// stint covers the days from the one it starts on to the one before it
+// ends, so the day of a move between teams goes to the new team.
+func (m Member) ActiveOn(day string) bool {
+	return (m.Joined == nil || day >= m.Joined.UTC().Format(time.DateOnly)) &&
+		(m.Left == nil || day < m.Left.UTC().Format(time.DateOnly))
+}
+
+// Stints returns the team's memberships. A Team built in code with only
+// Members set has each of them as a member since always.
+func (t Team) Stints() []Member {
+	if len(t.History) > 0 || len(t.Members) == 0 {
+		return t.History
+	}
+	out := make([]Member, len(t.Members))
+	for i, login := range t.Members {
+		out[i] = Member{Login: login}
+	}
+	return out
+}
+
+// Headcount returns how many people were in the team at some point between
+// from and to; a zero from means since always.
+func (t Team) Headcount(from, to time.Time) int {
+	logins := map[string]struct{}{}
+	for _, m := range t.Stints() {
+		if (m.Joined == nil || m.Joined.Before(to)) && (from.IsZero() || m.Left == nil || m.Left.After(from)) {
+			logins[strings.ToLower(m.Login)] = struct{}{}
+		}
+	}
+	return len(logins)
 }
 
 // Config is the parsed teams.yaml plus the file mtime, surfaced to the
@@ -37,6 +109,16 @@ type Config struct {
 	LastSyncedAt  time.Time `yaml:"-"              json:"lastSyncedAt"`
 }
 
+// Provider returns the roster to use for one request. main backs it with
+// a configwatch.Watcher so edits to teams.yaml apply without a restart;
+// callers must not hold on to the result across requests.
+type Provider func() *Config
+
+// Static is a Provider that always returns cfg.
+func Static(cfg *Config) Provider {
+	return func() *Config { return cfg }
+}
+
 func Load(path string) (*Config, error) {
 	info, err := os.Stat(path)
 	if err != nil {
@@ -46,20 +128,51 @@ func Load(path string) (*Config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("read %s: %w", path, err)
 	}
+	cfg, err := Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	cfg.LastSyncedAt = info.ModTime().UTC()
+	return cfg, nil
+}
+
+// Parse decodes and validates a roster in the teams.yaml format, with
+// Members as of now. JSON is accepted too, being valid YAML.
+func Parse(raw []byte) (*Config, error) {
 	var cfg Config
 	if err := yaml.Unmarshal(raw, &cfg); err != nil {
-		return nil, fmt.Errorf("parse %s: %w", path, err)
+		return nil, err
 	}
-	if cfg.SchemaVersion != SchemaVersion {
+	if cfg.SchemaVersion != 1 && cfg.SchemaVersion != SchemaVersion {
 		return nil, fmt.Errorf("schemaVersion = %d, want %d", cfg.SchemaVersion, SchemaVersion)
 	}
 	if err := validate(cfg.Teams); err != nil {
 		return nil, err
 	}
-	cfg.LastSyncedAt = info.ModTime().UTC()
+	cfg.SchemaVersion = SchemaVersion
+	now := time.Now()
+	for i := range cfg.Teams {
+		cfg.Teams[i].Members = membersAt(cfg.Teams[i].History, now)
+	}
 	return &cfg, nil
 }
 
+// Marshal encodes cfg in the teams.yaml format, so an exported roster can
+// be committed back as the config file.
+func Marshal(cfg *Config) ([]byte, error) {
+	return yaml.Marshal(cfg)
+}
+
+func membersAt(history []Member, t time.Time) []string {
+	out := []string{}
+	for _, m := range history {
+		if m.ActiveAt(t) {
+			out = append(out, m.Login)
+		}
+	}
+	return out
+}
+
 func (c *Config) FindBySlug(slug string) (Team, bool) {
 	want := strings.ToLower(slug)
 	for _, t := range c.Teams {
@@ -70,6 +183,19 @@ func (c *Config) FindBySlug(slug string) (Team, bool) {
 	return Team{}, false
 }
 
+// TeamOn returns the slug of the team login belonged to on the UTC day
+// ("YYYY-MM-DD"); see Member.ActiveOn.
+func (c *Config) TeamOn(login, day string) (string, bool) {
+	for _, t := range c.Teams {
+		for _, m := range t.Stints() {
+			if strings.EqualFold(m.Login, login) && m.ActiveOn(day) {
+				return t.Slug, true
+			}
+		}
+	}
+	return "", false
+}
+
 // MembersOf returns the case-preserved member list for a slug, or nil.
 // Convenience for callers that only need the member set.
 func (c *Config) MembersOf(slug string) []string {
@@ -84,9 +210,8 @@ func validate(teams []Team) error {
 	if len(teams) == 0 {
 		return fmt.Errorf("teams: empty roster")
 	}
-	seenSlug := map[string]string{}  // lower -> original
-	seenName := map[string]string{}  // lower -> original
-	memberTeam := map[string]string{} // lower -> slug
+	seenSlug := map[string]string{} // lower -> original
+	seenName := map[string]string{} // lower -> original
 	for _, t := range teams {
 		if err := checkWhitespace("slug", t.Slug); err != nil {
 			return err
@@ -103,7 +228,8 @@ func validate(teams []Team) error {
 		if _, ok := validColors[t.Color]; !ok {
 			return fmt.Errorf("team %q: invalid color %q", t.Slug, t.Color)
 		}
-		if len(t.Members) == 0 {
+		stints := t.Stints()
+		if len(stints) == 0 {
 			return fmt.Errorf("team %q: members must be non-empty", t.Slug)
 		}
 		slugKey := strings.ToLower(t.Slug)
@@ -116,18 +242,50 @@ func validate(teams []Team) error {
 			return fmt.Errorf("duplicate name %q (also seen as %q)", t.Name, prev)
 		}
 		seenName[nameKey] = t.Name
-		for _, m := range t.Members {
-			if err := checkWhitespace("member", m); err != nil {
+		for _, m := range stints {
+			if err := checkWhitespace("member", m.Login); err != nil {
 				return fmt.Errorf("team %q: %w", t.Slug, err)
 			}
-			if m == "" {
+			if m.Login == "" {
 				return fmt.Errorf("team %q: empty member entry", t.Slug)
 			}
-			key := strings.ToLower(m)
-			if otherSlug, ok := memberTeam[key]; ok && otherSlug != t.Slug {
-				return fmt.Errorf("contributor %q double-attributed (in %q and %q)", m, otherSlug, t.Slug)
+			if m.Joined != nil && m.Left != nil && !m.Left.After(*m.Joined) {
+				return fmt.Errorf("team %q: %s leaves before joining", t.Slug, m.Login)
+			}
+		}
+	}
+	return checkOverlaps(teams)
+}
+
+// checkOverlaps rejects a contributor in two teams at the same time. Two
+// stints overlap iff the later start is inside both, so it's enough to look
+// at the roster at every start date, and before all of them for the
+// stints that have none.
+func checkOverlaps(teams []Team) error {
+	dates := []time.Time{{}}
+	for _, t := range teams {
+		for _, m := range t.Stints() {
+			if m.Joined != nil {
+				dates = append(dates, *m.Joined)
+			}
+		}
+	}
+	for _, d := range dates {
+		memberTeam := map[string]string{} // lower -> slug
+		for _, t := range teams {
+			for _, m := range t.Stints() {
+				if !m.ActiveAt(d) {
+					continue
+				}
+				key := strings.ToLower(m.Login)
+				if otherSlug, ok := memberTeam[key]; ok && otherSlug != t.Slug {
+					if d.IsZero() {
+						return fmt.Errorf("contributor %q double-attributed (in %q and %q)", m.Login, otherSlug, t.Slug)
+					}
+					return fmt.Errorf("contributor %q double-attributed on %s (in %q and %q)", m.Login, d.UTC().Format(time.RFC3339), otherSlug, t.Slug)
+				}
+				memberTeam[key] = t.Slug
 			}
-			memberTeam[key] = t.Slug
 		}
 	}
 	return nil
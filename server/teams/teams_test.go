@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func writeYAML(t *testing.T, body string) string {
@@ -35,8 +36,9 @@ func TestLoadValidYAML(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Load: %v", err)
 	}
-	if cfg.SchemaVersion != 1 {
-		t.Fatalf("schemaVersion = %d, want 1", cfg.SchemaVersion)
+	// Version 1 files are read as version 2.
+	if cfg.SchemaVersion != SchemaVersion {
+		t.Fatalf("schemaVersion = %d, want %d", cfg.SchemaVersion, SchemaVersion)
 	}
 	if len(cfg.Teams) != 2 {
 		t.Fatalf("teams = %d, want 2", len(cfg.Teams))
@@ -151,3 +153,101 @@ func TestLoadRealConfigFile(t *testing.T) {
 		t.Fatalf("expected real config to have multiple teams, got %d", len(cfg.Teams))
 	}
 }
+
+const datedYAML = `
+schemaVersion: 2
+teams:
+  - slug: a
+    name: A
+    color: purple
+    members:
+      - alice
+      - {login: bob, left: 2025-03-01}
+  - slug: b
+    name: B
+    color: red
+    members:
+      - login: bob
+        joined: 2025-03-01
+`
+
+func TestLoadDatedMembers(t *testing.T) {
+	cfg, err := Load(writeYAML(t, datedYAML))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := strings.Join(cfg.MembersOf("a"), ","); got != "alice" {
+		t.Errorf("a members = %s", got)
+	}
+	if got := strings.Join(cfg.MembersOf("b"), ","); got != "bob" {
+		t.Errorf("b members = %s", got)
+	}
+	for day, want := range map[string]string{"2025-02-28": "a", "2025-03-01": "b", "2026-01-01": "b"} {
+		if got, _ := cfg.TeamOn("BOB", day); got != want {
+			t.Errorf("TeamOn(bob, %s) = %q, want %q", day, got, want)
+		}
+	}
+	if _, ok := cfg.TeamOn("carol", "2025-03-01"); ok {
+		t.Error("TeamOn should miss for a non-member")
+	}
+
+	// Undated members are written back as bare logins.
+	raw, err := Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(raw), "- alice\n") || !strings.Contains(string(raw), "login: bob") {
+		t.Errorf("Marshal = %s", raw)
+	}
+	again, err := Parse(raw)
+	if err != nil {
+		t.Fatalf("Parse(Marshal): %v", err)
+	}
+	if got, _ := again.TeamOn("bob", "2025-02-28"); got != "a" {
+		t.Errorf("round trip lost bob's dates: %+v", again.Teams)
+	}
+}
+
+func TestRejectOverlappingStints(t *testing.T) {
+	yaml := strings.Replace(datedYAML, "joined: 2025-03-01", "joined: 2025-02-01", 1)
+	_, err := Load(writeYAML(t, yaml))
+	if err == nil || !strings.Contains(err.Error(), "double-attributed") || !strings.Contains(err.Error(), "2025-02-01") {
+		t.Fatalf("want dated double-attribution error, got %v", err)
+	}
+}
+
+func TestRejectLeavingBeforeJoining(t *testing.T) {
+	yaml := `
+schemaVersion: 2
+teams:
+  - slug: a
+    name: A
+    color: purple
+    members:
+      - {login: alice, joined: 2025-03-01, left: 2025-02-01}
+`
+	_, err := Load(writeYAML(t, yaml))
+	if err == nil || !strings.Contains(err.Error(), "leaves before joining") {
+		t.Fatalf("want leaves-before-joining error, got %v", err)
+	}
+}
+
+func TestHeadcount(t *testing.T) {
+	cfg, err := Load(writeYAML(t, datedYAML))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	a, _ := cfg.FindBySlug("a")
+	b, _ := cfg.FindBySlug("b")
+	feb := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	apr := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	if got := a.Headcount(apr, apr.AddDate(0, 1, 0)); got != 1 {
+		t.Errorf("a headcount after bob left = %d, want 1", got)
+	}
+	if got := a.Headcount(feb, apr); got != 2 {
+		t.Errorf("a headcount around the move = %d, want 2", got)
+	}
+	if got := b.Headcount(time.Time{}, feb); got != 0 {
+		t.Errorf("b headcount before bob joined = %d, want 0", got)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/samouraiworld/topofgnomes/server/db"
+	"github.com/samouraiworld/topofgnomes/server/topics"
+)
+
+const topicsUsage = "usage: topics eval [-json] <labels.csv|labels.jsonl>"
+
+// runTopics implements the `topics` subcommand against the database
+// configured by DATABASE_URL / DATABASE_PATH and the taxonomy at
+// TOPICS_CONFIG_PATH:
+//
+//	topics eval [-json] FILE  score the classifier against labelled PRs
+func runTopics(out io.Writer, args []string) error {
+	if len(args) == 0 || args[0] != "eval" {
+		return errors.New(topicsUsage)
+	}
+	fs := flag.NewFlagSet("topics eval", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	if err := fs.Parse(args[1:]); err != nil || fs.NArg() != 1 {
+		return errors.New(topicsUsage)
+	}
+
+	path := os.Getenv("TOPICS_CONFIG_PATH")
+	if path == "" {
+		path = "config/topics.yaml"
+	}
+	cfg, err := topics.Load(path)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	dataset, err := topics.ReadExpectations(f, f.Name())
+	if err != nil {
+		return fmt.Errorf("read %s: %w", f.Name(), err)
+	}
+	conn, err := db.Open()
+	if err != nil {
+		return err
+	}
+	report, err := topics.Evaluate(conn, cfg, dataset)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	return writeEvalReport(out, report)
+}
+
+func writeEvalReport(out io.Writer, r *topics.EvalReport) error {
+	accuracy := 0.0
+	if r.Evaluated > 0 {
+		accuracy = 100 * float64(r.Correct) / float64(r.Evaluated)
+	}
+	fmt.Fprintf(out, "%d pull requests evaluated, %d classified right (%.1f%%)\n", r.Evaluated, r.Correct, accuracy)
+	if len(r.Missing) > 0 {
+		fmt.Fprintf(out, "%d not in the database: %s\n", len(r.Missing), strings.Join(r.Missing, ", "))
+	}
+
+	fmt.Fprintln(out)
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TOPIC\tEXPECTED\tPREDICTED\tCORRECT\tPRECISION\tRECALL")
+	var seen []string // topics in the dataset or the predictions
+	for _, s := range r.Topics {
+		if s.Expected == 0 && s.Predicted == 0 {
+			continue
+		}
+		seen = append(seen, s.Slug)
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%s\t%s\n", s.Slug, s.Expected, s.Predicted, s.Correct,
+			ratio(s.Precision, s.Predicted), ratio(s.Recall, s.Expected))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, "\nconfusion matrix (rows: main expected topic, columns: predicted)")
+	tw = tabwriter.NewWriter(out, 0, 0, 1, ' ', tabwriter.AlignRight)
+	fmt.Fprint(tw, "\t")
+	for _, slug := range seen {
+		fmt.Fprintf(tw, "%s\t", slug)
+	}
+	fmt.Fprintln(tw)
+	for _, expected := range seen {
+		row, ok := r.Confusion[expected]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t", expected)
+		for _, got := range seen {
+			fmt.Fprintf(tw, "%d\t", row[got])
+		}
+		fmt.Fprintln(tw)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(r.Unclassified) > 0 {
+		fmt.Fprintf(out, "\nclassified as %s:\n", topics.OtherSlug)
+		tw = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		for _, u := range r.Unclassified {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", u.PullRequestID, u.Repo, strings.Join(u.Expected, ";"), u.Title)
+		}
+		return tw.Flush()
+	}
+	return nil
+}
+
+// ratio formats a precision or recall, or "-" when it's undefined.
+func ratio(v float64, of int) string {
+	if of == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f", v)
+}
@@ -0,0 +1,149 @@
+package topics
+
+import (
+	"fmt"
+
+	"github.com/samouraiworld/topofgnomes/server/models"
+	"gorm.io/gorm"
+)
+
+// reclassifyBatch is how many pull requests Reclassify reads at a time.
+const reclassifyBatch = 1000
+
+// Rows returns matches as the pull_request_topics rows of a pull request.
+func Rows(prID string, matches []Match) []models.PullRequestTopic {
+	rows := make([]models.PullRequestTopic, len(matches))
+	for i, m := range matches {
+		rows[i] = models.PullRequestTopic{
+			PullRequestID: prID,
+			Topic:         m.Slug,
+			Rank:          i + 1,
+			Score:         m.Score,
+			Confidence:    m.Confidence,
+		}
+	}
+	return rows
+}
+
+// SaveMatches replaces the ranked topics stored for a pull request. It
+// leaves pull_requests.topic to the caller.
+func SaveMatches(tx *gorm.DB, prID string, matches []Match) error {
+	if err := tx.Where("pull_request_id = ?", prID).Delete(&models.PullRequestTopic{}).Error; err != nil {
+		return err
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+	return tx.Create(Rows(prID, matches)).Error
+}
+
+// SignalsOf returns what the classifier knows about a stored pull request,
+// given the paths of the files it changes.
+func SignalsOf(pr models.PullRequest, paths []string) Signals {
+	return Signals{Repo: pr.RepositoryID, Title: pr.Title, Paths: paths, Labels: pr.Labels}
+}
+
+// Reclassify ranks every pull request against cfg and, where the result
+// differs from what is stored, updates its topic (see
+// models.PullRequest.Topic) and its pull_request_topics rows. It returns
+// how many pull requests changed, on error those of the batches committed
+// before it. The syncer classifies the PRs it saves; this catches up the
+// others when topics.yaml changes. updated_at is left alone.
+func Reclassify(db *gorm.DB, cfg *Config) (int, error) {
+	changed := 0
+	after := ""
+	for {
+		var batch []models.PullRequest
+		err := db.Select("id, repository_id, title, topic, labels").
+			Where("id > ?", after).
+			Order("id").
+			Limit(reclassifyBatch).
+			Find(&batch).Error
+		if err != nil {
+			return changed, fmt.Errorf("reclassify: %w", err)
+		}
+		if len(batch) == 0 {
+			return changed, nil
+		}
+		ids := make([]string, len(batch))
+		for i, pr := range batch {
+			ids[i] = pr.ID
+		}
+		paths, stored, err := loadSignals(db, ids)
+		if err != nil {
+			return changed, fmt.Errorf("reclassify: %w", err)
+		}
+
+		moves := map[string][]string{}
+		var reranked []string
+		var rows []models.PullRequestTopic
+		n := 0
+		for _, pr := range batch {
+			matches := cfg.Rank(SignalsOf(pr, paths[pr.ID]))
+			topic := Primary(matches)
+			if topic != pr.Topic {
+				moves[topic] = append(moves[topic], pr.ID)
+			}
+			if want := Rows(pr.ID, matches); !sameRows(stored[pr.ID], want) {
+				reranked = append(reranked, pr.ID)
+				rows = append(rows, want...)
+			} else if topic == pr.Topic {
+				continue
+			}
+			n++
+		}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			for topic, ids := range moves {
+				if err := tx.Model(&models.PullRequest{}).Where("id IN ?", ids).UpdateColumn("topic", topic).Error; err != nil {
+					return err
+				}
+			}
+			if len(reranked) == 0 {
+				return nil
+			}
+			if err := tx.Where("pull_request_id IN ?", reranked).Delete(&models.PullRequestTopic{}).Error; err != nil {
+				return err
+			}
+			return tx.CreateInBatches(rows, 500).Error
+		})
+		if err != nil {
+			return changed, fmt.Errorf("reclassify: %w", err)
+		}
+		changed += n
+		after = batch[len(batch)-1].ID
+	}
+}
+
+// loadSignals reads the changed paths and the stored ranked topics of the
+// pull requests ids, by pull request.
+func loadSignals(db *gorm.DB, ids []string) (map[string][]string, map[string][]models.PullRequestTopic, error) {
+	var files []models.PullRequestFile
+	if err := db.Select("pull_request_id, path").Where("pull_request_id IN ?", ids).Find(&files).Error; err != nil {
+		return nil, nil, err
+	}
+	paths := map[string][]string{}
+	for _, f := range files {
+		paths[f.PullRequestID] = append(paths[f.PullRequestID], f.Path)
+	}
+	var rows []models.PullRequestTopic
+	if err := db.Where("pull_request_id IN ?", ids).Order("pull_request_id, rank").Find(&rows).Error; err != nil {
+		return nil, nil, err
+	}
+	stored := map[string][]models.PullRequestTopic{}
+	for _, row := range rows {
+		stored[row.PullRequestID] = append(stored[row.PullRequestID], row)
+	}
+	return paths, stored, nil
+}
+
+func sameRows(a, b []models.PullRequestTopic) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
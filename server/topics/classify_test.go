@@ -0,0 +1,126 @@
+package topics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/samouraiworld/topofgnomes/server/db/dbtest"
+	"github.com/samouraiworld/topofgnomes/server/models"
+)
+
+func TestReclassify(t *testing.T) {
+	db := dbtest.Open(t, &models.PullRequest{}, &models.PullRequestFile{}, &models.PullRequestTopic{})
+	updated := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	prs := []models.PullRequest{
+		{ID: "a", RepositoryID: "gnolang/adena-wallet", Title: "fix popup", UpdatedAt: updated},
+		{ID: "b", RepositoryID: "gnolang/gno", Title: "feat: indexer api", Topic: "indexer", UpdatedAt: updated},
+		{ID: "c", RepositoryID: "gnolang/gno", Title: "chore: bump", Topic: "wallet", UpdatedAt: updated},
+	}
+	if err := db.Create(&prs).Error; err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(writeYAML(t, validYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := Reclassify(db, cfg)
+	if err != nil {
+		t.Fatalf("Reclassify: %v", err)
+	}
+	// b keeps its topic but gets its ranked topics stored.
+	if n != 3 {
+		t.Errorf("changed %d, want 3", n)
+	}
+	want := map[string]string{"a": "wallet", "b": "indexer", "c": OtherSlug}
+	var got []models.PullRequest
+	db.Order("id").Find(&got)
+	for _, pr := range got {
+		if pr.Topic != want[pr.ID] {
+			t.Errorf("%s: topic = %q, want %q", pr.ID, pr.Topic, want[pr.ID])
+		}
+		if !pr.UpdatedAt.Equal(updated) {
+			t.Errorf("%s: updated_at moved to %v", pr.ID, pr.UpdatedAt)
+		}
+	}
+
+	var ranked []models.PullRequestTopic
+	db.Order("pull_request_id").Find(&ranked)
+	if len(ranked) != 2 || ranked[0].PullRequestID != "a" || ranked[0].Rank != 1 || ranked[0].Confidence != 1 {
+		t.Errorf("ranked topics = %+v", ranked)
+	}
+
+	if n, _ := Reclassify(db, cfg); n != 0 {
+		t.Errorf("second run changed %d", n)
+	}
+}
+
+func TestReclassifyCountsOnlyCommittedBatches(t *testing.T) {
+	db := dbtest.Open(t, &models.PullRequest{}, &models.PullRequestFile{}, &models.PullRequestTopic{})
+	prs := []models.PullRequest{
+		{ID: "a", RepositoryID: "gnolang/adena-wallet", Title: "fix popup"},
+		{ID: "c", RepositoryID: "gnolang/gno", Title: "chore: bump", Topic: "wallet"},
+	}
+	if err := db.Create(&prs).Error; err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(writeYAML(t, validYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	boom := errors.New("boom")
+	if err := db.Callback().Update().Before("gorm:update").Register("fail", func(tx *gorm.DB) { _ = tx.AddError(boom) }); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := Reclassify(db, cfg)
+	if !errors.Is(err, boom) {
+		t.Fatalf("Reclassify: %v, want %v", err, boom)
+	}
+	if n != 0 {
+		t.Errorf("changed %d, want 0: the batch was rolled back", n)
+	}
+}
+
+func TestReclassifyUsesFilesAndLabels(t *testing.T) {
+	db := dbtest.Open(t, &models.PullRequest{}, &models.PullRequestFile{}, &models.PullRequestTopic{})
+	prs := []models.PullRequest{
+		{ID: "a", RepositoryID: "gnolang/gno", Title: "fix(gnovm): typo", Topic: "gnovm"},
+		{ID: "b", RepositoryID: "gnolang/gno", Title: "chore: bump", Labels: []string{"Documentation"}},
+	}
+	if err := db.Create(&prs).Error; err != nil {
+		t.Fatal(err)
+	}
+	files := []models.PullRequestFile{
+		{PullRequestID: "a", Path: "docs/a.md"},
+		{PullRequestID: "a", Path: "docs/b.md"},
+		{PullRequestID: "a", Path: "docs/c.md"},
+		{PullRequestID: "a", Path: "docs/d.md"},
+		{PullRequestID: "a", Path: "gnovm/op.go"},
+	}
+	if err := db.Create(&files).Error; err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(writeYAML(t, weightedYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Reclassify(db, cfg); err != nil {
+		t.Fatalf("Reclassify: %v", err)
+	}
+	// a: docs' 4/5 + 4/5 from paths beat gnovm's 1 + 2*1/5.
+	var got []models.PullRequest
+	db.Order("id").Find(&got)
+	if got[0].Topic != "docs" || got[1].Topic != "docs" {
+		t.Errorf("topics = %q, %q, want docs, docs", got[0].Topic, got[1].Topic)
+	}
+	var ranked []models.PullRequestTopic
+	db.Where("pull_request_id = ?", "a").Order("rank").Find(&ranked)
+	if len(ranked) != 2 || ranked[1].Topic != "gnovm" {
+		t.Errorf("a ranked topics = %+v", ranked)
+	}
+}
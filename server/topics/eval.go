@@ -0,0 +1,243 @@
+package topics
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/samouraiworld/topofgnomes/server/models"
+	"gorm.io/gorm"
+)
+
+// Expectation is a pull request of a labelled dataset and the topics a
+// maintainer filed it under, the main one first.
+type Expectation struct {
+	PullRequestID string   `json:"id"`
+	Topics        []string `json:"topics"`
+}
+
+// ReadExpectations reads a labelled dataset, in CSV or JSONL depending on
+// the extension of name.
+//
+// CSV rows are a pull request id followed by its topics, either in further
+// columns or `;`-separated in one; a header row starting with `id` is
+// skipped. JSONL lines are objects like {"id": "PR_...", "topics": [...]},
+// where a single "topic" may stand for "topics".
+func ReadExpectations(r io.Reader, name string) ([]Expectation, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return readCSV(r)
+	case ".jsonl", ".ndjson":
+		return readJSONL(r)
+	default:
+		return nil, fmt.Errorf("%s: want a .csv or .jsonl file", name)
+	}
+}
+
+func readCSV(r io.Reader) ([]Expectation, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	var out []Expectation
+	for line := 1; ; line++ {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return out, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		id := strings.TrimSpace(rec[0])
+		if line == 1 && (strings.EqualFold(id, "id") || strings.EqualFold(id, "pr_id")) {
+			continue
+		}
+		e := Expectation{PullRequestID: id}
+		for _, field := range rec[1:] {
+			for _, slug := range strings.Split(field, ";") {
+				if slug = strings.TrimSpace(slug); slug != "" {
+					e.Topics = append(e.Topics, slug)
+				}
+			}
+		}
+		if err := e.check(); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		out = append(out, e)
+	}
+}
+
+func readJSONL(r io.Reader) ([]Expectation, error) {
+	var out []Expectation
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		if strings.TrimSpace(sc.Text()) == "" {
+			continue
+		}
+		var row struct {
+			Expectation
+			Topic string `json:"topic"`
+		}
+		if err := json.Unmarshal(sc.Bytes(), &row); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		e := row.Expectation
+		if row.Topic != "" {
+			e.Topics = append([]string{row.Topic}, e.Topics...)
+		}
+		if err := e.check(); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		out = append(out, e)
+	}
+	return out, sc.Err()
+}
+
+func (e Expectation) check() error {
+	if e.PullRequestID == "" {
+		return errors.New("missing pull request id")
+	}
+	if len(e.Topics) == 0 {
+		return fmt.Errorf("%s: no expected topic", e.PullRequestID)
+	}
+	return nil
+}
+
+// TopicScore is how well the classifier picks one topic.
+type TopicScore struct {
+	Slug string `json:"slug"`
+	// Expected counts the PRs labelled with the topic, Predicted those
+	// classified under it and Correct both.
+	Expected  int `json:"expected"`
+	Predicted int `json:"predicted"`
+	Correct   int `json:"correct"`
+	// Precision is Correct/Predicted and Recall Correct/Expected; both are
+	// 0 when there is nothing to divide by.
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+}
+
+// Unclassified is a labelled pull request the classifier put in OtherSlug.
+type Unclassified struct {
+	PullRequestID string   `json:"id"`
+	Repo          string   `json:"repo"`
+	Title         string   `json:"title"`
+	Expected      []string `json:"expected"`
+}
+
+// EvalReport compares the classifier to a labelled dataset.
+type EvalReport struct {
+	// Evaluated counts the labelled PRs found in the database, and Correct
+	// those classified under one of their expected topics.
+	Evaluated int `json:"evaluated"`
+	Correct   int `json:"correct"`
+	// Missing lists the labelled PR ids the database doesn't have.
+	Missing []string `json:"missing"`
+	// Topics follows topics.yaml, then OtherSlug.
+	Topics []TopicScore `json:"topics"`
+	// Confusion counts the PRs by main expected topic, then by predicted
+	// topic.
+	Confusion    map[string]map[string]int `json:"confusion"`
+	Unclassified []Unclassified            `json:"unclassified"`
+}
+
+// Evaluate classifies the stored pull requests of a labelled dataset with
+// cfg, as the syncer would, and scores each PR's topic against its expected
+// ones: it is right when it is any of them. Expected topics cfg doesn't
+// define are an error, as they are usually typos.
+func Evaluate(db *gorm.DB, cfg *Config, dataset []Expectation) (*EvalReport, error) {
+	report := &EvalReport{Missing: []string{}, Confusion: map[string]map[string]int{}, Unclassified: []Unclassified{}}
+	index := map[string]int{}
+	for _, t := range cfg.Topics {
+		index[t.Slug] = len(report.Topics)
+		report.Topics = append(report.Topics, TopicScore{Slug: t.Slug})
+	}
+	index[OtherSlug] = len(report.Topics)
+	report.Topics = append(report.Topics, TopicScore{Slug: OtherSlug})
+
+	ids := make([]string, len(dataset))
+	for i, e := range dataset {
+		for j, slug := range e.Topics {
+			if t, ok := cfg.FindBySlug(slug); ok {
+				e.Topics[j] = t.Slug
+			} else if slug != OtherSlug {
+				return nil, fmt.Errorf("%s: unknown topic %q", e.PullRequestID, slug)
+			}
+		}
+		ids[i] = e.PullRequestID
+	}
+
+	prs := map[string]models.PullRequest{}
+	paths := map[string][]string{}
+	for start := 0; start < len(ids); start += reclassifyBatch {
+		chunk := ids[start:min(start+reclassifyBatch, len(ids))]
+		var batch []models.PullRequest
+		if err := db.Select("id, repository_id, title, labels").Where("id IN ?", chunk).Find(&batch).Error; err != nil {
+			return nil, fmt.Errorf("evaluate: %w", err)
+		}
+		for _, pr := range batch {
+			prs[pr.ID] = pr
+		}
+		chunkPaths, _, err := loadSignals(db, chunk)
+		if err != nil {
+			return nil, fmt.Errorf("evaluate: %w", err)
+		}
+		for id, p := range chunkPaths {
+			paths[id] = p
+		}
+	}
+
+	for _, e := range dataset {
+		pr, ok := prs[e.PullRequestID]
+		if !ok {
+			report.Missing = append(report.Missing, e.PullRequestID)
+			continue
+		}
+		report.Evaluated++
+		got := Primary(cfg.Rank(SignalsOf(pr, paths[pr.ID])))
+		report.Topics[index[got]].Predicted++
+		right := false
+		seen := map[string]bool{}
+		for _, slug := range e.Topics {
+			if seen[slug] {
+				continue
+			}
+			seen[slug] = true
+			report.Topics[index[slug]].Expected++
+			if slug == got {
+				right = true
+				report.Topics[index[slug]].Correct++
+			}
+		}
+		if right {
+			report.Correct++
+		}
+		row := report.Confusion[e.Topics[0]]
+		if row == nil {
+			row = map[string]int{}
+			report.Confusion[e.Topics[0]] = row
+		}
+		row[got]++
+		if got == OtherSlug {
+			report.Unclassified = append(report.Unclassified, Unclassified{
+				PullRequestID: pr.ID, Repo: pr.RepositoryID, Title: pr.Title, Expected: e.Topics,
+			})
+		}
+	}
+	for i := range report.Topics {
+		s := &report.Topics[i]
+		if s.Predicted > 0 {
+			s.Precision = float64(s.Correct) / float64(s.Predicted)
+		}
+		if s.Expected > 0 {
+			s.Recall = float64(s.Correct) / float64(s.Expected)
+		}
+	}
+	sort.Strings(report.Missing)
+	return report, nil
+}
@@ -0,0 +1,92 @@
+package topics
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/samouraiworld/topofgnomes/server/db/dbtest"
+	"github.com/samouraiworld/topofgnomes/server/models"
+)
+
+func TestReadExpectations(t *testing.T) {
+	want := []Expectation{
+		{PullRequestID: "PR_a", Topics: []string{"wallet"}},
+		{PullRequestID: "PR_b", Topics: []string{"indexer", "wallet"}},
+	}
+	csv := "id,topics\nPR_a,wallet\nPR_b,indexer;wallet\n"
+	if got, err := ReadExpectations(strings.NewReader(csv), "labels.csv"); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("csv = %+v, %v", got, err)
+	}
+	columns := "PR_a,wallet\nPR_b, indexer, wallet\n"
+	if got, err := ReadExpectations(strings.NewReader(columns), "labels.csv"); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("csv columns = %+v, %v", got, err)
+	}
+	jsonl := `{"id": "PR_a", "topic": "wallet"}
+
+{"id": "PR_b", "topics": ["indexer", "wallet"]}
+`
+	if got, err := ReadExpectations(strings.NewReader(jsonl), "labels.jsonl"); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("jsonl = %+v, %v", got, err)
+	}
+
+	if _, err := ReadExpectations(strings.NewReader("PR_a\n"), "labels.csv"); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("want line 1 error, got %v", err)
+	}
+	if _, err := ReadExpectations(strings.NewReader(""), "labels.txt"); err == nil {
+		t.Error("want an error for an unknown extension")
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	db := dbtest.Open(t, &models.PullRequest{}, &models.PullRequestFile{}, &models.PullRequestTopic{})
+	prs := []models.PullRequest{
+		{ID: "a", RepositoryID: "onbloc/adena-wallet", Title: "fix popup"},
+		{ID: "b", RepositoryID: "gnolang/gno", Title: "feat: indexer api"},
+		{ID: "c", RepositoryID: "gnolang/gno", Title: "feat: wallet indexer"},
+		{ID: "d", RepositoryID: "gnolang/gno", Title: "chore: bump"},
+	}
+	if err := db.Create(&prs).Error; err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(writeYAML(t, validYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Evaluate(db, cfg, []Expectation{
+		{PullRequestID: "a", Topics: []string{"Wallet"}},
+		{PullRequestID: "b", Topics: []string{"indexer"}},
+		{PullRequestID: "c", Topics: []string{"indexer", "wallet"}}, // wallet by the tie
+		{PullRequestID: "d", Topics: []string{"indexer"}},
+		{PullRequestID: "gone", Topics: []string{"wallet"}},
+	})
+	if err != nil {
+		t.Fatalf("Evaluate: %v", err)
+	}
+	if report.Evaluated != 4 || report.Correct != 3 || !reflect.DeepEqual(report.Missing, []string{"gone"}) {
+		t.Errorf("evaluated %d, correct %d, missing %v", report.Evaluated, report.Correct, report.Missing)
+	}
+	want := []TopicScore{
+		{Slug: "wallet", Expected: 2, Predicted: 2, Correct: 2, Precision: 1, Recall: 1},
+		{Slug: "indexer", Expected: 3, Predicted: 1, Correct: 1, Precision: 1, Recall: 1.0 / 3},
+		{Slug: OtherSlug, Predicted: 1},
+	}
+	if !reflect.DeepEqual(report.Topics, want) {
+		t.Errorf("topics = %+v, want %+v", report.Topics, want)
+	}
+	confusion := map[string]map[string]int{
+		"wallet":  {"wallet": 1},
+		"indexer": {"indexer": 1, "wallet": 1, OtherSlug: 1},
+	}
+	if !reflect.DeepEqual(report.Confusion, confusion) {
+		t.Errorf("confusion = %v, want %v", report.Confusion, confusion)
+	}
+	if len(report.Unclassified) != 1 || report.Unclassified[0].PullRequestID != "d" {
+		t.Errorf("unclassified = %+v", report.Unclassified)
+	}
+
+	if _, err := Evaluate(db, cfg, []Expectation{{PullRequestID: "a", Topics: []string{"walet"}}}); err == nil || !strings.Contains(err.Error(), "walet") {
+		t.Errorf("want unknown-topic error, got %v", err)
+	}
+}
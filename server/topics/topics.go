@@ -11,14 +11,17 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
-// SchemaVersion is the YAML shape this loader understands.
-const SchemaVersion = 1
+// SchemaVersion is the YAML shape this loader writes. Version 1 files
+// (patterns as plain strings, no paths or labels) are still accepted and
+// keep their first-match ordering; see Rank.
+const SchemaVersion = 2
 
 // OtherSlug is the fallback bucket the classifier returns when no rule
 // matches. It's reserved at the YAML level — config cannot declare a topic
@@ -27,12 +30,60 @@ const SchemaVersion = 1
 const OtherSlug = "other"
 
 type Topic struct {
-	Slug     string   `yaml:"slug"     json:"slug"`
-	Label    string   `yaml:"label"    json:"label"`
-	Patterns []string `yaml:"patterns" json:"patterns"`
+	Slug  string `yaml:"slug"  json:"slug"`
+	Label string `yaml:"label" json:"label"`
+	// Patterns are RE2 regexes over the lowercased `${repo} ${title}`.
+	Patterns []Rule `yaml:"patterns,omitempty" json:"patterns"`
+	// Paths are globs over the files a PR changes: `*` stays within a
+	// directory, `**` crosses them.
+	Paths []Rule `yaml:"paths,omitempty" json:"paths"`
+	// Labels are GitHub label names, compared case-insensitively.
+	Labels []Rule `yaml:"labels,omitempty" json:"labels"`
 }
 
-// Config is the parsed topics.yaml plus compiled regexes and the file mtime
+// Rule is one classifier rule and what a match adds to its topic's score.
+// In topics.yaml a rule of weight 1 may be written as the bare string.
+type Rule struct {
+	Match  string  `yaml:"match"  json:"match"`
+	Weight float64 `yaml:"weight" json:"weight"`
+}
+
+func (r *Rule) UnmarshalYAML(n *yaml.Node) error {
+	*r = Rule{Weight: 1}
+	if n.Kind == yaml.ScalarNode {
+		return n.Decode(&r.Match)
+	}
+	type plain Rule
+	return n.Decode((*plain)(r))
+}
+
+func (r Rule) MarshalYAML() (any, error) {
+	if r.Weight == 1 {
+		return r.Match, nil
+	}
+	type plain Rule
+	return plain(r), nil
+}
+
+// Signals is what the classifier knows about a pull request.
+type Signals struct {
+	Repo   string   `json:"repo"`
+	Title  string   `json:"title"`
+	Paths  []string `json:"paths,omitempty"`
+	Labels []string `json:"labels,omitempty"`
+}
+
+// Match is a topic a pull request is classified under. Score sums the
+// weights of the topic's matching rules, path rules counting for the share
+// of changed files they match; Confidence is the topic's share of the
+// scores of all matches.
+type Match struct {
+	Slug       string  `json:"slug"`
+	Score      float64 `json:"score"`
+	Confidence float64 `json:"confidence"`
+}
+
+// Config is the parsed topics.yaml plus compiled rules and the file mtime
 // (surfaced to the frontend as `lastSyncedAt` so the cache key in
 // `useGnoloveTopics` bumps when ops re-deploys the taxonomy).
 type Config struct {
@@ -40,10 +91,28 @@ type Config struct {
 	Topics        []Topic   `yaml:"topics"        json:"topics"`
 	LastSyncedAt  time.Time `yaml:"-"             json:"lastSyncedAt"`
 
-	// compiled[i] is the regex set for Topics[i], in declaration order.
-	// Kept off the wire — clients receive raw pattern strings and compile
-	// them in JS (or use them as-is in their own classifier).
-	compiled [][]*regexp.Regexp
+	// compiled[i] holds the rules of Topics[i]. Kept off the wire —
+	// clients receive the raw rules.
+	compiled []compiledTopic
+	// firstMatch is set for version 1 files, which rank matches in
+	// topics.yaml order instead of by score.
+	firstMatch bool
+}
+
+type compiledTopic struct {
+	patterns []*regexp.Regexp
+	paths    []*regexp.Regexp
+	labels   []string // lowercased
+}
+
+// Provider returns the taxonomy to use for one request. main backs it with
+// a configwatch.Watcher so edits to topics.yaml apply without a restart;
+// callers must not hold on to the result across requests.
+type Provider func() *Config
+
+// Static is a Provider that always returns cfg.
+func Static(cfg *Config) Provider {
+	return func() *Config { return cfg }
 }
 
 func Load(path string) (*Config, error) {
@@ -59,7 +128,17 @@ func Load(path string) (*Config, error) {
 	if err := yaml.Unmarshal(raw, &cfg); err != nil {
 		return nil, fmt.Errorf("parse %s: %w", path, err)
 	}
-	if cfg.SchemaVersion != SchemaVersion {
+	switch cfg.SchemaVersion {
+	case 1:
+		for _, t := range cfg.Topics {
+			if len(t.Paths) > 0 || len(t.Labels) > 0 {
+				return nil, fmt.Errorf("topic %q: paths and labels need schemaVersion %d", t.Slug, SchemaVersion)
+			}
+		}
+		cfg.SchemaVersion = SchemaVersion
+		cfg.firstMatch = true
+	case SchemaVersion:
+	default:
 		return nil, fmt.Errorf("schemaVersion = %d, want %d", cfg.SchemaVersion, SchemaVersion)
 	}
 	compiled, err := validate(cfg.Topics)
@@ -82,29 +161,80 @@ func (c *Config) FindBySlug(slug string) (Topic, bool) {
 	return Topic{}, false
 }
 
-// Classify returns the slug of the first matching topic for the given
-// `(repo, title)` pair, or [OtherSlug] when none match. Matching is done
-// against a lowercased `${repo} ${title}` haystack — keep YAML patterns
-// lowercase (or case-fold via `(?i)`) accordingly.
+// Classify returns the slug of the best-ranked topic for the given
+// `(repo, title)` pair, or [OtherSlug] when none match; see Rank.
 func (c *Config) Classify(repo, title string) string {
-	haystack := strings.ToLower(repo + " " + title)
+	return Primary(c.Rank(Signals{Repo: repo, Title: title}))
+}
+
+// Rank returns the topics whose rules match s, best first. Patterns are
+// matched against a lowercased `${repo} ${title}` haystack — keep YAML
+// patterns lowercase (or case-fold via `(?i)`) accordingly. Equal scores
+// keep the topics.yaml order, so earlier topics win ties. Taxonomies loaded
+// from a version 1 file keep every match in topics.yaml order, so the first
+// topic with a matching pattern stays the primary one as before scoring.
+func (c *Config) Rank(s Signals) []Match {
+	haystack := strings.ToLower(s.Repo + " " + s.Title)
+	labels := make(map[string]bool, len(s.Labels))
+	for _, l := range s.Labels {
+		labels[strings.ToLower(l)] = true
+	}
+	var out []Match
+	total := 0.0
 	for i, t := range c.Topics {
-		for _, re := range c.compiled[i] {
+		ct := c.compiled[i]
+		score := 0.0
+		for j, re := range ct.patterns {
 			if re.MatchString(haystack) {
-				return t.Slug
+				score += t.Patterns[j].Weight
 			}
 		}
+		if len(s.Paths) > 0 {
+			for j, re := range ct.paths {
+				n := 0
+				for _, p := range s.Paths {
+					if re.MatchString(p) {
+						n++
+					}
+				}
+				score += t.Paths[j].Weight * float64(n) / float64(len(s.Paths))
+			}
+		}
+		for j, l := range ct.labels {
+			if labels[l] {
+				score += t.Labels[j].Weight
+			}
+		}
+		if score > 0 {
+			out = append(out, Match{Slug: t.Slug, Score: score})
+			total += score
+		}
+	}
+	if !c.firstMatch {
+		sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	}
+	for i := range out {
+		out[i].Confidence = out[i].Score / total
 	}
-	return OtherSlug
+	return out
 }
 
-func validate(topics []Topic) ([][]*regexp.Regexp, error) {
+// Primary returns the slug of the best match, or [OtherSlug] when there is
+// none.
+func Primary(matches []Match) string {
+	if len(matches) == 0 {
+		return OtherSlug
+	}
+	return matches[0].Slug
+}
+
+func validate(topics []Topic) ([]compiledTopic, error) {
 	if len(topics) == 0 {
 		return nil, fmt.Errorf("topics: empty taxonomy")
 	}
 	seenSlug := map[string]string{}  // lower -> original
 	seenLabel := map[string]string{} // lower -> original
-	compiled := make([][]*regexp.Regexp, len(topics))
+	compiled := make([]compiledTopic, len(topics))
 	for i, t := range topics {
 		if err := checkWhitespace("slug", t.Slug); err != nil {
 			return nil, err
@@ -133,24 +263,72 @@ func validate(topics []Topic) ([][]*regexp.Regexp, error) {
 		}
 		seenLabel[labelKey] = t.Label
 
-		if len(t.Patterns) == 0 {
-			return nil, fmt.Errorf("topic %q: patterns must be non-empty", t.Slug)
+		if len(t.Patterns)+len(t.Paths)+len(t.Labels) == 0 {
+			return nil, fmt.Errorf("topic %q: patterns, paths or labels must be non-empty", t.Slug)
 		}
-		compiled[i] = make([]*regexp.Regexp, len(t.Patterns))
+		var ct compiledTopic
 		for j, p := range t.Patterns {
-			if p == "" {
-				return nil, fmt.Errorf("topic %q: empty pattern at index %d", t.Slug, j)
+			if err := checkRule(t.Slug, "pattern", j, p); err != nil {
+				return nil, err
 			}
-			re, err := regexp.Compile(p)
+			re, err := regexp.Compile(p.Match)
 			if err != nil {
-				return nil, fmt.Errorf("topic %q: invalid regex %q: %w", t.Slug, p, err)
+				return nil, fmt.Errorf("topic %q: invalid regex %q: %w", t.Slug, p.Match, err)
+			}
+			ct.patterns = append(ct.patterns, re)
+		}
+		for j, p := range t.Paths {
+			if err := checkRule(t.Slug, "path", j, p); err != nil {
+				return nil, err
 			}
-			compiled[i][j] = re
+			ct.paths = append(ct.paths, globRegexp(p.Match))
 		}
+		for j, l := range t.Labels {
+			if err := checkRule(t.Slug, "label", j, l); err != nil {
+				return nil, err
+			}
+			ct.labels = append(ct.labels, strings.ToLower(l.Match))
+		}
+		compiled[i] = ct
 	}
 	return compiled, nil
 }
 
+func checkRule(slug, kind string, i int, r Rule) error {
+	if r.Match == "" {
+		return fmt.Errorf("topic %q: empty %s at index %d", slug, kind, i)
+	}
+	if r.Weight <= 0 {
+		return fmt.Errorf("topic %q: %s %q: weight must be positive", slug, kind, r.Match)
+	}
+	return nil
+}
+
+// globRegexp compiles a path glob: `**` matches across directories (and
+// `**/` none at all), `*` and `?` within one.
+func globRegexp(glob string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case glob[i] == '*':
+			b.WriteString("[^/]*")
+		case glob[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
 func checkWhitespace(field, val string) error {
 	if val != strings.TrimSpace(val) {
 		return fmt.Errorf("%s %q has leading/trailing whitespace", field, val)
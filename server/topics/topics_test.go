@@ -1,6 +1,7 @@
 package topics
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -33,8 +34,12 @@ func TestLoadValidYAML(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Load: %v", err)
 	}
-	if cfg.SchemaVersion != 1 {
-		t.Fatalf("schemaVersion = %d, want 1", cfg.SchemaVersion)
+	// Version 1 files load as version 2 without paths or labels.
+	if cfg.SchemaVersion != SchemaVersion {
+		t.Fatalf("schemaVersion = %d, want %d", cfg.SchemaVersion, SchemaVersion)
+	}
+	if p := cfg.Topics[0].Patterns[1]; p.Match != `\bwallet\b` || p.Weight != 1 {
+		t.Fatalf("patterns[1] = %+v, want \\bwallet\\b of weight 1", p)
 	}
 	if len(cfg.Topics) != 2 {
 		t.Fatalf("topics = %d, want 2", len(cfg.Topics))
@@ -128,7 +133,7 @@ topics:
 `
 	_, err := Load(writeYAML(t, yaml))
 	if err == nil || !strings.Contains(err.Error(), "patterns") {
-		t.Fatalf("want empty-patterns error, got %v", err)
+		t.Fatalf("want empty-rules error, got %v", err)
 	}
 }
 
@@ -194,15 +199,144 @@ func TestClassifyIsCaseInsensitive(t *testing.T) {
 	}
 }
 
-func TestClassifyFirstMatchWins(t *testing.T) {
-	// wallet rule comes before indexer; a hit on both wins for wallet.
+func TestClassifyTieGoesToEarlierTopic(t *testing.T) {
+	// wallet comes before indexer; one hit on each wins for wallet.
 	cfg, err := Load(writeYAML(t, validYAML))
 	if err != nil {
 		t.Fatalf("Load: %v", err)
 	}
-	got := cfg.Classify("onbloc/adena-wallet", "feat: indexer rewrite")
+	got := cfg.Classify("some/repo", "feat: wallet indexer")
 	if got != "wallet" {
-		t.Errorf("Classify first-match = %q, want wallet", got)
+		t.Errorf("Classify tie = %q, want wallet", got)
+	}
+}
+
+const weightedYAML = `
+schemaVersion: 2
+topics:
+  - slug: gnovm
+    label: Gno VM
+    patterns: ['\(gnovm\)']
+    paths: [{match: 'gnovm/**', weight: 2}]
+  - slug: consensus
+    label: Consensus
+    patterns: [{match: '\bconsensus\b', weight: 3}]
+    paths: ['tm2/pkg/bft/**']
+  - slug: docs
+    label: Docs
+    paths: ['docs/**', '**/*.md']
+    labels: ['Documentation']
+`
+
+func TestRankScoresWeightedRules(t *testing.T) {
+	cfg, err := Load(writeYAML(t, weightedYAML))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	got := cfg.Rank(Signals{
+		Repo:  "gnolang/gno",
+		Title: "fix(gnovm): consensus doc",
+		Paths: []string{
+			"gnovm/pkg/gnolang/op_call.go",
+			"gnovm/README.md",
+			"tm2/pkg/bft/consensus/state.go",
+			"README.md",
+		},
+		Labels: []string{"documentation"},
+	})
+	// gnovm: pattern 1 + 2*2/4 paths; consensus: pattern 3 + 1*1/4 paths;
+	// docs: 1*0/4 + 1*2/4 paths + label 1.
+	want := []Match{
+		{Slug: "consensus", Score: 3.25},
+		{Slug: "gnovm", Score: 2},
+		{Slug: "docs", Score: 1.5},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Rank = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i].Slug != want[i].Slug || got[i].Score != want[i].Score {
+			t.Errorf("Rank[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+		if c := want[i].Score / 6.75; got[i].Confidence != c {
+			t.Errorf("Rank[%d].Confidence = %v, want %v", i, got[i].Confidence, c)
+		}
+	}
+	if Primary(got) != "consensus" {
+		t.Errorf("Primary = %q, want consensus", Primary(got))
+	}
+	if m := cfg.Rank(Signals{Repo: "x/y", Title: "chore"}); len(m) != 0 || Primary(m) != OtherSlug {
+		t.Errorf("Rank unmatched = %+v, want none", m)
+	}
+}
+
+func TestPathGlobs(t *testing.T) {
+	for _, tc := range []struct {
+		glob, path string
+		want       bool
+	}{
+		{"gnovm/**", "gnovm/pkg/gnolang/op_call.go", true},
+		{"gnovm/**", "gnovm2/main.go", false},
+		{"gnovm/*", "gnovm/Makefile", true},
+		{"gnovm/*", "gnovm/pkg/doc.go", false},
+		{"**/*.md", "README.md", true},
+		{"**/*.md", "docs/a/b.md", true},
+		{"**/*_test.go", "tm2/pkg/amino/amino_test.go", true},
+		{"?.go", "a.go", true},
+		{"?.go", "ab.go", false},
+		{"gno.land/**", "gnoXland/main.go", false},
+	} {
+		if got := globRegexp(tc.glob).MatchString(tc.path); got != tc.want {
+			t.Errorf("%q matches %q = %v, want %v", tc.glob, tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestSchemaVersion1KeepsFirstMatch(t *testing.T) {
+	body := `
+schemaVersion: %d
+topics:
+  - {slug: gnovm, label: GnoVM, patterns: ['gnoland/gno']}
+  - {slug: consensus, label: Consensus, patterns: ['consensus', 'core']}
+`
+	for _, tc := range []struct {
+		version int
+		want    string
+	}{
+		{1, "gnovm"},
+		{2, "consensus"},
+	} {
+		cfg, err := Load(writeYAML(t, fmt.Sprintf(body, tc.version)))
+		if err != nil {
+			t.Fatalf("Load v%d: %v", tc.version, err)
+		}
+		if got := cfg.Classify("gnoland/gno", "feat: core consensus tweak"); got != tc.want {
+			t.Errorf("v%d Classify = %q, want %q", tc.version, got, tc.want)
+		}
+	}
+}
+
+func TestRejectPathsInSchemaVersion1(t *testing.T) {
+	yaml := `
+schemaVersion: 1
+topics:
+  - {slug: a, label: A, patterns: ['x'], paths: ['a/**']}
+`
+	_, err := Load(writeYAML(t, yaml))
+	if err == nil || !strings.Contains(err.Error(), "schemaVersion") {
+		t.Fatalf("want schemaVersion error, got %v", err)
+	}
+}
+
+func TestRejectNonPositiveWeight(t *testing.T) {
+	yaml := `
+schemaVersion: 2
+topics:
+  - {slug: a, label: A, labels: [{match: bug, weight: 0}]}
+`
+	_, err := Load(writeYAML(t, yaml))
+	if err == nil || !strings.Contains(err.Error(), "weight") {
+		t.Fatalf("want weight error, got %v", err)
 	}
 }
 
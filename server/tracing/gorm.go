@@ -0,0 +1,84 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// GormPlugin traces GORM statements. A span is only opened when the
+// statement's context (db.WithContext) already carries a span: the syncers
+// issue thousands of upserts per cycle, and tracing those as root spans
+// would drown the request traces they are meant to explain. Handlers query
+// through db.WithContext(r.Context()) so their statements join the request
+// span opened by Middleware.
+type GormPlugin struct{}
+
+func (GormPlugin) Name() string { return "tracing" }
+
+type parentCtxKey struct{}
+
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("*").Register("tracing:before_create", before("gorm.create")),
+		cb.Create().After("*").Register("tracing:after_create", after),
+		cb.Query().Before("*").Register("tracing:before_query", before("gorm.query")),
+		cb.Query().After("*").Register("tracing:after_query", after),
+		cb.Update().Before("*").Register("tracing:before_update", before("gorm.update")),
+		cb.Update().After("*").Register("tracing:after_update", after),
+		cb.Delete().Before("*").Register("tracing:before_delete", before("gorm.delete")),
+		cb.Delete().After("*").Register("tracing:after_delete", after),
+		cb.Row().Before("*").Register("tracing:before_row", before("gorm.row")),
+		cb.Row().After("*").Register("tracing:after_row", after),
+		cb.Raw().Before("*").Register("tracing:before_raw", before("gorm.raw")),
+		cb.Raw().After("*").Register("tracing:after_raw", after),
+	)
+}
+
+func before(name string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		parent := tx.Statement.Context
+		if parent == nil || tx.DryRun || !trace.SpanContextFromContext(parent).IsValid() {
+			return
+		}
+		ctx, _ := Start(parent, name, dbSystem(tx))
+		tx.Statement.Context = context.WithValue(ctx, parentCtxKey{}, parent)
+	}
+}
+
+func dbSystem(tx *gorm.DB) attribute.KeyValue {
+	if tx.Dialector.Name() == "postgres" {
+		return semconv.DBSystemPostgreSQL
+	}
+	return semconv.DBSystemSqlite
+}
+
+func after(tx *gorm.DB) {
+	if tx.Statement.Context == nil {
+		return
+	}
+	parent, ok := tx.Statement.Context.Value(parentCtxKey{}).(context.Context)
+	if !ok {
+		return
+	}
+	span := trace.SpanFromContext(tx.Statement.Context)
+	tx.Statement.Context = parent
+
+	span.SetAttributes(
+		semconv.DBQueryText(tx.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", tx.Statement.RowsAffected),
+	)
+	if tx.Statement.Table != "" {
+		span.SetAttributes(semconv.DBCollectionName(tx.Statement.Table))
+	}
+	err := tx.Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil
+	}
+	End(span, err)
+}
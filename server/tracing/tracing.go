@@ -0,0 +1,91 @@
+// Package tracing wires OpenTelemetry tracing through the server: HTTP
+// handlers (Middleware), outgoing HTTP calls such as GitHub GraphQL queries
+// (Transport), GORM statements (GormPlugin) and hand-made spans around gno
+// RPC and LLM calls (Start/End).
+//
+// Spans are exported over OTLP/HTTP when OTEL_EXPORTER_OTLP_ENDPOINT (or
+// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT) is set; the exporter reads the rest of
+// the standard OTEL_* variables itself. Without an endpoint the global
+// provider stays the no-op one and every helper here costs next to nothing.
+// Tests install an in-memory exporter with Install.
+package tracing
+
+import (
+	"context"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const instrumentationName = "github.com/samouraiworld/topofgnomes/server"
+
+const serviceName = "gnolove-server"
+
+// Init installs the OTLP exporter when one is configured and returns the
+// function that flushes and stops it on shutdown.
+func Init(ctx context.Context) (shutdown func(context.Context) error, err error) {
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the defaults.
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(serviceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	tp := Install(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	return tp.Shutdown, nil
+}
+
+// Install makes a tracer provider built from opts the global one, with W3C
+// trace context propagation. Tests pass sdktrace.WithSyncer and an
+// in-memory exporter (tracetest.NewInMemoryExporter).
+func Install(opts ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	tp := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return tp
+}
+
+// Start opens a span named name as a child of whatever span ctx carries.
+// The tracer is looked up on every call, so spans follow a provider
+// installed after the caller was initialised.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err, if any, on span and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Logger returns l annotated with the trace and span IDs of ctx, so log
+// lines can be matched with their trace. l is returned as is outside a
+// trace.
+func Logger(ctx context.Context, l *zap.SugaredLogger) *zap.SugaredLogger {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return l
+	}
+	return l.With("trace_id", sc.TraceID().String(), "span_id", sc.SpanID().String())
+}
@@ -0,0 +1,169 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func newExporter(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exp := tracetest.NewInMemoryExporter()
+	tp := Install(sdktrace.WithSyncer(exp))
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+	return exp
+}
+
+func attr(s tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, kv := range s.Attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestMiddlewareNamesSpanAfterRoute(t *testing.T) {
+	exp := newExporter(t)
+
+	router := chi.NewRouter()
+	router.Use(Middleware)
+	router.Get("/contributors/{login}", func(w http.ResponseWriter, r *http.Request) {
+		_, span := Start(r.Context(), "child")
+		span.End()
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/contributors/alice", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exp.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	child, server := spans[0], spans[1]
+	if server.Name != "GET /contributors/{login}" {
+		t.Errorf("server span name = %q", server.Name)
+	}
+	if got := server.SpanContext.TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("incoming trace not continued: trace id %s", got)
+	}
+	if child.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Error("handler span is not a child of the request span")
+	}
+	if got := attr(server, "http.response.status_code").AsInt64(); got != 500 {
+		t.Errorf("status attribute = %d", got)
+	}
+	if server.Status.Code != codes.Error {
+		t.Errorf("5xx should mark the span as failed, got %v", server.Status.Code)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	exp := newExporter(t)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("traceparent") != "" {
+			t.Error("trace headers must not leak to third-party APIs")
+		}
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer upstream.Close()
+
+	client := &http.Client{Transport: Transport("github.graphql", nil)}
+	resp, err := client.Post(upstream.URL+"/graphql", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	spans := exp.GetSpans()
+	if len(spans) != 1 || spans[0].Name != "github.graphql" {
+		t.Fatalf("spans = %+v", spans)
+	}
+	if spans[0].Status.Code != codes.Error {
+		t.Error("4xx response should mark the span as failed")
+	}
+}
+
+func TestGormPlugin(t *testing.T) {
+	exp := newExporter(t)
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: gormlogger.Default.LogMode(gormlogger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(GormPlugin{}); err != nil {
+		t.Fatal(err)
+	}
+	type thing struct {
+		ID   uint
+		Name string
+	}
+	if err := db.AutoMigrate(&thing{}); err != nil {
+		t.Fatal(err)
+	}
+	db.Create(&thing{Name: "untraced"})
+	if n := len(exp.GetSpans()); n != 0 {
+		t.Fatalf("statements outside a trace produced %d spans", n)
+	}
+
+	ctx, parent := Start(context.Background(), "request")
+	var got thing
+	if err := db.WithContext(ctx).First(&got, "name = ?", "untraced").Error; err != nil {
+		t.Fatal(err)
+	}
+	err = db.WithContext(ctx).First(&got, "name = ?", "missing").Error
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("err = %v", err)
+	}
+	parent.End()
+
+	spans := exp.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("got %d spans, want 2 queries and the parent", len(spans))
+	}
+	for _, s := range spans[:2] {
+		if s.Name != "gorm.query" || s.Parent.SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("span %q has parent %s", s.Name, s.Parent.SpanID())
+		}
+		if q := attr(s, "db.query.text").AsString(); !strings.Contains(q, "SELECT") {
+			t.Errorf("db.query.text = %q", q)
+		}
+		if s.Status.Code == codes.Error {
+			t.Error("a missing record is not a failed query")
+		}
+	}
+}
+
+func TestLogger(t *testing.T) {
+	newExporter(t)
+	core, logs := observer.New(zap.InfoLevel)
+	base := zap.New(core).Sugar()
+
+	Logger(context.Background(), base).Info("outside")
+	ctx, span := Start(context.Background(), "op")
+	Logger(ctx, base).Info("inside")
+	span.End()
+
+	entries := logs.All()
+	if _, ok := entries[0].ContextMap()["trace_id"]; ok {
+		t.Error("no trace_id expected outside a span")
+	}
+	if got := entries[1].ContextMap()["trace_id"]; got != span.SpanContext().TraceID().String() {
+		t.Errorf("trace_id = %v", got)
+	}
+}
@@ -253,10 +253,13 @@ export const getNamespacesByUser = async (address: string) => {
   return NamespacesSchema.parse(data);
 };
 
-// On-chain proposals
-export const getProposals = async (address?: string) => {
+// On-chain proposals. The list leaves file bodies out unless `fields` asks for them.
+const PROPOSAL_FIELDS_WITH_FILES = 'id,title,description,address,path,blockHeight,files,votes,executionHeight,status';
+
+export const getProposals = async (address?: string, withFiles = false) => {
   const url = new URL('/onchain/proposals', ENV.NEXT_PUBLIC_API_URL);
   if (address) url.searchParams.set('address', address);
+  if (withFiles) url.searchParams.set('fields', PROPOSAL_FIELDS_WITH_FILES);
 
   const data = await fetchJson(url.toString(), { next: { revalidate: REVALIDATE_SECONDS.ONCHAIN_300 } });
 
@@ -274,7 +277,7 @@ export const getGovdaoMembers = async () => {
 
 export const getProposalsByUser = async (address: string) => {
   if (!address) return [];
-  return getProposals(address);
+  return getProposals(address, true);
 };
 
 export const getProposal = async (id: string) => {